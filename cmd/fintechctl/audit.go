@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"backend_path/internal/audit"
+	"backend_path/internal/config"
+	"backend_path/pkg/database"
+)
+
+func runAudit(cfg *config.Config, args []string) error {
+	usage := func() {
+		fmt.Fprintln(os.Stderr, "Usage: fintechctl audit <verify|checkpoint>")
+	}
+
+	if len(args) == 0 {
+		usage()
+		return errors.New("missing subcommand")
+	}
+
+	var run func(ctx context.Context, service audit.AuditService) error
+	switch args[0] {
+	case "verify":
+		run = verifyAudit
+	case "checkpoint":
+		run = checkpointAudit
+	default:
+		usage()
+		return fmt.Errorf("unknown subcommand %q", args[0])
+	}
+
+	db, err := database.NewConnection(cfg.DatabaseURL)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return run(ctx, audit.NewService(db.DB, audit.NewSQLRepository(db.DB), cfg.AuditSigningKey))
+}
+
+// verifyAudit walks the audit chain and fails on the first broken link
+func verifyAudit(ctx context.Context, service audit.AuditService) error {
+	result, err := service.Verify(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Verified %d chained entries and %d checkpoints up to entry %d\n", result.Checked, result.Checkpoints, result.LastLogID)
+	if result.Unchained > 0 {
+		fmt.Printf("Skipped %d entries written before the chain was introduced\n", result.Unchained)
+	}
+
+	if link := result.BrokenLink; link != nil {
+		fmt.Printf("Broken link at entry %d: %s\n", link.LogID, link.Reason)
+		if link.CheckpointID != 0 {
+			fmt.Printf("  checkpoint: %d\n", link.CheckpointID)
+		}
+		if link.Expected != "" || link.Actual != "" {
+			fmt.Printf("  expected:   %s\n  actual:     %s\n", link.Expected, link.Actual)
+		}
+		return errors.New("audit chain is broken")
+	}
+
+	fmt.Println("Audit chain is intact")
+	return nil
+}
+
+// checkpointAudit signs the current head of the audit chain
+func checkpointAudit(ctx context.Context, service audit.AuditService) error {
+	checkpoint, err := service.Checkpoint(ctx)
+	if err != nil {
+		return err
+	}
+
+	if checkpoint == nil {
+		fmt.Println("Nothing recorded since the last checkpoint")
+		return nil
+	}
+
+	fmt.Printf("Created checkpoint %d at entry %d\n", checkpoint.ID, checkpoint.LastLogID)
+	return nil
+}
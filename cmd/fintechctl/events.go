@@ -0,0 +1,287 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"backend_path/internal/balance"
+	"backend_path/internal/config"
+	"backend_path/internal/events"
+	"backend_path/internal/webhook"
+	"backend_path/pkg/database"
+	pkgredis "backend_path/pkg/redis"
+)
+
+// replayHandler is an event handler events can be replayed into
+type replayHandler struct {
+	name        string
+	description string
+	handler     events.EventHandler
+	// rebuild discards the handler's read model and rebuilds it from every
+	// event in the store; nil if the handler has no read model
+	rebuild func(ctx context.Context) (int, error)
+}
+
+func runEvents(cfg *config.Config, args []string) error {
+	usage := func() {
+		fmt.Fprintln(os.Stderr, "Usage: fintechctl events <replay|handlers> [flags]")
+	}
+
+	if len(args) == 0 {
+		usage()
+		return errors.New("missing subcommand")
+	}
+
+	switch args[0] {
+	case "replay":
+		return runEventsReplay(cfg, args[1:])
+	case "handlers":
+		for _, h := range handlerDescriptions() {
+			fmt.Printf("  %-10s %s\n", h.name, h.description)
+		}
+		return nil
+	default:
+		usage()
+		return fmt.Errorf("unknown subcommand %q", args[0])
+	}
+}
+
+// handlerDescriptions lists the available handlers without connecting to anything
+func handlerDescriptions() []replayHandler {
+	return []replayHandler{
+		{name: "balances", description: "Balances and balance history read models (supports -rebuild)"},
+		{name: "webhooks", description: "Queue webhook deliveries for matching endpoints, deriving balance.updated from balance events"},
+		{name: "publisher", description: "Republish to the Redis event streams (EVENT_PUBLISHER=redis)"},
+	}
+}
+
+func runEventsReplay(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("events replay", flag.ContinueOnError)
+	aggregateID := fs.String("aggregate", "", "replay only the events of this aggregate ID")
+	types := fs.String("type", "", "comma-separated event types to replay")
+	since := fs.String("since", "", "replay events that occurred at or after this RFC3339 time")
+	until := fs.String("until", "", "replay events that occurred at or before this RFC3339 time")
+	handlerNames := fs.String("handlers", "", "comma-separated handlers to replay into (see 'fintechctl events handlers')")
+	dryRun := fs.Bool("dry-run", false, "list what would be replayed without calling any handler")
+	rebuild := fs.Bool("rebuild", false, "discard and rebuild the read models of the selected handlers from every event")
+	batchSize := fs.Int("batch-size", 500, "number of events read per batch")
+	continueOnError := fs.Bool("continue-on-error", false, "keep replaying when a handler fails")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	filter := events.ReplayFilter{
+		AggregateID: *aggregateID,
+		Types:       splitList(*types),
+	}
+	var err error
+	if filter.Since, err = parseTime(*since); err != nil {
+		return fmt.Errorf("invalid -since: %w", err)
+	}
+	if filter.Until, err = parseTime(*until); err != nil {
+		return fmt.Errorf("invalid -until: %w", err)
+	}
+
+	names := splitList(*handlerNames)
+	if len(names) == 0 {
+		return errors.New("-handlers is required")
+	}
+	if *rebuild && (filter.AggregateID != "" || len(filter.Types) > 0 || !filter.Since.IsZero() || !filter.Until.IsZero()) {
+		return errors.New("-rebuild replays every event and cannot be combined with filters")
+	}
+
+	db, err := database.NewConnection(cfg.DatabaseURL)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	store := events.NewSQLEventStore(db.DB)
+
+	handlers, cleanup, err := buildHandlers(cfg, db, store, names)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if *rebuild {
+		return rebuildHandlers(ctx, handlers, *dryRun)
+	}
+
+	return replay(ctx, store, filter, handlers, *batchSize, *dryRun, *continueOnError)
+}
+
+// buildHandlers creates the named handlers. The returned cleanup releases any
+// connections they hold.
+func buildHandlers(cfg *config.Config, db *database.Database, store *events.SQLEventStore, names []string) ([]replayHandler, func(), error) {
+	var handlers []replayHandler
+	var closers []func()
+	cleanup := func() {
+		for _, closeFn := range closers {
+			closeFn()
+		}
+	}
+
+	for _, name := range names {
+		switch name {
+		case "balances":
+			projection := balance.NewProjection(db.DB, balance.NewSQLRepository(db.DB), store)
+			handlers = append(handlers, replayHandler{
+				name:    name,
+				handler: projection,
+				rebuild: projection.Rebuild,
+			})
+		case "webhooks":
+			// balance.updated is only ever published through the outbox,
+			// so it is derived from the balance events being replayed
+			webhookService := webhook.NewService(webhook.NewSQLRepository(db.DB))
+			handlers = append(handlers, replayHandler{
+				name:    name,
+				handler: balance.NewNotificationReplayer(store, events.EventHandlerFunc(webhookService.HandleEvent)),
+			})
+		case "publisher":
+			if cfg.EventPublisher != "redis" {
+				cleanup()
+				return nil, nil, errors.New("the publisher handler requires EVENT_PUBLISHER=redis")
+			}
+			client, err := pkgredis.NewConnectionFromURL(cfg.RedisURL)
+			if err != nil {
+				cleanup()
+				return nil, nil, fmt.Errorf("failed to connect to redis: %w", err)
+			}
+			publisher := events.NewRedisStreamPublisher(client, events.DefaultRedisStreamConfig())
+			closers = append(closers, func() {
+				publisher.Close()
+				client.Close()
+			})
+			handlers = append(handlers, replayHandler{
+				name:    name,
+				handler: events.EventHandlerFunc(publisher.Publish),
+			})
+		default:
+			cleanup()
+			return nil, nil, fmt.Errorf("unknown handler %q", name)
+		}
+	}
+
+	return handlers, cleanup, nil
+}
+
+// rebuildHandlers rebuilds the read model of every handler that has one
+func rebuildHandlers(ctx context.Context, handlers []replayHandler, dryRun bool) error {
+	for _, h := range handlers {
+		if h.rebuild == nil {
+			return fmt.Errorf("handler %q has no read model to rebuild", h.name)
+		}
+	}
+
+	for _, h := range handlers {
+		if dryRun {
+			fmt.Printf("[dry-run] would rebuild %s from every event in the store\n", h.name)
+			continue
+		}
+
+		fmt.Printf("Rebuilding %s...\n", h.name)
+		start := time.Now()
+		projected, err := h.rebuild(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to rebuild %s: %w", h.name, err)
+		}
+		fmt.Printf("Rebuilt %s from %d events in %s\n", h.name, projected, time.Since(start).Round(time.Millisecond))
+	}
+
+	return nil
+}
+
+// replay feeds the selected events to every handler and prints progress after each batch
+func replay(ctx context.Context, store events.EventStore, filter events.ReplayFilter, handlers []replayHandler, batchSize int, dryRun, continueOnError bool) error {
+	start := time.Now()
+	replayed, failed := 0, 0
+	byType := make(map[string]int)
+
+	err := events.Replay(store, filter, batchSize, func(batch []*events.Event) error {
+		for _, event := range batch {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			byType[event.Type]++
+			replayed++
+			if dryRun {
+				continue
+			}
+
+			for _, h := range handlers {
+				if err := h.handler.Handle(event); err != nil {
+					if !continueOnError {
+						return fmt.Errorf("handler %s failed on event %s (%s): %w", h.name, event.ID, event.Type, err)
+					}
+					failed++
+					fmt.Fprintf(os.Stderr, "handler %s failed on event %s (%s): %v\n", h.name, event.ID, event.Type, err)
+				}
+			}
+		}
+
+		last := batch[len(batch)-1]
+		fmt.Printf("%s %d events, last %s at %s (%.0f events/s)\n",
+			progressVerb(dryRun), replayed, last.AggregateID, last.Timestamp.Format(time.RFC3339),
+			float64(replayed)/time.Since(start).Seconds())
+		return nil
+	})
+
+	printSummary(byType, replayed, failed, time.Since(start), dryRun)
+	return err
+}
+
+func progressVerb(dryRun bool) string {
+	if dryRun {
+		return "[dry-run] matched"
+	}
+	return "replayed"
+}
+
+func printSummary(byType map[string]int, replayed, failed int, elapsed time.Duration, dryRun bool) {
+	types := make([]string, 0, len(byType))
+	for eventType := range byType {
+		types = append(types, eventType)
+	}
+	sort.Strings(types)
+
+	fmt.Println()
+	for _, eventType := range types {
+		fmt.Printf("  %-24s %d\n", eventType, byType[eventType])
+	}
+	fmt.Printf("%s %d events in %s", progressVerb(dryRun), replayed, elapsed.Round(time.Millisecond))
+	if failed > 0 {
+		fmt.Printf(", %d handler failures", failed)
+	}
+	fmt.Println()
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
@@ -0,0 +1,59 @@
+// Command fintechctl runs operational tasks against the GoFintech database
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"backend_path/internal/config"
+	"backend_path/pkg/logger"
+
+	"github.com/joho/godotenv"
+)
+
+// command is a fintechctl subcommand
+type command struct {
+	name  string
+	usage string
+	run   func(cfg *config.Config, args []string) error
+}
+
+var commands = []command{
+	{name: "events", usage: "Replay events from the event store into handlers", run: runEvents},
+	{name: "audit", usage: "Verify or checkpoint the tamper-evident audit chain", run: runAudit},
+}
+
+func main() {
+	_ = godotenv.Load()
+
+	if len(os.Args) < 2 {
+		printUsage()
+		os.Exit(2)
+	}
+
+	cfg := config.Load()
+	logger.InitLogger("warn", cfg.Environment == "development")
+
+	for _, cmd := range commands {
+		if cmd.name == os.Args[1] {
+			if err := cmd.run(cfg, os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "fintechctl %s: %v\n", cmd.name, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "fintechctl: unknown command %q\n\n", os.Args[1])
+	printUsage()
+	os.Exit(2)
+}
+
+func printUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: fintechctl <command> [arguments]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", cmd.name, cmd.usage)
+	}
+}
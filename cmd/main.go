@@ -1,17 +1,33 @@
 package main
 
 import (
+	"backend_path/internal/account"
 	"backend_path/internal/api"
 	"backend_path/internal/api/handler"
+	"backend_path/internal/api/middleware"
+	"backend_path/internal/apikey"
+	"backend_path/internal/audit"
 	"backend_path/internal/auth"
 	"backend_path/internal/balance"
 	"backend_path/internal/config"
+	"backend_path/internal/events"
+	"backend_path/internal/lockout"
+	"backend_path/internal/mail"
+	"backend_path/internal/mfa"
+	"backend_path/internal/oauth"
+	"backend_path/internal/outbox"
+	"backend_path/internal/password"
+	"backend_path/internal/session"
+	"backend_path/internal/signingkey"
+	"backend_path/internal/stream"
 	"backend_path/internal/transaction"
 	"backend_path/internal/user"
+	"backend_path/internal/webhook"
 	"backend_path/pkg/cache"
 	"backend_path/pkg/database"
 	"backend_path/pkg/jwt"
 	"backend_path/pkg/logger"
+	pkgredis "backend_path/pkg/redis"
 	"backend_path/pkg/server"
 	"backend_path/pkg/tracing"
 	"backend_path/pkg/validator"
@@ -31,12 +47,20 @@ func main() {
 
 	// Initialize logger
 	logger.InitLogger("info", cfg.Environment == "development")
+	if err := cfg.Validate(); err != nil {
+		logger.Fatal("Invalid configuration", err, nil)
+	}
+
+	// Forwarding headers are only believed from trusted proxies
+	if err := middleware.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		logger.Fatal("Invalid trusted proxies", err, nil)
+	}
 
 	// Initialize validator
 	validator.InitValidator()
 
-	// Initialize RBAC manager (for future use)
-	_ = auth.NewRBACManager()
+	// Initialize RBAC manager
+	rbacManager := auth.NewRBACManager()
 
 	// Initialize advanced cache (for future use)
 	redisClient := redis.NewClient(&redis.Options{
@@ -61,29 +85,220 @@ func main() {
 	}
 	defer db.Close()
 
-	// Initialize JWT service
+	// Initialize JWT service. Asymmetric keys are shared by every instance
+	// through the database and rotated in the background.
 	jwtService := jwt.NewJWTService(cfg.JWTSecret, 1*time.Hour)
+	if cfg.JWTSigningAlgorithm != jwt.AlgorithmHS256 {
+		keyConfig := signingkey.DefaultConfig()
+		keyConfig.Algorithm = cfg.JWTSigningAlgorithm
+		keyConfig.RotationInterval = time.Duration(cfg.JWTKeyRotationDays) * 24 * time.Hour
+		keyConfig.GracePeriod = time.Duration(cfg.JWTKeyGraceDays) * 24 * time.Hour
+		keyRing := jwt.NewKeyRing()
+		keyRotator, err := signingkey.NewRotator(db.DB, signingkey.NewSQLRepository(db.DB), keyRing, cfg.JWTKeyEncryptionKey, keyConfig)
+		if err != nil {
+			logger.Fatal("Failed to initialize signing keys", err, nil)
+		}
+		if err := keyRotator.Refresh(context.Background()); err != nil {
+			logger.Fatal("Failed to load signing keys", err, nil)
+		}
+		keyRotator.Start()
+		defer keyRotator.Stop()
+		jwtService = jwt.NewJWTServiceWithKeys(keyRing, 1*time.Hour)
+	}
+
+	// Revoked access tokens are tracked in Redis and checked on every request
+	authClient, err := pkgredis.NewConnectionFromURL(cfg.RedisURL)
+	if err != nil {
+		logger.Fatal("Failed to connect to redis for token revocation", err, nil)
+	}
+	defer authClient.Close()
 
 	// Initialize repositories
 	userRepo := user.NewSQLRepository(db.DB)
 	transactionRepo := transaction.NewSQLRepository(db.DB)
 	balanceRepo := balance.NewSQLRepository(db.DB)
+	outboxRepo := outbox.NewSQLRepository(db.DB)
+	webhookRepo := webhook.NewSQLRepository(db.DB)
+	auditRepo := audit.NewSQLRepository(db.DB)
+	sessionRepo := session.NewSQLRepository(db.DB)
+	mfaRepo := mfa.NewSQLRepository(db.DB)
+	roleRepo := auth.NewSQLRepository(db.DB)
+	accountRepo := account.NewSQLRepository(db.DB)
+	apiKeyRepo := apikey.NewSQLRepository(db.DB)
+	oauthRepo := oauth.NewSQLRepository(db.DB)
+
+	// Initialize event store, snapshots and outbox recorder
+	eventStore := events.NewSQLEventStore(db.DB)
+	snapshotStore := events.NewSQLSnapshotStore(db.DB)
+	eventRecorder := outbox.NewRecorder(eventStore, outboxRepo)
+
+	// Initialize event publisher
+	var eventPublisher events.EventPublisher
+	switch cfg.EventPublisher {
+	case "redis":
+		streamClient, err := pkgredis.NewConnectionFromURL(cfg.RedisURL)
+		if err != nil {
+			logger.Fatal("Failed to connect to redis for event streams", err, nil)
+		}
+		defer streamClient.Close()
+
+		redisPublisher := events.NewRedisStreamPublisher(streamClient, events.DefaultRedisStreamConfig())
+		defer redisPublisher.Close()
+		eventPublisher = redisPublisher
+	default:
+		inProcessPublisher := events.NewInProcessPublisher(1000)
+		defer inProcessPublisher.Close()
+		eventPublisher = inProcessPublisher
+	}
+
+	// Initialize mail sender
+	var mailSender mail.Sender
+	switch cfg.MailSender {
+	case "smtp":
+		mailSender = mail.NewSMTPSender(mail.SMTPConfig{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.MailFrom,
+		})
+	case "file":
+		fileSender, err := mail.NewFileSender(cfg.MailDir, cfg.MailFrom)
+		if err != nil {
+			logger.Fatal("Failed to initialize mail sender", err, map[string]interface{}{
+				"mail_dir": cfg.MailDir,
+			})
+		}
+		mailSender = fileSender
+	default:
+		mailSender = mail.NewLogSender()
+	}
 
 	// Initialize services
-	userService := user.NewService(userRepo)
-	balanceService := balance.NewService(balanceRepo)
-	transactionService := transaction.NewService(transactionRepo, balanceService)
+	auditService := audit.NewService(db.DB, auditRepo, cfg.AuditSigningKey)
+	passwordPolicy := password.DefaultPolicy()
+	passwordPolicy.MinLength = cfg.PasswordMinLength
+	passwordPolicy.MinClasses = cfg.PasswordMinClasses
+	passwordParams := password.DefaultParams()
+	passwordParams.Memory = uint32(cfg.Argon2MemoryKiB)
+	passwordParams.Iterations = uint32(cfg.Argon2Iterations)
+	passwordParams.Parallelism = uint8(cfg.Argon2Parallelism)
+	var breaches password.BreachChecker
+	if cfg.PasswordBreachListDir != "" {
+		breachList, err := password.NewBreachList(cfg.PasswordBreachListDir)
+		if err != nil {
+			logger.Fatal("Failed to open breached password list", err, map[string]interface{}{
+				"dir": cfg.PasswordBreachListDir,
+			})
+		}
+		breaches = breachList
+	}
+	passwords := password.NewManager(passwordPolicy, breaches, passwordParams)
+	userService := user.NewService(userRepo, passwords)
+	revocations := session.NewRevocationStore(authClient.Client)
+	sessionService := session.NewService(db.DB, sessionRepo, jwtService, revocations, cfg.MaxSessionsPerUser)
+	mfaService, err := mfa.NewService(db.DB, mfaRepo, cfg.MFAEncryptionKey)
+	if err != nil {
+		logger.Fatal("Failed to initialize two-factor authentication", err, nil)
+	}
+	balanceAggregates := balance.NewAggregateStore(eventStore, snapshotStore, eventRecorder, cfg.SnapshotInterval)
+	balanceProjection := balance.NewProjection(db.DB, balanceRepo, eventStore)
+	balanceService := balance.NewService(db.DB, balanceRepo, balanceAggregates, balanceProjection, auditService)
+	transactionService := transaction.NewService(db.DB, transactionRepo, balanceService, eventRecorder, auditService)
+	webhookService := webhook.NewService(webhookRepo)
+	loginGuardConfig := lockout.DefaultConfig()
+	loginGuardConfig.MaxFailures = cfg.LoginMaxFailures
+	loginGuardConfig.MaxIPFailures = cfg.LoginMaxIPFailures
+	loginGuardConfig.LockoutDuration = time.Duration(cfg.LoginLockoutMinutes) * time.Minute
+	loginGuard := lockout.NewGuard(authClient.Client, loginGuardConfig)
+	accountConfig := account.DefaultConfig()
+	accountConfig.BaseURL = cfg.AppBaseURL
+	accountService := account.NewService(db.DB, accountRepo, userRepo, mailSender, passwords, accountConfig)
+	apiKeyService := apikey.NewService(db.DB, apiKeyRepo, userService)
+	oauthService := oauth.NewService(db.DB, oauthRepo, jwtService, userService, revocations)
+	roleInvalidator := auth.NewInvalidator(authClient.Client)
+	roleService := auth.NewRoleService(db.DB, roleRepo, rbacManager, roleInvalidator)
+
+	// Load persisted roles and policies, seeding the defaults on first run,
+	// and reload them whenever another instance changes them
+	if err := roleService.Seed(context.Background()); err != nil {
+		logger.Fatal("Failed to seed roles and policies", err, nil)
+	}
+	if err := roleService.Reload(); err != nil {
+		logger.Fatal("Failed to load roles and policies", err, nil)
+	}
+	if err := roleInvalidator.Start(roleService.Reload); err != nil {
+		logger.Fatal("Failed to subscribe to role changes", err, nil)
+	}
+	defer roleInvalidator.Close()
+
+	// Fan published events out to webhook endpoints and start delivering them
+	for _, eventType := range webhook.SupportedEventTypes {
+		webhookHandler := events.EventHandlerFunc(webhookService.HandleEvent)
+		if err := events.SubscribeHandler(eventPublisher, "webhooks."+eventType, eventType, webhookHandler); err != nil {
+			logger.Fatal("Failed to subscribe webhooks to events", err, map[string]interface{}{
+				"event_type": eventType,
+			})
+		}
+	}
+
+	webhookDispatcher := webhook.NewDispatcher(webhookRepo, webhook.DefaultDispatcherConfig())
+	webhookDispatcher.Start()
+	defer webhookDispatcher.Stop()
+
+	// Push balance and transaction events to connected clients
+	var streamHub *stream.Hub
+	hubClient, err := pkgredis.NewConnectionFromURL(cfg.RedisURL)
+	if err != nil {
+		logger.Error("Failed to connect to redis, real-time streaming is disabled", err, nil)
+	} else {
+		defer hubClient.Close()
+
+		streamHub = stream.NewHub(hubClient, stream.DefaultHubConfig())
+		if err := streamHub.Start(); err != nil {
+			logger.Fatal("Failed to start stream hub", err, nil)
+		}
+		defer streamHub.Close()
+
+		for _, eventType := range stream.EventTypes {
+			if err := events.SubscribeHandler(eventPublisher, "stream."+eventType, eventType, events.EventHandlerFunc(streamHub.HandleEvent)); err != nil {
+				logger.Fatal("Failed to subscribe stream hub to events", err, map[string]interface{}{
+					"event_type": eventType,
+				})
+			}
+		}
+	}
+
+	// Periodically sign the head of the audit chain
+	auditCheckpointer := audit.NewCheckpointer(auditService, time.Duration(cfg.AuditCheckpointMinutes)*time.Minute)
+	auditCheckpointer.Start()
+	defer auditCheckpointer.Stop()
+
+	// Start relaying the outbox once every subscriber is registered
+	outboxRelay := outbox.NewRelay(outboxRepo, eventPublisher, outbox.DefaultRelayConfig())
+	outboxRelay.Start()
+	defer outboxRelay.Stop()
 
 	// Set service dependencies in handlers
 	handler.SetUserService(userService)
 	handler.SetTransactionService(transactionService)
 	handler.SetBalanceService(balanceService)
+	handler.SetWebhookService(webhookService)
+	handler.SetAuditService(auditService)
+	handler.SetRBACManager(rbacManager)
+	handler.SetRoleService(roleService)
+	handler.SetAPIKeyService(apiKeyService)
+	handler.SetOAuthService(oauthService)
+	handler.SetStreamHub(streamHub)
 
 	// Create router with dependencies
-	router := api.NewRouter(userService, jwtService, cfg)
+	router := api.NewRouter(userService, sessionService, mfaService, loginGuard, accountService, apiKeyService, rbacManager, auditService, jwtService, stream.NewTicketStore(authClient.Client), cfg)
 
 	// Create server
 	srv := server.NewServer(":"+cfg.Port, router)
+	if streamHub != nil {
+		srv.RegisterOnShutdown(streamHub.Close)
+	}
 
 	// Start server with graceful shutdown
 	logger.Info("Starting application", map[string]interface{}{
@@ -91,6 +306,7 @@ func main() {
 		"environment": cfg.Environment,
 		"jaeger_url":  cfg.JaegerURL,
 		"rate_limit":  cfg.RateLimit,
+		"mail_sender": cfg.MailSender,
 	})
 
 	// Graceful shutdown with tracing cleanup
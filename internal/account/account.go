@@ -0,0 +1,90 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"backend_path/internal/domain"
+)
+
+var (
+	// ErrInvalidToken is returned for tokens that are unknown, expired,
+	// already used or issued for another purpose
+	ErrInvalidToken = errors.New("invalid or expired token")
+	// ErrAlreadyVerified is returned when verification is requested for a
+	// user whose email address is already verified
+	ErrAlreadyVerified = errors.New("email address already verified")
+	// ErrUsernameRequired is returned when accepting an invitation without
+	// a username
+	ErrUsernameRequired = errors.New("username is required")
+	// ErrEmailTaken is returned when inviting an address that already
+	// belongs to a user
+	ErrEmailTaken = errors.New("email address already registered")
+	// ErrInvitationNotFound is returned for unknown or no longer pending
+	// invitations
+	ErrInvitationNotFound = errors.New("invitation not found")
+)
+
+// Token purposes
+const (
+	PurposePasswordReset = "password_reset"
+	PurposeVerifyEmail   = "verify_email"
+)
+
+// Token is the server-side record of a token mailed to a user. Only the
+// SHA-256 hash of the token is stored.
+type Token struct {
+	ID        int
+	UserID    int
+	Purpose   string
+	TokenHash string
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// Invitation invites someone to create an account with a given role. Only
+// the SHA-256 hash of its token is stored.
+type Invitation struct {
+	ID         int
+	Email      string
+	Role       string
+	TokenHash  string
+	InvitedBy  int
+	ExpiresAt  time.Time
+	AcceptedAt *time.Time
+	// UserID is the account created by accepting the invitation
+	UserID    *int
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
+
+// AccountService recovers accounts and verifies email addresses with
+// single-use tokens sent by mail
+type AccountService interface {
+	// SendVerification mails user a link to verify their email address
+	SendVerification(ctx context.Context, user *domain.User) error
+	// VerifyEmail marks the email address of the owner of token as verified
+	// and returns the owner's ID
+	VerifyEmail(ctx context.Context, token string) (int, error)
+
+	// RequestPasswordReset mails a password reset link to the user with
+	// email. It does nothing if there is no such user, so callers must not
+	// reveal whether the address is registered.
+	RequestPasswordReset(ctx context.Context, email string) error
+	// ResetPassword sets the password of the owner of token and returns the
+	// owner. Any other reset tokens of the owner are invalidated. A password
+	// the policy rejects leaves the token unused.
+	ResetPassword(ctx context.Context, token, password string) (*domain.User, error)
+
+	// Invite mails an invitation to create an account with role to email,
+	// revoking any pending invitation to the same address
+	Invite(ctx context.Context, email, role string, invitedBy int) (*Invitation, error)
+	// ListInvitations returns the invitations that can still be accepted
+	ListInvitations() ([]*Invitation, error)
+	RevokeInvitation(ctx context.Context, id int) error
+	// AcceptInvitation creates the account the invitation with token is for.
+	// The email address counts as verified since the token was mailed to it.
+	AcceptInvitation(ctx context.Context, token, username, password string) (*domain.User, error)
+}
@@ -0,0 +1,42 @@
+package account
+
+import (
+	"database/sql"
+	"time"
+
+	"backend_path/internal/domain"
+)
+
+// Repository stores mailed tokens and the account changes they authorize
+type Repository interface {
+	CreateToken(token *Token) error
+	// UseToken marks the unused, unexpired token with hash and purpose as
+	// used and returns it, or returns nil if there is no such token
+	UseToken(hash, purpose string, at time.Time) (*Token, error)
+	// InvalidateTokens marks every unused token of userID for purpose as used
+	InvalidateTokens(userID int, purpose string, at time.Time) error
+
+	SetPasswordHash(userID int, hash string, at time.Time) error
+	MarkEmailVerified(userID int, at time.Time) error
+
+	CreateInvitation(invitation *Invitation) error
+	// ListPendingInvitations returns the invitations that are neither
+	// accepted, revoked nor expired at now
+	ListPendingInvitations(now time.Time) ([]*Invitation, error)
+	// RevokeInvitation revokes the pending invitation with id. It returns
+	// false if there is no such invitation.
+	RevokeInvitation(id int, at time.Time) (bool, error)
+	// RevokeInvitationsFor revokes every pending invitation to email
+	RevokeInvitationsFor(email string, at time.Time) error
+	// AcceptInvitation marks the pending, unexpired invitation with hash as
+	// accepted and returns it, or returns nil if there is no such invitation
+	AcceptInvitation(hash string, at time.Time) (*Invitation, error)
+	// SetInvitationUser records the account created from an invitation
+	SetInvitationUser(id, userID int) error
+	// CreateUser creates an invited user with their role and verification
+	// time
+	CreateUser(user *domain.User) error
+
+	// WithTx returns a repository that runs its queries inside tx
+	WithTx(tx *sql.Tx) Repository
+}
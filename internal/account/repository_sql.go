@@ -0,0 +1,272 @@
+package account
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"backend_path/internal/domain"
+	"backend_path/pkg/database"
+)
+
+type sqlRepository struct {
+	db database.Executor
+}
+
+func NewSQLRepository(db *sql.DB) Repository {
+	return &sqlRepository{db: db}
+}
+
+func (r *sqlRepository) WithTx(tx *sql.Tx) Repository {
+	return &sqlRepository{db: tx}
+}
+
+func (r *sqlRepository) CreateToken(token *Token) error {
+	query := `
+		INSERT INTO user_tokens (user_id, purpose, token_hash, expires_at, created_at)
+		OUTPUT INSERTED.id
+		VALUES (?, ?, ?, ?, ?)
+	`
+
+	err := r.db.QueryRow(query, token.UserID, token.Purpose, token.TokenHash, token.ExpiresAt, token.CreatedAt).Scan(&token.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create user token: %w", err)
+	}
+	return nil
+}
+
+func (r *sqlRepository) UseToken(hash, purpose string, at time.Time) (*Token, error) {
+	// A single conditional update, so concurrent uses of a token cannot
+	// both succeed
+	query := `
+		UPDATE user_tokens
+		SET used_at = ?
+		OUTPUT INSERTED.id, INSERTED.user_id, INSERTED.purpose, INSERTED.token_hash, INSERTED.expires_at, INSERTED.used_at, INSERTED.created_at
+		WHERE token_hash = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?
+	`
+
+	token := &Token{}
+	var usedAt time.Time
+	err := r.db.QueryRow(query, at, hash, purpose, at).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.Purpose,
+		&token.TokenHash,
+		&token.ExpiresAt,
+		&usedAt,
+		&token.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to use user token: %w", err)
+	}
+
+	token.UsedAt = &usedAt
+	return token, nil
+}
+
+func (r *sqlRepository) InvalidateTokens(userID int, purpose string, at time.Time) error {
+	query := `UPDATE user_tokens SET used_at = ? WHERE user_id = ? AND purpose = ? AND used_at IS NULL`
+	if _, err := r.db.Exec(query, at, userID, purpose); err != nil {
+		return fmt.Errorf("failed to invalidate user tokens: %w", err)
+	}
+	return nil
+}
+
+func (r *sqlRepository) SetPasswordHash(userID int, hash string, at time.Time) error {
+	query := `UPDATE users SET password_hash = ?, updated_at = ? WHERE id = ?`
+	result, err := r.db.Exec(query, hash, at, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("user not found: %d", userID)
+	}
+
+	return nil
+}
+
+func (r *sqlRepository) MarkEmailVerified(userID int, at time.Time) error {
+	query := `UPDATE users SET email_verified_at = ?, updated_at = ? WHERE id = ? AND email_verified_at IS NULL`
+	if _, err := r.db.Exec(query, at, at, userID); err != nil {
+		return fmt.Errorf("failed to verify email address: %w", err)
+	}
+	return nil
+}
+
+func (r *sqlRepository) CreateInvitation(invitation *Invitation) error {
+	query := `
+		INSERT INTO invitations (email, role, token_hash, invited_by, expires_at, created_at)
+		OUTPUT INSERTED.id
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+
+	err := r.db.QueryRow(
+		query,
+		invitation.Email,
+		invitation.Role,
+		invitation.TokenHash,
+		invitation.InvitedBy,
+		invitation.ExpiresAt,
+		invitation.CreatedAt,
+	).Scan(&invitation.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create invitation: %w", err)
+	}
+	return nil
+}
+
+func (r *sqlRepository) ListPendingInvitations(now time.Time) ([]*Invitation, error) {
+	query := `
+		SELECT id, email, role, token_hash, invited_by, expires_at, accepted_at, user_id, revoked_at, created_at
+		FROM invitations
+		WHERE accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?
+		ORDER BY created_at DESC
+	`
+
+	rows, err := r.db.Query(query, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list invitations: %w", err)
+	}
+	defer rows.Close()
+
+	var invitations []*Invitation
+	for rows.Next() {
+		invitation, err := scanInvitation(rows)
+		if err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, invitation)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating invitations: %w", err)
+	}
+
+	return invitations, nil
+}
+
+func (r *sqlRepository) RevokeInvitation(id int, at time.Time) (bool, error) {
+	query := `UPDATE invitations SET revoked_at = ? WHERE id = ? AND accepted_at IS NULL AND revoked_at IS NULL`
+	result, err := r.db.Exec(query, at, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke invitation: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+func (r *sqlRepository) RevokeInvitationsFor(email string, at time.Time) error {
+	query := `UPDATE invitations SET revoked_at = ? WHERE email = ? AND accepted_at IS NULL AND revoked_at IS NULL`
+	if _, err := r.db.Exec(query, at, email); err != nil {
+		return fmt.Errorf("failed to revoke invitations: %w", err)
+	}
+	return nil
+}
+
+func (r *sqlRepository) AcceptInvitation(hash string, at time.Time) (*Invitation, error) {
+	// A single conditional update, so an invitation cannot be accepted twice
+	query := `
+		UPDATE invitations
+		SET accepted_at = ?
+		OUTPUT INSERTED.id, INSERTED.email, INSERTED.role, INSERTED.token_hash, INSERTED.invited_by, INSERTED.expires_at,
+			INSERTED.accepted_at, INSERTED.user_id, INSERTED.revoked_at, INSERTED.created_at
+		WHERE token_hash = ? AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?
+	`
+
+	invitation, err := scanInvitation(r.db.QueryRow(query, at, hash, at))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return invitation, nil
+}
+
+func (r *sqlRepository) SetInvitationUser(id, userID int) error {
+	if _, err := r.db.Exec(`UPDATE invitations SET user_id = ? WHERE id = ?`, userID, id); err != nil {
+		return fmt.Errorf("failed to update invitation: %w", err)
+	}
+	return nil
+}
+
+func (r *sqlRepository) CreateUser(user *domain.User) error {
+	query := `
+		INSERT INTO users (username, email, password_hash, role, email_verified_at, created_at, updated_at)
+		OUTPUT INSERTED.id
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+
+	err := r.db.QueryRow(
+		query,
+		user.Username,
+		user.Email,
+		user.PasswordHash,
+		user.Role,
+		user.EmailVerifiedAt,
+		user.CreatedAt,
+		user.UpdatedAt,
+	).Scan(&user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+	return nil
+}
+
+// scanner is implemented by *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanInvitation scans a single invitation row. It returns sql.ErrNoRows
+// unwrapped.
+func scanInvitation(row scanner) (*Invitation, error) {
+	invitation := &Invitation{}
+	var acceptedAt, revokedAt sql.NullTime
+	var userID sql.NullInt64
+	err := row.Scan(
+		&invitation.ID,
+		&invitation.Email,
+		&invitation.Role,
+		&invitation.TokenHash,
+		&invitation.InvitedBy,
+		&invitation.ExpiresAt,
+		&acceptedAt,
+		&userID,
+		&revokedAt,
+		&invitation.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan invitation: %w", err)
+	}
+
+	if acceptedAt.Valid {
+		invitation.AcceptedAt = &acceptedAt.Time
+	}
+	if userID.Valid {
+		id := int(userID.Int64)
+		invitation.UserID = &id
+	}
+	if revokedAt.Valid {
+		invitation.RevokedAt = &revokedAt.Time
+	}
+
+	return invitation, nil
+}
@@ -0,0 +1,353 @@
+package account
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"backend_path/internal/domain"
+	"backend_path/internal/mail"
+	"backend_path/internal/password"
+	"backend_path/internal/user"
+	"backend_path/pkg/database"
+	"backend_path/pkg/logger"
+)
+
+// Config tunes the links and lifetimes of mailed tokens
+type Config struct {
+	// BaseURL is the address of the frontend that links in emails point to
+	BaseURL       string
+	ResetTokenTTL time.Duration
+	VerifyTTL     time.Duration
+	InviteTTL     time.Duration
+	// SendTimeout bounds the delivery of a single email
+	SendTimeout time.Duration
+}
+
+// DefaultConfig returns the default token settings
+func DefaultConfig() Config {
+	return Config{
+		BaseURL:       "http://localhost:3000",
+		ResetTokenTTL: time.Hour,
+		VerifyTTL:     24 * time.Hour,
+		InviteTTL:     72 * time.Hour,
+		SendTimeout:   30 * time.Second,
+	}
+}
+
+type service struct {
+	db        *sql.DB
+	repo      Repository
+	users     user.Repository
+	sender    mail.Sender
+	passwords *password.Manager
+	config    Config
+}
+
+// NewService creates an account service that mails tokens with sender and
+// checks new passwords against the policy of passwords
+func NewService(db *sql.DB, repo Repository, users user.Repository, sender mail.Sender, passwords *password.Manager, config Config) AccountService {
+	return &service{
+		db:        db,
+		repo:      repo,
+		users:     users,
+		sender:    sender,
+		passwords: passwords,
+		config:    config,
+	}
+}
+
+func (s *service) SendVerification(ctx context.Context, user *domain.User) error {
+	if user.EmailVerified() {
+		return ErrAlreadyVerified
+	}
+
+	token, err := s.issue(ctx, user.ID, PurposeVerifyEmail, s.config.VerifyTTL)
+	if err != nil {
+		return err
+	}
+
+	s.deliver(&mail.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hello %s,\r\n\r\nConfirm your email address by opening the link below. It expires in %s.\r\n\r\n%s\r\n",
+			user.Username, s.config.VerifyTTL, s.link("/verify-email", token)),
+	})
+	return nil
+}
+
+func (s *service) VerifyEmail(ctx context.Context, token string) (int, error) {
+	var userID int
+	err := database.WithTransaction(ctx, s.db, func(tx *sql.Tx) error {
+		repo := s.repo.WithTx(tx)
+		now := time.Now()
+
+		record, err := repo.UseToken(hashToken(token), PurposeVerifyEmail, now)
+		if err != nil {
+			return err
+		}
+		if record == nil {
+			return ErrInvalidToken
+		}
+		userID = record.UserID
+
+		if err := repo.MarkEmailVerified(userID, now); err != nil {
+			return err
+		}
+		return repo.InvalidateTokens(userID, PurposeVerifyEmail, now)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return userID, nil
+}
+
+func (s *service) RequestPasswordReset(ctx context.Context, email string) error {
+	owner, err := s.users.GetByEmail(strings.TrimSpace(email))
+	if errors.Is(err, user.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	token, err := s.issue(ctx, owner.ID, PurposePasswordReset, s.config.ResetTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	s.deliver(&mail.Message{
+		To:      owner.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hello %s,\r\n\r\nSomeone asked to reset the password of your account. Choose a new password by opening the link below. It expires in %s and can be used once.\r\n\r\n%s\r\n\r\nIf you did not ask for this, you can ignore this email.\r\n",
+			owner.Username, s.config.ResetTokenTTL, s.link("/reset-password", token)),
+	})
+	return nil
+}
+
+func (s *service) ResetPassword(ctx context.Context, token, newPassword string) (*domain.User, error) {
+	var userID int
+	err := database.WithTransaction(ctx, s.db, func(tx *sql.Tx) error {
+		repo := s.repo.WithTx(tx)
+		now := time.Now()
+
+		record, err := repo.UseToken(hashToken(token), PurposePasswordReset, now)
+		if err != nil {
+			return err
+		}
+		if record == nil {
+			return ErrInvalidToken
+		}
+		userID = record.UserID
+
+		owner, err := s.users.GetByID(userID)
+		if err != nil {
+			return err
+		}
+		if err := s.passwords.Validate(newPassword, owner.Username, owner.Email); err != nil {
+			return err
+		}
+		hash, err := s.passwords.Hash(newPassword)
+		if err != nil {
+			return err
+		}
+
+		if err := repo.SetPasswordHash(userID, hash, now); err != nil {
+			return err
+		}
+		return repo.InvalidateTokens(userID, PurposePasswordReset, now)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.users.GetByID(userID)
+}
+
+func (s *service) Invite(ctx context.Context, email, role string, invitedBy int) (*Invitation, error) {
+	email = strings.TrimSpace(email)
+	_, err := s.users.GetByEmail(email)
+	if err == nil {
+		return nil, ErrEmailTaken
+	}
+	if !errors.Is(err, user.ErrNotFound) {
+		return nil, err
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	invitation := &Invitation{
+		Email:     email,
+		Role:      role,
+		TokenHash: hashToken(token),
+		InvitedBy: invitedBy,
+		ExpiresAt: now.Add(s.config.InviteTTL),
+		CreatedAt: now,
+	}
+
+	err = database.WithTransaction(ctx, s.db, func(tx *sql.Tx) error {
+		repo := s.repo.WithTx(tx)
+		if err := repo.RevokeInvitationsFor(email, now); err != nil {
+			return err
+		}
+		return repo.CreateInvitation(invitation)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.deliver(&mail.Message{
+		To:      email,
+		Subject: "You are invited to GoFintech",
+		Body: fmt.Sprintf("Hello,\r\n\r\nYou are invited to join GoFintech as %s. Choose a username and password by opening the link below. It expires in %s and can be used once.\r\n\r\n%s\r\n",
+			role, s.config.InviteTTL, s.link("/accept-invitation", token)),
+	})
+	return invitation, nil
+}
+
+func (s *service) ListInvitations() ([]*Invitation, error) {
+	return s.repo.ListPendingInvitations(time.Now())
+}
+
+func (s *service) RevokeInvitation(ctx context.Context, id int) error {
+	return database.WithTransaction(ctx, s.db, func(tx *sql.Tx) error {
+		revoked, err := s.repo.WithTx(tx).RevokeInvitation(id, time.Now())
+		if err != nil {
+			return err
+		}
+		if !revoked {
+			return ErrInvitationNotFound
+		}
+		return nil
+	})
+}
+
+func (s *service) AcceptInvitation(ctx context.Context, token, username, newPassword string) (*domain.User, error) {
+	username = strings.TrimSpace(username)
+	if username == "" {
+		return nil, ErrUsernameRequired
+	}
+
+	var invitee *domain.User
+	err := database.WithTransaction(ctx, s.db, func(tx *sql.Tx) error {
+		repo := s.repo.WithTx(tx)
+		now := time.Now()
+
+		invitation, err := repo.AcceptInvitation(hashToken(token), now)
+		if err != nil {
+			return err
+		}
+		if invitation == nil {
+			return ErrInvalidToken
+		}
+
+		if err := s.passwords.Validate(newPassword, username, invitation.Email); err != nil {
+			return err
+		}
+		hash, err := s.passwords.Hash(newPassword)
+		if err != nil {
+			return err
+		}
+
+		invitee = &domain.User{
+			Username:        username,
+			Email:           invitation.Email,
+			PasswordHash:    hash,
+			Role:            invitation.Role,
+			EmailVerifiedAt: &now,
+			CreatedAt:       now,
+			UpdatedAt:       now,
+		}
+		if err := invitee.Validate(); err != nil {
+			return err
+		}
+		if err := repo.CreateUser(invitee); err != nil {
+			return err
+		}
+		return repo.SetInvitationUser(invitation.ID, invitee.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return invitee, nil
+}
+
+// issue stores a new token for userID in place of any unused token with the
+// same purpose and returns it
+func (s *service) issue(ctx context.Context, userID int, purpose string, ttl time.Duration) (string, error) {
+	token, err := generateToken()
+	if err != nil {
+		return "", err
+	}
+
+	err = database.WithTransaction(ctx, s.db, func(tx *sql.Tx) error {
+		repo := s.repo.WithTx(tx)
+		now := time.Now()
+
+		if err := repo.InvalidateTokens(userID, purpose, now); err != nil {
+			return err
+		}
+		return repo.CreateToken(&Token{
+			UserID:    userID,
+			Purpose:   purpose,
+			TokenHash: hashToken(token),
+			ExpiresAt: now.Add(ttl),
+			CreatedAt: now,
+		})
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// deliver sends message in the background so that the response time does
+// not depend on the mail server, nor reveal whether an email was sent
+func (s *service) deliver(message *mail.Message) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), s.config.SendTimeout)
+		defer cancel()
+
+		if err := s.sender.Send(ctx, message); err != nil {
+			logger.Error("Failed to send mail", err, map[string]interface{}{
+				"to":      message.To,
+				"subject": message.Subject,
+			})
+		}
+	}()
+}
+
+// link returns the frontend address of path carrying token
+func (s *service) link(path, token string) string {
+	return strings.TrimRight(s.config.BaseURL, "/") + path + "?token=" + url.QueryEscape(token)
+}
+
+// generateToken returns a random URL-safe token
+func generateToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// hashToken returns the hash a token is stored and looked up by
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -1,13 +1,15 @@
 package dto
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // RegisterRequest represents user registration request
 type RegisterRequest struct {
 	Username string `json:"username" validate:"required,min=3,max=50"`
 	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"required,min=8"`
-	Role     string `json:"role" validate:"required,oneof=user admin"`
+	Password string `json:"password" validate:"required"`
 }
 
 // LoginRequest represents user login request
@@ -21,6 +23,12 @@ type RefreshRequest struct {
 	RefreshToken string `json:"refresh_token" validate:"required"`
 }
 
+// LogoutRequest represents logout request. The refresh token is optional; if
+// given, it is revoked along with the access token.
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token,omitempty"`
+}
+
 // AuthResponse represents authentication response
 type AuthResponse struct {
 	Token        string    `json:"token"`
@@ -30,15 +38,199 @@ type AuthResponse struct {
 	Timestamp    time.Time `json:"timestamp"`
 }
 
-// UserInfo represents user information in responses
-type UserInfo struct {
+// ImpersonationResponse carries a read-only access token acting as User on
+// behalf of the caller. It cannot be refreshed.
+type ImpersonationResponse struct {
+	Token          string    `json:"token"`
+	ExpiresIn      int       `json:"expires_in"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	User           UserInfo  `json:"user"`
+	ImpersonatorID int       `json:"impersonator_id"`
+}
+
+// MFAChallengeResponse is returned instead of tokens when a login needs a
+// two-factor code. The MFA token is exchanged with the code at
+// /auth/mfa/verify.
+type MFAChallengeResponse struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// MFAVerifyRequest represents the second step of a login
+type MFAVerifyRequest struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
+// MFACodeRequest carries a TOTP or recovery code
+type MFACodeRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+// MFAEnrollResponse represents a pending TOTP enrollment. The provisioning
+// URI is meant to be shown as a QR code.
+type MFAEnrollResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// RecoveryCodesResponse lists one-time recovery codes. They are only shown
+// once.
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// ForgotPasswordRequest asks for a password reset link
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// ResetPasswordRequest sets a new password with a mailed reset token
+type ResetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+// VerifyEmailRequest verifies an email address with a mailed token
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// InvitationRequest invites someone to create an account with a role
+type InvitationRequest struct {
+	Email string `json:"email" validate:"required,email"`
+	Role  string `json:"role" validate:"required"`
+}
+
+// InvitationResponse represents a pending invitation
+type InvitationResponse struct {
 	ID        int       `json:"id"`
-	Username  string    `json:"username"`
 	Email     string    `json:"email"`
 	Role      string    `json:"role"`
+	InvitedBy int       `json:"invited_by"`
+	ExpiresAt time.Time `json:"expires_at"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
+// AcceptInvitationRequest creates an invited account
+type AcceptInvitationRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Username string `json:"username" validate:"required,min=3,max=50"`
+	Password string `json:"password" validate:"required"`
+}
+
+// APIKeyRequest issues an API key. The key belongs to the caller unless
+// user_id is given.
+type APIKeyRequest struct {
+	UserID     int        `json:"user_id,omitempty"`
+	Name       string     `json:"name" validate:"required,max=100"`
+	Scopes     []string   `json:"scopes" validate:"required,min=1"`
+	AllowedIPs []string   `json:"allowed_ips,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+}
+
+// APIKeyResponse represents an API key. Key is only set when the key is
+// issued or rotated and cannot be retrieved later.
+type APIKeyResponse struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"user_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Key        string     `json:"key,omitempty"`
+	Scopes     []string   `json:"scopes"`
+	AllowedIPs []string   `json:"allowed_ips"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedBy  int        `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// OAuthClientRequest registers an OAuth client. Public clients, such as
+// mobile apps, have no secret and must use the authorization code grant.
+// Client credentials tokens act as user_id.
+type OAuthClientRequest struct {
+	Name         string   `json:"name" validate:"required,max=100"`
+	RedirectURIs []string `json:"redirect_uris,omitempty"`
+	GrantTypes   []string `json:"grant_types" validate:"required,min=1"`
+	Scopes       []string `json:"scopes" validate:"required,min=1"`
+	Confidential bool     `json:"confidential"`
+	UserID       *int     `json:"user_id,omitempty"`
+}
+
+// OAuthClientResponse represents an OAuth client. ClientSecret is only set
+// when the client is registered and cannot be retrieved later.
+type OAuthClientResponse struct {
+	ID           int       `json:"id"`
+	ClientID     string    `json:"client_id"`
+	ClientSecret string    `json:"client_secret,omitempty"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	GrantTypes   []string  `json:"grant_types"`
+	Scopes       []string  `json:"scopes"`
+	Confidential bool      `json:"confidential"`
+	UserID       *int      `json:"user_id,omitempty"`
+	CreatedBy    int       `json:"created_by"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// OAuthConsentResponse describes an authorization request for the consent
+// screen. Consented is true if the user already granted every scope.
+type OAuthConsentResponse struct {
+	ClientID    string   `json:"client_id"`
+	ClientName  string   `json:"client_name"`
+	RedirectURI string   `json:"redirect_uri"`
+	Scopes      []string `json:"scopes"`
+	Consented   bool     `json:"consented"`
+}
+
+// OAuthAuthorizeRequest carries the user's decision on an authorization
+// request, along with its parameters
+type OAuthAuthorizeRequest struct {
+	ResponseType        string `json:"response_type"`
+	ClientID            string `json:"client_id"`
+	RedirectURI         string `json:"redirect_uri,omitempty"`
+	Scope               string `json:"scope,omitempty"`
+	State               string `json:"state,omitempty"`
+	CodeChallenge       string `json:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method"`
+	Approve             bool   `json:"approve"`
+}
+
+// OAuthRedirectResponse is where the user agent is sent back to the client
+type OAuthRedirectResponse struct {
+	RedirectURI string `json:"redirect_uri"`
+}
+
+// StreamTicketResponse carries a single-use ticket for the stream endpoint
+type StreamTicketResponse struct {
+	Ticket    string `json:"ticket"`
+	ExpiresIn int    `json:"expires_in"`
+}
+
+// SessionResponse represents a login of the caller. Current marks the
+// session of the request.
+type SessionResponse struct {
+	ID         string    `json:"id"`
+	DeviceName string    `json:"device_name,omitempty"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
+
+// UserInfo represents user information in responses
+type UserInfo struct {
+	ID            int       `json:"id"`
+	Username      string    `json:"username"`
+	Email         string    `json:"email"`
+	Role          string    `json:"role"`
+	EmailVerified bool      `json:"email_verified"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
 // CreditRequest represents credit transaction request
 type CreditRequest struct {
 	Amount      float64 `json:"amount" validate:"required,gt=0"`
@@ -128,3 +320,183 @@ type UpdateUserRequest struct {
 	Email    string `json:"email" validate:"required,email"`
 	Role     string `json:"role" validate:"required,oneof=user admin"`
 }
+
+// CreateWebhookRequest represents webhook endpoint registration request
+type CreateWebhookRequest struct {
+	URL         string   `json:"url" validate:"required,url"`
+	EventTypes  []string `json:"event_types" validate:"required,min=1"`
+	Description string   `json:"description,omitempty"`
+	ClientID    string   `json:"client_id,omitempty"`
+}
+
+// UpdateWebhookRequest represents webhook endpoint update request
+type UpdateWebhookRequest struct {
+	URL         string   `json:"url" validate:"required,url"`
+	EventTypes  []string `json:"event_types" validate:"required,min=1"`
+	Description string   `json:"description,omitempty"`
+	Active      bool     `json:"active"`
+}
+
+// WebhookEndpointResponse represents webhook endpoint response. Secret is only
+// returned when the endpoint is created.
+type WebhookEndpointResponse struct {
+	ID          int       `json:"id"`
+	UserID      int       `json:"user_id,omitempty"`
+	ClientID    string    `json:"client_id,omitempty"`
+	URL         string    `json:"url"`
+	Secret      string    `json:"secret,omitempty"`
+	EventTypes  []string  `json:"event_types"`
+	Description string    `json:"description,omitempty"`
+	Active      bool      `json:"active"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// WebhookDeliveryResponse represents webhook delivery response
+type WebhookDeliveryResponse struct {
+	ID             int64      `json:"id"`
+	EndpointID     int        `json:"endpoint_id"`
+	EventID        string     `json:"event_id"`
+	EventType      string     `json:"event_type"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	LastStatusCode int        `json:"last_status_code,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+}
+
+// WebhookAttemptResponse represents a single webhook delivery attempt
+type WebhookAttemptResponse struct {
+	Attempt      int       `json:"attempt"`
+	StatusCode   int       `json:"status_code,omitempty"`
+	ResponseBody string    `json:"response_body,omitempty"`
+	Error        string    `json:"error,omitempty"`
+	DurationMs   int       `json:"duration_ms"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// AuditLogResponse represents a single audit log entry
+type AuditLogResponse struct {
+	ID             int             `json:"id"`
+	EntityType     string          `json:"entity_type"`
+	EntityID       int             `json:"entity_id"`
+	Action         string          `json:"action"`
+	ActorID        int             `json:"actor_id,omitempty"`
+	ImpersonatorID int             `json:"impersonator_id,omitempty"`
+	IPAddress      string          `json:"ip_address,omitempty"`
+	UserAgent      string          `json:"user_agent,omitempty"`
+	TraceID        string          `json:"trace_id,omitempty"`
+	Before         json.RawMessage `json:"before,omitempty"`
+	After          json.RawMessage `json:"after,omitempty"`
+	Changes        json.RawMessage `json:"changes,omitempty"`
+	Details        json.RawMessage `json:"details,omitempty"`
+	PrevHash       string          `json:"prev_hash,omitempty"`
+	Hash           string          `json:"hash,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+// AuditLogListResponse represents a page of audit log entries
+type AuditLogListResponse struct {
+	Items  []AuditLogResponse `json:"items"`
+	Total  int                `json:"total"`
+	Limit  int                `json:"limit"`
+	Offset int                `json:"offset"`
+}
+
+// AuditVerifyResponse represents the result of verifying the audit chain
+type AuditVerifyResponse struct {
+	Valid       bool                     `json:"valid"`
+	Checked     int                      `json:"checked"`
+	Unchained   int                      `json:"unchained"`
+	Checkpoints int                      `json:"checkpoints"`
+	LastLogID   int                      `json:"last_log_id"`
+	BrokenLink  *AuditBrokenLinkResponse `json:"broken_link,omitempty"`
+}
+
+// AuditBrokenLinkResponse describes the first broken link of the audit chain
+type AuditBrokenLinkResponse struct {
+	LogID        int    `json:"log_id"`
+	CheckpointID int    `json:"checkpoint_id,omitempty"`
+	Reason       string `json:"reason"`
+	Expected     string `json:"expected,omitempty"`
+	Actual       string `json:"actual,omitempty"`
+}
+
+// PermissionDTO represents a permission of a role
+type PermissionDTO struct {
+	Resource   string `json:"resource" validate:"required"`
+	Action     string `json:"action" validate:"required"`
+	ResourceID string `json:"resource_id,omitempty"`
+}
+
+// RoleRequest represents role create and update request. The name is taken
+// from the URL on update.
+type RoleRequest struct {
+	Name        string          `json:"name,omitempty"`
+	Permissions []PermissionDTO `json:"permissions"`
+	Inherits    []string        `json:"inherits,omitempty"`
+}
+
+// RoleResponse represents role response
+type RoleResponse struct {
+	Name        string          `json:"name"`
+	Permissions []PermissionDTO `json:"permissions"`
+	Inherits    []string        `json:"inherits,omitempty"`
+}
+
+// PolicyRequest represents policy create and update request
+type PolicyRequest struct {
+	Resource   string                 `json:"resource" validate:"required"`
+	Actions    []string               `json:"actions" validate:"required"`
+	Roles      []string               `json:"roles" validate:"required"`
+	Conditions map[string]interface{} `json:"conditions,omitempty"`
+}
+
+// PolicyResponse represents policy response
+type PolicyResponse struct {
+	ID         int                    `json:"id"`
+	Resource   string                 `json:"resource"`
+	Actions    []string               `json:"actions"`
+	Roles      []string               `json:"roles"`
+	Conditions map[string]interface{} `json:"conditions,omitempty"`
+}
+
+// PolicyEvaluateRequest represents an access request to explain. Attributes
+// are the values policy conditions are evaluated against, keyed like
+// "amount" or "subject.id".
+type PolicyEvaluateRequest struct {
+	Roles      []string               `json:"roles" validate:"required"`
+	Resource   string                 `json:"resource" validate:"required"`
+	Action     string                 `json:"action" validate:"required"`
+	ResourceID string                 `json:"resource_id,omitempty"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// PolicyEvaluateResponse represents the decision on an access request
+type PolicyEvaluateResponse struct {
+	Allowed    bool                   `json:"allowed"`
+	GrantedBy  string                 `json:"granted_by,omitempty"`
+	Permission *PermissionDTO         `json:"permission,omitempty"`
+	Policies   []PolicyResultResponse `json:"policies"`
+}
+
+// PolicyResultResponse explains the evaluation of one policy
+type PolicyResultResponse struct {
+	PolicyID      int                       `json:"policy_id"`
+	RoleMatched   bool                      `json:"role_matched"`
+	ActionMatched bool                      `json:"action_matched"`
+	Conditions    []ConditionResultResponse `json:"conditions,omitempty"`
+	Error         string                    `json:"error,omitempty"`
+	Allowed       bool                      `json:"allowed"`
+	Denied        bool                      `json:"denied"`
+}
+
+// ConditionResultResponse explains the evaluation of one policy condition
+type ConditionResultResponse struct {
+	Name       string `json:"name"`
+	Expression string `json:"expression"`
+	Result     bool   `json:"result"`
+	Error      string `json:"error,omitempty"`
+}
@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"backend_path/internal/account"
+	"backend_path/internal/api/dto"
+	"backend_path/internal/audit"
+	"backend_path/internal/password"
+	"backend_path/pkg/logger"
+)
+
+// ForgotPassword mails a password reset link. It responds the same way
+// whether or not the email address is registered.
+func (h *AuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req dto.ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if req.Email == "" {
+		respondWithError(w, http.StatusBadRequest, "Email is required", nil)
+		return
+	}
+
+	if err := h.accountService.RequestPasswordReset(r.Context(), req.Email); err != nil {
+		logger.Error("Failed to request password reset", err, nil)
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// ResetPassword sets a new password with a mailed reset token. Every session
+// of the user is logged out and any login lockout is lifted.
+func (h *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req dto.ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	user, err := h.accountService.ResetPassword(r.Context(), req.Token, req.Password)
+	if err != nil {
+		respondWithAccountError(w, "Failed to reset password", err)
+		return
+	}
+
+	ctx := audit.WithActor(r.Context(), user.ID)
+	if err := h.sessionService.LogoutAll(ctx, user.ID); err != nil {
+		logger.Error("Failed to log out sessions after password reset", err, map[string]interface{}{
+			"user_id": user.ID,
+		})
+	}
+	if err := h.loginGuard.Unlock(ctx, user.Email); err != nil {
+		logger.Error("Failed to clear login failures after password reset", err, map[string]interface{}{
+			"user_id": user.ID,
+		})
+	}
+
+	recordAudit(ctx, &audit.Entry{
+		EntityType: audit.EntityUser,
+		EntityID:   user.ID,
+		Action:     audit.ActionPasswordReset,
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// VerifyEmail verifies an email address with a mailed token
+func (h *AuthHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req dto.VerifyEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	userID, err := h.accountService.VerifyEmail(r.Context(), req.Token)
+	if err != nil {
+		respondWithAccountError(w, "Failed to verify email address", err)
+		return
+	}
+
+	recordAudit(audit.WithActor(r.Context(), userID), &audit.Entry{
+		EntityType: audit.EntityUser,
+		EntityID:   userID,
+		Action:     audit.ActionEmailVerified,
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ResendVerification mails the caller a new verification link
+func (h *AuthHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	userID := getUserIDFromContext(r)
+	if userID == 0 {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated", nil)
+		return
+	}
+
+	user, err := h.userService.GetByID(userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "User not found", err)
+		return
+	}
+
+	if err := h.accountService.SendVerification(r.Context(), user); err != nil {
+		respondWithAccountError(w, "Failed to send verification email", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// respondWithAccountError maps account service errors to a response status
+func respondWithAccountError(w http.ResponseWriter, message string, err error) {
+	switch {
+	case errors.Is(err, account.ErrInvalidToken):
+		respondWithError(w, http.StatusBadRequest, "Invalid or expired token", err)
+	case errors.Is(err, password.ErrWeakPassword):
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+	case errors.Is(err, account.ErrAlreadyVerified):
+		respondWithError(w, http.StatusConflict, err.Error(), err)
+	default:
+		logger.Error(message, err, nil)
+		respondWithError(w, http.StatusInternalServerError, message, err)
+	}
+}
@@ -0,0 +1,203 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"backend_path/internal/api/dto"
+	"backend_path/internal/apikey"
+	"backend_path/internal/audit"
+	"backend_path/internal/auth"
+	"backend_path/internal/user"
+	apperrors "backend_path/pkg/errors"
+	"backend_path/pkg/logger"
+
+	"github.com/go-chi/chi/v5"
+)
+
+var apiKeyService apikey.APIKeyService
+
+// SetAPIKeyService sets the API key service dependency
+func SetAPIKeyService(service apikey.APIKeyService) {
+	apiKeyService = service
+}
+
+func ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := apiKeyService.List()
+	if err != nil {
+		logger.Error("Failed to list API keys", err, nil)
+		respondWithError(w, http.StatusInternalServerError, "Failed to list API keys", err)
+		return
+	}
+
+	response := make([]dto.APIKeyResponse, len(keys))
+	for i, key := range keys {
+		response[i] = toAPIKeyResponse(key, "")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+func GetAPIKey(w http.ResponseWriter, r *http.Request) {
+	keyID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid API key ID", err)
+		return
+	}
+
+	key, err := apiKeyService.Get(keyID)
+	if err != nil {
+		respondWithAPIKeyError(w, "Failed to get API key", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(toAPIKeyResponse(key, ""))
+}
+
+// CreateAPIKey issues an API key and returns it once. Callers can only
+// issue keys to users whose role they hold themselves.
+func CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	callerID := getUserIDFromContext(r)
+	if callerID == 0 {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated", nil)
+		return
+	}
+
+	var req dto.APIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if req.UserID == 0 {
+		req.UserID = callerID
+	}
+
+	owner, err := userService.GetByID(req.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "User not found", err)
+		return
+	}
+	roles, err := auth.CallerRoles(r.Context(), userService)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated", err)
+		return
+	}
+	if !rbacManager.HasRole(roles, owner.Role) {
+		apperrors.WriteError(w, apperrors.InsufficientRole("Cannot issue keys to users with a role you do not hold").WithDetails(map[string]interface{}{
+			"role": owner.Role,
+		}), r.Context())
+		return
+	}
+
+	key := &apikey.APIKey{
+		UserID:     owner.ID,
+		Name:       req.Name,
+		Scopes:     req.Scopes,
+		AllowedIPs: req.AllowedIPs,
+		ExpiresAt:  req.ExpiresAt,
+		CreatedBy:  callerID,
+	}
+	plaintext, err := apiKeyService.Issue(r.Context(), key)
+	if err != nil {
+		respondWithAPIKeyError(w, "Failed to issue API key", err)
+		return
+	}
+
+	recordAudit(r.Context(), &audit.Entry{
+		EntityType: audit.EntityAPIKey,
+		EntityID:   key.ID,
+		Action:     audit.ActionCreate,
+		After:      toAPIKeyResponse(key, ""),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(toAPIKeyResponse(key, plaintext))
+}
+
+// RotateAPIKey replaces an API key by a new one with the same settings and
+// returns it once. The old key stops working immediately.
+func RotateAPIKey(w http.ResponseWriter, r *http.Request) {
+	keyID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid API key ID", err)
+		return
+	}
+
+	key, plaintext, err := apiKeyService.Rotate(r.Context(), keyID)
+	if err != nil {
+		respondWithAPIKeyError(w, "Failed to rotate API key", err)
+		return
+	}
+
+	recordAudit(r.Context(), &audit.Entry{
+		EntityType: audit.EntityAPIKey,
+		EntityID:   key.ID,
+		Action:     audit.ActionRotate,
+		Details: map[string]interface{}{
+			"rotated_from": keyID,
+		},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(toAPIKeyResponse(key, plaintext))
+}
+
+func RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	keyID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid API key ID", err)
+		return
+	}
+
+	if err := apiKeyService.Revoke(r.Context(), keyID); err != nil {
+		respondWithAPIKeyError(w, "Failed to revoke API key", err)
+		return
+	}
+
+	recordAudit(r.Context(), &audit.Entry{
+		EntityType: audit.EntityAPIKey,
+		EntityID:   keyID,
+		Action:     audit.ActionDelete,
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// respondWithAPIKeyError maps API key service errors to a response status
+func respondWithAPIKeyError(w http.ResponseWriter, message string, err error) {
+	switch {
+	case errors.Is(err, apikey.ErrNotFound):
+		respondWithError(w, http.StatusNotFound, "API key not found", err)
+	case errors.Is(err, apikey.ErrInvalidAPIKey):
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+	case errors.Is(err, user.ErrNotFound):
+		respondWithError(w, http.StatusNotFound, "User not found", err)
+	default:
+		logger.Error(message, err, nil)
+		respondWithError(w, http.StatusInternalServerError, message, err)
+	}
+}
+
+func toAPIKeyResponse(key *apikey.APIKey, plaintext string) dto.APIKeyResponse {
+	return dto.APIKeyResponse{
+		ID:         key.ID,
+		UserID:     key.UserID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Key:        plaintext,
+		Scopes:     key.Scopes,
+		AllowedIPs: key.AllowedIPs,
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+		CreatedBy:  key.CreatedBy,
+		CreatedAt:  key.CreatedAt,
+	}
+}
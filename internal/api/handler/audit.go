@@ -0,0 +1,181 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"backend_path/internal/api/dto"
+	"backend_path/internal/audit"
+	"backend_path/internal/auth"
+	"backend_path/internal/domain"
+	"backend_path/pkg/logger"
+)
+
+var auditService audit.AuditService
+
+var rbacManager *auth.RBACManager
+
+// SetAuditService sets the audit service dependency
+func SetAuditService(service audit.AuditService) {
+	auditService = service
+}
+
+// SetRBACManager sets the RBAC manager dependency
+func SetRBACManager(manager *auth.RBACManager) {
+	rbacManager = manager
+}
+
+func ListAuditLogs(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseAuditFilter(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid audit log filter", err)
+		return
+	}
+
+	logs, total, err := auditService.List(filter)
+	if err != nil {
+		logger.Error("Failed to list audit logs", err, nil)
+		respondWithError(w, http.StatusInternalServerError, "Failed to list audit logs", err)
+		return
+	}
+
+	if filter.Limit <= 0 {
+		filter.Limit = audit.DefaultPageSize
+	}
+	if filter.Limit > audit.MaxPageSize {
+		filter.Limit = audit.MaxPageSize
+	}
+
+	response := dto.AuditLogListResponse{
+		Items:  make([]dto.AuditLogResponse, len(logs)),
+		Total:  total,
+		Limit:  filter.Limit,
+		Offset: filter.Offset,
+	}
+	for i, log := range logs {
+		response.Items[i] = toAuditLogResponse(log)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+func VerifyAuditLogs(w http.ResponseWriter, r *http.Request) {
+	result, err := auditService.Verify(r.Context())
+	if err != nil {
+		logger.Error("Failed to verify audit logs", err, nil)
+		respondWithError(w, http.StatusInternalServerError, "Failed to verify audit logs", err)
+		return
+	}
+
+	response := dto.AuditVerifyResponse{
+		Valid:       result.Valid,
+		Checked:     result.Checked,
+		Unchained:   result.Unchained,
+		Checkpoints: result.Checkpoints,
+		LastLogID:   result.LastLogID,
+	}
+	if link := result.BrokenLink; link != nil {
+		response.BrokenLink = &dto.AuditBrokenLinkResponse{
+			LogID:        link.LogID,
+			CheckpointID: link.CheckpointID,
+			Reason:       link.Reason,
+			Expected:     link.Expected,
+			Actual:       link.Actual,
+		}
+		logger.Warn("Audit chain is broken", map[string]interface{}{
+			"log_id": link.LogID,
+			"reason": link.Reason,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// parseAuditFilter reads the audit log filter from the query string
+func parseAuditFilter(r *http.Request) (audit.Filter, error) {
+	query := r.URL.Query()
+	filter := audit.Filter{
+		EntityType: query.Get("entity_type"),
+		Action:     query.Get("action"),
+	}
+
+	var err error
+	for name, target := range map[string]*int{
+		"entity_id":       &filter.EntityID,
+		"actor_id":        &filter.ActorID,
+		"impersonator_id": &filter.ImpersonatorID,
+		"limit":           &filter.Limit,
+		"offset":          &filter.Offset,
+	} {
+		if value := query.Get(name); value != "" {
+			if *target, err = strconv.Atoi(value); err != nil {
+				return filter, err
+			}
+		}
+	}
+
+	for name, target := range map[string]*time.Time{
+		"from": &filter.From,
+		"to":   &filter.To,
+	} {
+		if value := query.Get(name); value != "" {
+			if *target, err = time.Parse(time.RFC3339, value); err != nil {
+				return filter, err
+			}
+		}
+	}
+
+	return filter, nil
+}
+
+func toAuditLogResponse(log *domain.AuditLog) dto.AuditLogResponse {
+	response := dto.AuditLogResponse{
+		ID:             log.ID,
+		EntityType:     log.EntityType,
+		EntityID:       log.EntityID,
+		Action:         log.Action,
+		ActorID:        log.ActorID,
+		ImpersonatorID: log.ImpersonatorID,
+		IPAddress:      log.IPAddress,
+		UserAgent:      log.UserAgent,
+		TraceID:        log.TraceID,
+		Before:         log.Before,
+		After:          log.After,
+		Changes:        log.Changes,
+		PrevHash:       log.PrevHash,
+		Hash:           log.Hash,
+		CreatedAt:      log.CreatedAt,
+	}
+	if log.Details != "" {
+		// Older entries may hold plain text details
+		if json.Valid([]byte(log.Details)) {
+			response.Details = json.RawMessage(log.Details)
+		} else {
+			response.Details, _ = json.Marshal(log.Details)
+		}
+	}
+	return response
+}
+
+// recordAudit records entry outside of any transaction. A failure is logged
+// but does not fail the request that caused it.
+func recordAudit(ctx context.Context, entry *audit.Entry) {
+	if auditService == nil {
+		return
+	}
+	if err := auditService.Record(ctx, entry); err != nil {
+		logger.Warn("Failed to record audit entry", map[string]interface{}{
+			"entity_type": entry.EntityType,
+			"entity_id":   entry.EntityID,
+			"action":      entry.Action,
+			"error":       err.Error(),
+		})
+	}
+}
@@ -2,25 +2,47 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net"
 	"net/http"
+	"strconv"
 	"time"
 
+	"backend_path/internal/account"
 	"backend_path/internal/api/dto"
+	"backend_path/internal/audit"
 	"backend_path/internal/domain"
+	"backend_path/internal/lockout"
+	"backend_path/internal/metrics"
+	"backend_path/internal/mfa"
+	"backend_path/internal/password"
+	"backend_path/internal/session"
 	"backend_path/internal/user"
 	"backend_path/pkg/jwt"
 	"backend_path/pkg/logger"
+
+	"github.com/go-chi/chi/v5"
 )
 
 type AuthHandler struct {
-	userService user.UserService
-	jwtService  *jwt.JWTService
+	userService    user.UserService
+	jwtService     *jwt.JWTService
+	sessionService session.SessionService
+	mfaService     mfa.MFAService
+	loginGuard     *lockout.Guard
+	accountService account.AccountService
 }
 
-func NewAuthHandler(userService user.UserService, jwtService *jwt.JWTService) *AuthHandler {
+func NewAuthHandler(userService user.UserService, jwtService *jwt.JWTService, sessionService session.SessionService, mfaService mfa.MFAService, loginGuard *lockout.Guard, accountService account.AccountService) *AuthHandler {
 	return &AuthHandler{
-		userService: userService,
-		jwtService:  jwtService,
+		userService:    userService,
+		jwtService:     jwtService,
+		sessionService: sessionService,
+		mfaService:     mfaService,
+		loginGuard:     loginGuard,
+		accountService: accountService,
 	}
 }
 
@@ -35,11 +57,14 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	user := &domain.User{
 		Username: req.Username,
 		Email:    req.Email,
-		Role:     req.Role,
 	}
 
 	// Register user
 	if err := h.userService.Register(user, req.Password); err != nil {
+		if errors.Is(err, password.ErrWeakPassword) {
+			respondWithError(w, http.StatusBadRequest, err.Error(), err)
+			return
+		}
 		logger.Error("Failed to register user", err, map[string]interface{}{
 			"email": req.Email,
 		})
@@ -47,38 +72,22 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Generate tokens
-	token, err := h.jwtService.GenerateToken(user.ID, user.Username, user.Email, user.Role)
-	if err != nil {
-		logger.Error("Failed to generate token", err, nil)
-		respondWithError(w, http.StatusInternalServerError, "Failed to generate token", err)
-		return
-	}
+	recordAudit(audit.WithActor(r.Context(), user.ID), &audit.Entry{
+		EntityType: audit.EntityUser,
+		EntityID:   user.ID,
+		Action:     audit.ActionCreate,
+		After:      user,
+	})
 
-	refreshToken, err := h.jwtService.GenerateRefreshToken(user.ID, user.Username)
-	if err != nil {
-		logger.Error("Failed to generate refresh token", err, nil)
-		respondWithError(w, http.StatusInternalServerError, "Failed to generate refresh token", err)
-		return
-	}
-
-	response := dto.AuthResponse{
-		Token:        token,
-		RefreshToken: refreshToken,
-		ExpiresIn:    3600, // 1 hour
-		User: dto.UserInfo{
-			ID:        user.ID,
-			Username:  user.Username,
-			Email:     user.Email,
-			Role:      user.Role,
-			CreatedAt: user.CreatedAt,
-		},
-		Timestamp: time.Now(),
+	// The account works without a verified address, but cannot move money
+	// until it is verified
+	if err := h.accountService.SendVerification(r.Context(), user); err != nil {
+		logger.Error("Failed to send verification email", err, map[string]interface{}{
+			"user_id": user.ID,
+		})
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(response)
+	h.respondWithTokens(w, r, user, http.StatusCreated)
 }
 
 func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
@@ -88,47 +97,304 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !allowLogin(w, r, h.loginGuard, req.Email) {
+		return
+	}
+
 	// Authenticate user
 	user, err := h.userService.Authenticate(req.Email, req.Password)
 	if err != nil {
 		logger.Error("Authentication failed", err, map[string]interface{}{
 			"email": req.Email,
 		})
+		recordAudit(r.Context(), &audit.Entry{
+			EntityType: audit.EntityUser,
+			Action:     audit.ActionLoginFailed,
+			Details: map[string]interface{}{
+				"email": req.Email,
+			},
+		})
+		recordLoginFailure(r, h.loginGuard, req.Email, "invalid_credentials")
 		respondWithError(w, http.StatusUnauthorized, "Invalid credentials", err)
 		return
 	}
 
-	// Generate tokens
-	token, err := h.jwtService.GenerateToken(user.ID, user.Username, user.Email, user.Role)
+	// Users with two-factor authentication get an MFA token to exchange,
+	// with a code, for their tokens
+	mfaEnabled, err := h.mfaService.IsEnabled(user.ID)
 	if err != nil {
-		logger.Error("Failed to generate token", err, nil)
-		respondWithError(w, http.StatusInternalServerError, "Failed to generate token", err)
+		logger.Error("Failed to check two-factor authentication", err, map[string]interface{}{
+			"user_id": user.ID,
+		})
+		respondWithError(w, http.StatusInternalServerError, "Failed to log in", err)
+		return
+	}
+	if mfaEnabled {
+		mfaToken, err := h.jwtService.GenerateMFAToken(user.ID, user.Username)
+		if err != nil {
+			logger.Error("Failed to generate MFA token", err, nil)
+			respondWithError(w, http.StatusInternalServerError, "Failed to generate token", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(dto.MFAChallengeResponse{
+			MFARequired: true,
+			MFAToken:    mfaToken,
+			ExpiresIn:   int(jwt.MFATokenDuration.Seconds()),
+		})
+		return
+	}
+
+	recordAudit(audit.WithActor(r.Context(), user.ID), &audit.Entry{
+		EntityType: audit.EntityUser,
+		EntityID:   user.ID,
+		Action:     audit.ActionLogin,
+	})
+	recordLoginSuccess(r, h.loginGuard, user.Email)
+
+	h.respondWithTokens(w, r, user, http.StatusOK)
+}
+
+// VerifyMFA completes a login with the MFA token from Login and a TOTP or
+// recovery code
+func (h *AuthHandler) VerifyMFA(w http.ResponseWriter, r *http.Request) {
+	var req dto.MFAVerifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	claims, err := h.jwtService.ValidateMFAToken(req.MFAToken)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid MFA token", err)
+		return
+	}
+
+	user, err := h.userService.GetByID(claims.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "User not found", err)
+		return
+	}
+
+	// Wrong codes count towards the lockout of the account like wrong
+	// passwords do
+	if !allowLogin(w, r, h.loginGuard, user.Email) {
+		return
+	}
+
+	ctx := audit.WithActor(r.Context(), user.ID)
+	if err := h.mfaService.Verify(ctx, user.ID, req.Code); err != nil {
+		if err == mfa.ErrInvalidCode || err == mfa.ErrNotEnabled {
+			recordAudit(ctx, &audit.Entry{
+				EntityType: audit.EntityUser,
+				EntityID:   user.ID,
+				Action:     audit.ActionMFAFailed,
+			})
+			recordLoginFailure(r, h.loginGuard, user.Email, "invalid_mfa_code")
+			respondWithError(w, http.StatusUnauthorized, "Invalid two-factor code", err)
+			return
+		}
+		logger.Error("Failed to verify two-factor code", err, map[string]interface{}{
+			"user_id": user.ID,
+		})
+		respondWithError(w, http.StatusInternalServerError, "Failed to verify two-factor code", err)
+		return
+	}
+
+	recordAudit(ctx, &audit.Entry{
+		EntityType: audit.EntityUser,
+		EntityID:   user.ID,
+		Action:     audit.ActionLogin,
+		Details: map[string]interface{}{
+			"mfa": true,
+		},
+	})
+	recordLoginSuccess(r, h.loginGuard, user.Email)
+
+	h.respondWithTokens(w, r, user, http.StatusOK)
+}
+
+// allowLogin refuses login attempts for email, or from the client's IP
+// address, while they are locked out or delayed after earlier failures. It
+// fails closed if the failure counts cannot be read.
+func allowLogin(w http.ResponseWriter, r *http.Request, guard *lockout.Guard, email string) bool {
+	block, err := guard.Check(r.Context(), email, clientIP(r))
+	if err != nil {
+		logger.Error("Failed to check login lockout", err, nil)
+		respondWithError(w, http.StatusServiceUnavailable, "Login is temporarily unavailable", err)
+		return false
+	}
+	if block == nil {
+		return true
+	}
+
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(block.RetryAfter.Seconds()))))
+	if block.Locked {
+		metrics.LoginFailuresTotal.WithLabelValues("locked").Inc()
+		respondWithError(w, http.StatusTooManyRequests, "Too many failed login attempts, try again later", nil)
+		return false
+	}
+	metrics.LoginFailuresTotal.WithLabelValues("throttled").Inc()
+	respondWithError(w, http.StatusTooManyRequests, "Too many failed login attempts, slow down", nil)
+	return false
+}
+
+// clientIP returns the client address AuditMiddleware resolved for r, which
+// only believes forwarding headers from trusted proxies. Without it, the
+// peer address is used; the headers themselves are never read here.
+func clientIP(r *http.Request) string {
+	if ip := audit.RequestInfoFromContext(r.Context()).IP; ip != "" {
+		return ip
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// recordLoginFailure counts a failed login, or a wrong two-factor code, and
+// audits any lockout it causes
+func recordLoginFailure(r *http.Request, guard *lockout.Guard, email, reason string) {
+	metrics.LoginFailuresTotal.WithLabelValues(reason).Inc()
+
+	ip := clientIP(r)
+	result, err := guard.RecordFailure(r.Context(), email, ip)
+	if err != nil {
+		logger.Error("Failed to record login failure", err, map[string]interface{}{
+			"email": email,
+		})
 		return
 	}
 
-	refreshToken, err := h.jwtService.GenerateRefreshToken(user.ID, user.Username)
+	for scope, locked := range map[string]bool{lockout.ScopeEmail: result.EmailLocked, lockout.ScopeIP: result.IPLocked} {
+		if !locked {
+			continue
+		}
+		logger.Warn("Logins locked out after repeated failures", map[string]interface{}{
+			"scope": scope,
+			"email": email,
+			"ip":    ip,
+		})
+		recordAudit(r.Context(), &audit.Entry{
+			EntityType: audit.EntityUser,
+			Action:     audit.ActionLockout,
+			Details: map[string]interface{}{
+				"scope": scope,
+				"email": email,
+				"ip":    ip,
+			},
+		})
+	}
+}
+
+// recordLoginSuccess forgets the failed logins of email
+func recordLoginSuccess(r *http.Request, guard *lockout.Guard, email string) {
+	if err := guard.RecordSuccess(r.Context(), email); err != nil {
+		logger.Error("Failed to clear login failures", err, map[string]interface{}{
+			"email": email,
+		})
+	}
+}
+
+// UnlockUser lifts the login lockout of a user
+func (h *AuthHandler) UnlockUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+
+	user, err := h.userService.GetByID(userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "User not found", err)
+		return
+	}
+
+	if err := h.loginGuard.Unlock(r.Context(), user.Email); err != nil {
+		logger.Error("Failed to unlock user", err, map[string]interface{}{
+			"user_id": userID,
+		})
+		respondWithError(w, http.StatusInternalServerError, "Failed to unlock user", err)
+		return
+	}
+
+	recordAudit(r.Context(), &audit.Entry{
+		EntityType: audit.EntityUser,
+		EntityID:   userID,
+		Action:     audit.ActionUnlock,
+		Details: map[string]interface{}{
+			"scope": lockout.ScopeEmail,
+			"email": user.Email,
+		},
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UnlockIP lifts the login lockout of an IP address
+func (h *AuthHandler) UnlockIP(w http.ResponseWriter, r *http.Request) {
+	ip := chi.URLParam(r, "ip")
+	if net.ParseIP(ip) == nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid IP address", nil)
+		return
+	}
+
+	if err := h.loginGuard.UnlockIP(r.Context(), ip); err != nil {
+		logger.Error("Failed to unlock IP address", err, map[string]interface{}{
+			"ip": ip,
+		})
+		respondWithError(w, http.StatusInternalServerError, "Failed to unlock IP address", err)
+		return
+	}
+
+	recordAudit(r.Context(), &audit.Entry{
+		EntityType: audit.EntityUser,
+		Action:     audit.ActionUnlock,
+		Details: map[string]interface{}{
+			"scope": lockout.ScopeIP,
+			"ip":    ip,
+		},
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// respondWithTokens starts a session for user on the requesting device and
+// responds with its access and refresh tokens
+func (h *AuthHandler) respondWithTokens(w http.ResponseWriter, r *http.Request, user *domain.User, status int) {
+	session, refreshToken, err := h.sessionService.Issue(r.Context(), user, requestDevice(r))
 	if err != nil {
 		logger.Error("Failed to generate refresh token", err, nil)
 		respondWithError(w, http.StatusInternalServerError, "Failed to generate refresh token", err)
 		return
 	}
 
+	token, err := h.jwtService.GenerateSessionToken(user.ID, user.Username, user.Email, user.Role, session.ID)
+	if err != nil {
+		logger.Error("Failed to generate token", err, nil)
+		respondWithError(w, http.StatusInternalServerError, "Failed to generate token", err)
+		return
+	}
+
 	response := dto.AuthResponse{
 		Token:        token,
 		RefreshToken: refreshToken,
 		ExpiresIn:    3600, // 1 hour
 		User: dto.UserInfo{
-			ID:        user.ID,
-			Username:  user.Username,
-			Email:     user.Email,
-			Role:      user.Role,
-			CreatedAt: user.CreatedAt,
+			ID:            user.ID,
+			Username:      user.Username,
+			Email:         user.Email,
+			Role:          user.Role,
+			EmailVerified: user.EmailVerified(),
+			CreatedAt:     user.CreatedAt,
 		},
 		Timestamp: time.Now(),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
+	w.WriteHeader(status)
 	json.NewEncoder(w).Encode(response)
 }
 
@@ -139,10 +405,24 @@ func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Validate refresh token
-	claims, err := h.jwtService.ValidateToken(req.RefreshToken)
+	// Rotate the refresh token; access tokens and used tokens are rejected
+	claims, refreshToken, err := h.sessionService.Rotate(r.Context(), req.RefreshToken, requestDevice(r))
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Invalid refresh token", err)
+		if err == session.ErrTokenReused {
+			recordAudit(audit.WithActor(r.Context(), claims.UserID), &audit.Entry{
+				EntityType: audit.EntityUser,
+				EntityID:   claims.UserID,
+				Action:     audit.ActionTokenReuse,
+				Details: map[string]interface{}{
+					"family_id": claims.FamilyID,
+				},
+			})
+		}
+		if err == session.ErrInvalidToken || err == session.ErrTokenRevoked || err == session.ErrTokenReused {
+			respondWithError(w, http.StatusUnauthorized, "Invalid refresh token", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Failed to refresh token", err)
 		return
 	}
 
@@ -154,30 +434,24 @@ func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Generate new tokens
-	token, err := h.jwtService.GenerateToken(user.ID, user.Username, user.Email, user.Role)
+	token, err := h.jwtService.GenerateSessionToken(user.ID, user.Username, user.Email, user.Role, claims.FamilyID)
 	if err != nil {
 		logger.Error("Failed to generate token", err, nil)
 		respondWithError(w, http.StatusInternalServerError, "Failed to generate token", err)
 		return
 	}
 
-	refreshToken, err := h.jwtService.GenerateRefreshToken(user.ID, user.Username)
-	if err != nil {
-		logger.Error("Failed to generate refresh token", err, nil)
-		respondWithError(w, http.StatusInternalServerError, "Failed to generate refresh token", err)
-		return
-	}
-
 	response := dto.AuthResponse{
 		Token:        token,
 		RefreshToken: refreshToken,
 		ExpiresIn:    3600, // 1 hour
 		User: dto.UserInfo{
-			ID:        user.ID,
-			Username:  user.Username,
-			Email:     user.Email,
-			Role:      user.Role,
-			CreatedAt: user.CreatedAt,
+			ID:            user.ID,
+			Username:      user.Username,
+			Email:         user.Email,
+			Role:          user.Role,
+			EmailVerified: user.EmailVerified(),
+			CreatedAt:     user.CreatedAt,
 		},
 		Timestamp: time.Now(),
 	}
@@ -187,6 +461,71 @@ func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	claims := jwt.ClaimsFromContext(r.Context())
+	if claims == nil {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated", nil)
+		return
+	}
+
+	// The body is optional
+	var req dto.LogoutRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	if err := h.sessionService.Logout(r.Context(), claims, req.RefreshToken); err != nil {
+		logger.Error("Failed to log out", err, map[string]interface{}{
+			"user_id": claims.UserID,
+		})
+		respondWithError(w, http.StatusInternalServerError, "Failed to log out", err)
+		return
+	}
+
+	recordAudit(r.Context(), &audit.Entry{
+		EntityType: audit.EntityUser,
+		EntityID:   claims.UserID,
+		Action:     audit.ActionLogout,
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AuthHandler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	userID := getUserIDFromContext(r)
+	if userID == 0 {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated", nil)
+		return
+	}
+
+	if err := h.sessionService.LogoutAll(r.Context(), userID); err != nil {
+		logger.Error("Failed to log out all sessions", err, map[string]interface{}{
+			"user_id": userID,
+		})
+		respondWithError(w, http.StatusInternalServerError, "Failed to log out all sessions", err)
+		return
+	}
+
+	recordAudit(r.Context(), &audit.Entry{
+		EntityType: audit.EntityUser,
+		EntityID:   userID,
+		Action:     audit.ActionLogoutAll,
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// JWKS publishes the public keys tokens are verified with, so other
+// services can verify them without sharing a secret
+func (h *AuthHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	// Keys are published well before they sign, so caching is safe
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(h.jwtService.JWKS())
+}
+
 // Helper function to respond with error
 func respondWithError(w http.ResponseWriter, statusCode int, message string, err error) {
 	response := dto.ErrorResponse{
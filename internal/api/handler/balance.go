@@ -3,8 +3,10 @@ package handler
 import (
 	"encoding/json"
 	"net/http"
+	"time"
 
 	"backend_path/internal/api/dto"
+	"backend_path/internal/auth"
 	"backend_path/internal/balance"
 	"backend_path/pkg/logger"
 )
@@ -46,6 +48,37 @@ func CurrentBalance(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// UserBalance returns the current balance of the user in the userID URL
+// parameter, authorized by LoadBalance
+func UserBalance(w http.ResponseWriter, r *http.Request) {
+	resource := auth.ResourceFromContext(r.Context())
+	if resource == nil {
+		respondWithError(w, http.StatusNotFound, "Balance not found", nil)
+		return
+	}
+	userID := resource.ID
+
+	currentBalance, err := balanceService.GetCurrentBalance(userID)
+	if err != nil {
+		logger.Error("Failed to get current balance", err, map[string]interface{}{
+			"user_id": userID,
+		})
+		respondWithError(w, http.StatusInternalServerError, "Failed to get current balance", err)
+		return
+	}
+
+	response := dto.BalanceResponse{
+		UserID:  userID,
+		Amount:  currentBalance,
+		Type:    "current",
+		Updated: "now",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
 func HistoricalBalance(w http.ResponseWriter, r *http.Request) {
 	// Get user ID from context (set by auth middleware)
 	userID := getUserIDFromContext(r)
@@ -54,13 +87,27 @@ func HistoricalBalance(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get query parameters for date range (for future implementation)
-	_ = r.URL.Query().Get("from")
-	_ = r.URL.Query().Get("to")
+	// Get query parameters for date range, defaulting to the whole history
+	from := time.Time{}
+	to := time.Now()
+	if value := r.URL.Query().Get("from"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid 'from' parameter, expected RFC3339", err)
+			return
+		}
+		from = parsed
+	}
+	if value := r.URL.Query().Get("to"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid 'to' parameter, expected RFC3339", err)
+			return
+		}
+		to = parsed
+	}
 
-	// For now, return current balance as historical data
-	// In a real implementation, you would query historical balance data
-	currentBalance, err := balanceService.GetCurrentBalance(userID)
+	history, err := balanceService.GetBalanceHistory(userID, from, to)
 	if err != nil {
 		logger.Error("Failed to get historical balance", err, map[string]interface{}{
 			"user_id": userID,
@@ -69,13 +116,14 @@ func HistoricalBalance(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response := []dto.BalanceResponse{
-		{
+	response := make([]dto.BalanceResponse, 0, len(history))
+	for _, entry := range history {
+		response = append(response, dto.BalanceResponse{
 			UserID:  userID,
-			Amount:  currentBalance,
+			Amount:  entry.Balance,
 			Type:    "historical",
-			Updated: "now",
-		},
+			Updated: entry.OccurredAt.Format(time.RFC3339),
+		})
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"backend_path/internal/api/dto"
+	"backend_path/internal/audit"
+	"backend_path/internal/auth"
+	apperrors "backend_path/pkg/errors"
+	"backend_path/pkg/jwt"
+	"backend_path/pkg/logger"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Impersonate issues the caller a short-lived, read-only access token acting
+// as another user, naming the caller in its act claim. Only users whose role
+// the caller holds themselves can be impersonated.
+func (h *AuthHandler) Impersonate(w http.ResponseWriter, r *http.Request) {
+	claims := jwt.ClaimsFromContext(r.Context())
+	if claims == nil {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated", nil)
+		return
+	}
+
+	userID, err := strconv.Atoi(chi.URLParam(r, "userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+	if userID == claims.UserID {
+		respondWithError(w, http.StatusBadRequest, "Cannot impersonate yourself", nil)
+		return
+	}
+
+	target, err := h.userService.GetByID(userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "User not found", err)
+		return
+	}
+
+	roles, err := auth.CallerRoles(r.Context(), h.userService)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated", err)
+		return
+	}
+	if !rbacManager.HasRole(roles, target.Role) {
+		apperrors.WriteError(w, apperrors.InsufficientRole("Cannot impersonate users with a role you do not hold").WithDetails(map[string]interface{}{
+			"role": target.Role,
+		}), r.Context())
+		return
+	}
+
+	token, tokenClaims, err := h.jwtService.GenerateImpersonationToken(target.ID, target.Username, target.Email, target.Role, &jwt.Actor{
+		UserID:   claims.UserID,
+		Username: claims.Username,
+	})
+	if err != nil {
+		logger.Error("Failed to generate impersonation token", err, map[string]interface{}{
+			"user_id":         target.ID,
+			"impersonator_id": claims.UserID,
+		})
+		respondWithError(w, http.StatusInternalServerError, "Failed to generate token", err)
+		return
+	}
+
+	recordAudit(r.Context(), &audit.Entry{
+		EntityType: audit.EntityUser,
+		EntityID:   target.ID,
+		Action:     audit.ActionImpersonate,
+		Details: map[string]interface{}{
+			"token_id":   tokenClaims.ID,
+			"expires_at": tokenClaims.ExpiresAt.Time,
+		},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(dto.ImpersonationResponse{
+		Token:     token,
+		ExpiresIn: int(jwt.ImpersonationTokenDuration.Seconds()),
+		ExpiresAt: tokenClaims.ExpiresAt.Time,
+		User: dto.UserInfo{
+			ID:            target.ID,
+			Username:      target.Username,
+			Email:         target.Email,
+			Role:          target.Role,
+			EmailVerified: target.EmailVerified(),
+			CreatedAt:     target.CreatedAt,
+		},
+		ImpersonatorID: claims.UserID,
+	})
+}
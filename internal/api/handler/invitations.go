@@ -0,0 +1,166 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"backend_path/internal/account"
+	"backend_path/internal/api/dto"
+	"backend_path/internal/audit"
+	"backend_path/internal/auth"
+	apperrors "backend_path/pkg/errors"
+	"backend_path/pkg/logger"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// CreateInvitation mails an invitation to create an account with a role.
+// Callers can only invite to roles they hold themselves.
+func (h *AuthHandler) CreateInvitation(w http.ResponseWriter, r *http.Request) {
+	userID := getUserIDFromContext(r)
+	if userID == 0 {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated", nil)
+		return
+	}
+
+	var req dto.InvitationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if req.Email == "" || req.Role == "" {
+		respondWithError(w, http.StatusBadRequest, "Email and role are required", nil)
+		return
+	}
+	if !rbacManager.ValidateRole(req.Role) {
+		respondWithError(w, http.StatusBadRequest, "Unknown role", nil)
+		return
+	}
+
+	roles, err := auth.CallerRoles(r.Context(), h.userService)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated", err)
+		return
+	}
+	if !rbacManager.HasRole(roles, req.Role) {
+		apperrors.WriteError(w, apperrors.InsufficientRole("Cannot invite to a role you do not hold").WithDetails(map[string]interface{}{
+			"role": req.Role,
+		}), r.Context())
+		return
+	}
+
+	invitation, err := h.accountService.Invite(r.Context(), req.Email, req.Role, userID)
+	if err != nil {
+		respondWithInvitationError(w, "Failed to create invitation", err)
+		return
+	}
+
+	recordAudit(r.Context(), &audit.Entry{
+		EntityType: audit.EntityInvitation,
+		EntityID:   invitation.ID,
+		Action:     audit.ActionCreate,
+		Details: map[string]interface{}{
+			"email": invitation.Email,
+			"role":  invitation.Role,
+		},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(toInvitationResponse(invitation))
+}
+
+// ListInvitations lists the invitations that can still be accepted
+func (h *AuthHandler) ListInvitations(w http.ResponseWriter, r *http.Request) {
+	invitations, err := h.accountService.ListInvitations()
+	if err != nil {
+		logger.Error("Failed to list invitations", err, nil)
+		respondWithError(w, http.StatusInternalServerError, "Failed to list invitations", err)
+		return
+	}
+
+	response := make([]dto.InvitationResponse, len(invitations))
+	for i, invitation := range invitations {
+		response[i] = toInvitationResponse(invitation)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// RevokeInvitation revokes a pending invitation
+func (h *AuthHandler) RevokeInvitation(w http.ResponseWriter, r *http.Request) {
+	invitationID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid invitation ID", err)
+		return
+	}
+
+	if err := h.accountService.RevokeInvitation(r.Context(), invitationID); err != nil {
+		respondWithInvitationError(w, "Failed to revoke invitation", err)
+		return
+	}
+
+	recordAudit(r.Context(), &audit.Entry{
+		EntityType: audit.EntityInvitation,
+		EntityID:   invitationID,
+		Action:     audit.ActionDelete,
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AcceptInvitation creates the invited account with a username and password
+// and logs it in
+func (h *AuthHandler) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	var req dto.AcceptInvitationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	user, err := h.accountService.AcceptInvitation(r.Context(), req.Token, req.Username, req.Password)
+	if err != nil {
+		respondWithInvitationError(w, "Failed to accept invitation", err)
+		return
+	}
+
+	recordAudit(audit.WithActor(r.Context(), user.ID), &audit.Entry{
+		EntityType: audit.EntityUser,
+		EntityID:   user.ID,
+		Action:     audit.ActionCreate,
+		After:      user,
+		Details: map[string]interface{}{
+			"invited": true,
+		},
+	})
+
+	h.respondWithTokens(w, r, user, http.StatusOK)
+}
+
+// respondWithInvitationError maps invitation errors to a response status
+func respondWithInvitationError(w http.ResponseWriter, message string, err error) {
+	switch err {
+	case account.ErrInvitationNotFound:
+		respondWithError(w, http.StatusNotFound, "Invitation not found", err)
+	case account.ErrEmailTaken:
+		respondWithError(w, http.StatusConflict, err.Error(), err)
+	case account.ErrUsernameRequired:
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+	default:
+		respondWithAccountError(w, message, err)
+	}
+}
+
+func toInvitationResponse(invitation *account.Invitation) dto.InvitationResponse {
+	return dto.InvitationResponse{
+		ID:        invitation.ID,
+		Email:     invitation.Email,
+		Role:      invitation.Role,
+		InvitedBy: invitation.InvitedBy,
+		ExpiresAt: invitation.ExpiresAt,
+		CreatedAt: invitation.CreatedAt,
+	}
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+
+	"backend_path/internal/auth"
+	"backend_path/pkg/logger"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Resource loaders for RequireResourcePermission and attribute loaders for
+// RequireSelfPermissionWith. Lookup failures are reported as not found, like
+// the handlers do.
+
+// LoadTransaction loads the transaction in the id URL parameter. Its sender
+// and receiver own it.
+func LoadTransaction(r *http.Request) (*auth.Resource, error) {
+	transactionID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		return nil, auth.ErrResourceNotFound
+	}
+
+	tx, err := transactionService.GetTransaction(transactionID)
+	if err != nil {
+		logger.Error("Failed to get transaction", err, map[string]interface{}{
+			"transaction_id": transactionID,
+		})
+		return nil, auth.ErrResourceNotFound
+	}
+
+	resource := &auth.Resource{
+		ID:         tx.ID,
+		Value:      tx,
+		Attributes: auth.Attributes{auth.AttrAmount: tx.Amount},
+	}
+	for _, userID := range []int{tx.FromUserID, tx.ToUserID} {
+		if userID != 0 {
+			resource.OwnerIDs = append(resource.OwnerIDs, userID)
+		}
+	}
+	return resource, nil
+}
+
+// LoadUser loads the user in the id URL parameter, who owns themselves
+func LoadUser(r *http.Request) (*auth.Resource, error) {
+	userID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		return nil, auth.ErrResourceNotFound
+	}
+
+	user, err := userService.GetByID(userID)
+	if err != nil {
+		return nil, auth.ErrResourceNotFound
+	}
+
+	return &auth.Resource{ID: user.ID, OwnerIDs: []int{user.ID}, Value: user}, nil
+}
+
+// LoadBalance loads the balance of the user in the userID URL parameter
+func LoadBalance(r *http.Request) (*auth.Resource, error) {
+	userID, err := strconv.Atoi(chi.URLParam(r, "userID"))
+	if err != nil {
+		return nil, auth.ErrResourceNotFound
+	}
+
+	if _, err := userService.GetByID(userID); err != nil {
+		return nil, auth.ErrResourceNotFound
+	}
+
+	return &auth.Resource{ID: userID, OwnerIDs: []int{userID}}, nil
+}
+
+// TransactionAttributes reads the amount, and the currency if given, from the
+// body of a credit, debit or transfer request. The body is put back for the
+// handler.
+func TransactionAttributes(r *http.Request) (auth.Attributes, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	var req struct {
+		Amount   float64 `json:"amount"`
+		Currency string  `json:"currency"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, err
+	}
+
+	attrs := auth.Attributes{auth.AttrAmount: req.Amount}
+	if req.Currency != "" {
+		attrs[auth.AttrCurrency] = req.Currency
+	}
+	return attrs, nil
+}
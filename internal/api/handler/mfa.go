@@ -0,0 +1,232 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"backend_path/internal/api/dto"
+	"backend_path/internal/audit"
+	"backend_path/internal/lockout"
+	"backend_path/internal/mfa"
+	apperrors "backend_path/pkg/errors"
+	"backend_path/pkg/logger"
+)
+
+// MFACodeHeader carries the two-factor code that steps up a request
+const MFACodeHeader = "X-MFA-Code"
+
+var mfaService mfa.MFAService
+
+// stepUpGuard counts wrong step-up codes towards the login lockout
+var stepUpGuard *lockout.Guard
+
+// stepUpThreshold is the transfer amount above which a two-factor code is
+// required
+var stepUpThreshold float64
+
+// SetMFAService sets the MFA service dependency, the guard wrong step-up
+// codes are counted by, and the transfer amount above which transfers need
+// a two-factor code; zero disables step-up
+func SetMFAService(service mfa.MFAService, guard *lockout.Guard, threshold float64) {
+	mfaService = service
+	stepUpGuard = guard
+	stepUpThreshold = threshold
+}
+
+// EnrollMFA starts TOTP enrollment for the caller
+func (h *AuthHandler) EnrollMFA(w http.ResponseWriter, r *http.Request) {
+	userID := getUserIDFromContext(r)
+	if userID == 0 {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated", nil)
+		return
+	}
+
+	user, err := h.userService.GetByID(userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "User not found", err)
+		return
+	}
+
+	provisioning, err := h.mfaService.Enroll(r.Context(), user)
+	if err != nil {
+		if err == mfa.ErrAlreadyEnabled {
+			respondWithError(w, http.StatusConflict, "Two-factor authentication is already enabled", err)
+			return
+		}
+		logger.Error("Failed to enroll two-factor authentication", err, map[string]interface{}{
+			"user_id": userID,
+		})
+		respondWithError(w, http.StatusInternalServerError, "Failed to enroll two-factor authentication", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dto.MFAEnrollResponse{
+		Secret:          provisioning.Secret,
+		ProvisioningURI: provisioning.URI,
+	})
+}
+
+// ConfirmMFA enables two-factor authentication with a code from the pending
+// enrollment and returns the recovery codes
+func (h *AuthHandler) ConfirmMFA(w http.ResponseWriter, r *http.Request) {
+	userID := getUserIDFromContext(r)
+	if userID == 0 {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated", nil)
+		return
+	}
+
+	var req dto.MFACodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	codes, err := h.mfaService.Confirm(r.Context(), userID, req.Code)
+	if err != nil {
+		respondWithMFAError(w, userID, "Failed to confirm two-factor authentication", err)
+		return
+	}
+
+	recordAudit(r.Context(), &audit.Entry{
+		EntityType: audit.EntityUser,
+		EntityID:   userID,
+		Action:     audit.ActionMFAEnabled,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dto.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// DisableMFA turns two-factor authentication off after checking a code
+func (h *AuthHandler) DisableMFA(w http.ResponseWriter, r *http.Request) {
+	userID := getUserIDFromContext(r)
+	if userID == 0 {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated", nil)
+		return
+	}
+
+	var req dto.MFACodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	if err := h.mfaService.Disable(r.Context(), userID, req.Code); err != nil {
+		respondWithMFAError(w, userID, "Failed to disable two-factor authentication", err)
+		return
+	}
+
+	recordAudit(r.Context(), &audit.Entry{
+		EntityType: audit.EntityUser,
+		EntityID:   userID,
+		Action:     audit.ActionMFADisabled,
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RegenerateRecoveryCodes replaces the caller's recovery codes after
+// checking a code
+func (h *AuthHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	userID := getUserIDFromContext(r)
+	if userID == 0 {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated", nil)
+		return
+	}
+
+	var req dto.MFACodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	codes, err := h.mfaService.RegenerateRecoveryCodes(r.Context(), userID, req.Code)
+	if err != nil {
+		respondWithMFAError(w, userID, "Failed to regenerate recovery codes", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dto.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// respondWithMFAError maps MFA service errors to a response status
+func respondWithMFAError(w http.ResponseWriter, userID int, message string, err error) {
+	switch err {
+	case mfa.ErrInvalidCode:
+		respondWithError(w, http.StatusUnauthorized, "Invalid two-factor code", err)
+	case mfa.ErrNotEnrolled, mfa.ErrNotEnabled, mfa.ErrAlreadyEnabled:
+		respondWithError(w, http.StatusConflict, err.Error(), err)
+	default:
+		logger.Error(message, err, map[string]interface{}{
+			"user_id": userID,
+		})
+		respondWithError(w, http.StatusInternalServerError, message, err)
+	}
+}
+
+// requireStepUp checks the two-factor code in the X-MFA-Code header when
+// amount is above the step-up threshold. It writes an error response and
+// returns false if the code is missing or wrong, or if the user has not
+// enabled two-factor authentication. Wrong codes count towards the login
+// lockout of the user, which also refuses step-up while it lasts.
+func requireStepUp(w http.ResponseWriter, r *http.Request, userID int, amount float64) bool {
+	if mfaService == nil || stepUpThreshold <= 0 || amount <= stepUpThreshold {
+		return true
+	}
+
+	details := map[string]interface{}{
+		"threshold": stepUpThreshold,
+		"header":    MFACodeHeader,
+	}
+
+	code := r.Header.Get(MFACodeHeader)
+	if code == "" {
+		apperrors.WriteError(w, apperrors.MFARequired(fmt.Sprintf("A two-factor code is required for amounts above %.2f", stepUpThreshold)).WithDetails(details), r.Context())
+		return false
+	}
+
+	user, err := userService.GetByID(userID)
+	if err != nil {
+		logger.Error("Failed to load user for step-up", err, map[string]interface{}{
+			"user_id": userID,
+		})
+		apperrors.WriteError(w, apperrors.InternalError("Failed to verify two-factor code"), r.Context())
+		return false
+	}
+	if !allowLogin(w, r, stepUpGuard, user.Email) {
+		return false
+	}
+
+	err = mfaService.Verify(r.Context(), userID, code)
+	switch err {
+	case nil:
+		recordLoginSuccess(r, stepUpGuard, user.Email)
+		return true
+	case mfa.ErrNotEnabled:
+		apperrors.WriteError(w, apperrors.MFARequired("Two-factor authentication must be enabled for amounts above the threshold").WithDetails(details), r.Context())
+	case mfa.ErrInvalidCode:
+		recordAudit(r.Context(), &audit.Entry{
+			EntityType: audit.EntityUser,
+			EntityID:   userID,
+			Action:     audit.ActionMFAFailed,
+			Details: map[string]interface{}{
+				"step_up": true,
+				"amount":  amount,
+			},
+		})
+		recordLoginFailure(r, stepUpGuard, user.Email, "invalid_step_up_code")
+		apperrors.WriteError(w, apperrors.InvalidMFACode("Invalid two-factor code"), r.Context())
+	default:
+		logger.Error("Failed to verify two-factor code", err, map[string]interface{}{
+			"user_id": userID,
+		})
+		apperrors.WriteError(w, apperrors.InternalError("Failed to verify two-factor code"), r.Context())
+	}
+	return false
+}
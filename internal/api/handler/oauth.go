@@ -0,0 +1,346 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"backend_path/internal/api/dto"
+	"backend_path/internal/audit"
+	"backend_path/internal/auth"
+	"backend_path/internal/oauth"
+	"backend_path/internal/user"
+	apperrors "backend_path/pkg/errors"
+	"backend_path/pkg/logger"
+
+	"github.com/go-chi/chi/v5"
+)
+
+var oauthService oauth.OAuthService
+
+// SetOAuthService sets the OAuth service dependency
+func SetOAuthService(service oauth.OAuthService) {
+	oauthService = service
+}
+
+// GetOAuthAuthorization validates an authorization request, given in the
+// query string, and describes it for the consent screen
+func GetOAuthAuthorization(w http.ResponseWriter, r *http.Request) {
+	userID := getUserIDFromContext(r)
+	if userID == 0 {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated", nil)
+		return
+	}
+
+	query := r.URL.Query()
+	request := &oauth.AuthorizationRequest{
+		ResponseType:        query.Get("response_type"),
+		ClientID:            query.Get("client_id"),
+		RedirectURI:         query.Get("redirect_uri"),
+		Scope:               query.Get("scope"),
+		State:               query.Get("state"),
+		CodeChallenge:       query.Get("code_challenge"),
+		CodeChallengeMethod: query.Get("code_challenge_method"),
+	}
+
+	authorization, err := oauthService.PrepareAuthorization(request, userID)
+	if err != nil {
+		respondWithOAuthError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dto.OAuthConsentResponse{
+		ClientID:    authorization.Client.ClientID,
+		ClientName:  authorization.Client.Name,
+		RedirectURI: authorization.RedirectURI,
+		Scopes:      authorization.Scopes,
+		Consented:   authorization.Consented,
+	})
+}
+
+// AuthorizeOAuth records the user's decision on an authorization request and
+// returns the redirect URI to send the user agent to
+func AuthorizeOAuth(w http.ResponseWriter, r *http.Request) {
+	userID := getUserIDFromContext(r)
+	if userID == 0 {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated", nil)
+		return
+	}
+
+	var req dto.OAuthAuthorizeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	request := &oauth.AuthorizationRequest{
+		ResponseType:        req.ResponseType,
+		ClientID:            req.ClientID,
+		RedirectURI:         req.RedirectURI,
+		Scope:               req.Scope,
+		State:               req.State,
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: req.CodeChallengeMethod,
+	}
+	redirectURI, err := oauthService.Authorize(r.Context(), request, userID, req.Approve)
+	if err != nil {
+		respondWithOAuthError(w, err)
+		return
+	}
+
+	if req.Approve {
+		recordAudit(r.Context(), &audit.Entry{
+			EntityType: audit.EntityUser,
+			EntityID:   userID,
+			Action:     audit.ActionConsent,
+			Details: map[string]interface{}{
+				"client_id": req.ClientID,
+				"scope":     req.Scope,
+			},
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dto.OAuthRedirectResponse{RedirectURI: redirectURI})
+}
+
+// OAuthToken is the token endpoint. It takes a form body and client
+// credentials in either the Authorization header or the body.
+func OAuthToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		respondWithOAuthError(w, &oauth.Error{Code: oauth.ErrorInvalidRequest, Description: "malformed form body"})
+		return
+	}
+
+	clientID, clientSecret := clientCredentials(r)
+	token, err := oauthService.Token(r.Context(), &oauth.TokenRequest{
+		GrantType:    r.PostForm.Get("grant_type"),
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Code:         r.PostForm.Get("code"),
+		RedirectURI:  r.PostForm.Get("redirect_uri"),
+		CodeVerifier: r.PostForm.Get("code_verifier"),
+		Scope:        r.PostForm.Get("scope"),
+	})
+	if err != nil {
+		respondWithOAuthError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(token)
+}
+
+// RevokeOAuthToken revokes an access token issued to the calling client
+// (RFC 7009)
+func RevokeOAuthToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		respondWithOAuthError(w, &oauth.Error{Code: oauth.ErrorInvalidRequest, Description: "malformed form body"})
+		return
+	}
+
+	clientID, clientSecret := clientCredentials(r)
+	if err := oauthService.Revoke(r.Context(), clientID, clientSecret, r.PostForm.Get("token")); err != nil {
+		respondWithOAuthError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// IntrospectOAuthToken describes a token to a resource server (RFC 7662)
+func IntrospectOAuthToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		respondWithOAuthError(w, &oauth.Error{Code: oauth.ErrorInvalidRequest, Description: "malformed form body"})
+		return
+	}
+
+	clientID, clientSecret := clientCredentials(r)
+	introspection, err := oauthService.Introspect(r.Context(), clientID, clientSecret, r.PostForm.Get("token"))
+	if err != nil {
+		respondWithOAuthError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(introspection)
+}
+
+func ListOAuthClients(w http.ResponseWriter, r *http.Request) {
+	clients, err := oauthService.ListClients()
+	if err != nil {
+		logger.Error("Failed to list OAuth clients", err, nil)
+		respondWithError(w, http.StatusInternalServerError, "Failed to list OAuth clients", err)
+		return
+	}
+
+	response := make([]dto.OAuthClientResponse, len(clients))
+	for i, client := range clients {
+		response[i] = toOAuthClientResponse(client, "")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// CreateOAuthClient registers an OAuth client and returns its secret once.
+// Client credentials clients can only act as users whose role the caller
+// holds themselves.
+func CreateOAuthClient(w http.ResponseWriter, r *http.Request) {
+	callerID := getUserIDFromContext(r)
+	if callerID == 0 {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated", nil)
+		return
+	}
+
+	var req dto.OAuthClientRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	if req.UserID != nil {
+		owner, err := userService.GetByID(*req.UserID)
+		if err != nil {
+			respondWithError(w, http.StatusNotFound, "User not found", err)
+			return
+		}
+		roles, err := auth.CallerRoles(r.Context(), userService)
+		if err != nil {
+			respondWithError(w, http.StatusUnauthorized, "User not authenticated", err)
+			return
+		}
+		if !rbacManager.HasRole(roles, owner.Role) {
+			apperrors.WriteError(w, apperrors.InsufficientRole("Cannot register clients acting as users with a role you do not hold").WithDetails(map[string]interface{}{
+				"role": owner.Role,
+			}), r.Context())
+			return
+		}
+	}
+
+	client := &oauth.Client{
+		Name:         req.Name,
+		RedirectURIs: req.RedirectURIs,
+		GrantTypes:   req.GrantTypes,
+		Scopes:       req.Scopes,
+		UserID:       req.UserID,
+		CreatedBy:    callerID,
+	}
+	secret, err := oauthService.RegisterClient(r.Context(), client, req.Confidential)
+	if err != nil {
+		switch {
+		case errors.Is(err, oauth.ErrInvalidClientDefinition):
+			respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		case errors.Is(err, user.ErrNotFound):
+			respondWithError(w, http.StatusNotFound, "User not found", err)
+		default:
+			logger.Error("Failed to register OAuth client", err, nil)
+			respondWithError(w, http.StatusInternalServerError, "Failed to register OAuth client", err)
+		}
+		return
+	}
+
+	recordAudit(r.Context(), &audit.Entry{
+		EntityType: audit.EntityOAuthClient,
+		EntityID:   client.ID,
+		Action:     audit.ActionCreate,
+		After:      toOAuthClientResponse(client, ""),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(toOAuthClientResponse(client, secret))
+}
+
+func RevokeOAuthClient(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid OAuth client ID", err)
+		return
+	}
+
+	if err := oauthService.RevokeClient(r.Context(), id); err != nil {
+		if errors.Is(err, oauth.ErrClientNotFound) {
+			respondWithError(w, http.StatusNotFound, "OAuth client not found", err)
+			return
+		}
+		logger.Error("Failed to revoke OAuth client", err, nil)
+		respondWithError(w, http.StatusInternalServerError, "Failed to revoke OAuth client", err)
+		return
+	}
+
+	recordAudit(r.Context(), &audit.Entry{
+		EntityType: audit.EntityOAuthClient,
+		EntityID:   id,
+		Action:     audit.ActionDelete,
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// clientCredentials returns the client credentials of a request, from HTTP
+// Basic authentication if present and the form body otherwise
+func clientCredentials(r *http.Request) (string, string) {
+	if clientID, clientSecret, ok := r.BasicAuth(); ok {
+		// Basic credentials are form-encoded first (RFC 6749, section 2.3.1)
+		if unescaped, err := url.QueryUnescape(clientID); err == nil {
+			clientID = unescaped
+		}
+		if unescaped, err := url.QueryUnescape(clientSecret); err == nil {
+			clientSecret = unescaped
+		}
+		return clientID, clientSecret
+	}
+	return r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+}
+
+// respondWithOAuthError writes an OAuth error response (RFC 6749, section
+// 5.2). Errors the client should not see become server_error.
+func respondWithOAuthError(w http.ResponseWriter, err error) {
+	var oauthErr *oauth.Error
+	status := http.StatusBadRequest
+	switch {
+	case errors.As(err, &oauthErr):
+		status = oauthErr.Status()
+	case errors.Is(err, user.ErrNotFound):
+		oauthErr = &oauth.Error{Code: oauth.ErrorInvalidGrant, Description: "the authorizing user no longer exists"}
+	default:
+		logger.Error("OAuth request failed", err, nil)
+		oauthErr = &oauth.Error{Code: "server_error"}
+		status = http.StatusInternalServerError
+	}
+
+	if status == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(oauthErr)
+}
+
+func toOAuthClientResponse(client *oauth.Client, secret string) dto.OAuthClientResponse {
+	return dto.OAuthClientResponse{
+		ID:           client.ID,
+		ClientID:     client.ClientID,
+		ClientSecret: secret,
+		Name:         client.Name,
+		RedirectURIs: client.RedirectURIs,
+		GrantTypes:   client.GrantTypes,
+		Scopes:       client.Scopes,
+		Confidential: client.Confidential(),
+		UserID:       client.UserID,
+		CreatedBy:    client.CreatedBy,
+		CreatedAt:    client.CreatedAt,
+	}
+}
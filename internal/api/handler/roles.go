@@ -0,0 +1,373 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"backend_path/internal/api/dto"
+	"backend_path/internal/audit"
+	"backend_path/internal/auth"
+	"backend_path/pkg/logger"
+
+	"github.com/go-chi/chi/v5"
+)
+
+var roleService auth.RoleService
+
+// SetRoleService sets the role service dependency
+func SetRoleService(service auth.RoleService) {
+	roleService = service
+}
+
+func ListRoles(w http.ResponseWriter, r *http.Request) {
+	roles, err := roleService.ListRoles()
+	if err != nil {
+		logger.Error("Failed to list roles", err, nil)
+		respondWithError(w, http.StatusInternalServerError, "Failed to list roles", err)
+		return
+	}
+
+	response := make([]dto.RoleResponse, len(roles))
+	for i, role := range roles {
+		response[i] = toRoleResponse(role)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+func GetRole(w http.ResponseWriter, r *http.Request) {
+	role, err := roleService.GetRole(chi.URLParam(r, "name"))
+	if err != nil {
+		respondWithRoleError(w, "Failed to get role", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(toRoleResponse(role))
+}
+
+func CreateRole(w http.ResponseWriter, r *http.Request) {
+	var req dto.RoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	role := toRole(req.Name, req)
+	if err := roleService.CreateRole(r.Context(), role); err != nil {
+		respondWithRoleError(w, "Failed to create role", err)
+		return
+	}
+
+	recordAudit(r.Context(), &audit.Entry{
+		EntityType: audit.EntityRole,
+		Action:     audit.ActionCreate,
+		After:      role,
+		Details:    map[string]interface{}{"role": role.Name},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(toRoleResponse(role))
+}
+
+func UpdateRole(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	var req dto.RoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	before, err := roleService.GetRole(name)
+	if err != nil {
+		respondWithRoleError(w, "Failed to get role", err)
+		return
+	}
+
+	role := toRole(name, req)
+	if err := roleService.UpdateRole(r.Context(), role); err != nil {
+		respondWithRoleError(w, "Failed to update role", err)
+		return
+	}
+
+	recordAudit(r.Context(), &audit.Entry{
+		EntityType: audit.EntityRole,
+		Action:     audit.ActionUpdate,
+		Before:     before,
+		After:      role,
+		Details:    map[string]interface{}{"role": role.Name},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(toRoleResponse(role))
+}
+
+func DeleteRole(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	before, err := roleService.GetRole(name)
+	if err != nil {
+		respondWithRoleError(w, "Failed to get role", err)
+		return
+	}
+
+	if err := roleService.DeleteRole(r.Context(), name); err != nil {
+		respondWithRoleError(w, "Failed to delete role", err)
+		return
+	}
+
+	recordAudit(r.Context(), &audit.Entry{
+		EntityType: audit.EntityRole,
+		Action:     audit.ActionDelete,
+		Before:     before,
+		Details:    map[string]interface{}{"role": name},
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func ListPolicies(w http.ResponseWriter, r *http.Request) {
+	policies, err := roleService.ListPolicies()
+	if err != nil {
+		logger.Error("Failed to list policies", err, nil)
+		respondWithError(w, http.StatusInternalServerError, "Failed to list policies", err)
+		return
+	}
+
+	response := make([]dto.PolicyResponse, len(policies))
+	for i, policy := range policies {
+		response[i] = toPolicyResponse(policy)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+func CreatePolicy(w http.ResponseWriter, r *http.Request) {
+	var req dto.PolicyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	policy := toPolicy(0, req)
+	if err := roleService.CreatePolicy(r.Context(), policy); err != nil {
+		respondWithRoleError(w, "Failed to create policy", err)
+		return
+	}
+
+	recordAudit(r.Context(), &audit.Entry{
+		EntityType: audit.EntityPolicy,
+		EntityID:   policy.ID,
+		Action:     audit.ActionCreate,
+		After:      policy,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(toPolicyResponse(policy))
+}
+
+func UpdatePolicy(w http.ResponseWriter, r *http.Request) {
+	policyID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid policy ID", err)
+		return
+	}
+
+	var req dto.PolicyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	before, err := roleService.GetPolicy(policyID)
+	if err != nil {
+		respondWithRoleError(w, "Failed to get policy", err)
+		return
+	}
+
+	policy := toPolicy(policyID, req)
+	if err := roleService.UpdatePolicy(r.Context(), policy); err != nil {
+		respondWithRoleError(w, "Failed to update policy", err)
+		return
+	}
+
+	recordAudit(r.Context(), &audit.Entry{
+		EntityType: audit.EntityPolicy,
+		EntityID:   policyID,
+		Action:     audit.ActionUpdate,
+		Before:     before,
+		After:      policy,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(toPolicyResponse(policy))
+}
+
+func DeletePolicy(w http.ResponseWriter, r *http.Request) {
+	policyID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid policy ID", err)
+		return
+	}
+
+	before, err := roleService.GetPolicy(policyID)
+	if err != nil {
+		respondWithRoleError(w, "Failed to get policy", err)
+		return
+	}
+
+	if err := roleService.DeletePolicy(r.Context(), policyID); err != nil {
+		respondWithRoleError(w, "Failed to delete policy", err)
+		return
+	}
+
+	recordAudit(r.Context(), &audit.Entry{
+		EntityType: audit.EntityPolicy,
+		EntityID:   policyID,
+		Action:     audit.ActionDelete,
+		Before:     before,
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// EvaluatePolicies explains how an access request would be decided, for
+// debugging policies
+func EvaluatePolicies(w http.ResponseWriter, r *http.Request) {
+	var req dto.PolicyEvaluateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if len(req.Roles) == 0 || req.Resource == "" || req.Action == "" {
+		respondWithError(w, http.StatusBadRequest, "Roles, resource and action are required", nil)
+		return
+	}
+
+	request := &auth.AccessRequest{
+		Roles:      req.Roles,
+		Resource:   req.Resource,
+		Action:     req.Action,
+		Attributes: auth.Attributes(req.Attributes),
+	}
+	if req.ResourceID != "" {
+		request.ResourceID = req.ResourceID
+	}
+	decision := rbacManager.Authorize(request)
+
+	response := dto.PolicyEvaluateResponse{
+		Allowed:   decision.Allowed,
+		GrantedBy: decision.GrantedBy,
+		Policies:  make([]dto.PolicyResultResponse, len(decision.Policies)),
+	}
+	if decision.Permission != nil {
+		response.Permission = &dto.PermissionDTO{
+			Resource:   decision.Permission.Resource,
+			Action:     decision.Permission.Action,
+			ResourceID: decision.Permission.ResourceID,
+		}
+	}
+	for i, result := range decision.Policies {
+		response.Policies[i] = dto.PolicyResultResponse{
+			PolicyID:      result.PolicyID,
+			RoleMatched:   result.RoleMatched,
+			ActionMatched: result.ActionMatched,
+			Error:         result.Error,
+			Allowed:       result.Allowed,
+			Denied:        result.Denied,
+		}
+		for _, condition := range result.Conditions {
+			response.Policies[i].Conditions = append(response.Policies[i].Conditions, dto.ConditionResultResponse{
+				Name:       condition.Name,
+				Expression: condition.Expression,
+				Result:     condition.Result,
+				Error:      condition.Error,
+			})
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// respondWithRoleError maps role service errors to a response status
+func respondWithRoleError(w http.ResponseWriter, message string, err error) {
+	switch {
+	case errors.Is(err, auth.ErrRoleNotFound):
+		respondWithError(w, http.StatusNotFound, "Role not found", err)
+	case errors.Is(err, auth.ErrPolicyNotFound):
+		respondWithError(w, http.StatusNotFound, "Policy not found", err)
+	case errors.Is(err, auth.ErrRoleExists), errors.Is(err, auth.ErrRoleInUse):
+		respondWithError(w, http.StatusConflict, err.Error(), err)
+	case errors.Is(err, auth.ErrInvalidRole), errors.Is(err, auth.ErrInvalidPolicy):
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+	default:
+		logger.Error(message, err, nil)
+		respondWithError(w, http.StatusInternalServerError, message, err)
+	}
+}
+
+func toRole(name string, req dto.RoleRequest) *auth.Role {
+	role := &auth.Role{
+		Name:        name,
+		Permissions: make([]auth.Permission, len(req.Permissions)),
+		Inherits:    req.Inherits,
+	}
+	for i, permission := range req.Permissions {
+		role.Permissions[i] = auth.Permission{
+			Resource:   permission.Resource,
+			Action:     permission.Action,
+			ResourceID: permission.ResourceID,
+		}
+	}
+	return role
+}
+
+func toRoleResponse(role *auth.Role) dto.RoleResponse {
+	response := dto.RoleResponse{
+		Name:        role.Name,
+		Permissions: make([]dto.PermissionDTO, len(role.Permissions)),
+		Inherits:    role.Inherits,
+	}
+	for i, permission := range role.Permissions {
+		response.Permissions[i] = dto.PermissionDTO{
+			Resource:   permission.Resource,
+			Action:     permission.Action,
+			ResourceID: permission.ResourceID,
+		}
+	}
+	return response
+}
+
+func toPolicy(id int, req dto.PolicyRequest) *auth.Policy {
+	return &auth.Policy{
+		ID:         id,
+		Resource:   req.Resource,
+		Actions:    req.Actions,
+		Roles:      req.Roles,
+		Conditions: req.Conditions,
+	}
+}
+
+func toPolicyResponse(policy *auth.Policy) dto.PolicyResponse {
+	return dto.PolicyResponse{
+		ID:         policy.ID,
+		Resource:   policy.Resource,
+		Actions:    policy.Actions,
+		Roles:      policy.Roles,
+		Conditions: policy.Conditions,
+	}
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"backend_path/internal/api/dto"
+	"backend_path/internal/audit"
+	"backend_path/internal/session"
+	"backend_path/pkg/jwt"
+	"backend_path/pkg/logger"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// DeviceNameHeader names the device a login comes from, such as "Work
+// laptop", to tell sessions apart
+const DeviceNameHeader = "X-Device-Name"
+
+// ListSessions lists the active sessions of the caller
+func (h *AuthHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	claims := jwt.ClaimsFromContext(r.Context())
+	if claims == nil {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated", nil)
+		return
+	}
+
+	sessions, err := h.sessionService.List(claims.UserID)
+	if err != nil {
+		logger.Error("Failed to list sessions", err, map[string]interface{}{
+			"user_id": claims.UserID,
+		})
+		respondWithError(w, http.StatusInternalServerError, "Failed to list sessions", err)
+		return
+	}
+
+	response := make([]dto.SessionResponse, len(sessions))
+	for i, s := range sessions {
+		response[i] = dto.SessionResponse{
+			ID:         s.ID,
+			DeviceName: s.DeviceName,
+			UserAgent:  s.UserAgent,
+			IP:         s.IP,
+			CreatedAt:  s.CreatedAt,
+			LastSeenAt: s.LastSeenAt,
+			ExpiresAt:  s.ExpiresAt,
+			Current:    s.ID == claims.FamilyID,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// RevokeSession logs the caller out of one of their sessions, typically on
+// another device. Its refresh and access tokens stop working immediately.
+func (h *AuthHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	userID := getUserIDFromContext(r)
+	if userID == 0 {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated", nil)
+		return
+	}
+
+	sessionID := chi.URLParam(r, "id")
+	if err := h.sessionService.Revoke(r.Context(), userID, sessionID); err != nil {
+		if err == session.ErrSessionNotFound {
+			respondWithError(w, http.StatusNotFound, "Session not found", err)
+			return
+		}
+		logger.Error("Failed to revoke session", err, map[string]interface{}{
+			"user_id": userID,
+		})
+		respondWithError(w, http.StatusInternalServerError, "Failed to revoke session", err)
+		return
+	}
+
+	recordAudit(r.Context(), &audit.Entry{
+		EntityType: audit.EntityUser,
+		EntityID:   userID,
+		Action:     audit.ActionLogout,
+		Details: map[string]interface{}{
+			"session_id": sessionID,
+		},
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// requestDevice describes the device a request comes from
+func requestDevice(r *http.Request) *session.Device {
+	info := audit.RequestInfoFromContext(r.Context())
+	return &session.Device{
+		Name:      r.Header.Get(DeviceNameHeader),
+		UserAgent: r.UserAgent(),
+		IP:        info.IP,
+	}
+}
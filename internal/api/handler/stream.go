@@ -0,0 +1,213 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"backend_path/internal/api/dto"
+	"backend_path/internal/metrics"
+	"backend_path/internal/stream"
+	"backend_path/pkg/jwt"
+	"backend_path/pkg/logger"
+
+	"golang.org/x/net/websocket"
+)
+
+// streamWriteTimeout bounds a single write so that dead clients are noticed
+const streamWriteTimeout = 10 * time.Second
+
+var streamHub *stream.Hub
+
+// SetStreamHub sets the stream hub dependency
+func SetStreamHub(hub *stream.Hub) {
+	streamHub = hub
+}
+
+var streamTickets *stream.TicketStore
+
+// SetStreamTickets sets the stream ticket store dependency
+func SetStreamTickets(tickets *stream.TicketStore) {
+	streamTickets = tickets
+}
+
+// IssueStreamTicket returns a single-use ticket that authenticates one
+// stream request in its ticket query parameter, for clients that cannot set
+// an Authorization header
+func IssueStreamTicket(w http.ResponseWriter, r *http.Request) {
+	claims := jwt.ClaimsFromContext(r.Context())
+	if claims == nil {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated", nil)
+		return
+	}
+
+	ticket, err := streamTickets.Issue(r.Context(), claims)
+	if err != nil {
+		logger.Error("Failed to issue stream ticket", err, map[string]interface{}{
+			"user_id": claims.UserID,
+		})
+		respondWithError(w, http.StatusInternalServerError, "Failed to issue stream ticket", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(dto.StreamTicketResponse{
+		Ticket:    ticket,
+		ExpiresIn: int(stream.TicketTTL.Seconds()),
+	})
+}
+
+// Stream pushes the caller's balance and transaction events over Server-Sent
+// Events, or over a WebSocket when the request asks for an upgrade. Clients
+// resume with the Last-Event-ID header or the last_event_id query parameter.
+func Stream(w http.ResponseWriter, r *http.Request) {
+	userID := getUserIDFromContext(r)
+	if userID == 0 {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated", nil)
+		return
+	}
+
+	if streamHub == nil {
+		respondWithError(w, http.StatusServiceUnavailable, "Streaming is not available", nil)
+		return
+	}
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("last_event_id")
+	}
+	if lastEventID != "" && !stream.ValidID(lastEventID) {
+		respondWithError(w, http.StatusBadRequest, "Invalid Last-Event-ID", nil)
+		return
+	}
+
+	if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		server := websocket.Server{
+			Handler: func(ws *websocket.Conn) {
+				serveWebSocket(ws, userID, lastEventID)
+			},
+		}
+		server.ServeHTTP(w, r)
+		return
+	}
+
+	serveSSE(w, r, userID, lastEventID)
+}
+
+// serveSSE streams messages as Server-Sent Events
+func serveSSE(w http.ResponseWriter, r *http.Request, userID int, lastEventID string) {
+	rc := http.NewResponseController(w)
+	// Streams outlive the server's write timeout; each write sets its own deadline
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		respondWithError(w, http.StatusInternalServerError, "Streaming is not supported", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	writer := &sseWriter{w: w, rc: rc}
+	// Tell EventSource clients how long to wait before reconnecting
+	if err := writer.write("retry: 3000\n\n"); err != nil {
+		return
+	}
+
+	metrics.StreamConnectionsActive.WithLabelValues("sse").Inc()
+	defer metrics.StreamConnectionsActive.WithLabelValues("sse").Dec()
+
+	logStreamEnd(stream.Serve(r.Context(), streamHub, userID, lastEventID, writer), userID, "sse")
+}
+
+// sseWriter writes messages in the text/event-stream format
+type sseWriter struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+}
+
+func (s *sseWriter) WriteMessage(message *stream.Message) error {
+	return s.write(fmt.Sprintf("id: %s\nevent: %s\ndata: %s\n\n", message.ID, message.Type, message.Data))
+}
+
+func (s *sseWriter) WriteHeartbeat() error {
+	return s.write(": heartbeat\n\n")
+}
+
+func (s *sseWriter) write(frame string) error {
+	_ = s.rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+	if _, err := s.w.Write([]byte(frame)); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
+
+// serveWebSocket streams messages as JSON WebSocket frames
+func serveWebSocket(ws *websocket.Conn, userID int, lastEventID string) {
+	defer ws.Close()
+
+	// The hijacked connection keeps the server's deadlines; reset them
+	_ = ws.SetDeadline(time.Time{})
+
+	// Clients do not send anything; reading only detects when they go away
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		defer cancel()
+		var discard []byte
+		for {
+			if err := websocket.Message.Receive(ws, &discard); err != nil {
+				return
+			}
+		}
+	}()
+
+	metrics.StreamConnectionsActive.WithLabelValues("websocket").Inc()
+	defer metrics.StreamConnectionsActive.WithLabelValues("websocket").Dec()
+
+	logStreamEnd(stream.Serve(ctx, streamHub, userID, lastEventID, &webSocketWriter{ws: ws}), userID, "websocket")
+}
+
+// webSocketFrame is the JSON frame sent to WebSocket clients
+type webSocketFrame struct {
+	ID   string      `json:"id,omitempty"`
+	Type string      `json:"type"`
+	Data interface{} `json:"data,omitempty"`
+}
+
+// webSocketWriter writes messages as JSON frames
+type webSocketWriter struct {
+	ws *websocket.Conn
+}
+
+func (s *webSocketWriter) WriteMessage(message *stream.Message) error {
+	return s.send(webSocketFrame{ID: message.ID, Type: message.Type, Data: message.Data})
+}
+
+func (s *webSocketWriter) WriteHeartbeat() error {
+	return s.send(webSocketFrame{Type: "heartbeat"})
+}
+
+func (s *webSocketWriter) send(frame webSocketFrame) error {
+	_ = s.ws.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+	return websocket.JSON.Send(s.ws, frame)
+}
+
+// logStreamEnd logs why a stream connection ended
+func logStreamEnd(err error, userID int, transport string) {
+	fields := map[string]interface{}{
+		"user_id":   userID,
+		"transport": transport,
+	}
+	if err != nil {
+		fields["reason"] = err.Error()
+	}
+	logger.Debug("Stream connection closed", fields)
+}
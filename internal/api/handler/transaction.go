@@ -2,14 +2,16 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
-	"strconv"
 
 	"backend_path/internal/api/dto"
+	"backend_path/internal/auth"
+	"backend_path/internal/balance"
+	"backend_path/internal/domain"
 	"backend_path/internal/transaction"
+	apperrors "backend_path/pkg/errors"
 	"backend_path/pkg/logger"
-
-	"github.com/go-chi/chi/v5"
 )
 
 var transactionService transaction.TransactionService
@@ -40,13 +42,13 @@ func Credit(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Process credit transaction
-	tx, err := transactionService.ProcessCredit(userID, req.Amount)
+	tx, err := transactionService.ProcessCredit(r.Context(), userID, req.Amount)
 	if err != nil {
 		logger.Error("Failed to process credit", err, map[string]interface{}{
 			"user_id": userID,
 			"amount":  req.Amount,
 		})
-		respondWithError(w, http.StatusInternalServerError, "Failed to process credit", err)
+		respondWithTransactionError(w, r, "Failed to process credit", err)
 		return
 	}
 
@@ -86,13 +88,13 @@ func Debit(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Process debit transaction
-	tx, err := transactionService.ProcessDebit(userID, req.Amount)
+	tx, err := transactionService.ProcessDebit(r.Context(), userID, req.Amount)
 	if err != nil {
 		logger.Error("Failed to process debit", err, map[string]interface{}{
 			"user_id": userID,
 			"amount":  req.Amount,
 		})
-		respondWithError(w, http.StatusInternalServerError, "Failed to process debit", err)
+		respondWithTransactionError(w, r, "Failed to process debit", err)
 		return
 	}
 
@@ -136,15 +138,20 @@ func Transfer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Large transfers need a fresh two-factor code
+	if !requireStepUp(w, r, fromUserID, req.Amount) {
+		return
+	}
+
 	// Process transfer transaction
-	tx, err := transactionService.ProcessTransfer(fromUserID, req.ToUserID, req.Amount)
+	tx, err := transactionService.ProcessTransfer(r.Context(), fromUserID, req.ToUserID, req.Amount)
 	if err != nil {
 		logger.Error("Failed to process transfer", err, map[string]interface{}{
 			"from_user_id": fromUserID,
 			"to_user_id":   req.ToUserID,
 			"amount":       req.Amount,
 		})
-		respondWithError(w, http.StatusInternalServerError, "Failed to process transfer", err)
+		respondWithTransactionError(w, r, "Failed to process transfer", err)
 		return
 	}
 
@@ -201,23 +208,13 @@ func TransactionHistory(w http.ResponseWriter, r *http.Request) {
 }
 
 func GetTransaction(w http.ResponseWriter, r *http.Request) {
-	// Get transaction ID from URL
-	transactionIDStr := chi.URLParam(r, "id")
-	transactionID, err := strconv.Atoi(transactionIDStr)
-	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid transaction ID", err)
-		return
-	}
-
-	// Get transaction
-	tx, err := transactionService.GetTransaction(transactionID)
-	if err != nil {
-		logger.Error("Failed to get transaction", err, map[string]interface{}{
-			"transaction_id": transactionID,
-		})
-		respondWithError(w, http.StatusNotFound, "Transaction not found", err)
+	// The transaction was loaded and authorized by LoadTransaction
+	resource := auth.ResourceFromContext(r.Context())
+	if resource == nil {
+		respondWithError(w, http.StatusNotFound, "Transaction not found", nil)
 		return
 	}
+	tx := resource.Value.(*domain.Transaction)
 
 	response := dto.TransactionResponse{
 		ID:         tx.ID,
@@ -247,3 +244,18 @@ func getUserIDFromContext(r *http.Request) int {
 
 	return 0
 }
+
+// respondWithTransactionError maps transaction service errors to a response
+// status
+func respondWithTransactionError(w http.ResponseWriter, r *http.Request, message string, err error) {
+	switch {
+	case errors.Is(err, balance.ErrInsufficientFunds):
+		apperrors.WriteError(w, apperrors.InsufficientBalance("Insufficient funds"), r.Context())
+	case errors.Is(err, transaction.ErrInvalidAmount), errors.Is(err, transaction.ErrSameUser):
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+	case errors.Is(err, transaction.ErrConflict):
+		respondWithError(w, http.StatusConflict, "Balance was modified concurrently, please retry", err)
+	default:
+		respondWithError(w, http.StatusInternalServerError, message, err)
+	}
+}
@@ -6,6 +6,10 @@ import (
 	"strconv"
 
 	"backend_path/internal/api/dto"
+	"backend_path/internal/audit"
+	"backend_path/internal/auth"
+	"backend_path/internal/domain"
+	"backend_path/internal/session"
 	"backend_path/internal/user"
 	"backend_path/pkg/logger"
 
@@ -14,11 +18,18 @@ import (
 
 var userService user.UserService
 
+var sessionService session.SessionService
+
 // SetUserService sets the user service dependency
 func SetUserService(service user.UserService) {
 	userService = service
 }
 
+// SetSessionService sets the session service dependency
+func SetSessionService(service session.SessionService) {
+	sessionService = service
+}
+
 func ListUsers(w http.ResponseWriter, r *http.Request) {
 	users, err := userService.GetAllUsers()
 	if err != nil {
@@ -44,21 +55,13 @@ func ListUsers(w http.ResponseWriter, r *http.Request) {
 }
 
 func GetUser(w http.ResponseWriter, r *http.Request) {
-	userIDStr := chi.URLParam(r, "id")
-	userID, err := strconv.Atoi(userIDStr)
-	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
-		return
-	}
-
-	user, err := userService.GetByID(userID)
-	if err != nil {
-		logger.Error("Failed to get user", err, map[string]interface{}{
-			"user_id": userID,
-		})
-		respondWithError(w, http.StatusNotFound, "User not found", err)
+	// The user was loaded and authorized by LoadUser
+	resource := auth.ResourceFromContext(r.Context())
+	if resource == nil {
+		respondWithError(w, http.StatusNotFound, "User not found", nil)
 		return
 	}
+	user := resource.Value.(*domain.User)
 
 	response := dto.UserResponse{
 		ID:        user.ID,
@@ -87,6 +90,25 @@ func UpdateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	before, err := userService.GetByID(userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "User not found", err)
+		return
+	}
+
+	// Tokens carry the role they were issued with, so make the user log in
+	// again to pick up the new one. Sessions are revoked before the role is
+	// changed so that a change never takes effect while they stay valid.
+	if req.Role != before.Role {
+		if err := sessionService.LogoutAll(r.Context(), userID); err != nil {
+			logger.Error("Failed to revoke sessions before role change", err, map[string]interface{}{
+				"user_id": userID,
+			})
+			respondWithError(w, http.StatusServiceUnavailable, "Existing sessions could not be revoked, role not changed", err)
+			return
+		}
+	}
+
 	// Update user
 	user, err := userService.UpdateUser(userID, req.Username, req.Email, req.Role)
 	if err != nil {
@@ -97,6 +119,23 @@ func UpdateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	recordAudit(r.Context(), &audit.Entry{
+		EntityType: audit.EntityUser,
+		EntityID:   userID,
+		Action:     audit.ActionUpdate,
+		Before:     before,
+		After:      user,
+	})
+	if before.Role != user.Role {
+		recordAudit(r.Context(), &audit.Entry{
+			EntityType: audit.EntityUser,
+			EntityID:   userID,
+			Action:     audit.ActionRoleChange,
+			Before:     map[string]string{"role": before.Role},
+			After:      map[string]string{"role": user.Role},
+		})
+	}
+
 	response := dto.UserResponse{
 		ID:        user.ID,
 		Username:  user.Username,
@@ -118,6 +157,12 @@ func DeleteUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	before, err := userService.GetByID(userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "User not found", err)
+		return
+	}
+
 	if err := userService.DeleteUser(userID); err != nil {
 		logger.Error("Failed to delete user", err, map[string]interface{}{
 			"user_id": userID,
@@ -126,5 +171,12 @@ func DeleteUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	recordAudit(r.Context(), &audit.Entry{
+		EntityType: audit.EntityUser,
+		EntityID:   userID,
+		Action:     audit.ActionDelete,
+		Before:     before,
+	})
+
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -0,0 +1,335 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"backend_path/internal/api/dto"
+	"backend_path/internal/auth"
+	"backend_path/internal/webhook"
+	"backend_path/pkg/logger"
+
+	"github.com/go-chi/chi/v5"
+)
+
+var webhookService webhook.WebhookService
+
+// SetWebhookService sets the webhook service dependency
+func SetWebhookService(service webhook.WebhookService) {
+	webhookService = service
+}
+
+func CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	userID := getUserIDFromContext(r)
+	if userID == 0 {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated", nil)
+		return
+	}
+
+	var req dto.CreateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	endpoint := &webhook.Endpoint{
+		UserID:      userID,
+		URL:         req.URL,
+		EventTypes:  req.EventTypes,
+		Description: req.Description,
+	}
+
+	// Client endpoints receive every user's events, so only admins may register them
+	if req.ClientID != "" {
+		if !isAdmin(r) {
+			respondWithError(w, http.StatusForbidden, "Only admins can register client webhooks", nil)
+			return
+		}
+		endpoint.UserID = 0
+		endpoint.ClientID = req.ClientID
+	}
+
+	if err := webhookService.CreateEndpoint(endpoint); err != nil {
+		logger.Error("Failed to create webhook", err, map[string]interface{}{
+			"user_id": userID,
+		})
+		respondWithError(w, http.StatusBadRequest, "Failed to create webhook", err)
+		return
+	}
+
+	response := toWebhookEndpointResponse(endpoint)
+	response.Secret = endpoint.Secret
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+func ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	userID := getUserIDFromContext(r)
+	if userID == 0 {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated", nil)
+		return
+	}
+
+	var endpoints []*webhook.Endpoint
+	var err error
+	if r.URL.Query().Get("scope") == "client" {
+		if !isAdmin(r) {
+			respondWithError(w, http.StatusForbidden, "Only admins can list client webhooks", nil)
+			return
+		}
+		endpoints, err = webhookService.ListClientEndpoints()
+	} else {
+		endpoints, err = webhookService.ListEndpoints(userID)
+	}
+	if err != nil {
+		logger.Error("Failed to list webhooks", err, map[string]interface{}{
+			"user_id": userID,
+		})
+		respondWithError(w, http.StatusInternalServerError, "Failed to list webhooks", err)
+		return
+	}
+
+	response := make([]dto.WebhookEndpointResponse, len(endpoints))
+	for i, endpoint := range endpoints {
+		response[i] = toWebhookEndpointResponse(endpoint)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+func GetWebhook(w http.ResponseWriter, r *http.Request) {
+	endpoint, ok := loadWebhookEndpoint(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(toWebhookEndpointResponse(endpoint))
+}
+
+func UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	endpoint, ok := loadWebhookEndpoint(w, r)
+	if !ok {
+		return
+	}
+
+	var req dto.UpdateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	endpoint.URL = req.URL
+	endpoint.EventTypes = req.EventTypes
+	endpoint.Description = req.Description
+	endpoint.Active = req.Active
+
+	if err := webhookService.UpdateEndpoint(endpoint); err != nil {
+		logger.Error("Failed to update webhook", err, map[string]interface{}{
+			"endpoint_id": endpoint.ID,
+		})
+		respondWithError(w, http.StatusBadRequest, "Failed to update webhook", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(toWebhookEndpointResponse(endpoint))
+}
+
+func DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	endpoint, ok := loadWebhookEndpoint(w, r)
+	if !ok {
+		return
+	}
+
+	if err := webhookService.DeleteEndpoint(endpoint.ID); err != nil {
+		logger.Error("Failed to delete webhook", err, map[string]interface{}{
+			"endpoint_id": endpoint.ID,
+		})
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete webhook", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func ListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	endpoint, ok := loadWebhookEndpoint(w, r)
+	if !ok {
+		return
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+
+	deliveries, err := webhookService.ListDeliveries(endpoint.ID, limit, offset)
+	if err != nil {
+		logger.Error("Failed to list webhook deliveries", err, map[string]interface{}{
+			"endpoint_id": endpoint.ID,
+		})
+		respondWithError(w, http.StatusInternalServerError, "Failed to list webhook deliveries", err)
+		return
+	}
+
+	response := make([]dto.WebhookDeliveryResponse, len(deliveries))
+	for i, delivery := range deliveries {
+		response[i] = toWebhookDeliveryResponse(delivery)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+func ListWebhookDeliveryAttempts(w http.ResponseWriter, r *http.Request) {
+	delivery, ok := loadWebhookDelivery(w, r)
+	if !ok {
+		return
+	}
+
+	attempts, err := webhookService.ListAttempts(delivery.ID)
+	if err != nil {
+		logger.Error("Failed to list webhook delivery attempts", err, map[string]interface{}{
+			"delivery_id": delivery.ID,
+		})
+		respondWithError(w, http.StatusInternalServerError, "Failed to list webhook delivery attempts", err)
+		return
+	}
+
+	response := make([]dto.WebhookAttemptResponse, len(attempts))
+	for i, attempt := range attempts {
+		response[i] = dto.WebhookAttemptResponse{
+			Attempt:      attempt.Attempt,
+			StatusCode:   attempt.StatusCode,
+			ResponseBody: attempt.ResponseBody,
+			Error:        attempt.Error,
+			DurationMs:   attempt.DurationMs,
+			CreatedAt:    attempt.CreatedAt,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+func RedeliverWebhook(w http.ResponseWriter, r *http.Request) {
+	delivery, ok := loadWebhookDelivery(w, r)
+	if !ok {
+		return
+	}
+
+	redelivered, err := webhookService.Redeliver(delivery.ID)
+	if err != nil {
+		logger.Error("Failed to redeliver webhook", err, map[string]interface{}{
+			"delivery_id": delivery.ID,
+		})
+		respondWithError(w, http.StatusInternalServerError, "Failed to redeliver webhook", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(toWebhookDeliveryResponse(redelivered))
+}
+
+// loadWebhookEndpoint loads the endpoint in the URL and checks that the caller
+// may manage it. It writes the error response and returns false otherwise.
+func loadWebhookEndpoint(w http.ResponseWriter, r *http.Request) (*webhook.Endpoint, bool) {
+	userID := getUserIDFromContext(r)
+	if userID == 0 {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated", nil)
+		return nil, false
+	}
+
+	endpointID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid webhook ID", err)
+		return nil, false
+	}
+
+	endpoint, err := webhookService.GetEndpoint(endpointID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Webhook not found", err)
+		return nil, false
+	}
+
+	// Hide other users' endpoints instead of revealing that they exist
+	owned := endpoint.UserID == userID || (endpoint.UserID == 0 && isAdmin(r))
+	if !owned {
+		respondWithError(w, http.StatusNotFound, "Webhook not found", nil)
+		return nil, false
+	}
+
+	return endpoint, true
+}
+
+// loadWebhookDelivery loads the delivery in the URL and checks that it belongs
+// to an endpoint the caller may manage
+func loadWebhookDelivery(w http.ResponseWriter, r *http.Request) (*webhook.Delivery, bool) {
+	endpoint, ok := loadWebhookEndpoint(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	deliveryID, err := strconv.ParseInt(chi.URLParam(r, "deliveryID"), 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid delivery ID", err)
+		return nil, false
+	}
+
+	delivery, err := webhookService.GetDelivery(deliveryID)
+	if err != nil || delivery.EndpointID != endpoint.ID {
+		respondWithError(w, http.StatusNotFound, "Delivery not found", err)
+		return nil, false
+	}
+
+	return delivery, true
+}
+
+// isAdmin reports whether the caller has the admin role, directly or by
+// inheritance
+func isAdmin(r *http.Request) bool {
+	roles, err := auth.CallerRoles(r.Context(), userService)
+	if err != nil {
+		return false
+	}
+	return rbacManager.HasRole(roles, "admin")
+}
+
+func toWebhookEndpointResponse(endpoint *webhook.Endpoint) dto.WebhookEndpointResponse {
+	return dto.WebhookEndpointResponse{
+		ID:          endpoint.ID,
+		UserID:      endpoint.UserID,
+		ClientID:    endpoint.ClientID,
+		URL:         endpoint.URL,
+		EventTypes:  endpoint.EventTypes,
+		Description: endpoint.Description,
+		Active:      endpoint.Active,
+		CreatedAt:   endpoint.CreatedAt,
+		UpdatedAt:   endpoint.UpdatedAt,
+	}
+}
+
+func toWebhookDeliveryResponse(delivery *webhook.Delivery) dto.WebhookDeliveryResponse {
+	return dto.WebhookDeliveryResponse{
+		ID:             delivery.ID,
+		EndpointID:     delivery.EndpointID,
+		EventID:        delivery.EventID,
+		EventType:      delivery.EventType,
+		Status:         string(delivery.Status),
+		Attempts:       delivery.Attempts,
+		NextAttemptAt:  delivery.NextAttemptAt,
+		LastStatusCode: delivery.LastStatusCode,
+		LastError:      delivery.LastError,
+		CreatedAt:      delivery.CreatedAt,
+		DeliveredAt:    delivery.DeliveredAt,
+	}
+}
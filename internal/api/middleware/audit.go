@@ -0,0 +1,19 @@
+package middleware
+
+import (
+	"net/http"
+
+	"backend_path/internal/audit"
+)
+
+// AuditMiddleware stores the client IP address and user agent in the request
+// context so audit log entries can be attributed to the request
+func AuditMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := audit.WithRequestInfo(r.Context(), audit.RequestInfo{
+			IP:        getClientIP(r),
+			UserAgent: r.UserAgent(),
+		})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,307 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"backend_path/internal/audit"
+	"backend_path/internal/auth"
+	"backend_path/pkg/errors"
+	"backend_path/pkg/jwt"
+	"backend_path/pkg/logger"
+)
+
+// auditRecorder records audit log entries
+type auditRecorder interface {
+	Record(ctx context.Context, entry *audit.Entry) error
+}
+
+// ResourceLoader loads the resource a request targets. It returns
+// auth.ErrResourceNotFound if there is none.
+type ResourceLoader func(r *http.Request) (*auth.Resource, error)
+
+// AttributeLoader reads the attributes of a request for policy conditions,
+// such as the amount in its body. It must leave the body readable.
+type AttributeLoader func(r *http.Request) (auth.Attributes, error)
+
+// Authorizer checks the caller's roles against the RBAC manager. It must run
+// after AuthMiddleware.
+type Authorizer struct {
+	rbac    *auth.RBACManager
+	users   auth.UserLookup
+	auditor auditRecorder
+}
+
+// NewAuthorizer creates an authorizer backed by rbac that records cross-user
+// access with auditor
+func NewAuthorizer(rbac *auth.RBACManager, users auth.UserLookup, auditor auditRecorder) *Authorizer {
+	return &Authorizer{
+		rbac:    rbac,
+		users:   users,
+		auditor: auditor,
+	}
+}
+
+// RequirePermission allows the request if the caller may perform action on
+// any resource of the given type
+func (a *Authorizer) RequirePermission(resource, action string) func(http.Handler) http.Handler {
+	return a.require(resource, action, nil, nil)
+}
+
+// RequireSelfPermission allows the request if the caller may perform action
+// on their own resources of the given type. Handlers behind it must only act
+// on the caller's resources.
+func (a *Authorizer) RequireSelfPermission(resource, action string) func(http.Handler) http.Handler {
+	return a.require(resource, action, auth.ScopeSelf, nil)
+}
+
+// RequireSelfPermissionWith is RequireSelfPermission with policy conditions
+// also evaluated against the attributes load reads from the request.
+// Requests whose attributes cannot be read are rejected.
+func (a *Authorizer) RequireSelfPermissionWith(resource, action string, load AttributeLoader) func(http.Handler) http.Handler {
+	return a.require(resource, action, auth.ScopeSelf, load)
+}
+
+func (a *Authorizer) require(resource, action string, resourceID interface{}, load AttributeLoader) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			roles, ok := a.callerRoles(w, r)
+			if !ok {
+				return
+			}
+
+			var attrs auth.Attributes
+			if load != nil {
+				var err error
+				if attrs, err = load(r); err != nil {
+					errors.WriteError(w, errors.BadRequest("Invalid request body"), r.Context())
+					return
+				}
+			}
+
+			if !a.authorize(r, roles, resource, action, resourceID, nil, attrs) {
+				errors.WriteError(w, errors.InsufficientRole("Insufficient permissions").WithDetails(map[string]interface{}{
+					"resource": resource,
+					"action":   action,
+				}), r.Context())
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireResourcePermission loads the resource the request targets and
+// allows the request if the caller may perform action on it: either on any
+// resource of the type, or through a self-scoped permission on a resource
+// they own. Access to other users' resources is audited. Resources the
+// caller may not access are reported as not found so their existence is not
+// revealed. The handler finds the resource with auth.ResourceFromContext.
+func (a *Authorizer) RequireResourcePermission(resource, action string, load ResourceLoader) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			roles, ok := a.callerRoles(w, r)
+			if !ok {
+				return
+			}
+
+			target, err := load(r)
+			if err == auth.ErrResourceNotFound {
+				errors.WriteError(w, errors.ResourceNotFound("Resource not found"), r.Context())
+				return
+			}
+			if err != nil {
+				logger.Error("Failed to load resource", err, map[string]interface{}{
+					"resource": resource,
+				})
+				errors.WriteError(w, errors.InternalError("Failed to load resource"), r.Context())
+				return
+			}
+
+			callerID := jwt.ClaimsFromContext(r.Context()).UserID
+			switch {
+			case target.OwnedBy(callerID) && a.authorize(r, roles, resource, action, auth.ScopeSelf, target, nil):
+			case a.authorize(r, roles, resource, action, nil, target, nil):
+				if !target.OwnedBy(callerID) {
+					a.recordAccess(r, resource, action, target)
+				}
+			default:
+				errors.WriteError(w, errors.ResourceNotFound("Resource not found"), r.Context())
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(auth.WithResource(r.Context(), target)))
+		})
+	}
+}
+
+// authorize asks the RBAC manager whether roles may perform action on
+// resourceID, evaluating policy conditions against the caller, the request,
+// its attributes and target. attrs and target may be nil.
+func (a *Authorizer) authorize(r *http.Request, roles []string, resource, action string, resourceID interface{}, target *auth.Resource, attrs auth.Attributes) bool {
+	// Scoped credentials are further restricted to their scopes
+	if claims := jwt.ClaimsFromContext(r.Context()); claims != nil && claims.Scoped() && !auth.ScopesAllow(claims.Scopes(), resource, action) {
+		return false
+	}
+
+	attributes := auth.CallerAttributes(r.Context(), a.users, roles, getClientIP(r), target)
+	for name, value := range attrs {
+		attributes[name] = value
+	}
+
+	return a.rbac.Authorize(&auth.AccessRequest{
+		Roles:      roles,
+		Resource:   resource,
+		Action:     action,
+		ResourceID: resourceID,
+		Attributes: attributes,
+	}).Allowed
+}
+
+// recordAccess audits an access to another user's resource
+func (a *Authorizer) recordAccess(r *http.Request, resource, action string, target *auth.Resource) {
+	err := a.auditor.Record(r.Context(), &audit.Entry{
+		EntityType: resource,
+		EntityID:   target.ID,
+		Action:     audit.ActionAccess,
+		Details: map[string]interface{}{
+			"action":    action,
+			"owner_ids": target.OwnerIDs,
+			"path":      r.URL.Path,
+		},
+	})
+	if err != nil {
+		logger.Warn("Failed to record audit entry", map[string]interface{}{
+			"entity_type": resource,
+			"entity_id":   target.ID,
+			"action":      audit.ActionAccess,
+			"error":       err.Error(),
+		})
+	}
+}
+
+// RequireRole allows the request if the caller has role, directly or by
+// inheritance. Scoped credentials such as API keys are refused since scopes
+// name permissions, not roles.
+func (a *Authorizer) RequireRole(role string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			roles, ok := a.callerRoles(w, r)
+			if !ok {
+				return
+			}
+
+			if claims := jwt.ClaimsFromContext(r.Context()); claims.Scoped() {
+				errors.WriteError(w, errors.InsufficientRole("Scoped credentials cannot access this endpoint"), r.Context())
+				return
+			}
+
+			if !a.rbac.HasRole(roles, role) {
+				errors.WriteError(w, errors.InsufficientRole("Insufficient role").WithDetails(map[string]interface{}{
+					"required_role": role,
+				}), r.Context())
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireVerifiedEmail allows the request if the caller has verified their
+// email address
+func (a *Authorizer) RequireVerifiedEmail() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims := jwt.ClaimsFromContext(r.Context())
+			if claims == nil {
+				errors.WriteError(w, errors.Unauthorized("User not authenticated"), r.Context())
+				return
+			}
+
+			user, err := a.users.GetByID(claims.UserID)
+			if err != nil {
+				logger.Error("Failed to load caller", err, map[string]interface{}{
+					"user_id": claims.UserID,
+				})
+				errors.WriteError(w, errors.Unauthorized("User not authenticated"), r.Context())
+				return
+			}
+
+			if !user.EmailVerified() {
+				errors.WriteError(w, errors.EmailNotVerified("Verify your email address before moving money"), r.Context())
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// GuardImpersonation makes requests with impersonation tokens read-only and
+// audits each of them with both the impersonated and the acting user.
+// Other requests pass through.
+func (a *Authorizer) GuardImpersonation(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims := jwt.ClaimsFromContext(r.Context())
+		if claims == nil || !claims.Impersonated() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(wrapped, r)
+		default:
+			errors.WriteError(wrapped, errors.ImpersonationReadOnly("Impersonation tokens are read-only"), r.Context())
+		}
+
+		err := a.auditor.Record(r.Context(), &audit.Entry{
+			EntityType: audit.EntityUser,
+			EntityID:   claims.UserID,
+			Action:     audit.ActionImpersonation,
+			Details: map[string]interface{}{
+				"method": r.Method,
+				"path":   r.URL.Path,
+				"status": wrapped.statusCode,
+			},
+		})
+		if err != nil {
+			logger.Warn("Failed to record audit entry", map[string]interface{}{
+				"entity_type":     audit.EntityUser,
+				"entity_id":       claims.UserID,
+				"impersonator_id": claims.Actor.UserID,
+				"action":          audit.ActionImpersonation,
+				"error":           err.Error(),
+			})
+		}
+	})
+}
+
+// RejectImpersonation refuses requests with impersonation tokens whatever
+// their method. It guards the endpoints that move money.
+func (a *Authorizer) RejectImpersonation() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if claims := jwt.ClaimsFromContext(r.Context()); claims != nil && claims.Impersonated() {
+				errors.WriteError(w, errors.ImpersonationReadOnly("Impersonation tokens cannot move money"), r.Context())
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// callerRoles returns the caller's roles, writing an error response and
+// returning false if they cannot be determined
+func (a *Authorizer) callerRoles(w http.ResponseWriter, r *http.Request) ([]string, bool) {
+	roles, err := auth.CallerRoles(r.Context(), a.users)
+	if err != nil {
+		logger.Error("Failed to resolve caller roles", err, nil)
+		errors.WriteError(w, errors.Unauthorized("User not authenticated"), r.Context())
+		return nil, false
+	}
+	return roles, true
+}
@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+var (
+	trustedProxiesMu sync.RWMutex
+	trustedProxies   []*net.IPNet
+)
+
+// SetTrustedProxies sets the addresses, single IPs or CIDR ranges, of the
+// reverse proxies whose X-Forwarded-For and X-Real-IP headers are believed.
+// Requests from anywhere else are attributed to their peer address.
+func SetTrustedProxies(proxies []string) error {
+	nets := make([]*net.IPNet, 0, len(proxies))
+	for _, proxy := range proxies {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return fmt.Errorf("invalid trusted proxy address: %s", proxy)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return fmt.Errorf("invalid trusted proxy range %s: %w", proxy, err)
+		}
+		nets = append(nets, network)
+	}
+
+	trustedProxiesMu.Lock()
+	defer trustedProxiesMu.Unlock()
+	trustedProxies = nets
+	return nil
+}
+
+// trustedProxy reports whether ip belongs to a trusted proxy
+func trustedProxy(ip net.IP) bool {
+	trustedProxiesMu.RLock()
+	defer trustedProxiesMu.RUnlock()
+	for _, network := range trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// getClientIP returns the address of the client of r: the peer address,
+// unless the peer is a trusted proxy. Forwarding headers are then read
+// from the right, where proxies append, and the first address that is not
+// a trusted proxy is the client; everything left of it may be forged.
+func getClientIP(r *http.Request) string {
+	peer := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		peer = host
+	}
+	peerIP := net.ParseIP(peer)
+	if peerIP == nil || !trustedProxy(peerIP) {
+		return peer
+	}
+
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	if len(hops) == 0 {
+		if ip := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); ip != nil {
+			return ip.String()
+		}
+		return peer
+	}
+
+	client := peer
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := net.ParseIP(strings.TrimSpace(hops[i]))
+		if ip == nil {
+			// A malformed hop was not written by a trusted proxy; the last
+			// trusted address is the closest one known to be real
+			break
+		}
+		client = ip.String()
+		if !trustedProxy(ip) {
+			break
+		}
+	}
+	return client
+}
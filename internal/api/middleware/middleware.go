@@ -1,58 +1,163 @@
 package middleware
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
+	"net"
 	"net/http"
 	"strings"
 	"time"
 
+	"backend_path/internal/apikey"
+	"backend_path/internal/audit"
 	"backend_path/internal/metrics"
+	"backend_path/internal/stream"
 	"backend_path/pkg/jwt"
+	"backend_path/pkg/logger"
 )
 
 type contextKey string
 
 const userContextKey = "user"
 
-// Real authentication middleware with JWT validation
-func AuthMiddleware(jwtService *jwt.JWTService) func(http.Handler) http.Handler {
+// TokenRevocationChecker reports whether an access token was revoked before
+// it expired
+type TokenRevocationChecker interface {
+	IsRevoked(ctx context.Context, claims *jwt.Claims) (bool, error)
+}
+
+// APIKeyHeader carries the API key of server-to-server clients
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyAuthenticator checks an API key used from an IP address and returns
+// the claims its requests act with
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(ctx context.Context, key, ip string) (*jwt.Claims, error)
+}
+
+// Real authentication middleware with JWT validation. If apiKeys is not nil,
+// the routes also accept scoped credentials: OAuth access tokens, and API
+// keys in an X-API-Key header for requests without an Authorization header.
+func AuthMiddleware(jwtService *jwt.JWTService, revocations TokenRevocationChecker, apiKeys APIKeyAuthenticator) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			authHeader := r.Header.Get("Authorization")
+			if key := r.Header.Get(APIKeyHeader); authHeader == "" && key != "" && apiKeys != nil {
+				// The allowlist is checked against the peer address, or
+				// the client a trusted proxy forwarded for
+				claims, err := apiKeys.AuthenticateAPIKey(r.Context(), key, getClientIP(r))
+				switch err {
+				case nil:
+					next.ServeHTTP(w, r.WithContext(withCaller(r.Context(), claims)))
+				case apikey.ErrInvalidKey:
+					http.Error(w, "Invalid API key", http.StatusUnauthorized)
+				case apikey.ErrIPNotAllowed:
+					http.Error(w, "API key not allowed from this address", http.StatusForbidden)
+				default:
+					logger.Error("Failed to authenticate API key", err, nil)
+					http.Error(w, "Unable to verify API key", http.StatusServiceUnavailable)
+				}
+				return
+			}
+
 			if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
 				http.Error(w, "Missing or invalid authorization header", http.StatusUnauthorized)
 				return
 			}
 
 			tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-			claims, err := jwtService.ValidateToken(tokenString)
+			validate := jwtService.ValidateAccessToken
+			if apiKeys != nil {
+				validate = jwtService.ValidateResourceToken
+			}
+			claims, err := validate(tokenString)
 			if err != nil {
 				http.Error(w, "Invalid token", http.StatusUnauthorized)
 				return
 			}
 
-			// Set user ID in context
-			ctx := context.WithValue(r.Context(), userContextKey, claims.UserID)
-			next.ServeHTTP(w, r.WithContext(ctx))
+			// Logged out tokens stay valid until they expire, so check the
+			// revocation list. Fail closed if it cannot be reached.
+			revoked, err := revocations.IsRevoked(r.Context(), claims)
+			if err != nil {
+				logger.Error("Failed to check token revocation", err, map[string]interface{}{
+					"user_id": claims.UserID,
+				})
+				http.Error(w, "Unable to verify token", http.StatusServiceUnavailable)
+				return
+			}
+			if revoked {
+				http.Error(w, "Token has been revoked", http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(withCaller(r.Context(), claims)))
 		})
 	}
 }
 
-// Role-based authorization middleware
-func RoleMiddleware(requiredRole string) func(http.Handler) http.Handler {
+// withCaller returns a copy of ctx carrying the authenticated caller
+func withCaller(ctx context.Context, claims *jwt.Claims) context.Context {
+	ctx = context.WithValue(ctx, userContextKey, claims.UserID)
+	ctx = jwt.WithClaims(ctx, claims)
+	if claims.Impersonated() {
+		ctx = audit.WithImpersonator(ctx, claims.Actor.UserID)
+	}
+	return audit.WithActor(ctx, claims.UserID)
+}
+
+// StreamTicketRedeemer consumes a single-use stream ticket and returns the
+// claims of the access token it was issued for
+type StreamTicketRedeemer interface {
+	Redeem(ctx context.Context, ticket string) (*jwt.Claims, error)
+}
+
+// StreamTicketMiddleware lets clients that cannot set request headers, such
+// as browser EventSource and WebSocket clients, authenticate with a ticket
+// in the ticket query parameter instead of an access token, which would end
+// up in request logs. Requests without a ticket are authenticated by
+// authenticate.
+func StreamTicketMiddleware(tickets StreamTicketRedeemer, revocations TokenRevocationChecker, authenticate func(http.Handler) http.Handler) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
+		authenticated := authenticate(next)
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			userID := r.Context().Value(userContextKey)
-			if userID == nil {
-				http.Error(w, "User not authenticated", http.StatusUnauthorized)
+			ticket := r.URL.Query().Get("ticket")
+			if ticket == "" || r.Header.Get("Authorization") != "" {
+				authenticated.ServeHTTP(w, r)
 				return
 			}
 
-			// Note: Role-based authorization is currently simplified
-			// In production, implement proper role checking by injecting userService
-			// and validating user roles against the requiredRole parameter
-			next.ServeHTTP(w, r)
+			claims, err := tickets.Redeem(r.Context(), ticket)
+			if err == stream.ErrInvalidTicket {
+				http.Error(w, "Invalid stream ticket", http.StatusUnauthorized)
+				return
+			}
+			if err != nil {
+				logger.Error("Failed to redeem stream ticket", err, nil)
+				http.Error(w, "Unable to verify stream ticket", http.StatusServiceUnavailable)
+				return
+			}
+			if claims.ExpiresAt == nil || !time.Now().Before(claims.ExpiresAt.Time) {
+				http.Error(w, "Invalid stream ticket", http.StatusUnauthorized)
+				return
+			}
+
+			// The token may have been revoked since the ticket was issued
+			revoked, err := revocations.IsRevoked(r.Context(), claims)
+			if err != nil {
+				logger.Error("Failed to check token revocation", err, map[string]interface{}{
+					"user_id": claims.UserID,
+				})
+				http.Error(w, "Unable to verify token", http.StatusServiceUnavailable)
+				return
+			}
+			if revoked {
+				http.Error(w, "Token has been revoked", http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(withCaller(r.Context(), claims)))
 		})
 	}
 }
@@ -106,3 +211,18 @@ func (rw *responseWriter) WriteHeader(code int) {
 func (rw *responseWriter) Write(b []byte) (int, error) {
 	return rw.ResponseWriter.Write(b)
 }
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// Flush supports streaming responses such as Server-Sent Events
+func (rw *responseWriter) Flush() {
+	http.NewResponseController(rw.ResponseWriter).Flush()
+}
+
+// Hijack supports WebSocket upgrades
+func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(rw.ResponseWriter).Hijack()
+}
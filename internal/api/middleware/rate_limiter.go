@@ -86,20 +86,6 @@ func (rl *RateLimiter) Allow(key string) bool {
 	return true
 }
 
-// getClientIP extracts the client IP from the request
-func getClientIP(r *http.Request) string {
-	// Check for forwarded headers first
-	if ip := r.Header.Get("X-Forwarded-For"); ip != "" {
-		return ip
-	}
-	if ip := r.Header.Get("X-Real-IP"); ip != "" {
-		return ip
-	}
-
-	// Fall back to remote address
-	return r.RemoteAddr
-}
-
 // Cleanup removes old entries from the rate limiter
 func (rl *RateLimiter) Cleanup() {
 	rl.mu.Lock()
@@ -1,6 +1,8 @@
 package middleware
 
 import (
+	"bufio"
+	"net"
 	"net/http"
 	"time"
 
@@ -70,6 +72,21 @@ func (rw *tracingResponseWriter) Write(b []byte) (int, error) {
 	return rw.ResponseWriter.Write(b)
 }
 
+// Unwrap lets http.ResponseController reach the underlying writer
+func (rw *tracingResponseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// Flush supports streaming responses such as Server-Sent Events
+func (rw *tracingResponseWriter) Flush() {
+	http.NewResponseController(rw.ResponseWriter).Flush()
+}
+
+// Hijack supports WebSocket upgrades
+func (rw *tracingResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(rw.ResponseWriter).Hijack()
+}
+
 // headersToMap converts http.Header to map[string]string
 func headersToMap(headers http.Header) map[string]string {
 	result := make(map[string]string)
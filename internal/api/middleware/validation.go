@@ -3,6 +3,7 @@ package middleware
 import (
 	"encoding/json"
 	"net/http"
+	"strings"
 
 	"backend_path/pkg/errors"
 	"backend_path/pkg/validator"
@@ -17,8 +18,12 @@ func ValidationMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		// Check content type
+		// Check content type. OAuth endpoints take form bodies (RFC 6749).
 		contentType := r.Header.Get("Content-Type")
+		if strings.HasPrefix(r.URL.Path, "/oauth/") && strings.HasPrefix(contentType, "application/x-www-form-urlencoded") {
+			next.ServeHTTP(w, r)
+			return
+		}
 		if contentType != "application/json" {
 			errors.WriteError(w, errors.BadRequest("Content-Type must be application/json"), r.Context())
 			return
@@ -4,9 +4,17 @@ import (
 	"net/http"
 	"time"
 
+	"backend_path/internal/account"
 	"backend_path/internal/api/handler"
 	mw "backend_path/internal/api/middleware"
+	"backend_path/internal/apikey"
+	"backend_path/internal/audit"
+	"backend_path/internal/auth"
 	"backend_path/internal/config"
+	"backend_path/internal/lockout"
+	"backend_path/internal/mfa"
+	"backend_path/internal/session"
+	"backend_path/internal/stream"
 	"backend_path/internal/user"
 	"backend_path/pkg/jwt"
 
@@ -16,20 +24,30 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
-func NewRouter(userService user.UserService, jwtService *jwt.JWTService, cfg *config.Config) http.Handler {
+func NewRouter(userService user.UserService, sessionService session.SessionService, mfaService mfa.MFAService, loginGuard *lockout.Guard, accountService account.AccountService, apiKeyService apikey.APIKeyService, rbacManager *auth.RBACManager, auditService audit.AuditService, jwtService *jwt.JWTService, streamTickets *stream.TicketStore, cfg *config.Config) http.Handler {
 	r := chi.NewRouter()
 
 	// Initialize handlers
-	authHandler := handler.NewAuthHandler(userService, jwtService)
+	authHandler := handler.NewAuthHandler(userService, jwtService, sessionService, mfaService, loginGuard, accountService)
+	authorizer := mw.NewAuthorizer(rbacManager, userService, auditService)
+	// Routes that back-office integrations use also accept API keys; the
+	// rest need an interactive login. Requests under impersonation are
+	// read-only and audited on either.
+	authMiddleware := chi.Chain(mw.AuthMiddleware(jwtService, sessionService, apiKeyService), authorizer.GuardImpersonation).Handler
+	userAuthMiddleware := chi.Chain(mw.AuthMiddleware(jwtService, sessionService, nil), authorizer.GuardImpersonation).Handler
 
 	// Set service dependencies for handlers
 	handler.SetUserService(userService)
+	handler.SetSessionService(sessionService)
+	handler.SetStreamTickets(streamTickets)
+	handler.SetMFAService(mfaService, loginGuard, cfg.MFAStepUpThreshold)
 	// Note: SetTransactionService should be called from main.go when transactionService is available
 
 	// Ortak middleware'ler
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 	r.Use(mw.TracingMiddleware)                                 // OpenTelemetry tracing
+	r.Use(mw.AuditMiddleware)                                   // Audit request info
 	r.Use(mw.SecurityHeadersMiddleware)                         // Security headers
 	r.Use(mw.ValidationMiddleware)                              // Request validation
 	r.Use(mw.ErrorHandlingMiddleware)                           // Error handling and panic recovery
@@ -38,7 +56,7 @@ func NewRouter(userService user.UserService, jwtService *jwt.JWTService, cfg *co
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"*"},
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "Last-Event-ID", handler.MFACodeHeader, handler.DeviceNameHeader, mw.APIKeyHeader},
 		AllowCredentials: true,
 	}))
 
@@ -50,39 +68,147 @@ func NewRouter(userService user.UserService, jwtService *jwt.JWTService, cfg *co
 		w.Write([]byte("API is up!"))
 	})
 
+	// Public keys for verifying tokens
+	r.Get("/.well-known/jwks.json", authHandler.JWKS)
+
 	// Auth route grubu
 	r.Route("/api/v1/auth", func(r chi.Router) {
 		r.Post("/register", authHandler.Register)
 		r.Post("/login", authHandler.Login)
 		r.Post("/refresh", authHandler.Refresh)
+		r.With(userAuthMiddleware).Post("/logout", authHandler.Logout)
+		r.With(userAuthMiddleware).Post("/logout-all", authHandler.LogoutAll)
+		r.Post("/mfa/verify", authHandler.VerifyMFA)
+		r.With(userAuthMiddleware).Post("/mfa/enroll", authHandler.EnrollMFA)
+		r.With(userAuthMiddleware).Post("/mfa/confirm", authHandler.ConfirmMFA)
+		r.With(userAuthMiddleware).Post("/mfa/disable", authHandler.DisableMFA)
+		r.With(userAuthMiddleware).Post("/mfa/recovery-codes", authHandler.RegenerateRecoveryCodes)
+		r.Post("/password/forgot", authHandler.ForgotPassword)
+		r.Post("/password/reset", authHandler.ResetPassword)
+		r.Post("/email/verify", authHandler.VerifyEmail)
+		r.With(userAuthMiddleware).Post("/email/resend", authHandler.ResendVerification)
+		r.Post("/invitations/accept", authHandler.AcceptInvitation)
+	})
+
+	// OAuth 2.0 authorization server. The consent screen API needs an
+	// interactive login; the other endpoints authenticate the client.
+	r.Route("/oauth", func(r chi.Router) {
+		r.With(userAuthMiddleware).Get("/authorize", handler.GetOAuthAuthorization)
+		r.With(userAuthMiddleware).Post("/authorize", handler.AuthorizeOAuth)
+		r.Post("/token", handler.OAuthToken)
+		r.Post("/revoke", handler.RevokeOAuthToken)
+		r.Post("/introspect", handler.IntrospectOAuthToken)
+	})
+
+	// Sessions of the caller
+	r.Route("/api/v1/me/sessions", func(r chi.Router) {
+		r.Use(userAuthMiddleware)
+		r.Get("/", authHandler.ListSessions)
+		r.Delete("/{id}", authHandler.RevokeSession)
 	})
 
 	// User route grubu (korumalı)
 	r.Route("/api/v1/users", func(r chi.Router) {
-		r.Use(mw.AuthMiddleware(jwtService))
-		r.Use(mw.RoleMiddleware("admin")) // örnek: sadece admin erişebilir
-		r.Get("/", handler.ListUsers)
-		r.Get("/{id}", handler.GetUser)
-		r.Put("/{id}", handler.UpdateUser)
-		r.Delete("/{id}", handler.DeleteUser)
+		r.Use(authMiddleware)
+		r.With(authorizer.RequirePermission("user", "read")).Get("/", handler.ListUsers)
+		r.With(authorizer.RequireResourcePermission("user", "read", handler.LoadUser)).Get("/{id}", handler.GetUser)
+		r.With(authorizer.RequirePermission("user", "update")).Put("/{id}", handler.UpdateUser)
+		r.With(authorizer.RequirePermission("user", "delete")).Delete("/{id}", handler.DeleteUser)
 	})
 
 	// Transaction route grubu (korumalı)
 	r.Route("/api/v1/transactions", func(r chi.Router) {
-		r.Use(mw.AuthMiddleware(jwtService))
-		r.Post("/credit", handler.Credit)
-		r.Post("/debit", handler.Debit)
-		r.Post("/transfer", handler.Transfer)
-		r.Get("/history", handler.TransactionHistory)
-		r.Get("/{id}", handler.GetTransaction)
+		r.Use(authMiddleware)
+		r.With(authorizer.RejectImpersonation(), authorizer.RequireSelfPermissionWith("transaction", "create", handler.TransactionAttributes), authorizer.RequireVerifiedEmail()).Post("/credit", handler.Credit)
+		r.With(authorizer.RejectImpersonation(), authorizer.RequireSelfPermissionWith("transaction", "create", handler.TransactionAttributes), authorizer.RequireVerifiedEmail()).Post("/debit", handler.Debit)
+		r.With(authorizer.RejectImpersonation(), authorizer.RequireSelfPermissionWith("transaction", "create", handler.TransactionAttributes), authorizer.RequireVerifiedEmail()).Post("/transfer", handler.Transfer)
+		r.With(authorizer.RequireSelfPermission("transaction", "read")).Get("/history", handler.TransactionHistory)
+		r.With(authorizer.RequireResourcePermission("transaction", "read", handler.LoadTransaction)).Get("/{id}", handler.GetTransaction)
 	})
 
 	// Balance route grubu (korumalı)
 	r.Route("/api/v1/balances", func(r chi.Router) {
-		r.Use(mw.AuthMiddleware(jwtService))
-		r.Get("/current", handler.CurrentBalance)
-		r.Get("/historical", handler.HistoricalBalance)
-		r.Get("/at-time", handler.BalanceAtTime)
+		r.Use(authMiddleware)
+		r.With(authorizer.RequireSelfPermission("balance", "read")).Get("/current", handler.CurrentBalance)
+		r.With(authorizer.RequireSelfPermission("balance", "read")).Get("/historical", handler.HistoricalBalance)
+		r.With(authorizer.RequireSelfPermission("balance", "read")).Get("/at-time", handler.BalanceAtTime)
+		r.With(authorizer.RequireResourcePermission("balance", "read", handler.LoadBalance)).Get("/{userID}", handler.UserBalance)
+	})
+
+	// Webhook route grubu (korumalı)
+	r.Route("/api/v1/webhooks", func(r chi.Router) {
+		r.Use(userAuthMiddleware)
+		r.Post("/", handler.CreateWebhook)
+		r.Get("/", handler.ListWebhooks)
+		r.Get("/{id}", handler.GetWebhook)
+		r.Put("/{id}", handler.UpdateWebhook)
+		r.Delete("/{id}", handler.DeleteWebhook)
+		r.Get("/{id}/deliveries", handler.ListWebhookDeliveries)
+		r.Get("/{id}/deliveries/{deliveryID}/attempts", handler.ListWebhookDeliveryAttempts)
+		r.Post("/{id}/deliveries/{deliveryID}/redeliver", handler.RedeliverWebhook)
+	})
+
+	// Audit log route grubu (korumalı)
+	r.Route("/api/v1/audit", func(r chi.Router) {
+		r.Use(authMiddleware)
+		r.Use(authorizer.RequirePermission("audit", "read"))
+		r.Get("/", handler.ListAuditLogs)
+		r.Get("/verify", handler.VerifyAuditLogs)
+	})
+
+	// Admin route grubu (korumalı)
+	r.Route("/api/v1/admin", func(r chi.Router) {
+		r.Use(userAuthMiddleware)
+		r.Use(authorizer.RequireRole("admin"))
+
+		r.Route("/roles", func(r chi.Router) {
+			r.Get("/", handler.ListRoles)
+			r.Post("/", handler.CreateRole)
+			r.Get("/{name}", handler.GetRole)
+			r.Put("/{name}", handler.UpdateRole)
+			r.Delete("/{name}", handler.DeleteRole)
+		})
+
+		r.Route("/policies", func(r chi.Router) {
+			r.Get("/", handler.ListPolicies)
+			r.Post("/", handler.CreatePolicy)
+			r.Post("/evaluate", handler.EvaluatePolicies)
+			r.Put("/{id}", handler.UpdatePolicy)
+			r.Delete("/{id}", handler.DeletePolicy)
+		})
+
+		r.Route("/invitations", func(r chi.Router) {
+			r.Get("/", authHandler.ListInvitations)
+			r.Post("/", authHandler.CreateInvitation)
+			r.Delete("/{id}", authHandler.RevokeInvitation)
+		})
+
+		r.Route("/api-keys", func(r chi.Router) {
+			r.Get("/", handler.ListAPIKeys)
+			r.Post("/", handler.CreateAPIKey)
+			r.Get("/{id}", handler.GetAPIKey)
+			r.Post("/{id}/rotate", handler.RotateAPIKey)
+			r.Delete("/{id}", handler.RevokeAPIKey)
+		})
+
+		r.Route("/oauth/clients", func(r chi.Router) {
+			r.Get("/", handler.ListOAuthClients)
+			r.Post("/", handler.CreateOAuthClient)
+			r.Delete("/{id}", handler.RevokeOAuthClient)
+		})
+
+		// Login lockouts
+		r.Post("/users/{id}/unlock", authHandler.UnlockUser)
+		r.Post("/ips/{ip}/unlock", authHandler.UnlockIP)
+
+		// Support impersonation
+		r.Post("/impersonate/{userID}", authHandler.Impersonate)
+	})
+
+	// Real-time notification stream (korumalı)
+	r.Route("/api/v1/stream", func(r chi.Router) {
+		r.With(mw.StreamTicketMiddleware(streamTickets, sessionService, mw.AuthMiddleware(jwtService, sessionService, nil)), authorizer.GuardImpersonation).Get("/", handler.Stream)
+		r.With(userAuthMiddleware).Post("/tickets", handler.IssueStreamTicket)
 	})
 
 	return r
@@ -0,0 +1,63 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"backend_path/pkg/jwt"
+)
+
+var (
+	// ErrInvalidKey is returned for keys that are malformed, unknown,
+	// revoked or expired
+	ErrInvalidKey = errors.New("invalid api key")
+	// ErrIPNotAllowed is returned when a key is used from an address outside
+	// its allowlist
+	ErrIPNotAllowed = errors.New("api key not allowed from this address")
+	// ErrNotFound is returned for unknown or revoked keys
+	ErrNotFound = errors.New("api key not found")
+	// ErrInvalidAPIKey is returned for keys with a missing name or
+	// malformed scopes, allowed IPs or expiry
+	ErrInvalidAPIKey = errors.New("invalid api key definition")
+)
+
+// APIKey is a credential for server-to-server clients. It acts as its owner,
+// restricted to its scopes. Only the SHA-256 hash of the key is stored; the
+// prefix identifies it in listings.
+type APIKey struct {
+	ID     int
+	UserID int
+	Name   string
+	Prefix string
+	// KeyHash is the hex SHA-256 hash of the full key
+	KeyHash string
+	// Scopes are "resource:action" permissions, see auth.ParseScope
+	Scopes []string
+	// AllowedIPs are IP addresses or CIDR ranges the key may be used from;
+	// empty allows any address
+	AllowedIPs []string
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+	CreatedBy  int
+	CreatedAt  time.Time
+}
+
+// APIKeyService issues and authenticates API keys
+type APIKeyService interface {
+	// Issue stores key and returns the plaintext key, which is only
+	// available now
+	Issue(ctx context.Context, key *APIKey) (string, error)
+	// List returns the keys that are not revoked
+	List() ([]*APIKey, error)
+	Get(id int) (*APIKey, error)
+	// Rotate replaces the key with id by a new key with the same settings
+	// and returns the new key and its plaintext
+	Rotate(ctx context.Context, id int) (*APIKey, string, error)
+	Revoke(ctx context.Context, id int) error
+
+	// AuthenticateAPIKey checks key, used from ip, and returns the claims
+	// its requests act with
+	AuthenticateAPIKey(ctx context.Context, key, ip string) (*jwt.Claims, error)
+}
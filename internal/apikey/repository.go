@@ -0,0 +1,26 @@
+package apikey
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Repository stores API keys
+type Repository interface {
+	Create(key *APIKey) error
+	// GetByID returns the key with id, revoked or not, or nil if there is none
+	GetByID(id int) (*APIKey, error)
+	// GetByHash returns the key with hash, revoked or not, or nil if there
+	// is none
+	GetByHash(hash string) (*APIKey, error)
+	// ListActive returns the keys that are not revoked
+	ListActive() ([]*APIKey, error)
+	// Revoke revokes the key with id. It returns false if there is no such
+	// key or it is already revoked.
+	Revoke(id int, at time.Time) (bool, error)
+	// Touch records a use of the key with id, at most once per interval
+	Touch(id int, at time.Time, interval time.Duration) error
+
+	// WithTx returns a repository that runs its queries inside tx
+	WithTx(tx *sql.Tx) Repository
+}
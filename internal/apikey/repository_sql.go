@@ -0,0 +1,167 @@
+package apikey
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"backend_path/pkg/database"
+)
+
+const selectColumns = `id, user_id, name, prefix, key_hash, scopes, allowed_ips, expires_at, last_used_at, revoked_at, created_by, created_at`
+
+type sqlRepository struct {
+	db database.Executor
+}
+
+func NewSQLRepository(db *sql.DB) Repository {
+	return &sqlRepository{db: db}
+}
+
+func (r *sqlRepository) WithTx(tx *sql.Tx) Repository {
+	return &sqlRepository{db: tx}
+}
+
+func (r *sqlRepository) Create(key *APIKey) error {
+	query := `
+		INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, allowed_ips, expires_at, created_by, created_at)
+		OUTPUT INSERTED.id
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	err := r.db.QueryRow(
+		query,
+		key.UserID,
+		key.Name,
+		key.Prefix,
+		key.KeyHash,
+		strings.Join(key.Scopes, ","),
+		strings.Join(key.AllowedIPs, ","),
+		key.ExpiresAt,
+		key.CreatedBy,
+		key.CreatedAt,
+	).Scan(&key.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create api key: %w", err)
+	}
+	return nil
+}
+
+func (r *sqlRepository) GetByID(id int) (*APIKey, error) {
+	key, err := scanAPIKey(r.db.QueryRow(`SELECT `+selectColumns+` FROM api_keys WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return key, err
+}
+
+func (r *sqlRepository) GetByHash(hash string) (*APIKey, error) {
+	key, err := scanAPIKey(r.db.QueryRow(`SELECT `+selectColumns+` FROM api_keys WHERE key_hash = ?`, hash))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return key, err
+}
+
+func (r *sqlRepository) ListActive() ([]*APIKey, error) {
+	rows, err := r.db.Query(`SELECT ` + selectColumns + ` FROM api_keys WHERE revoked_at IS NULL ORDER BY created_at DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []*APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating api keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+func (r *sqlRepository) Revoke(id int, at time.Time) (bool, error) {
+	result, err := r.db.Exec(`UPDATE api_keys SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL`, at, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke api key: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+func (r *sqlRepository) Touch(id int, at time.Time, interval time.Duration) error {
+	query := `UPDATE api_keys SET last_used_at = ? WHERE id = ? AND (last_used_at IS NULL OR last_used_at < ?)`
+	if _, err := r.db.Exec(query, at, id, at.Add(-interval)); err != nil {
+		return fmt.Errorf("failed to record api key use: %w", err)
+	}
+	return nil
+}
+
+// scanner is implemented by *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanAPIKey scans a single key row. It returns sql.ErrNoRows unwrapped.
+func scanAPIKey(row scanner) (*APIKey, error) {
+	key := &APIKey{}
+	var scopes, allowedIPs string
+	var expiresAt, lastUsedAt, revokedAt sql.NullTime
+	err := row.Scan(
+		&key.ID,
+		&key.UserID,
+		&key.Name,
+		&key.Prefix,
+		&key.KeyHash,
+		&scopes,
+		&allowedIPs,
+		&expiresAt,
+		&lastUsedAt,
+		&revokedAt,
+		&key.CreatedBy,
+		&key.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan api key: %w", err)
+	}
+
+	key.Scopes = splitList(scopes)
+	key.AllowedIPs = splitList(allowedIPs)
+	if expiresAt.Valid {
+		key.ExpiresAt = &expiresAt.Time
+	}
+	if lastUsedAt.Valid {
+		key.LastUsedAt = &lastUsedAt.Time
+	}
+	if revokedAt.Valid {
+		key.RevokedAt = &revokedAt.Time
+	}
+
+	return key, nil
+}
+
+// splitList splits a comma-separated column, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
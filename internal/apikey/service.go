@@ -0,0 +1,264 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"backend_path/internal/auth"
+	"backend_path/pkg/database"
+	"backend_path/pkg/jwt"
+	"backend_path/pkg/logger"
+)
+
+// keyPrefix starts every key so leaked keys are easy to recognize
+const keyPrefix = "gfk_"
+
+// touchInterval is how often the last use of a key is recorded at most
+const touchInterval = time.Minute
+
+type service struct {
+	db    *sql.DB
+	repo  Repository
+	users auth.UserLookup
+}
+
+// NewService creates an API key service that looks key owners up in users
+func NewService(db *sql.DB, repo Repository, users auth.UserLookup) APIKeyService {
+	return &service{
+		db:    db,
+		repo:  repo,
+		users: users,
+	}
+}
+
+func (s *service) Issue(ctx context.Context, key *APIKey) (string, error) {
+	if err := validateAPIKey(key); err != nil {
+		return "", err
+	}
+	if _, err := s.users.GetByID(key.UserID); err != nil {
+		return "", err
+	}
+
+	var plaintext string
+	err := database.WithTransaction(ctx, s.db, func(tx *sql.Tx) error {
+		var err error
+		plaintext, err = s.create(s.repo.WithTx(tx), key)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return plaintext, nil
+}
+
+func (s *service) List() ([]*APIKey, error) {
+	return s.repo.ListActive()
+}
+
+func (s *service) Get(id int) (*APIKey, error) {
+	key, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil || key.RevokedAt != nil {
+		return nil, ErrNotFound
+	}
+	return key, nil
+}
+
+func (s *service) Rotate(ctx context.Context, id int) (*APIKey, string, error) {
+	var rotated *APIKey
+	var plaintext string
+	err := database.WithTransaction(ctx, s.db, func(tx *sql.Tx) error {
+		repo := s.repo.WithTx(tx)
+
+		current, err := repo.GetByID(id)
+		if err != nil {
+			return err
+		}
+		if current == nil || current.RevokedAt != nil {
+			return ErrNotFound
+		}
+		if err := validateDelegation(current); err != nil {
+			return err
+		}
+		if _, err := repo.Revoke(id, time.Now()); err != nil {
+			return err
+		}
+
+		rotated = &APIKey{
+			UserID:     current.UserID,
+			Name:       current.Name,
+			Scopes:     current.Scopes,
+			AllowedIPs: current.AllowedIPs,
+			ExpiresAt:  current.ExpiresAt,
+			CreatedBy:  current.CreatedBy,
+		}
+		plaintext, err = s.create(repo, rotated)
+		return err
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return rotated, plaintext, nil
+}
+
+func (s *service) Revoke(ctx context.Context, id int) error {
+	return database.WithTransaction(ctx, s.db, func(tx *sql.Tx) error {
+		revoked, err := s.repo.WithTx(tx).Revoke(id, time.Now())
+		if err != nil {
+			return err
+		}
+		if !revoked {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
+
+func (s *service) AuthenticateAPIKey(ctx context.Context, plaintext, ip string) (*jwt.Claims, error) {
+	if !strings.HasPrefix(plaintext, keyPrefix) {
+		return nil, ErrInvalidKey
+	}
+
+	key, err := s.repo.GetByHash(hashKey(plaintext))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if key == nil || key.RevokedAt != nil || (key.ExpiresAt != nil && !now.Before(*key.ExpiresAt)) {
+		return nil, ErrInvalidKey
+	}
+	if !ipAllowed(key.AllowedIPs, ip) {
+		return nil, ErrIPNotAllowed
+	}
+
+	owner, err := s.users.GetByID(key.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Touch(key.ID, now, touchInterval); err != nil {
+		logger.Warn("Failed to record api key use", map[string]interface{}{
+			"api_key_id": key.ID,
+			"error":      err.Error(),
+		})
+	}
+
+	return &jwt.Claims{
+		UserID:    owner.ID,
+		Username:  owner.Username,
+		Email:     owner.Email,
+		Role:      owner.Role,
+		TokenType: jwt.TokenTypeAPIKey,
+		Scope:     strings.Join(key.Scopes, " "),
+	}, nil
+}
+
+// create generates a key for key, stores it and returns the plaintext
+func (s *service) create(repo Repository, key *APIKey) (string, error) {
+	prefix, plaintext, err := generateKey()
+	if err != nil {
+		return "", err
+	}
+
+	key.Prefix = prefix
+	key.KeyHash = hashKey(plaintext)
+	key.CreatedAt = time.Now()
+	if err := repo.Create(key); err != nil {
+		return "", err
+	}
+
+	return plaintext, nil
+}
+
+func validateAPIKey(key *APIKey) error {
+	if strings.TrimSpace(key.Name) == "" || len(key.Name) > 100 {
+		return fmt.Errorf("%w: name is required and at most 100 characters", ErrInvalidAPIKey)
+	}
+	if len(key.Scopes) == 0 {
+		return fmt.Errorf("%w: at least one scope is required", ErrInvalidAPIKey)
+	}
+	for _, scope := range key.Scopes {
+		if _, _, err := auth.ParseScope(scope); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidAPIKey, err)
+		}
+	}
+	for _, allowed := range key.AllowedIPs {
+		if net.ParseIP(allowed) == nil {
+			if _, _, err := net.ParseCIDR(allowed); err != nil {
+				return fmt.Errorf("%w: invalid IP address or CIDR range %q", ErrInvalidAPIKey, allowed)
+			}
+		}
+	}
+	if key.ExpiresAt != nil && !key.ExpiresAt.After(time.Now()) {
+		return fmt.Errorf("%w: expiry must be in the future", ErrInvalidAPIKey)
+	}
+	return validateDelegation(key)
+}
+
+// validateDelegation refuses money-moving scopes on keys issued for another
+// user than their creator. Such a key would move the owner's money with
+// nothing in its requests naming who actually acts.
+func validateDelegation(key *APIKey) error {
+	if key.UserID != key.CreatedBy && auth.ScopesAllow(key.Scopes, "transaction", "create") {
+		return fmt.Errorf("%w: keys issued for another user cannot have scopes that move money", ErrInvalidAPIKey)
+	}
+	return nil
+}
+
+// ipAllowed reports whether ip is in allowlist. An empty allowlist allows
+// any address.
+func ipAllowed(allowlist []string, ip string) bool {
+	if len(allowlist) == 0 {
+		return true
+	}
+
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	for _, allowed := range allowlist {
+		if allowedIP := net.ParseIP(allowed); allowedIP != nil {
+			if allowedIP.Equal(addr) {
+				return true
+			}
+			continue
+		}
+		if _, network, err := net.ParseCIDR(allowed); err == nil && network.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// generateKey returns a random key and its public prefix
+func generateKey() (string, string, error) {
+	id := make([]byte, 4)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(id); err != nil {
+		return "", "", fmt.Errorf("failed to generate api key: %w", err)
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", fmt.Errorf("failed to generate api key: %w", err)
+	}
+
+	prefix := keyPrefix + hex.EncodeToString(id)
+	return prefix, prefix + "_" + base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// hashKey returns the hash a key is stored and looked up by
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
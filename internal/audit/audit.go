@@ -0,0 +1,72 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+
+	"backend_path/internal/domain"
+)
+
+// Audited entity types
+const (
+	EntityUser        = "user"
+	EntityTransaction = "transaction"
+	EntityBalance     = "balance"
+	EntityRole        = "role"
+	EntityPolicy      = "policy"
+	EntityInvitation  = "invitation"
+	EntityAPIKey      = "api_key"
+	EntityOAuthClient = "oauth_client"
+)
+
+// Audited actions
+const (
+	ActionCreate        = "create"
+	ActionUpdate        = "update"
+	ActionDelete        = "delete"
+	ActionLogin         = "login"
+	ActionLoginFailed   = "login_failed"
+	ActionRoleChange    = "role_change"
+	ActionTokenReuse    = "refresh_token_reuse"
+	ActionLogout        = "logout"
+	ActionLogoutAll     = "logout_all"
+	ActionAccess        = "access"
+	ActionMFAEnabled    = "mfa_enabled"
+	ActionMFADisabled   = "mfa_disabled"
+	ActionMFAFailed     = "mfa_failed"
+	ActionLockout       = "lockout"
+	ActionUnlock        = "unlock"
+	ActionPasswordReset = "password_reset"
+	ActionEmailVerified = "email_verified"
+	ActionRotate        = "rotate"
+	ActionConsent       = "consent"
+	ActionImpersonate   = "impersonate"
+	ActionImpersonation = "impersonation"
+)
+
+// Entry describes an audited change. Before and After are the state of the
+// entity around the change and may be nil for creates and deletes.
+type Entry struct {
+	EntityType string
+	EntityID   int
+	Action     string
+	Before     interface{}
+	After      interface{}
+	Details    map[string]interface{}
+}
+
+// AuditService records and queries the audit log. The actor, IP address,
+// user agent and trace ID of a record are taken from ctx. Entries form a
+// hash chain that is periodically sealed by a signed checkpoint.
+type AuditService interface {
+	Record(ctx context.Context, entry *Entry) error
+	// RecordTx records entry inside tx so it is committed with the change it describes
+	RecordTx(ctx context.Context, tx *sql.Tx, entry *Entry) error
+	List(filter Filter) ([]*domain.AuditLog, int, error)
+	// Checkpoint signs the current head of the chain. It returns nil if
+	// nothing was recorded since the last checkpoint.
+	Checkpoint(ctx context.Context) (*Checkpoint, error)
+	// Verify walks the chain from the oldest entry and reports the first
+	// broken link, if any
+	Verify(ctx context.Context) (*VerifyResult, error)
+}
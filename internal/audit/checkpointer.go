@@ -0,0 +1,66 @@
+package audit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"backend_path/pkg/logger"
+)
+
+// Checkpointer periodically signs the head of the audit chain
+type Checkpointer struct {
+	service  AuditService
+	interval time.Duration
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+// NewCheckpointer creates a checkpointer that runs every interval
+func NewCheckpointer(service AuditService, interval time.Duration) *Checkpointer {
+	return &Checkpointer{
+		service:  service,
+		interval: interval,
+	}
+}
+
+// Start starts checkpointing in the background
+func (c *Checkpointer) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		c.run(ctx)
+	}()
+
+	logger.Info("Audit checkpointer started", map[string]interface{}{
+		"interval": c.interval.String(),
+	})
+}
+
+// Stop stops the checkpointer and waits for a running checkpoint to finish
+func (c *Checkpointer) Stop() {
+	if c.cancel != nil {
+		c.cancel()
+	}
+	c.wg.Wait()
+	logger.Info("Audit checkpointer stopped", nil)
+}
+
+func (c *Checkpointer) run(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := c.service.Checkpoint(ctx); err != nil {
+				logger.Error("Audit checkpoint failed", err, nil)
+			}
+		}
+	}
+}
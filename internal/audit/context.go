@@ -0,0 +1,47 @@
+package audit
+
+import (
+	"context"
+)
+
+// RequestInfo identifies who made a request and from where. ImpersonatorID
+// is the user acting as ActorID when the request was made under
+// impersonation. IP is the client address, taken from forwarding headers
+// only when the request came through a trusted proxy.
+type RequestInfo struct {
+	ActorID        int
+	ImpersonatorID int
+	IP             string
+	UserAgent      string
+}
+
+type contextKey struct{}
+
+// WithRequestInfo returns a copy of ctx carrying info
+func WithRequestInfo(ctx context.Context, info RequestInfo) context.Context {
+	return context.WithValue(ctx, contextKey{}, info)
+}
+
+// WithActor returns a copy of ctx whose request info names actorID as the actor
+func WithActor(ctx context.Context, actorID int) context.Context {
+	info := RequestInfoFromContext(ctx)
+	info.ActorID = actorID
+	return WithRequestInfo(ctx, info)
+}
+
+// WithImpersonator returns a copy of ctx whose request info names
+// impersonatorID as the user acting as the actor
+func WithImpersonator(ctx context.Context, impersonatorID int) context.Context {
+	info := RequestInfoFromContext(ctx)
+	info.ImpersonatorID = impersonatorID
+	return WithRequestInfo(ctx, info)
+}
+
+// RequestInfoFromContext returns the request info carried by ctx, if any
+func RequestInfoFromContext(ctx context.Context) RequestInfo {
+	if ctx == nil {
+		return RequestInfo{}
+	}
+	info, _ := ctx.Value(contextKey{}).(RequestInfo)
+	return info
+}
@@ -0,0 +1,63 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// FieldChange is the value of a field before and after a change
+type FieldChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// Diff compares the JSON representations of before and after and returns
+// the top-level fields whose values differ. Either side may be nil, e.g. for
+// creates and deletes.
+func Diff(before, after interface{}) (map[string]FieldChange, error) {
+	beforeFields, err := toFields(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := toFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(map[string]FieldChange)
+	for key, from := range beforeFields {
+		to, ok := afterFields[key]
+		if !ok || !reflect.DeepEqual(from, to) {
+			changes[key] = FieldChange{From: from, To: to}
+		}
+	}
+	for key, to := range afterFields {
+		if _, ok := beforeFields[key]; !ok {
+			changes[key] = FieldChange{From: nil, To: to}
+		}
+	}
+
+	return changes, nil
+}
+
+// toFields converts value to a map of its JSON fields
+func toFields(value interface{}) (map[string]interface{}, error) {
+	fields := make(map[string]interface{})
+	if value == nil {
+		return fields, nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal audit state: %w", err)
+	}
+	if string(data) == "null" {
+		return fields, nil
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("audit state must be a JSON object: %w", err)
+	}
+
+	return fields, nil
+}
@@ -0,0 +1,45 @@
+package audit
+
+import (
+	"database/sql"
+	"time"
+
+	"backend_path/internal/domain"
+)
+
+// Filter selects audit log entries. Zero fields match everything.
+type Filter struct {
+	EntityType     string
+	EntityID       int
+	Action         string
+	ActorID        int
+	ImpersonatorID int
+	From           time.Time
+	To             time.Time
+	Limit          int
+	Offset         int
+}
+
+// Repository stores audit log entries
+type Repository interface {
+	Create(log *domain.AuditLog) error
+	// List returns a page of entries matching filter, newest first, and the
+	// total number of matching entries
+	List(filter Filter) ([]*domain.AuditLog, int, error)
+	// ListAfter returns up to limit entries with an ID greater than afterID,
+	// oldest first
+	ListAfter(afterID, limit int) ([]*domain.AuditLog, error)
+	// LockTail returns the ID and hash of the newest entry, or zero values if
+	// there is none, and keeps other writers from appending until the
+	// surrounding transaction ends. It must be called inside a transaction.
+	LockTail() (int, string, error)
+
+	CreateCheckpoint(checkpoint *Checkpoint) error
+	// LatestCheckpoint returns the newest checkpoint, or nil if there is none
+	LatestCheckpoint() (*Checkpoint, error)
+	// ListCheckpoints returns every checkpoint, oldest first
+	ListCheckpoints() ([]*Checkpoint, error)
+
+	// WithTx returns a repository that runs its queries inside tx
+	WithTx(tx *sql.Tx) Repository
+}
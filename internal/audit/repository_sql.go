@@ -0,0 +1,303 @@
+package audit
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"backend_path/internal/domain"
+	"backend_path/pkg/database"
+)
+
+type sqlRepository struct {
+	db database.Executor
+}
+
+func NewSQLRepository(db *sql.DB) Repository {
+	return &sqlRepository{db: db}
+}
+
+func (r *sqlRepository) WithTx(tx *sql.Tx) Repository {
+	return &sqlRepository{db: tx}
+}
+
+const auditColumns = `id, entity_type, entity_id, action, details, actor_id, impersonator_id, ip_address, user_agent, trace_id,
+	before_state, after_state, changes, prev_hash, hash, created_at`
+
+func (r *sqlRepository) Create(log *domain.AuditLog) error {
+	query := `
+		INSERT INTO audit_logs (entity_type, entity_id, action, details, actor_id, impersonator_id, ip_address, user_agent, trace_id,
+			before_state, after_state, changes, prev_hash, hash, created_at)
+		OUTPUT INSERTED.id
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	err := r.db.QueryRow(query,
+		log.EntityType,
+		log.EntityID,
+		log.Action,
+		nullString(log.Details),
+		sql.NullInt64{Int64: int64(log.ActorID), Valid: log.ActorID != 0},
+		sql.NullInt64{Int64: int64(log.ImpersonatorID), Valid: log.ImpersonatorID != 0},
+		nullString(log.IPAddress),
+		nullString(log.UserAgent),
+		nullString(log.TraceID),
+		nullString(string(log.Before)),
+		nullString(string(log.After)),
+		nullString(string(log.Changes)),
+		nullString(log.PrevHash),
+		nullString(log.Hash),
+		log.CreatedAt,
+	).Scan(&log.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create audit log: %w", err)
+	}
+
+	return nil
+}
+
+func (r *sqlRepository) List(filter Filter) ([]*domain.AuditLog, int, error) {
+	var conditions []string
+	var args []interface{}
+
+	if filter.EntityType != "" {
+		conditions = append(conditions, "entity_type = ?")
+		args = append(args, filter.EntityType)
+	}
+	if filter.EntityID != 0 {
+		conditions = append(conditions, "entity_id = ?")
+		args = append(args, filter.EntityID)
+	}
+	if filter.Action != "" {
+		conditions = append(conditions, "action = ?")
+		args = append(args, filter.Action)
+	}
+	if filter.ActorID != 0 {
+		conditions = append(conditions, "actor_id = ?")
+		args = append(args, filter.ActorID)
+	}
+	if filter.ImpersonatorID != 0 {
+		conditions = append(conditions, "impersonator_id = ?")
+		args = append(args, filter.ImpersonatorID)
+	}
+	if !filter.From.IsZero() {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, filter.From)
+	}
+	if !filter.To.IsZero() {
+		conditions = append(conditions, "created_at <= ?")
+		args = append(args, filter.To)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM audit_logs `+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count audit logs: %w", err)
+	}
+
+	query := `SELECT ` + auditColumns + ` FROM audit_logs ` + where + `
+		ORDER BY id DESC
+		OFFSET ? ROWS FETCH NEXT ? ROWS ONLY`
+
+	rows, err := r.db.Query(query, append(args, filter.Offset, filter.Limit)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list audit logs: %w", err)
+	}
+	defer rows.Close()
+
+	var logs []*domain.AuditLog
+	for rows.Next() {
+		log, err := scanAuditLog(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		logs = append(logs, log)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating audit logs: %w", err)
+	}
+
+	return logs, total, nil
+}
+
+func (r *sqlRepository) ListAfter(afterID, limit int) ([]*domain.AuditLog, error) {
+	query := `SELECT TOP (?) ` + auditColumns + ` FROM audit_logs WHERE id > ? ORDER BY id`
+
+	rows, err := r.db.Query(query, limit, afterID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit logs: %w", err)
+	}
+	defer rows.Close()
+
+	var logs []*domain.AuditLog
+	for rows.Next() {
+		log, err := scanAuditLog(rows)
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, log)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating audit logs: %w", err)
+	}
+
+	return logs, nil
+}
+
+func (r *sqlRepository) LockTail() (int, string, error) {
+	// HOLDLOCK keeps the range past the newest entry locked as well, so a
+	// concurrent append waits even while the table is empty
+	query := `SELECT TOP 1 id, hash FROM audit_logs WITH (UPDLOCK, HOLDLOCK) ORDER BY id DESC`
+
+	var id int
+	var hash sql.NullString
+	err := r.db.QueryRow(query).Scan(&id, &hash)
+	if err == sql.ErrNoRows {
+		return 0, "", nil
+	}
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to lock audit chain: %w", err)
+	}
+
+	return id, hash.String, nil
+}
+
+func (r *sqlRepository) CreateCheckpoint(checkpoint *Checkpoint) error {
+	query := `
+		INSERT INTO audit_checkpoints (last_log_id, last_hash, signature, created_at)
+		OUTPUT INSERTED.id
+		VALUES (?, ?, ?, ?)
+	`
+
+	err := r.db.QueryRow(query,
+		checkpoint.LastLogID,
+		checkpoint.LastHash,
+		checkpoint.Signature,
+		checkpoint.CreatedAt,
+	).Scan(&checkpoint.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create audit checkpoint: %w", err)
+	}
+
+	return nil
+}
+
+func (r *sqlRepository) LatestCheckpoint() (*Checkpoint, error) {
+	query := `SELECT TOP 1 id, last_log_id, last_hash, signature, created_at FROM audit_checkpoints ORDER BY id DESC`
+
+	checkpoint := &Checkpoint{}
+	err := r.db.QueryRow(query).Scan(
+		&checkpoint.ID,
+		&checkpoint.LastLogID,
+		&checkpoint.LastHash,
+		&checkpoint.Signature,
+		&checkpoint.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit checkpoint: %w", err)
+	}
+
+	return checkpoint, nil
+}
+
+func (r *sqlRepository) ListCheckpoints() ([]*Checkpoint, error) {
+	query := `SELECT id, last_log_id, last_hash, signature, created_at FROM audit_checkpoints ORDER BY id`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit checkpoints: %w", err)
+	}
+	defer rows.Close()
+
+	var checkpoints []*Checkpoint
+	for rows.Next() {
+		checkpoint := &Checkpoint{}
+		if err := rows.Scan(
+			&checkpoint.ID,
+			&checkpoint.LastLogID,
+			&checkpoint.LastHash,
+			&checkpoint.Signature,
+			&checkpoint.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan audit checkpoint: %w", err)
+		}
+		checkpoints = append(checkpoints, checkpoint)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating audit checkpoints: %w", err)
+	}
+
+	return checkpoints, nil
+}
+
+// scanner is implemented by *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanAuditLog scans a single audit log row
+func scanAuditLog(row scanner) (*domain.AuditLog, error) {
+	log := &domain.AuditLog{}
+	var details, ipAddress, userAgent, traceID, before, after, changes, prevHash, hash sql.NullString
+	var actorID, impersonatorID sql.NullInt64
+
+	err := row.Scan(
+		&log.ID,
+		&log.EntityType,
+		&log.EntityID,
+		&log.Action,
+		&details,
+		&actorID,
+		&impersonatorID,
+		&ipAddress,
+		&userAgent,
+		&traceID,
+		&before,
+		&after,
+		&changes,
+		&prevHash,
+		&hash,
+		&log.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan audit log: %w", err)
+	}
+
+	log.Details = details.String
+	log.ActorID = int(actorID.Int64)
+	log.ImpersonatorID = int(impersonatorID.Int64)
+	log.IPAddress = ipAddress.String
+	log.UserAgent = userAgent.String
+	log.TraceID = traceID.String
+	log.Before = rawJSON(before)
+	log.After = rawJSON(after)
+	log.Changes = rawJSON(changes)
+	log.PrevHash = prevHash.String
+	log.Hash = hash.String
+
+	return log, nil
+}
+
+// nullString stores an empty string as NULL
+func nullString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
+
+// rawJSON returns a stored JSON column, or nil if it is NULL
+func rawJSON(value sql.NullString) json.RawMessage {
+	if !value.Valid || value.String == "" {
+		return nil
+	}
+	return json.RawMessage(value.String)
+}
@@ -0,0 +1,284 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"backend_path/internal/domain"
+	"backend_path/pkg/database"
+	"backend_path/pkg/logger"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DefaultPageSize is the number of entries returned when a filter has no limit
+const DefaultPageSize = 50
+
+// MaxPageSize caps the number of entries returned at once
+const MaxPageSize = 500
+
+// maxUserAgentLength is the size of the user_agent column
+const maxUserAgentLength = 512
+
+// verifyBatchSize is the number of entries read at a time while verifying
+const verifyBatchSize = 1000
+
+type service struct {
+	db         *sql.DB
+	repo       Repository
+	signingKey []byte
+}
+
+// NewService creates an audit service that signs checkpoints with signingKey
+func NewService(db *sql.DB, repo Repository, signingKey string) AuditService {
+	return &service{
+		db:         db,
+		repo:       repo,
+		signingKey: []byte(signingKey),
+	}
+}
+
+func (s *service) Record(ctx context.Context, entry *Entry) error {
+	return database.WithTransaction(ctx, s.db, func(tx *sql.Tx) error {
+		return s.record(ctx, s.repo.WithTx(tx), entry)
+	})
+}
+
+func (s *service) RecordTx(ctx context.Context, tx *sql.Tx, entry *Entry) error {
+	return s.record(ctx, s.repo.WithTx(tx), entry)
+}
+
+func (s *service) record(ctx context.Context, repo Repository, entry *Entry) error {
+	log, err := newAuditLog(ctx, entry)
+	if err != nil {
+		return err
+	}
+
+	// Chain the entry to the current tail, which stays locked until the
+	// transaction commits so entries are appended one at a time
+	_, prevHash, err := repo.LockTail()
+	if err != nil {
+		return err
+	}
+	log.PrevHash = prevHash
+	log.Hash = HashEntry(log)
+
+	if err := repo.Create(log); err != nil {
+		logger.Error("Failed to record audit log", err, map[string]interface{}{
+			"entity_type": entry.EntityType,
+			"entity_id":   entry.EntityID,
+			"action":      entry.Action,
+		})
+		return err
+	}
+
+	return nil
+}
+
+func (s *service) List(filter Filter) ([]*domain.AuditLog, int, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultPageSize
+	}
+	if filter.Limit > MaxPageSize {
+		filter.Limit = MaxPageSize
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	return s.repo.List(filter)
+}
+
+func (s *service) Checkpoint(ctx context.Context) (*Checkpoint, error) {
+	var checkpoint *Checkpoint
+	err := database.WithTransaction(ctx, s.db, func(tx *sql.Tx) error {
+		repo := s.repo.WithTx(tx)
+
+		// Only the chain can be checkpointed, not entries that predate it
+		lastID, lastHash, err := repo.LockTail()
+		if err != nil || lastHash == "" {
+			return err
+		}
+
+		latest, err := repo.LatestCheckpoint()
+		if err != nil {
+			return err
+		}
+		if latest != nil && latest.LastLogID == lastID {
+			return nil
+		}
+
+		checkpoint = &Checkpoint{
+			LastLogID: lastID,
+			LastHash:  lastHash,
+			CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+		}
+		checkpoint.Signature = signCheckpoint(s.signingKey, checkpoint)
+		return repo.CreateCheckpoint(checkpoint)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if checkpoint != nil {
+		logger.Info("Audit checkpoint created", map[string]interface{}{
+			"checkpoint_id": checkpoint.ID,
+			"last_log_id":   checkpoint.LastLogID,
+		})
+	}
+
+	return checkpoint, nil
+}
+
+func (s *service) Verify(ctx context.Context) (*VerifyResult, error) {
+	checkpoints, err := s.repo.ListCheckpoints()
+	if err != nil {
+		return nil, err
+	}
+
+	// Checkpoints are verified when the walk reaches the entry they cover
+	pending := make(map[int][]*Checkpoint)
+	for _, checkpoint := range checkpoints {
+		pending[checkpoint.LastLogID] = append(pending[checkpoint.LastLogID], checkpoint)
+	}
+
+	result := &VerifyResult{}
+	broken := func(link *BrokenLink) (*VerifyResult, error) {
+		result.BrokenLink = link
+		return result, nil
+	}
+
+	prevHash := ""
+	chained := false
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		logs, err := s.repo.ListAfter(result.LastLogID, verifyBatchSize)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, log := range logs {
+			result.LastLogID = log.ID
+
+			if log.Hash == "" {
+				// Entries written before chaining was introduced precede
+				// the chain; once it starts every entry must be hashed
+				if !chained {
+					result.Unchained++
+					continue
+				}
+				return broken(&BrokenLink{LogID: log.ID, Reason: "entry is not hashed"})
+			}
+			chained = true
+
+			if log.PrevHash != prevHash {
+				return broken(&BrokenLink{LogID: log.ID, Reason: "previous hash does not match the preceding entry", Expected: prevHash, Actual: log.PrevHash})
+			}
+			if hash := HashEntry(log); hash != log.Hash {
+				return broken(&BrokenLink{LogID: log.ID, Reason: "content hash does not match the entry", Expected: hash, Actual: log.Hash})
+			}
+
+			for _, checkpoint := range pending[log.ID] {
+				if !validCheckpoint(s.signingKey, checkpoint) {
+					return broken(&BrokenLink{LogID: log.ID, CheckpointID: checkpoint.ID, Reason: "checkpoint signature is invalid"})
+				}
+				if checkpoint.LastHash != log.Hash {
+					return broken(&BrokenLink{LogID: log.ID, CheckpointID: checkpoint.ID, Reason: "entry hash does not match the checkpoint", Expected: checkpoint.LastHash, Actual: log.Hash})
+				}
+				result.Checkpoints++
+			}
+			delete(pending, log.ID)
+
+			prevHash = log.Hash
+			result.Checked++
+		}
+
+		if len(logs) < verifyBatchSize {
+			break
+		}
+	}
+
+	// A checkpoint covering an entry the walk never reached means entries
+	// were removed from the chain
+	for _, checkpoint := range checkpoints {
+		if _, missing := pending[checkpoint.LastLogID]; missing {
+			return broken(&BrokenLink{LogID: checkpoint.LastLogID, CheckpointID: checkpoint.ID, Reason: "checkpointed entry is missing", Expected: checkpoint.LastHash})
+		}
+	}
+
+	result.Valid = true
+	return result, nil
+}
+
+// newAuditLog builds the stored record of entry, filling in the request
+// context and the before/after diff
+func newAuditLog(ctx context.Context, entry *Entry) (*domain.AuditLog, error) {
+	info := RequestInfoFromContext(ctx)
+	log := &domain.AuditLog{
+		EntityType:     entry.EntityType,
+		EntityID:       entry.EntityID,
+		Action:         entry.Action,
+		ActorID:        info.ActorID,
+		ImpersonatorID: info.ImpersonatorID,
+		IPAddress:      info.IP,
+		UserAgent:      info.UserAgent,
+		CreatedAt:      time.Now().UTC().Truncate(time.Microsecond),
+	}
+
+	if len(log.UserAgent) > maxUserAgentLength {
+		log.UserAgent = log.UserAgent[:maxUserAgentLength]
+	}
+
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.HasTraceID() {
+		log.TraceID = spanContext.TraceID().String()
+	}
+
+	var err error
+	if log.Before, err = marshalState(entry.Before); err != nil {
+		return nil, err
+	}
+	if log.After, err = marshalState(entry.After); err != nil {
+		return nil, err
+	}
+
+	changes, err := Diff(entry.Before, entry.After)
+	if err != nil {
+		return nil, err
+	}
+	if len(changes) > 0 {
+		if log.Changes, err = json.Marshal(changes); err != nil {
+			return nil, fmt.Errorf("failed to marshal audit changes: %w", err)
+		}
+	}
+
+	if len(entry.Details) > 0 {
+		details, err := json.Marshal(entry.Details)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal audit details: %w", err)
+		}
+		log.Details = string(details)
+	}
+
+	return log, nil
+}
+
+// marshalState encodes an entity state, keeping nil as no state
+func marshalState(state interface{}) (json.RawMessage, error) {
+	if state == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal audit state: %w", err)
+	}
+	if string(data) == "null" {
+		return nil, nil
+	}
+	return data, nil
+}
@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"backend_path/internal/domain"
+	"backend_path/pkg/jwt"
+)
+
+// ErrNotAuthenticated is returned when a request carries no token claims
+var ErrNotAuthenticated = errors.New("not authenticated")
+
+// UserLookup loads users whose role is not carried by their token
+type UserLookup interface {
+	GetByID(id int) (*domain.User, error)
+}
+
+// CallerRoles returns the roles of the authenticated caller of ctx. Roles are
+// taken from the token claims, which are revoked whenever a role changes,
+// and looked up for tokens that carry none.
+func CallerRoles(ctx context.Context, users UserLookup) ([]string, error) {
+	claims := jwt.ClaimsFromContext(ctx)
+	if claims == nil {
+		return nil, ErrNotAuthenticated
+	}
+	if claims.Role != "" {
+		return []string{claims.Role}, nil
+	}
+
+	user, err := users.GetByID(claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return []string{user.Role}, nil
+}
+
+// CallerAttributes returns the attributes policy conditions are evaluated
+// against for the authenticated caller of ctx: the caller as subject, the
+// client IP, the UTC time of day and weekday, and the target resource if
+// there is one. The caller's KYC tier is only looked up if a condition uses
+// it.
+func CallerAttributes(ctx context.Context, users UserLookup, roles []string, ip string, resource *Resource) Attributes {
+	attrs := Attributes{}
+	if resource != nil {
+		attrs = resource.attributes()
+	}
+
+	now := time.Now().UTC()
+	attrs[AttrIP] = ip
+	attrs[AttrTime] = now.Format("15:04")
+	attrs[AttrWeekday] = now.Weekday().String()
+
+	claims := jwt.ClaimsFromContext(ctx)
+	if claims == nil {
+		return attrs
+	}
+	attrs[AttrSubjectID] = claims.UserID
+	if len(roles) > 0 {
+		attrs[AttrSubjectRole] = roles[0]
+	}
+	attrs[AttrSubjectKYCTier] = AttributeFunc(func() (interface{}, error) {
+		user, err := users.GetByID(claims.UserID)
+		if err != nil {
+			return nil, err
+		}
+		return user.KYCTier, nil
+	})
+
+	return attrs
+}
@@ -0,0 +1,564 @@
+package auth
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Attributes describe the subject, resource and request a permission is
+// checked for. Keys are dotted names such as "subject.id",
+// "resource.owner_id" or "amount". A value may be an AttributeFunc, which is
+// only called if a condition refers to it.
+type Attributes map[string]interface{}
+
+// AttributeFunc computes an attribute on first use
+type AttributeFunc func() (interface{}, error)
+
+// Well-known attributes set by the authorizer and resource loaders
+const (
+	AttrSubjectID        = "subject.id"
+	AttrSubjectRole      = "subject.role"
+	AttrSubjectKYCTier   = "subject.kyc_tier"
+	AttrResourceID       = "resource.id"
+	AttrResourceOwnerID  = "resource.owner_id"
+	AttrResourceOwnerIDs = "resource.owner_ids"
+	AttrAmount           = "amount"
+	AttrCurrency         = "currency"
+	AttrIP               = "ip"
+	AttrTime             = "time"
+	AttrWeekday          = "weekday"
+)
+
+// lookup returns the value of the attribute name, normalized for comparison
+func (a Attributes) lookup(name string) (interface{}, error) {
+	value, ok := a[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown attribute %q", name)
+	}
+	if resolve, ok := value.(AttributeFunc); ok {
+		resolved, err := resolve()
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve attribute %q: %w", name, err)
+		}
+		a[name] = resolved
+		value = resolved
+	}
+	return normalize(value)
+}
+
+// Condition is a compiled policy condition. The language supports numbers,
+// quoted strings, true/false, lists in brackets, attribute names, the
+// comparisons == != < <= > >=, membership with in, and &&, || and ! with
+// parentheses. When the right side of in is a CIDR range, or a list holding
+// some, an IP address on the left is matched against the range:
+//
+//	amount <= 1000000 && currency in ["USD", "EUR"]
+//	resource.owner_id == subject.id || subject.kyc_tier >= 2
+//	ip in "10.0.0.0/8" && time >= "09:00" && time < "18:00"
+type Condition struct {
+	source string
+	root   node
+}
+
+// CompileCondition parses a condition expression
+func CompileCondition(source string) (*Condition, error) {
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %s at offset %d", p.peek(), p.peek().pos)
+	}
+
+	return &Condition{source: source, root: root}, nil
+}
+
+// String returns the source of the condition
+func (c *Condition) String() string {
+	return c.source
+}
+
+// Evaluate reports whether the condition holds for attrs. Conditions that
+// cannot be evaluated, for example because an attribute is missing, do not
+// hold and return the reason.
+func (c *Condition) Evaluate(attrs Attributes) (bool, error) {
+	value, err := c.root.eval(attrs)
+	if err != nil {
+		return false, err
+	}
+	result, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("condition evaluates to %v, not a boolean", value)
+	}
+	return result, nil
+}
+
+// compileConditions compiles the conditions of a policy. Each condition is
+// an expression stored under a descriptive name.
+func compileConditions(conditions map[string]interface{}) (map[string]*Condition, error) {
+	compiled := make(map[string]*Condition, len(conditions))
+	for name, value := range conditions {
+		source, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("condition %q must be an expression string", name)
+		}
+		condition, err := CompileCondition(source)
+		if err != nil {
+			return nil, fmt.Errorf("condition %q: %w", name, err)
+		}
+		compiled[name] = condition
+	}
+	return compiled, nil
+}
+
+// normalize converts numbers to float64 and lists to []interface{} so
+// values of different origins compare equal
+func normalize(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case bool, string, float64:
+		return v, nil
+	case int:
+		return float64(v), nil
+	case int32:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case uint:
+		return float64(v), nil
+	case float32:
+		return float64(v), nil
+	case interface{ Float64() (float64, error) }:
+		return v.Float64()
+	case []interface{}:
+		return v, nil
+	case []string:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			list[i] = item
+		}
+		return list, nil
+	case []int:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			list[i] = float64(item)
+		}
+		return list, nil
+	default:
+		return nil, fmt.Errorf("unsupported attribute type %T", value)
+	}
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenNumber
+	tokenString
+	tokenIdent
+	tokenOperator
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	value interface{}
+	pos   int
+}
+
+func (t token) String() string {
+	if t.kind == tokenEOF {
+		return "end of condition"
+	}
+	return strconv.Quote(t.text)
+}
+
+// operators lists the operator tokens, two-character ones first
+var operators = []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!", "(", ")", "[", "]", ","}
+
+func tokenize(source string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(source); {
+		c := rune(source[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+
+		case c == '"' || c == '\'':
+			end := strings.IndexByte(source[i+1:], source[i])
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string at offset %d", i)
+			}
+			text := source[i+1 : i+1+end]
+			tokens = append(tokens, token{kind: tokenString, text: text, value: text, pos: i})
+			i += end + 2
+
+		case unicode.IsDigit(c) || (c == '-' && i+1 < len(source) && unicode.IsDigit(rune(source[i+1]))):
+			start := i
+			i++
+			for i < len(source) && (unicode.IsDigit(rune(source[i])) || source[i] == '.') {
+				i++
+			}
+			number, err := strconv.ParseFloat(source[start:i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q at offset %d", source[start:i], start)
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: source[start:i], value: number, pos: start})
+
+		case unicode.IsLetter(c) || c == '_':
+			start := i
+			for i < len(source) && (unicode.IsLetter(rune(source[i])) || unicode.IsDigit(rune(source[i])) || source[i] == '_' || source[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: source[start:i], pos: start})
+
+		default:
+			matched := ""
+			for _, operator := range operators {
+				if strings.HasPrefix(source[i:], operator) {
+					matched = operator
+					break
+				}
+			}
+			if matched == "" {
+				return nil, fmt.Errorf("unexpected character %q at offset %d", c, i)
+			}
+			tokens = append(tokens, token{kind: tokenOperator, text: matched, pos: i})
+			i += len(matched)
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(source)}), nil
+}
+
+// parser is a recursive descent parser over the grammar
+//
+//	or         = and { "||" and }
+//	and        = unary { "&&" unary }
+//	unary      = "!" unary | comparison
+//	comparison = operand [ ( "==" | "!=" | "<" | "<=" | ">" | ">=" | "in" ) operand ]
+//	operand    = number | string | "true" | "false" | attribute | list | "(" or ")"
+//	list       = "[" [ operand { "," operand } ] "]"
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it is the operator or keyword text
+func (p *parser) accept(text string) bool {
+	t := p.peek()
+	if (t.kind == tokenOperator || t.kind == tokenIdent) && t.text == text {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(text string) error {
+	if !p.accept(text) {
+		return fmt.Errorf("expected %q at offset %d, got %s", text, p.peek().pos, p.peek())
+	}
+	return nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{or: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.accept("!") {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	for _, operator := range []string{"==", "!=", "<=", ">=", "<", ">", "in"} {
+		if p.accept(operator) {
+			right, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			return &comparisonNode{operator: operator, left: left, right: right}, nil
+		}
+	}
+	return left, nil
+}
+
+func (p *parser) parseOperand() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenNumber, tokenString:
+		return &literalNode{value: t.value}, nil
+	case tokenIdent:
+		switch t.text {
+		case "true":
+			return &literalNode{value: true}, nil
+		case "false":
+			return &literalNode{value: false}, nil
+		case "in":
+			return nil, fmt.Errorf("unexpected %s at offset %d", t, t.pos)
+		}
+		return &attributeNode{name: t.text}, nil
+	case tokenOperator:
+		switch t.text {
+		case "(":
+			inner, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			return inner, p.expect(")")
+		case "[":
+			list := &listNode{}
+			if p.accept("]") {
+				return list, nil
+			}
+			for {
+				item, err := p.parseOperand()
+				if err != nil {
+					return nil, err
+				}
+				list.items = append(list.items, item)
+				if p.accept("]") {
+					return list, nil
+				}
+				if err := p.expect(","); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return nil, fmt.Errorf("unexpected %s at offset %d", t, t.pos)
+}
+
+type node interface {
+	eval(attrs Attributes) (interface{}, error)
+}
+
+type literalNode struct {
+	value interface{}
+}
+
+func (n *literalNode) eval(Attributes) (interface{}, error) {
+	return n.value, nil
+}
+
+type attributeNode struct {
+	name string
+}
+
+func (n *attributeNode) eval(attrs Attributes) (interface{}, error) {
+	return attrs.lookup(n.name)
+}
+
+type listNode struct {
+	items []node
+}
+
+func (n *listNode) eval(attrs Attributes) (interface{}, error) {
+	list := make([]interface{}, len(n.items))
+	for i, item := range n.items {
+		value, err := item.eval(attrs)
+		if err != nil {
+			return nil, err
+		}
+		list[i] = value
+	}
+	return list, nil
+}
+
+type notNode struct {
+	operand node
+}
+
+func (n *notNode) eval(attrs Attributes) (interface{}, error) {
+	value, err := evalBool(n.operand, attrs)
+	if err != nil {
+		return nil, err
+	}
+	return !value, nil
+}
+
+type logicalNode struct {
+	or          bool
+	left, right node
+}
+
+func (n *logicalNode) eval(attrs Attributes) (interface{}, error) {
+	left, err := evalBool(n.left, attrs)
+	if err != nil {
+		return nil, err
+	}
+	if left == n.or {
+		return left, nil
+	}
+	return evalBool(n.right, attrs)
+}
+
+func evalBool(n node, attrs Attributes) (bool, error) {
+	value, err := n.eval(attrs)
+	if err != nil {
+		return false, err
+	}
+	result, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("expected a boolean, got %v", value)
+	}
+	return result, nil
+}
+
+type comparisonNode struct {
+	operator    string
+	left, right node
+}
+
+func (n *comparisonNode) eval(attrs Attributes) (interface{}, error) {
+	left, err := n.left.eval(attrs)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(attrs)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.operator {
+	case "==":
+		return equal(left, right)
+	case "!=":
+		result, err := equal(left, right)
+		return !result, err
+	case "in":
+		return contains(right, left)
+	}
+
+	switch l := left.(type) {
+	case float64:
+		if r, ok := right.(float64); ok {
+			return order(n.operator, compareFloat(l, r)), nil
+		}
+	case string:
+		if r, ok := right.(string); ok {
+			return order(n.operator, strings.Compare(l, r)), nil
+		}
+	}
+	return nil, fmt.Errorf("cannot compare %v %s %v", left, n.operator, right)
+}
+
+func equal(left, right interface{}) (bool, error) {
+	switch left.(type) {
+	case float64, string, bool, nil:
+	default:
+		return false, fmt.Errorf("cannot compare %v for equality", left)
+	}
+	if left != nil && right != nil && fmt.Sprintf("%T", left) != fmt.Sprintf("%T", right) {
+		return false, fmt.Errorf("cannot compare %v and %v of different types", left, right)
+	}
+	return left == right, nil
+}
+
+// contains reports whether value is in set, which is a list or a CIDR range
+func contains(set, value interface{}) (bool, error) {
+	switch s := set.(type) {
+	case []interface{}:
+		for _, item := range s {
+			found, err := contains(item, value)
+			if err != nil {
+				return false, err
+			}
+			if found {
+				return true, nil
+			}
+		}
+		return false, nil
+	case string:
+		if _, network, err := net.ParseCIDR(s); err == nil {
+			address, ok := value.(string)
+			ip := net.ParseIP(address)
+			if !ok || ip == nil {
+				return false, fmt.Errorf("%v is not an IP address", value)
+			}
+			return network.Contains(ip), nil
+		}
+	}
+	return equal(value, set)
+}
+
+func compareFloat(left, right float64) int {
+	switch {
+	case left < right:
+		return -1
+	case left > right:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func order(operator string, comparison int) bool {
+	switch operator {
+	case "<":
+		return comparison < 0
+	case "<=":
+		return comparison <= 0
+	case ">":
+		return comparison > 0
+	default:
+		return comparison >= 0
+	}
+}
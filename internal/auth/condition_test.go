@@ -0,0 +1,211 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestConditionEvaluate(t *testing.T) {
+	attrs := Attributes{
+		AttrSubjectID:        7,
+		AttrSubjectRole:      "user",
+		AttrSubjectKYCTier:   2,
+		AttrResourceOwnerID:  7,
+		AttrResourceOwnerIDs: []int{7, 9},
+		AttrAmount:           250.5,
+		AttrCurrency:         "USD",
+		AttrIP:               "10.1.2.3",
+		AttrTime:             "09:30",
+		AttrWeekday:          "Monday",
+	}
+
+	tests := []struct {
+		source string
+		want   bool
+	}{
+		{`amount <= 1000000`, true},
+		{`amount > 1000`, false},
+		{`amount == 250.5`, true},
+		{`amount != 250.5`, false},
+		{`amount >= -1`, true},
+		{`resource.owner_id == subject.id`, true},
+		{`subject.id in resource.owner_ids`, true},
+		{`8 in resource.owner_ids`, false},
+		{`currency in ["USD", 'EUR']`, true},
+		{`currency in []`, false},
+		{`currency == "EUR" || subject.kyc_tier >= 2`, true},
+		{`currency == "USD" && subject.kyc_tier > 2`, false},
+		{`!(currency == "EUR")`, true},
+		{`!!true`, true},
+		{`false || true && false`, false},
+		{`(false || true) && true`, true},
+		{`ip in "10.0.0.0/8"`, true},
+		{`ip in "192.168.0.0/16"`, false},
+		{`ip in ["192.168.0.0/16", "10.1.2.0/24"]`, true},
+		{`ip in ["192.168.0.0/16", "10.1.2.3"]`, true},
+		{`time >= "09:00" && time < "18:00"`, true},
+		{`weekday in ["Saturday", "Sunday"]`, false},
+		{`subject.role == "user"`, true},
+	}
+
+	for _, tt := range tests {
+		condition, err := CompileCondition(tt.source)
+		if err != nil {
+			t.Errorf("CompileCondition(%q) failed: %v", tt.source, err)
+			continue
+		}
+		got, err := condition.Evaluate(attrs)
+		if err != nil {
+			t.Errorf("Evaluate(%q) failed: %v", tt.source, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Evaluate(%q) = %v, want %v", tt.source, got, tt.want)
+		}
+	}
+}
+
+func TestConditionEvaluateErrors(t *testing.T) {
+	attrs := Attributes{
+		AttrAmount:   100,
+		AttrCurrency: "USD",
+		AttrIP:       "not-an-ip",
+	}
+
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`resource.owner_id == 1`, `unknown attribute "resource.owner_id"`},
+		{`amount == "100"`, "different types"},
+		{`amount < "100"`, "cannot compare"},
+		{`currency`, "not a boolean"},
+		{`!amount`, "expected a boolean"},
+		{`amount > 1 && currency`, "expected a boolean"},
+		{`ip in "10.0.0.0/8"`, "is not an IP address"},
+		{`[1] == [1]`, "for equality"},
+	}
+
+	for _, tt := range tests {
+		condition, err := CompileCondition(tt.source)
+		if err != nil {
+			t.Errorf("CompileCondition(%q) failed: %v", tt.source, err)
+			continue
+		}
+		got, err := condition.Evaluate(attrs)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Evaluate(%q) error = %v, want %q", tt.source, err, tt.want)
+		}
+		if got {
+			t.Errorf("Evaluate(%q) = true for a condition that cannot be evaluated", tt.source)
+		}
+	}
+}
+
+func TestConditionShortCircuit(t *testing.T) {
+	// The right side refers to a missing attribute and must not be evaluated
+	for _, source := range []string{`true || missing == 1`, `false && missing == 1`} {
+		condition, err := CompileCondition(source)
+		if err != nil {
+			t.Fatalf("CompileCondition(%q) failed: %v", source, err)
+		}
+		if _, err := condition.Evaluate(Attributes{}); err != nil {
+			t.Errorf("Evaluate(%q) failed: %v", source, err)
+		}
+	}
+}
+
+func TestCompileConditionErrors(t *testing.T) {
+	tests := []string{
+		``,
+		`amount <=`,
+		`amount <= 1 1`,
+		`(amount <= 1`,
+		`currency in ["USD" "EUR"]`,
+		`currency in ["USD",`,
+		`currency == "USD`,
+		`amount # 1`,
+		`in == 1`,
+		`1.2.3 == 1`,
+		`&& true`,
+	}
+
+	for _, source := range tests {
+		if _, err := CompileCondition(source); err == nil {
+			t.Errorf("CompileCondition(%q) succeeded, want an error", source)
+		}
+	}
+}
+
+func TestConditionString(t *testing.T) {
+	source := `amount <= 1000000`
+	condition, err := CompileCondition(source)
+	if err != nil {
+		t.Fatalf("CompileCondition failed: %v", err)
+	}
+	if condition.String() != source {
+		t.Errorf("String() = %q, want %q", condition.String(), source)
+	}
+}
+
+func TestAttributeFunc(t *testing.T) {
+	calls := 0
+	attrs := Attributes{
+		AttrSubjectKYCTier: AttributeFunc(func() (interface{}, error) {
+			calls++
+			return 3, nil
+		}),
+		AttrAmount: 10,
+	}
+
+	unused, err := CompileCondition(`amount > 1`)
+	if err != nil {
+		t.Fatalf("CompileCondition failed: %v", err)
+	}
+	if _, err := unused.Evaluate(attrs); err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+	if calls != 0 {
+		t.Errorf("attribute resolved %d times for a condition not using it", calls)
+	}
+
+	used, err := CompileCondition(`subject.kyc_tier >= 2 && subject.kyc_tier < 4`)
+	if err != nil {
+		t.Fatalf("CompileCondition failed: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		holds, err := used.Evaluate(attrs)
+		if err != nil || !holds {
+			t.Fatalf("Evaluate = %v, %v, want true", holds, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("attribute resolved %d times, want once", calls)
+	}
+
+	failing := errors.New("lookup failed")
+	attrs[AttrSubjectKYCTier] = AttributeFunc(func() (interface{}, error) {
+		return nil, failing
+	})
+	if _, err := used.Evaluate(attrs); !errors.Is(err, failing) {
+		t.Errorf("Evaluate error = %v, want %v", err, failing)
+	}
+}
+
+func TestCompileConditions(t *testing.T) {
+	if _, err := compileConditions(map[string]interface{}{"limit": 1000}); err == nil {
+		t.Error("compileConditions accepted a condition that is not a string")
+	}
+	if _, err := compileConditions(map[string]interface{}{"limit": "amount <="}); err == nil || !strings.Contains(err.Error(), `"limit"`) {
+		t.Errorf("compileConditions error = %v, want one naming the condition", err)
+	}
+
+	conditions, err := compileConditions(map[string]interface{}{"limit": "amount <= 1", "owner": "resource.owner_id == subject.id"})
+	if err != nil {
+		t.Fatalf("compileConditions failed: %v", err)
+	}
+	if len(conditions) != 2 {
+		t.Errorf("compileConditions returned %d conditions, want 2", len(conditions))
+	}
+}
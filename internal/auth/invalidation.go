@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"backend_path/pkg/logger"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// invalidationChannel carries notifications that roles or policies changed
+const invalidationChannel = "rbac:invalidate"
+
+// Invalidator tells every instance to reload roles and policies after one of
+// them changed them, using Redis pub/sub
+type Invalidator struct {
+	client    *redis.Client
+	pubsub    *redis.PubSub
+	done      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// NewInvalidator creates an invalidator on client
+func NewInvalidator(client *redis.Client) *Invalidator {
+	return &Invalidator{
+		client: client,
+		done:   make(chan struct{}),
+	}
+}
+
+// Start subscribes to invalidations and calls reload for each one
+func (i *Invalidator) Start(reload func() error) error {
+	i.pubsub = i.client.Subscribe(context.Background(), invalidationChannel)
+	if _, err := i.pubsub.Receive(context.Background()); err != nil {
+		i.pubsub.Close()
+		return fmt.Errorf("failed to subscribe to rbac invalidations: %w", err)
+	}
+
+	messages := i.pubsub.Channel()
+	i.wg.Add(1)
+	go func() {
+		defer i.wg.Done()
+		for {
+			select {
+			case <-i.done:
+				return
+			case _, ok := <-messages:
+				if !ok {
+					return
+				}
+				if err := reload(); err != nil {
+					logger.Error("Failed to reload roles and policies", err, nil)
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Publish notifies every instance, including this one, to reload
+func (i *Invalidator) Publish(ctx context.Context) error {
+	if err := i.client.Publish(ctx, invalidationChannel, "reload").Err(); err != nil {
+		return fmt.Errorf("failed to publish rbac invalidation: %w", err)
+	}
+	return nil
+}
+
+// Close stops listening for invalidations. It is safe to call more than once.
+func (i *Invalidator) Close() {
+	i.closeOnce.Do(func() {
+		close(i.done)
+		if i.pubsub != nil {
+			i.pubsub.Close()
+		}
+		i.wg.Wait()
+	})
+}
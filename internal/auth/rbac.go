@@ -2,9 +2,13 @@ package auth
 
 import (
 	"fmt"
+	"sort"
 	"sync"
 )
 
+// ScopeSelf restricts a permission to the caller's own resources
+const ScopeSelf = "self"
+
 // Permission represents a specific action on a resource
 type Permission struct {
 	Resource   string `json:"resource"`              // e.g., "user", "transaction", "balance"
@@ -19,121 +23,211 @@ type Role struct {
 	Inherits    []string     `json:"inherits,omitempty"` // Inherit from other roles
 }
 
-// Policy represents an access control policy
+// Policy represents an access control policy. A resource may have several.
+// A policy applies to requests by one of its roles for one of its actions:
+// it grants access if all its conditions hold, and denies access if any does
+// not, whatever the role permissions allow. Conditions maps descriptive
+// names to condition expressions; see Condition.
 type Policy struct {
+	ID         int                    `json:"id,omitempty"`
 	Resource   string                 `json:"resource"`
 	Actions    []string               `json:"actions"`
 	Roles      []string               `json:"roles"`
 	Conditions map[string]interface{} `json:"conditions,omitempty"`
 }
 
+// AccessRequest asks whether roles may perform action on a resource.
+// ResourceID is interpreted as by CheckPermission; Attributes are evaluated
+// by policy conditions.
+type AccessRequest struct {
+	Roles      []string
+	Resource   string
+	Action     string
+	ResourceID interface{}
+	Attributes Attributes
+}
+
+// Decision is the outcome of an access request and how it was reached
+type Decision struct {
+	Allowed bool
+	// GrantedBy is "super_admin", "permission" or "policy" if access was
+	// allowed
+	GrantedBy string
+	// Permission is the role permission that granted access, if any
+	Permission *Permission
+	// Policies explains every policy of the resource
+	Policies []PolicyResult
+}
+
+// PolicyResult explains the evaluation of one policy
+type PolicyResult struct {
+	PolicyID      int
+	RoleMatched   bool
+	ActionMatched bool
+	Conditions    []ConditionResult
+	// Error is set if the policy's conditions could not be compiled
+	Error   string
+	Allowed bool
+	// Denied is set if the policy applies but its conditions do not hold
+	Denied bool
+}
+
+// ConditionResult explains the evaluation of one policy condition
+type ConditionResult struct {
+	Name       string
+	Expression string
+	Result     bool
+	Error      string
+}
+
+// compiledPolicy is a policy with its conditions parsed
+type compiledPolicy struct {
+	*Policy
+	conditions map[string]*Condition
+	err        error
+}
+
+func compilePolicy(policy *Policy) *compiledPolicy {
+	conditions, err := compileConditions(policy.Conditions)
+	return &compiledPolicy{Policy: policy, conditions: conditions, err: err}
+}
+
+// ValidatePolicyConditions reports whether every condition of policy
+// compiles
+func ValidatePolicyConditions(policy *Policy) error {
+	_, err := compileConditions(policy.Conditions)
+	return err
+}
+
 // RBACManager manages role-based access control
 type RBACManager struct {
 	roles    map[string]*Role
-	policies map[string]*Policy
+	policies map[string][]*compiledPolicy
 	mu       sync.RWMutex
 }
 
-// NewRBACManager creates a new RBAC manager
+// NewRBACManager creates a new RBAC manager with the default roles and
+// policies. Use Replace to load persisted ones.
 func NewRBACManager() *RBACManager {
-	rbac := &RBACManager{
-		roles:    make(map[string]*Role),
-		policies: make(map[string]*Policy),
+	rbac := &RBACManager{}
+	rbac.Replace(DefaultRoles(), DefaultPolicies())
+	return rbac
+}
+
+// Replace swaps every role and policy for the given ones at once
+func (rbac *RBACManager) Replace(roles []*Role, policies []*Policy) {
+	roleMap := make(map[string]*Role, len(roles))
+	for _, role := range roles {
+		roleMap[role.Name] = role
 	}
 
-	// Initialize default roles
-	rbac.initializeDefaultRoles()
-	rbac.initializeDefaultPolicies()
+	policyMap := make(map[string][]*compiledPolicy)
+	for _, policy := range policies {
+		policyMap[policy.Resource] = append(policyMap[policy.Resource], compilePolicy(policy))
+	}
 
-	return rbac
+	rbac.mu.Lock()
+	defer rbac.mu.Unlock()
+	rbac.roles = roleMap
+	rbac.policies = policyMap
 }
 
-// initializeDefaultRoles creates default system roles
-func (rbac *RBACManager) initializeDefaultRoles() {
-	// Super Admin - Full access
-	rbac.AddRole(&Role{
-		Name: "super_admin",
-		Permissions: []Permission{
-			{Resource: "*", Action: "*"}, // All resources, all actions
+// DefaultRoles returns the built-in roles. They are seeded into an empty
+// role store on first run.
+func DefaultRoles() []*Role {
+	return []*Role{
+		// Super Admin - Full access
+		{
+			Name: "super_admin",
+			Permissions: []Permission{
+				{Resource: "*", Action: "*"}, // All resources, all actions
+			},
+			Inherits: []string{"admin"},
 		},
-	})
-
-	// Admin - Administrative access
-	rbac.AddRole(&Role{
-		Name: "admin",
-		Permissions: []Permission{
-			{Resource: "user", Action: "*"},
-			{Resource: "transaction", Action: "*"},
-			{Resource: "balance", Action: "*"},
-			{Resource: "audit", Action: "read"},
+
+		// Admin - Administrative access
+		{
+			Name: "admin",
+			Permissions: []Permission{
+				{Resource: "user", Action: "*"},
+				{Resource: "transaction", Action: "*"},
+				{Resource: "balance", Action: "*"},
+				{Resource: "audit", Action: "read"},
+			},
+			Inherits: []string{"manager"},
 		},
-	})
-
-	// Manager - Limited administrative access
-	rbac.AddRole(&Role{
-		Name: "manager",
-		Permissions: []Permission{
-			{Resource: "user", Action: "read"},
-			{Resource: "transaction", Action: "read"},
-			{Resource: "balance", Action: "read"},
-			{Resource: "audit", Action: "read"},
+
+		// Manager - Limited administrative access
+		{
+			Name: "manager",
+			Permissions: []Permission{
+				{Resource: "user", Action: "read"},
+				{Resource: "transaction", Action: "read"},
+				{Resource: "balance", Action: "read"},
+				{Resource: "audit", Action: "read"},
+			},
+			Inherits: []string{"support"},
 		},
-	})
-
-	// Support - Customer support access
-	rbac.AddRole(&Role{
-		Name: "support",
-		Permissions: []Permission{
-			{Resource: "user", Action: "read"},
-			{Resource: "transaction", Action: "read"},
-			{Resource: "balance", Action: "read"},
+
+		// Support - Customer support access
+		{
+			Name: "support",
+			Permissions: []Permission{
+				{Resource: "user", Action: "read"},
+				{Resource: "transaction", Action: "read"},
+				{Resource: "balance", Action: "read"},
+			},
+			Inherits: []string{"user"},
 		},
-	})
-
-	// User - Basic user access
-	rbac.AddRole(&Role{
-		Name: "user",
-		Permissions: []Permission{
-			{Resource: "user", Action: "read", ResourceID: "self"},
-			{Resource: "transaction", Action: "read", ResourceID: "self"},
-			{Resource: "balance", Action: "read", ResourceID: "self"},
-			{Resource: "transaction", Action: "create", ResourceID: "self"},
+
+		// User - Basic user access
+		{
+			Name: "user",
+			Permissions: []Permission{
+				{Resource: "user", Action: "read", ResourceID: "self"},
+				{Resource: "transaction", Action: "read", ResourceID: "self"},
+				{Resource: "balance", Action: "read", ResourceID: "self"},
+				{Resource: "transaction", Action: "create", ResourceID: "self"},
+			},
 		},
-	})
+	}
 }
 
-// initializeDefaultPolicies creates default access control policies
-func (rbac *RBACManager) initializeDefaultPolicies() {
-	// User management policies
-	rbac.AddPolicy(&Policy{
-		Resource: "user",
-		Actions:  []string{"create", "read", "update", "delete"},
-		Roles:    []string{"admin", "super_admin"},
-	})
-
-	// Transaction policies
-	rbac.AddPolicy(&Policy{
-		Resource: "transaction",
-		Actions:  []string{"create", "read", "update", "delete"},
-		Roles:    []string{"admin", "super_admin"},
-		Conditions: map[string]interface{}{
-			"max_amount": 1000000, // 1M limit for admins
+// DefaultPolicies returns the built-in access control policies. They are
+// seeded along with the default roles.
+func DefaultPolicies() []*Policy {
+	return []*Policy{
+		// User management policies
+		{
+			Resource: "user",
+			Actions:  []string{"create", "read", "update", "delete"},
+			Roles:    []string{"admin", "super_admin"},
+		},
+
+		// Transaction policies
+		{
+			Resource: "transaction",
+			Actions:  []string{"create"},
+			Roles:    []string{"admin", "super_admin"},
+			Conditions: map[string]interface{}{
+				"amount_limit": "amount <= 1000000", // 1M limit for admins
+			},
+		},
+
+		// Balance policies
+		{
+			Resource: "balance",
+			Actions:  []string{"read", "update"},
+			Roles:    []string{"admin", "super_admin"},
 		},
-	})
-
-	// Balance policies
-	rbac.AddPolicy(&Policy{
-		Resource: "balance",
-		Actions:  []string{"read", "update"},
-		Roles:    []string{"admin", "super_admin"},
-	})
-
-	// Audit policies
-	rbac.AddPolicy(&Policy{
-		Resource: "audit",
-		Actions:  []string{"read"},
-		Roles:    []string{"admin", "manager", "super_admin"},
-	})
+
+		// Audit policies
+		{
+			Resource: "audit",
+			Actions:  []string{"read"},
+			Roles:    []string{"admin", "manager", "super_admin"},
+		},
+	}
 }
 
 // AddRole adds a new role
@@ -160,71 +254,183 @@ func (rbac *RBACManager) GetRole(name string) (*Role, error) {
 func (rbac *RBACManager) AddPolicy(policy *Policy) {
 	rbac.mu.Lock()
 	defer rbac.mu.Unlock()
-	rbac.policies[policy.Resource] = policy
+	rbac.policies[policy.Resource] = append(rbac.policies[policy.Resource], compilePolicy(policy))
 }
 
-// CheckPermission checks if a user with given roles has permission for an action on a resource
+// CheckPermission checks if a user with given roles has permission for an
+// action on a resource. Roles include the roles they inherit. A nil
+// resourceID asks for access to any resource; ScopeSelf asks for access to
+// the caller's own resources only, which self-scoped permissions also grant.
+// There are no attributes to evaluate policy conditions against, so policies
+// with conditions deny access; use Authorize for those.
 func (rbac *RBACManager) CheckPermission(userRoles []string, resource, action string, resourceID interface{}) bool {
+	return rbac.Authorize(&AccessRequest{
+		Roles:      userRoles,
+		Resource:   resource,
+		Action:     action,
+		ResourceID: resourceID,
+	}).Allowed
+}
+
+// Authorize decides an access request. Access is allowed by the role
+// permissions, or by any policy of the resource whose conditions hold for
+// the request's attributes, unless a policy that applies to the request has
+// a condition that does not hold. Every policy is evaluated so the decision
+// explains each of them.
+func (rbac *RBACManager) Authorize(request *AccessRequest) *Decision {
 	rbac.mu.RLock()
 	defer rbac.mu.RUnlock()
 
+	decision := &Decision{}
+	roles := rbac.expandRoles(request.Roles)
+
 	// Check if user has super admin role
-	for _, role := range userRoles {
-		if role == "super_admin" {
-			return true
+	if roles["super_admin"] {
+		decision.Allowed = true
+		decision.GrantedBy = "super_admin"
+	}
+
+	// Check the permissions of the roles
+	for roleName := range roles {
+		role, exists := rbac.roles[roleName]
+		if !exists || decision.Allowed {
+			continue
+		}
+		for i, permission := range role.Permissions {
+			if permission.matches(request.Resource, request.Action, request.ResourceID) {
+				decision.Allowed = true
+				decision.GrantedBy = "permission"
+				decision.Permission = &role.Permissions[i]
+				break
+			}
 		}
 	}
 
 	// Check policies
-	policy, exists := rbac.policies[resource]
-	if !exists {
-		return false
+	denied := false
+	for _, policy := range rbac.policies[request.Resource] {
+		result := policy.evaluate(roles, request.Action, request.Attributes)
+		decision.Policies = append(decision.Policies, result)
+		if result.Denied {
+			denied = true
+		}
+		if result.Allowed && !decision.Allowed {
+			decision.Allowed = true
+			decision.GrantedBy = "policy"
+		}
+	}
+
+	// A failed condition overrides every grant, including super_admin's
+	if denied {
+		decision.Allowed = false
+		decision.GrantedBy = ""
+		decision.Permission = nil
 	}
 
+	return decision
+}
+
+// evaluate reports whether the policy grants or denies action to roles given
+// attrs. A policy that does not apply to roles and action does neither.
+func (p *compiledPolicy) evaluate(roles map[string]bool, action string, attrs Attributes) PolicyResult {
+	result := PolicyResult{PolicyID: p.ID}
+
 	// Check if user has required role
-	hasRole := false
-	for _, userRole := range userRoles {
-		for _, requiredRole := range policy.Roles {
-			if userRole == requiredRole {
-				hasRole = true
-				break
-			}
-		}
-		if hasRole {
+	for _, requiredRole := range p.Roles {
+		if roles[requiredRole] {
+			result.RoleMatched = true
 			break
 		}
 	}
 
-	if !hasRole {
-		return false
-	}
-
 	// Check if action is allowed
-	actionAllowed := false
-	for _, allowedAction := range policy.Actions {
+	for _, allowedAction := range p.Actions {
 		if allowedAction == "*" || allowedAction == action {
-			actionAllowed = true
+			result.ActionMatched = true
 			break
 		}
 	}
 
-	if !actionAllowed {
-		return false
+	if !result.RoleMatched || !result.ActionMatched {
+		return result
 	}
 
-	// Check conditions if any
-	if len(policy.Conditions) > 0 {
-		return rbac.checkConditions(policy.Conditions, resourceID)
+	// Conditions that do not compile never hold
+	if p.err != nil {
+		result.Error = p.err.Error()
+		result.Denied = true
+		return result
 	}
 
-	return true
+	// Check conditions if any, in a stable order
+	names := make([]string, 0, len(p.conditions))
+	for name := range p.conditions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result.Allowed = true
+	for _, name := range names {
+		condition := p.conditions[name]
+		holds, err := condition.Evaluate(attrs)
+		conditionResult := ConditionResult{Name: name, Expression: condition.String(), Result: holds}
+		if err != nil {
+			conditionResult.Error = err.Error()
+		}
+		result.Conditions = append(result.Conditions, conditionResult)
+		result.Allowed = result.Allowed && holds
+	}
+	result.Denied = !result.Allowed
+
+	return result
+}
+
+// HasRole reports whether userRoles include role, directly or by inheritance
+func (rbac *RBACManager) HasRole(userRoles []string, role string) bool {
+	rbac.mu.RLock()
+	defer rbac.mu.RUnlock()
+
+	return rbac.expandRoles(userRoles)[role]
+}
+
+// expandRoles returns userRoles and every role they inherit, directly or
+// transitively. Callers must hold the lock.
+func (rbac *RBACManager) expandRoles(userRoles []string) map[string]bool {
+	expanded := make(map[string]bool)
+	pending := append([]string(nil), userRoles...)
+	for len(pending) > 0 {
+		name := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if expanded[name] {
+			continue
+		}
+		expanded[name] = true
+
+		if role, exists := rbac.roles[name]; exists {
+			pending = append(pending, role.Inherits...)
+		}
+	}
+	return expanded
 }
 
-// checkConditions checks policy conditions
-func (rbac *RBACManager) checkConditions(conditions map[string]interface{}, resourceID interface{}) bool {
-	// Implement condition checking logic here
-	// For now, return true (can be extended with complex condition logic)
-	return true
+// matches reports whether the permission grants action on resourceID of
+// resource
+func (p Permission) matches(resource, action string, resourceID interface{}) bool {
+	if p.Resource != "*" && p.Resource != resource {
+		return false
+	}
+	if p.Action != "*" && p.Action != action {
+		return false
+	}
+
+	switch p.ResourceID {
+	case "", "all":
+		return true
+	case ScopeSelf:
+		return resourceID == ScopeSelf
+	default:
+		return resourceID != nil && fmt.Sprint(resourceID) == p.ResourceID
+	}
 }
 
 // GetUserPermissions returns all permissions for a user with given roles
@@ -235,14 +441,14 @@ func (rbac *RBACManager) GetUserPermissions(userRoles []string) []Permission {
 	var permissions []Permission
 	seen := make(map[string]bool)
 
-	for _, roleName := range userRoles {
+	for roleName := range rbac.expandRoles(userRoles) {
 		role, exists := rbac.roles[roleName]
 		if !exists {
 			continue
 		}
 
 		for _, permission := range role.Permissions {
-			key := fmt.Sprintf("%s:%s", permission.Resource, permission.Action)
+			key := fmt.Sprintf("%s:%s:%s", permission.Resource, permission.Action, permission.ResourceID)
 			if !seen[key] {
 				permissions = append(permissions, permission)
 				seen[key] = true
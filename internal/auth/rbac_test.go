@@ -0,0 +1,287 @@
+package auth
+
+import "testing"
+
+// newTestRBAC returns an RBAC manager with the default roles and the given
+// policies, numbered from 1
+func newTestRBAC(policies ...*Policy) *RBACManager {
+	for i, policy := range policies {
+		policy.ID = i + 1
+	}
+	rbac := &RBACManager{}
+	rbac.Replace(DefaultRoles(), policies)
+	return rbac
+}
+
+func TestCheckPermission(t *testing.T) {
+	rbac := newTestRBAC()
+
+	tests := []struct {
+		roles      []string
+		resource   string
+		action     string
+		resourceID interface{}
+		want       bool
+	}{
+		{[]string{"super_admin"}, "anything", "delete", nil, true},
+		{[]string{"admin"}, "transaction", "create", nil, true},
+		{[]string{"admin"}, "audit", "read", nil, true},
+		{[]string{"admin"}, "audit", "delete", nil, false},
+		{[]string{"manager"}, "user", "update", nil, false},
+		// Inherited from support and user
+		{[]string{"manager"}, "transaction", "create", ScopeSelf, true},
+		{[]string{"user"}, "balance", "read", ScopeSelf, true},
+		{[]string{"user"}, "balance", "read", nil, false},
+		{[]string{"user"}, "balance", "read", 42, false},
+		{[]string{"user"}, "user", "delete", ScopeSelf, false},
+		{[]string{"unknown"}, "user", "read", ScopeSelf, false},
+		{nil, "user", "read", ScopeSelf, false},
+	}
+
+	for _, tt := range tests {
+		got := rbac.CheckPermission(tt.roles, tt.resource, tt.action, tt.resourceID)
+		if got != tt.want {
+			t.Errorf("CheckPermission(%v, %s, %s, %v) = %v, want %v", tt.roles, tt.resource, tt.action, tt.resourceID, got, tt.want)
+		}
+	}
+}
+
+func TestPermissionMatchesResourceID(t *testing.T) {
+	permission := Permission{Resource: "transaction", Action: "read", ResourceID: "12"}
+	if !permission.matches("transaction", "read", 12) {
+		t.Error("permission for resource 12 does not match 12")
+	}
+	if permission.matches("transaction", "read", 13) {
+		t.Error("permission for resource 12 matches 13")
+	}
+	if permission.matches("transaction", "read", nil) {
+		t.Error("permission for resource 12 matches any resource")
+	}
+}
+
+func TestAuthorizePolicyGrants(t *testing.T) {
+	rbac := newTestRBAC(&Policy{
+		Resource:   "report",
+		Actions:    []string{"read"},
+		Roles:      []string{"support"},
+		Conditions: map[string]interface{}{"hours": `time >= "09:00" && time < "18:00"`},
+	})
+
+	// manager inherits support, which has no report permission
+	decision := rbac.Authorize(&AccessRequest{
+		Roles:      []string{"manager"},
+		Resource:   "report",
+		Action:     "read",
+		Attributes: Attributes{AttrTime: "10:00"},
+	})
+	if !decision.Allowed || decision.GrantedBy != "policy" {
+		t.Errorf("decision = %+v, want granted by policy", decision)
+	}
+
+	decision = rbac.Authorize(&AccessRequest{
+		Roles:      []string{"manager"},
+		Resource:   "report",
+		Action:     "read",
+		Attributes: Attributes{AttrTime: "20:00"},
+	})
+	if decision.Allowed {
+		t.Error("policy granted access outside its hours")
+	}
+
+	// The policy does not apply to other roles or actions
+	for _, request := range []*AccessRequest{
+		{Roles: []string{"user"}, Resource: "report", Action: "read", Attributes: Attributes{AttrTime: "10:00"}},
+		{Roles: []string{"manager"}, Resource: "report", Action: "delete", Attributes: Attributes{AttrTime: "10:00"}},
+	} {
+		decision := rbac.Authorize(request)
+		if decision.Allowed {
+			t.Errorf("Authorize(%v, %s) allowed by a policy that does not apply", request.Roles, request.Action)
+		}
+		if len(decision.Policies) != 1 || decision.Policies[0].Denied {
+			t.Errorf("Authorize(%v, %s) policies = %+v, want one that neither grants nor denies", request.Roles, request.Action, decision.Policies)
+		}
+	}
+}
+
+func TestAuthorizeFailedConditionDenies(t *testing.T) {
+	rbac := newTestRBAC(DefaultPolicies()...)
+
+	// admin holds transaction:*, and super_admin everything, but the seeded
+	// amount limit applies to both
+	for _, role := range []string{"admin", "super_admin"} {
+		request := &AccessRequest{
+			Roles:      []string{role},
+			Resource:   "transaction",
+			Action:     "create",
+			ResourceID: ScopeSelf,
+			Attributes: Attributes{AttrAmount: 5000},
+		}
+		if decision := rbac.Authorize(request); !decision.Allowed {
+			t.Errorf("%s denied a transaction under the limit: %+v", role, decision)
+		}
+
+		request.Attributes = Attributes{AttrAmount: 2000000}
+		decision := rbac.Authorize(request)
+		if decision.Allowed || decision.GrantedBy != "" || decision.Permission != nil {
+			t.Errorf("%s allowed a transaction over the limit: %+v", role, decision)
+		}
+
+		var denied bool
+		for _, result := range decision.Policies {
+			denied = denied || result.Denied
+		}
+		if !denied {
+			t.Errorf("%s decision does not explain the denying policy: %+v", role, decision.Policies)
+		}
+
+		// Without an amount the condition cannot hold
+		request.Attributes = Attributes{}
+		if decision := rbac.Authorize(request); decision.Allowed {
+			t.Errorf("%s allowed a transaction without an amount", role)
+		}
+
+		// The limit only applies to creating transactions
+		request.Action = "read"
+		request.ResourceID = nil
+		if decision := rbac.Authorize(request); !decision.Allowed {
+			t.Errorf("%s denied reading transactions: %+v", role, decision)
+		}
+	}
+
+	// The limit is not among the user role's policies
+	decision := rbac.Authorize(&AccessRequest{
+		Roles:      []string{"user"},
+		Resource:   "transaction",
+		Action:     "create",
+		ResourceID: ScopeSelf,
+		Attributes: Attributes{AttrAmount: 2000000},
+	})
+	if !decision.Allowed || decision.GrantedBy != "permission" {
+		t.Errorf("user decision = %+v, want granted by permission", decision)
+	}
+}
+
+func TestAuthorizeAnyFailedPolicyDenies(t *testing.T) {
+	rbac := newTestRBAC(
+		&Policy{
+			Resource:   "transaction",
+			Actions:    []string{"create"},
+			Roles:      []string{"user"},
+			Conditions: map[string]interface{}{"limit": "amount <= 1000"},
+		},
+		&Policy{
+			Resource:   "transaction",
+			Actions:    []string{"*"},
+			Roles:      []string{"user"},
+			Conditions: map[string]interface{}{"currency": `currency in ["USD", "EUR"]`},
+		},
+	)
+
+	tests := []struct {
+		attrs Attributes
+		want  bool
+	}{
+		{Attributes{AttrAmount: 500, AttrCurrency: "USD"}, true},
+		{Attributes{AttrAmount: 5000, AttrCurrency: "USD"}, false},
+		{Attributes{AttrAmount: 500, AttrCurrency: "TRY"}, false},
+		{Attributes{AttrAmount: 500}, false},
+	}
+
+	for _, tt := range tests {
+		decision := rbac.Authorize(&AccessRequest{
+			Roles:      []string{"user"},
+			Resource:   "transaction",
+			Action:     "create",
+			ResourceID: ScopeSelf,
+			Attributes: tt.attrs,
+		})
+		if decision.Allowed != tt.want {
+			t.Errorf("Authorize(%v) = %v, want %v", tt.attrs, decision.Allowed, tt.want)
+		}
+		if len(decision.Policies) != 2 {
+			t.Errorf("Authorize(%v) explained %d policies, want 2", tt.attrs, len(decision.Policies))
+		}
+	}
+}
+
+func TestAuthorizeInvalidPolicyDenies(t *testing.T) {
+	rbac := newTestRBAC(&Policy{
+		Resource:   "balance",
+		Actions:    []string{"read"},
+		Roles:      []string{"user"},
+		Conditions: map[string]interface{}{"broken": "amount <="},
+	})
+
+	decision := rbac.Authorize(&AccessRequest{
+		Roles:      []string{"user"},
+		Resource:   "balance",
+		Action:     "read",
+		ResourceID: ScopeSelf,
+	})
+	if decision.Allowed {
+		t.Error("a policy whose conditions do not compile did not deny access")
+	}
+	if len(decision.Policies) != 1 || decision.Policies[0].Error == "" || !decision.Policies[0].Denied {
+		t.Errorf("policies = %+v, want the compile error explained", decision.Policies)
+	}
+}
+
+func TestAuthorizeExplainsConditions(t *testing.T) {
+	rbac := newTestRBAC(&Policy{
+		Resource: "report",
+		Actions:  []string{"read"},
+		Roles:    []string{"user"},
+		Conditions: map[string]interface{}{
+			"owner": "resource.owner_id == subject.id",
+			"limit": "amount <= 1000",
+		},
+	})
+
+	decision := rbac.Authorize(&AccessRequest{
+		Roles:      []string{"user"},
+		Resource:   "report",
+		Action:     "read",
+		Attributes: Attributes{AttrAmount: 10, AttrSubjectID: 1},
+	})
+	if decision.Allowed {
+		t.Fatal("allowed with a condition on a missing attribute")
+	}
+
+	conditions := decision.Policies[0].Conditions
+	if len(conditions) != 2 {
+		t.Fatalf("explained %d conditions, want 2", len(conditions))
+	}
+	// Conditions are explained in name order
+	if conditions[0].Name != "limit" || !conditions[0].Result || conditions[0].Error != "" {
+		t.Errorf("limit = %+v, want it to hold", conditions[0])
+	}
+	if conditions[1].Name != "owner" || conditions[1].Result || conditions[1].Error == "" {
+		t.Errorf("owner = %+v, want it to fail with an error", conditions[1])
+	}
+	if conditions[1].Expression != "resource.owner_id == subject.id" {
+		t.Errorf("owner expression = %q", conditions[1].Expression)
+	}
+}
+
+func TestHasRoleAndPermissions(t *testing.T) {
+	rbac := newTestRBAC()
+
+	if !rbac.HasRole([]string{"admin"}, "support") {
+		t.Error("admin does not inherit support")
+	}
+	if rbac.HasRole([]string{"support"}, "admin") {
+		t.Error("support inherits admin")
+	}
+
+	permissions := rbac.GetUserPermissions([]string{"support"})
+	seen := make(map[Permission]bool)
+	for _, permission := range permissions {
+		if seen[permission] {
+			t.Errorf("permission %+v listed twice", permission)
+		}
+		seen[permission] = true
+	}
+	if !seen[Permission{Resource: "transaction", Action: "create", ResourceID: ScopeSelf}] {
+		t.Error("support permissions miss those inherited from user")
+	}
+}
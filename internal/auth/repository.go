@@ -0,0 +1,35 @@
+package auth
+
+import (
+	"database/sql"
+	"errors"
+)
+
+var (
+	// ErrRoleNotFound is returned when a role does not exist
+	ErrRoleNotFound = errors.New("role not found")
+	// ErrPolicyNotFound is returned when a policy does not exist
+	ErrPolicyNotFound = errors.New("policy not found")
+)
+
+// Repository stores roles, their permissions and inheritance, and policies
+type Repository interface {
+	CountRoles() (int, error)
+	ListRoles() ([]*Role, error)
+	GetRole(name string) (*Role, error)
+	CreateRole(role *Role) error
+	// UpdateRole replaces the permissions and inherited roles of a role
+	UpdateRole(role *Role) error
+	DeleteRole(name string) error
+	// RoleInUse reports whether users hold the role or other roles inherit it
+	RoleInUse(name string) (bool, error)
+
+	ListPolicies() ([]*Policy, error)
+	GetPolicy(id int) (*Policy, error)
+	CreatePolicy(policy *Policy) error
+	UpdatePolicy(policy *Policy) error
+	DeletePolicy(id int) error
+
+	// WithTx returns a repository that runs its queries inside tx
+	WithTx(tx *sql.Tx) Repository
+}
@@ -0,0 +1,347 @@
+package auth
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"backend_path/pkg/database"
+)
+
+type sqlRepository struct {
+	db database.Executor
+}
+
+func NewSQLRepository(db *sql.DB) Repository {
+	return &sqlRepository{db: db}
+}
+
+func (r *sqlRepository) WithTx(tx *sql.Tx) Repository {
+	return &sqlRepository{db: tx}
+}
+
+func (r *sqlRepository) CountRoles() (int, error) {
+	var count int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM roles`).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count roles: %w", err)
+	}
+	return count, nil
+}
+
+func (r *sqlRepository) ListRoles() ([]*Role, error) {
+	rows, err := r.db.Query(`SELECT name FROM roles ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list roles: %w", err)
+	}
+	defer rows.Close()
+
+	var roles []*Role
+	byName := make(map[string]*Role)
+	for rows.Next() {
+		role := &Role{}
+		if err := rows.Scan(&role.Name); err != nil {
+			return nil, fmt.Errorf("failed to scan role: %w", err)
+		}
+		roles = append(roles, role)
+		byName[role.Name] = role
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating roles: %w", err)
+	}
+
+	if err := r.loadRoleDetails(byName, ""); err != nil {
+		return nil, err
+	}
+
+	return roles, nil
+}
+
+func (r *sqlRepository) GetRole(name string) (*Role, error) {
+	role := &Role{}
+	err := r.db.QueryRow(`SELECT name FROM roles WHERE name = ?`, name).Scan(&role.Name)
+	if err == sql.ErrNoRows {
+		return nil, ErrRoleNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get role: %w", err)
+	}
+
+	if err := r.loadRoleDetails(map[string]*Role{role.Name: role}, role.Name); err != nil {
+		return nil, err
+	}
+
+	return role, nil
+}
+
+// loadRoleDetails fills in the permissions and inherited roles of roles,
+// limited to the role called only if it is set
+func (r *sqlRepository) loadRoleDetails(roles map[string]*Role, only string) error {
+	where, args := "", []interface{}{}
+	if only != "" {
+		where, args = "WHERE role_name = ?", []interface{}{only}
+	}
+
+	rows, err := r.db.Query(`SELECT role_name, resource, action, resource_id FROM role_permissions `+where+` ORDER BY id`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to list role permissions: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var roleName string
+		var permission Permission
+		var resourceID sql.NullString
+		if err := rows.Scan(&roleName, &permission.Resource, &permission.Action, &resourceID); err != nil {
+			return fmt.Errorf("failed to scan role permission: %w", err)
+		}
+		permission.ResourceID = resourceID.String
+		if role, ok := roles[roleName]; ok {
+			role.Permissions = append(role.Permissions, permission)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("error iterating role permissions: %w", err)
+	}
+
+	inheritRows, err := r.db.Query(`SELECT role_name, inherits FROM role_inherits `+where+` ORDER BY role_name, inherits`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to list role inheritance: %w", err)
+	}
+	defer inheritRows.Close()
+
+	for inheritRows.Next() {
+		var roleName, inherits string
+		if err := inheritRows.Scan(&roleName, &inherits); err != nil {
+			return fmt.Errorf("failed to scan role inheritance: %w", err)
+		}
+		if role, ok := roles[roleName]; ok {
+			role.Inherits = append(role.Inherits, inherits)
+		}
+	}
+	if err = inheritRows.Err(); err != nil {
+		return fmt.Errorf("error iterating role inheritance: %w", err)
+	}
+
+	return nil
+}
+
+func (r *sqlRepository) CreateRole(role *Role) error {
+	if _, err := r.db.Exec(`INSERT INTO roles (name) VALUES (?)`, role.Name); err != nil {
+		return fmt.Errorf("failed to create role: %w", err)
+	}
+	return r.insertRoleDetails(role)
+}
+
+func (r *sqlRepository) UpdateRole(role *Role) error {
+	result, err := r.db.Exec(`UPDATE roles SET updated_at = GETDATE() WHERE name = ?`, role.Name)
+	if err != nil {
+		return fmt.Errorf("failed to update role: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return ErrRoleNotFound
+	}
+
+	if _, err := r.db.Exec(`DELETE FROM role_permissions WHERE role_name = ?`, role.Name); err != nil {
+		return fmt.Errorf("failed to clear role permissions: %w", err)
+	}
+	if _, err := r.db.Exec(`DELETE FROM role_inherits WHERE role_name = ?`, role.Name); err != nil {
+		return fmt.Errorf("failed to clear role inheritance: %w", err)
+	}
+
+	return r.insertRoleDetails(role)
+}
+
+// insertRoleDetails stores the permissions and inherited roles of role
+func (r *sqlRepository) insertRoleDetails(role *Role) error {
+	for _, permission := range role.Permissions {
+		_, err := r.db.Exec(`INSERT INTO role_permissions (role_name, resource, action, resource_id) VALUES (?, ?, ?, ?)`,
+			role.Name, permission.Resource, permission.Action, sql.NullString{String: permission.ResourceID, Valid: permission.ResourceID != ""})
+		if err != nil {
+			return fmt.Errorf("failed to create role permission: %w", err)
+		}
+	}
+
+	for _, inherits := range role.Inherits {
+		if _, err := r.db.Exec(`INSERT INTO role_inherits (role_name, inherits) VALUES (?, ?)`, role.Name, inherits); err != nil {
+			return fmt.Errorf("failed to create role inheritance: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (r *sqlRepository) DeleteRole(name string) error {
+	result, err := r.db.Exec(`DELETE FROM roles WHERE name = ?`, name)
+	if err != nil {
+		return fmt.Errorf("failed to delete role: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return ErrRoleNotFound
+	}
+	return nil
+}
+
+func (r *sqlRepository) RoleInUse(name string) (bool, error) {
+	query := `
+		SELECT CASE WHEN EXISTS (SELECT 1 FROM users WHERE role = ?)
+			OR EXISTS (SELECT 1 FROM role_inherits WHERE inherits = ?)
+		THEN 1 ELSE 0 END
+	`
+
+	var inUse bool
+	if err := r.db.QueryRow(query, name, name).Scan(&inUse); err != nil {
+		return false, fmt.Errorf("failed to check role usage: %w", err)
+	}
+	return inUse, nil
+}
+
+const policyColumns = `id, resource, actions, roles, conditions`
+
+func (r *sqlRepository) ListPolicies() ([]*Policy, error) {
+	rows, err := r.db.Query(`SELECT ` + policyColumns + ` FROM policies ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list policies: %w", err)
+	}
+	defer rows.Close()
+
+	var policies []*Policy
+	for rows.Next() {
+		policy, err := scanPolicy(rows)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, policy)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating policies: %w", err)
+	}
+
+	return policies, nil
+}
+
+func (r *sqlRepository) GetPolicy(id int) (*Policy, error) {
+	policy, err := scanPolicy(r.db.QueryRow(`SELECT `+policyColumns+` FROM policies WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrPolicyNotFound
+	}
+	return policy, err
+}
+
+func (r *sqlRepository) CreatePolicy(policy *Policy) error {
+	conditions, err := marshalConditions(policy.Conditions)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO policies (resource, actions, roles, conditions)
+		OUTPUT INSERTED.id
+		VALUES (?, ?, ?, ?)
+	`
+
+	err = r.db.QueryRow(query,
+		policy.Resource,
+		strings.Join(policy.Actions, ","),
+		strings.Join(policy.Roles, ","),
+		conditions,
+	).Scan(&policy.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create policy: %w", err)
+	}
+
+	return nil
+}
+
+func (r *sqlRepository) UpdatePolicy(policy *Policy) error {
+	conditions, err := marshalConditions(policy.Conditions)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE policies
+		SET resource = ?, actions = ?, roles = ?, conditions = ?, updated_at = GETDATE()
+		WHERE id = ?
+	`
+
+	result, err := r.db.Exec(query,
+		policy.Resource,
+		strings.Join(policy.Actions, ","),
+		strings.Join(policy.Roles, ","),
+		conditions,
+		policy.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update policy: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return ErrPolicyNotFound
+	}
+
+	return nil
+}
+
+func (r *sqlRepository) DeletePolicy(id int) error {
+	result, err := r.db.Exec(`DELETE FROM policies WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete policy: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return ErrPolicyNotFound
+	}
+	return nil
+}
+
+// scanner is implemented by *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanPolicy scans a single policy row. It returns sql.ErrNoRows unwrapped.
+func scanPolicy(row scanner) (*Policy, error) {
+	policy := &Policy{}
+	var actions, roles string
+	var conditions sql.NullString
+
+	err := row.Scan(&policy.ID, &policy.Resource, &actions, &roles, &conditions)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan policy: %w", err)
+	}
+
+	policy.Actions = splitList(actions)
+	policy.Roles = splitList(roles)
+	if conditions.Valid && conditions.String != "" {
+		if err := json.Unmarshal([]byte(conditions.String), &policy.Conditions); err != nil {
+			return nil, fmt.Errorf("failed to decode policy conditions: %w", err)
+		}
+	}
+
+	return policy, nil
+}
+
+// marshalConditions encodes policy conditions, storing none as NULL
+func marshalConditions(conditions map[string]interface{}) (sql.NullString, error) {
+	if len(conditions) == 0 {
+		return sql.NullString{}, nil
+	}
+	data, err := json.Marshal(conditions)
+	if err != nil {
+		return sql.NullString{}, fmt.Errorf("failed to encode policy conditions: %w", err)
+	}
+	return sql.NullString{String: string(data), Valid: true}, nil
+}
+
+// splitList splits a comma-separated column, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
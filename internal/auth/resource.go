@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrResourceNotFound is returned by resource loaders when the target of a
+// request does not exist
+var ErrResourceNotFound = errors.New("resource not found")
+
+// Resource is the target of a request, loaded before authorization so that
+// self-scoped permissions can be checked against its owners
+type Resource struct {
+	ID       int
+	OwnerIDs []int
+	// Value is the loaded resource, passed on to the handler
+	Value interface{}
+	// Attributes are evaluated by policy conditions, such as AttrAmount
+	Attributes Attributes
+}
+
+// OwnedBy reports whether userID is one of the resource's owners
+func (r *Resource) OwnedBy(userID int) bool {
+	for _, ownerID := range r.OwnerIDs {
+		if ownerID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// attributes returns the resource's attributes along with its ID and
+// owners. resource.owner_id is the first owner and resource.owner_ids lists
+// all of them.
+func (r *Resource) attributes() Attributes {
+	attrs := Attributes{
+		AttrResourceID:       r.ID,
+		AttrResourceOwnerIDs: r.OwnerIDs,
+	}
+	if len(r.OwnerIDs) > 0 {
+		attrs[AttrResourceOwnerID] = r.OwnerIDs[0]
+	}
+	for name, value := range r.Attributes {
+		attrs[name] = value
+	}
+	return attrs
+}
+
+type resourceContextKey struct{}
+
+// WithResource returns a copy of ctx carrying the authorized resource
+func WithResource(ctx context.Context, resource *Resource) context.Context {
+	return context.WithValue(ctx, resourceContextKey{}, resource)
+}
+
+// ResourceFromContext returns the authorized resource carried by ctx, or nil
+func ResourceFromContext(ctx context.Context) *Resource {
+	resource, _ := ctx.Value(resourceContextKey{}).(*Resource)
+	return resource
+}
@@ -0,0 +1,34 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Scopes restrict credentials such as API keys to part of the permissions
+// of their owner. A scope names a permission as "resource:action"; either
+// part may be "*". A scoped caller is allowed an action only if both their
+// roles and their scopes allow it.
+
+// ParseScope splits scope into its resource and action
+func ParseScope(scope string) (resource, action string, err error) {
+	resource, action, ok := strings.Cut(scope, ":")
+	if !ok || resource == "" || action == "" || strings.ContainsAny(scope, " \t") {
+		return "", "", fmt.Errorf("invalid scope %q, expected resource:action", scope)
+	}
+	return resource, action, nil
+}
+
+// ScopesAllow reports whether any of scopes covers action on resource
+func ScopesAllow(scopes []string, resource, action string) bool {
+	for _, scope := range scopes {
+		scopeResource, scopeAction, err := ParseScope(scope)
+		if err != nil {
+			continue
+		}
+		if (scopeResource == "*" || scopeResource == resource) && (scopeAction == "*" || scopeAction == action) {
+			return true
+		}
+	}
+	return false
+}
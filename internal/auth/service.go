@@ -0,0 +1,280 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"backend_path/pkg/database"
+	"backend_path/pkg/logger"
+)
+
+var (
+	// ErrRoleExists is returned when creating a role whose name is taken
+	ErrRoleExists = errors.New("role already exists")
+	// ErrRoleInUse is returned when deleting a role held by users or
+	// inherited by other roles
+	ErrRoleInUse = errors.New("role is in use")
+	// ErrInvalidRole and ErrInvalidPolicy wrap validation failures
+	ErrInvalidRole   = errors.New("invalid role")
+	ErrInvalidPolicy = errors.New("invalid policy")
+)
+
+// RoleService manages the persisted roles and policies and keeps the RBAC
+// manager of every instance in sync with them
+type RoleService interface {
+	// Seed stores the default roles and policies if there are no roles yet
+	Seed(ctx context.Context) error
+	// Reload loads the persisted roles and policies into the RBAC manager
+	Reload() error
+
+	ListRoles() ([]*Role, error)
+	GetRole(name string) (*Role, error)
+	CreateRole(ctx context.Context, role *Role) error
+	UpdateRole(ctx context.Context, role *Role) error
+	DeleteRole(ctx context.Context, name string) error
+
+	ListPolicies() ([]*Policy, error)
+	GetPolicy(id int) (*Policy, error)
+	CreatePolicy(ctx context.Context, policy *Policy) error
+	UpdatePolicy(ctx context.Context, policy *Policy) error
+	DeletePolicy(ctx context.Context, id int) error
+}
+
+type roleService struct {
+	db          *sql.DB
+	repo        Repository
+	manager     *RBACManager
+	invalidator *Invalidator
+}
+
+func NewRoleService(db *sql.DB, repo Repository, manager *RBACManager, invalidator *Invalidator) RoleService {
+	return &roleService{
+		db:          db,
+		repo:        repo,
+		manager:     manager,
+		invalidator: invalidator,
+	}
+}
+
+func (s *roleService) Seed(ctx context.Context) error {
+	return database.WithTransaction(ctx, s.db, func(tx *sql.Tx) error {
+		repo := s.repo.WithTx(tx)
+
+		count, err := repo.CountRoles()
+		if err != nil || count > 0 {
+			return err
+		}
+
+		for _, role := range DefaultRoles() {
+			if err := repo.CreateRole(role); err != nil {
+				return err
+			}
+		}
+		for _, policy := range DefaultPolicies() {
+			if err := repo.CreatePolicy(policy); err != nil {
+				return err
+			}
+		}
+
+		logger.Info("Seeded default roles and policies", nil)
+		return nil
+	})
+}
+
+func (s *roleService) Reload() error {
+	roles, err := s.repo.ListRoles()
+	if err != nil {
+		return err
+	}
+	policies, err := s.repo.ListPolicies()
+	if err != nil {
+		return err
+	}
+
+	s.manager.Replace(roles, policies)
+
+	logger.Debug("Roles and policies reloaded", map[string]interface{}{
+		"roles":    len(roles),
+		"policies": len(policies),
+	})
+
+	return nil
+}
+
+func (s *roleService) ListRoles() ([]*Role, error) {
+	return s.repo.ListRoles()
+}
+
+func (s *roleService) GetRole(name string) (*Role, error) {
+	return s.repo.GetRole(name)
+}
+
+func (s *roleService) CreateRole(ctx context.Context, role *Role) error {
+	if err := s.validateRole(role); err != nil {
+		return err
+	}
+	if _, err := s.repo.GetRole(role.Name); err == nil {
+		return ErrRoleExists
+	} else if err != ErrRoleNotFound {
+		return err
+	}
+
+	err := database.WithTransaction(ctx, s.db, func(tx *sql.Tx) error {
+		return s.repo.WithTx(tx).CreateRole(role)
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.changed(ctx)
+}
+
+func (s *roleService) UpdateRole(ctx context.Context, role *Role) error {
+	if err := s.validateRole(role); err != nil {
+		return err
+	}
+
+	err := database.WithTransaction(ctx, s.db, func(tx *sql.Tx) error {
+		return s.repo.WithTx(tx).UpdateRole(role)
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.changed(ctx)
+}
+
+func (s *roleService) DeleteRole(ctx context.Context, name string) error {
+	inUse, err := s.repo.RoleInUse(name)
+	if err != nil {
+		return err
+	}
+	if inUse {
+		return ErrRoleInUse
+	}
+
+	if err := s.repo.DeleteRole(name); err != nil {
+		return err
+	}
+
+	return s.changed(ctx)
+}
+
+func (s *roleService) ListPolicies() ([]*Policy, error) {
+	return s.repo.ListPolicies()
+}
+
+func (s *roleService) GetPolicy(id int) (*Policy, error) {
+	return s.repo.GetPolicy(id)
+}
+
+func (s *roleService) CreatePolicy(ctx context.Context, policy *Policy) error {
+	if err := s.validatePolicy(policy); err != nil {
+		return err
+	}
+	if err := s.repo.CreatePolicy(policy); err != nil {
+		return err
+	}
+	return s.changed(ctx)
+}
+
+func (s *roleService) UpdatePolicy(ctx context.Context, policy *Policy) error {
+	if err := s.validatePolicy(policy); err != nil {
+		return err
+	}
+	if err := s.repo.UpdatePolicy(policy); err != nil {
+		return err
+	}
+	return s.changed(ctx)
+}
+
+func (s *roleService) DeletePolicy(ctx context.Context, id int) error {
+	if err := s.repo.DeletePolicy(id); err != nil {
+		return err
+	}
+	return s.changed(ctx)
+}
+
+// changed reloads this instance and tells the others to reload. The change
+// is already stored, so failing to notify is logged rather than returned.
+func (s *roleService) changed(ctx context.Context) error {
+	if err := s.Reload(); err != nil {
+		return err
+	}
+	if err := s.invalidator.Publish(ctx); err != nil {
+		logger.Error("Failed to notify instances of role changes", err, nil)
+	}
+	return nil
+}
+
+// validateRole checks a role's fields and that the roles it inherits exist
+// without forming a cycle
+func (s *roleService) validateRole(role *Role) error {
+	if role.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidRole)
+	}
+	for _, permission := range role.Permissions {
+		if permission.Resource == "" || permission.Action == "" {
+			return fmt.Errorf("%w: permissions need a resource and an action", ErrInvalidRole)
+		}
+	}
+
+	roles, err := s.repo.ListRoles()
+	if err != nil {
+		return err
+	}
+	inherits := make(map[string][]string, len(roles)+1)
+	for _, existing := range roles {
+		inherits[existing.Name] = existing.Inherits
+	}
+	for _, parent := range role.Inherits {
+		if _, exists := inherits[parent]; !exists {
+			return fmt.Errorf("%w: inherited role not found: %s", ErrInvalidRole, parent)
+		}
+	}
+	inherits[role.Name] = role.Inherits
+
+	// Walk up from the role; reaching it again means a cycle
+	visited := make(map[string]bool)
+	pending := append([]string(nil), role.Inherits...)
+	for len(pending) > 0 {
+		name := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if name == role.Name {
+			return fmt.Errorf("%w: inheritance must not form a cycle", ErrInvalidRole)
+		}
+		if visited[name] {
+			continue
+		}
+		visited[name] = true
+		pending = append(pending, inherits[name]...)
+	}
+
+	return nil
+}
+
+// validatePolicy checks a policy's fields and conditions and that its roles
+// exist
+func (s *roleService) validatePolicy(policy *Policy) error {
+	if policy.Resource == "" {
+		return fmt.Errorf("%w: resource is required", ErrInvalidPolicy)
+	}
+	if len(policy.Actions) == 0 || len(policy.Roles) == 0 {
+		return fmt.Errorf("%w: at least one action and one role are required", ErrInvalidPolicy)
+	}
+	if err := ValidatePolicyConditions(policy); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPolicy, err)
+	}
+
+	for _, name := range policy.Roles {
+		if _, err := s.repo.GetRole(name); err == ErrRoleNotFound {
+			return fmt.Errorf("%w: role not found: %s", ErrInvalidPolicy, name)
+		} else if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,302 @@
+package balance
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend_path/internal/domain"
+	"backend_path/internal/events"
+)
+
+// aggregatePrefix prefixes the aggregate ID of every balance event stream
+const aggregatePrefix = "balance-"
+
+// ErrInsufficientFunds is returned when a debit or an outgoing transfer is
+// larger than the balance
+var ErrInsufficientFunds = errors.New("insufficient funds")
+
+// AggregateID returns the ID of the event stream holding a user's balance
+func AggregateID(userID int) string {
+	return aggregatePrefix + strconv.Itoa(userID)
+}
+
+// userIDFromAggregateID parses the user ID out of a balance aggregate ID
+func userIDFromAggregateID(aggregateID string) (int, bool) {
+	if !strings.HasPrefix(aggregateID, aggregatePrefix) {
+		return 0, false
+	}
+	userID, err := strconv.Atoi(strings.TrimPrefix(aggregateID, aggregatePrefix))
+	if err != nil {
+		return 0, false
+	}
+	return userID, true
+}
+
+// Aggregate is the event-sourced balance of a single user. Its state only
+// changes by applying events; commands validate their input and raise the
+// events describing the change.
+type Aggregate struct {
+	UserID        int       `json:"user_id"`
+	Amount        float64   `json:"amount"`
+	Version       int       `json:"version"`
+	LastUpdatedAt time.Time `json:"last_updated_at"`
+
+	changes []*events.Event
+}
+
+// NewAggregate creates an empty balance aggregate for a user
+func NewAggregate(userID int) *Aggregate {
+	return &Aggregate{UserID: userID}
+}
+
+// applyFunc mutates an aggregate according to an event
+type applyFunc func(a *Aggregate, event *events.Event) error
+
+// applyFuncs holds the apply function of every balance event type
+var applyFuncs = map[string]applyFunc{
+	events.EventBalanceOpened:      applyBalanceSet,
+	events.EventBalanceUpdated:     applyBalanceSet,
+	events.EventBalanceCredited:    applyCredited,
+	events.EventBalanceDebited:     applyDebited,
+	events.EventBalanceTransferred: applyTransferred,
+	events.EventBalanceReversed:    applyReversed,
+}
+
+// IsBalanceEvent reports whether eventType changes a balance aggregate
+func IsBalanceEvent(eventType string) bool {
+	_, ok := applyFuncs[eventType]
+	return ok
+}
+
+// Apply applies the next event of the aggregate's stream
+func (a *Aggregate) Apply(event *events.Event) error {
+	if event.Version != a.Version+1 {
+		return fmt.Errorf("balance %d: expected version %d, got %d", a.UserID, a.Version+1, event.Version)
+	}
+	if err := a.mutate(event); err != nil {
+		return err
+	}
+	a.Version = event.Version
+	return nil
+}
+
+// mutate changes the state according to event without checking its version
+func (a *Aggregate) mutate(event *events.Event) error {
+	apply, ok := applyFuncs[event.Type]
+	if !ok {
+		return fmt.Errorf("balance %d: unknown event type %s", a.UserID, event.Type)
+	}
+	if err := apply(a, event); err != nil {
+		return fmt.Errorf("balance %d: failed to apply %s: %w", a.UserID, event.Type, err)
+	}
+	a.LastUpdatedAt = event.Timestamp
+	return nil
+}
+
+// applyBalanceSet sets the balance carried by opening events and by
+// balance.updated events recorded before the aggregate existed
+func applyBalanceSet(a *Aggregate, event *events.Event) error {
+	amount, err := floatField(event.Data, "balance")
+	if err != nil {
+		return err
+	}
+	a.Amount = amount
+	return nil
+}
+
+func applyCredited(a *Aggregate, event *events.Event) error {
+	amount, err := floatField(event.Data, "amount")
+	if err != nil {
+		return err
+	}
+	a.Amount += amount
+	return nil
+}
+
+func applyDebited(a *Aggregate, event *events.Event) error {
+	amount, err := floatField(event.Data, "amount")
+	if err != nil {
+		return err
+	}
+	a.Amount -= amount
+	return nil
+}
+
+// applyTransferred moves money out of or into the balance depending on
+// which side of the transfer the aggregate is on
+func applyTransferred(a *Aggregate, event *events.Event) error {
+	amount, err := floatField(event.Data, "amount")
+	if err != nil {
+		return err
+	}
+	fromUserID, err := intField(event.Data, "from_user_id")
+	if err != nil {
+		return err
+	}
+
+	if fromUserID == a.UserID {
+		a.Amount -= amount
+	} else {
+		a.Amount += amount
+	}
+	return nil
+}
+
+// applyReversed applies the signed amount that undoes an earlier transaction
+func applyReversed(a *Aggregate, event *events.Event) error {
+	amount, err := floatField(event.Data, "amount")
+	if err != nil {
+		return err
+	}
+	a.Amount += amount
+	return nil
+}
+
+// Credit adds amount to the balance
+func (a *Aggregate) Credit(amount float64, transactionID int) error {
+	if amount <= 0 {
+		return errors.New("credit amount must be positive")
+	}
+	return a.raise(events.EventBalanceCredited, map[string]interface{}{
+		"user_id":        a.UserID,
+		"amount":         amount,
+		"transaction_id": transactionID,
+	})
+}
+
+// Debit subtracts amount from the balance
+func (a *Aggregate) Debit(amount float64, transactionID int) error {
+	if amount <= 0 {
+		return errors.New("debit amount must be positive")
+	}
+	if amount > a.Amount {
+		return ErrInsufficientFunds
+	}
+	return a.raise(events.EventBalanceDebited, map[string]interface{}{
+		"user_id":        a.UserID,
+		"amount":         amount,
+		"transaction_id": transactionID,
+	})
+}
+
+// Transfer records this balance's side of a transfer between two users
+func (a *Aggregate) Transfer(fromUserID, toUserID int, amount float64, transactionID int) error {
+	if amount <= 0 {
+		return errors.New("transfer amount must be positive")
+	}
+	if fromUserID == toUserID {
+		return errors.New("cannot transfer to same user")
+	}
+	if a.UserID != fromUserID && a.UserID != toUserID {
+		return fmt.Errorf("balance %d is not part of the transfer", a.UserID)
+	}
+	if a.UserID == fromUserID && amount > a.Amount {
+		return ErrInsufficientFunds
+	}
+	return a.raise(events.EventBalanceTransferred, map[string]interface{}{
+		"user_id":        a.UserID,
+		"from_user_id":   fromUserID,
+		"to_user_id":     toUserID,
+		"amount":         amount,
+		"transaction_id": transactionID,
+	})
+}
+
+// Reverse applies the signed amount that undoes reversedTransactionID
+func (a *Aggregate) Reverse(amount float64, transactionID, reversedTransactionID int) error {
+	if amount == 0 {
+		return errors.New("reversal amount must not be zero")
+	}
+	return a.raise(events.EventBalanceReversed, map[string]interface{}{
+		"user_id":                 a.UserID,
+		"amount":                  amount,
+		"transaction_id":          transactionID,
+		"reversed_transaction_id": reversedTransactionID,
+	})
+}
+
+// raise applies a new event and keeps it as an uncommitted change
+func (a *Aggregate) raise(eventType string, data map[string]interface{}) error {
+	event := events.NewEvent(eventType, AggregateID(a.UserID), data)
+	event.Version = a.Version + 1
+
+	if err := a.Apply(event); err != nil {
+		return err
+	}
+	a.changes = append(a.changes, event)
+	return nil
+}
+
+// Changes returns the events raised since the aggregate was loaded or saved
+func (a *Aggregate) Changes() []*events.Event {
+	return a.changes
+}
+
+// markSaved forgets the uncommitted changes once they are recorded
+func (a *Aggregate) markSaved() {
+	a.changes = nil
+}
+
+// Snapshot captures the current state of the aggregate
+func (a *Aggregate) Snapshot() (*events.Snapshot, error) {
+	state, err := json.Marshal(a)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal balance snapshot: %w", err)
+	}
+
+	return &events.Snapshot{
+		AggregateID: AggregateID(a.UserID),
+		Version:     a.Version,
+		State:       state,
+		CreatedAt:   time.Now(),
+	}, nil
+}
+
+// restore loads the aggregate state from a snapshot
+func (a *Aggregate) restore(snapshot *events.Snapshot) error {
+	if err := json.Unmarshal(snapshot.State, a); err != nil {
+		return fmt.Errorf("failed to unmarshal balance snapshot: %w", err)
+	}
+	a.Version = snapshot.Version
+	return nil
+}
+
+// toBalance converts the aggregate to the balances read model
+func (a *Aggregate) toBalance() *domain.Balance {
+	return &domain.Balance{
+		UserID:        a.UserID,
+		Amount:        a.Amount,
+		Version:       a.Version,
+		LastUpdatedAt: a.LastUpdatedAt,
+	}
+}
+
+// floatField reads a number from event data, which holds float64 values once
+// decoded from JSON
+func floatField(data map[string]interface{}, key string) (float64, error) {
+	switch value := data[key].(type) {
+	case float64:
+		return value, nil
+	case int:
+		return float64(value), nil
+	case json.Number:
+		return value.Float64()
+	case string:
+		return strconv.ParseFloat(value, 64)
+	default:
+		return 0, fmt.Errorf("event field %s is missing or not a number", key)
+	}
+}
+
+// intField reads an integer from event data
+func intField(data map[string]interface{}, key string) (int, error) {
+	value, err := floatField(data, key)
+	if err != nil {
+		return 0, err
+	}
+	return int(value), nil
+}
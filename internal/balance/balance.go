@@ -1,8 +1,24 @@
 package balance
 
-// BalanceService provides balance-related operations
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"backend_path/internal/domain"
+)
+
+// BalanceService provides balance-related operations. Balances are event
+// sourced: the *Tx methods record balance events inside the caller's
+// transaction and keep the read models in step with them.
 type BalanceService interface {
 	UpdateBalance(userID int, amount float64) error
+	CreditTx(ctx context.Context, tx *sql.Tx, userID int, amount float64, transactionID int) error
+	DebitTx(ctx context.Context, tx *sql.Tx, userID int, amount float64, transactionID int) error
+	TransferTx(ctx context.Context, tx *sql.Tx, fromUserID, toUserID int, amount float64, transactionID int) error
+	ReverseTx(ctx context.Context, tx *sql.Tx, userID int, amount float64, transactionID, reversedTransactionID int) error
 	GetCurrentBalance(userID int) (float64, error)
 	GetHistoricalBalance(userID int, atTime string) (float64, error)
+	GetBalanceHistory(userID int, from, to time.Time) ([]*domain.BalanceHistoryEntry, error)
+	RebuildProjection(ctx context.Context) (int, error)
 }
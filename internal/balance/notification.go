@@ -0,0 +1,121 @@
+package balance
+
+import (
+	"strconv"
+
+	"backend_path/internal/events"
+
+	"github.com/google/uuid"
+)
+
+// notificationNamespace is the UUID namespace of balance.updated notification
+// IDs
+var notificationNamespace = uuid.NewSHA1(uuid.NameSpaceURL, []byte(events.EventBalanceUpdated))
+
+// notifyingEvents holds the balance event types that are published together
+// with a balance.updated notification
+var notifyingEvents = map[string]bool{
+	events.EventBalanceCredited:    true,
+	events.EventBalanceDebited:     true,
+	events.EventBalanceTransferred: true,
+	events.EventBalanceReversed:    true,
+}
+
+// balanceUpdatedEvent builds the notification published when a balance
+// changes. It is not part of the aggregate's stream, so it only goes to the
+// outbox; its ID is derived from the balance and its version so that
+// NotificationReplayer rebuilds the same event.
+func balanceUpdatedEvent(aggregate *Aggregate, delta float64, transactionID int) *events.Event {
+	event := events.NewEvent(events.EventBalanceUpdated, AggregateID(aggregate.UserID), map[string]interface{}{
+		"user_id":        aggregate.UserID,
+		"amount":         delta,
+		"balance":        aggregate.Amount,
+		"transaction_id": transactionID,
+	})
+	event.ID = notificationID(aggregate.UserID, aggregate.Version)
+	event.Version = aggregate.Version
+	return event
+}
+
+// notificationID returns the ID of the balance.updated notification for the
+// given version of a user's balance
+func notificationID(userID, version int) string {
+	name := AggregateID(userID) + "/" + strconv.Itoa(version)
+	return uuid.NewSHA1(notificationNamespace, []byte(name)).String()
+}
+
+// NotificationReplayer wraps the handler events are replayed into and hands it
+// the balance.updated notification of every credit, debit, transfer and
+// reversal after the event itself. Notifications are never appended to the
+// event store, so replays rebuild them from the balance stream; they carry the
+// same IDs as the notifications published live.
+type NotificationReplayer struct {
+	store    events.EventStore
+	handler  events.EventHandler
+	balances map[int]*Aggregate
+}
+
+// NewNotificationReplayer creates a new notification replayer
+func NewNotificationReplayer(store events.EventStore, handler events.EventHandler) *NotificationReplayer {
+	return &NotificationReplayer{
+		store:    store,
+		handler:  handler,
+		balances: make(map[int]*Aggregate),
+	}
+}
+
+// Handle passes event to the wrapped handler, followed by the balance.updated
+// notification it caused
+func (r *NotificationReplayer) Handle(event *events.Event) error {
+	if err := r.handler.Handle(event); err != nil {
+		return err
+	}
+
+	userID, ok := userIDFromAggregateID(event.AggregateID)
+	if !ok || !notifyingEvents[event.Type] {
+		return nil
+	}
+
+	aggregate, err := r.balanceBefore(userID, event.Version)
+	if err != nil {
+		return err
+	}
+
+	before := aggregate.Amount
+	if err := aggregate.Apply(event); err != nil {
+		delete(r.balances, userID)
+		return err
+	}
+
+	transactionID, _ := intField(event.Data, "transaction_id")
+	notification := balanceUpdatedEvent(aggregate, aggregate.Amount-before, transactionID)
+	notification.Timestamp = event.Timestamp
+	return r.handler.Handle(notification)
+}
+
+// balanceBefore returns the balance of userID as it was just before version.
+// Replays usually walk a stream in order, so the balance left by the previous
+// event is reused and the stream is only read on a gap.
+func (r *NotificationReplayer) balanceBefore(userID, version int) (*Aggregate, error) {
+	if aggregate, ok := r.balances[userID]; ok && aggregate.Version == version-1 {
+		return aggregate, nil
+	}
+
+	evts, err := r.store.GetEvents(AggregateID(userID))
+	if err != nil {
+		return nil, err
+	}
+
+	aggregate := NewAggregate(userID)
+	for _, event := range evts {
+		if event.Version >= version {
+			break
+		}
+		if err := aggregate.Apply(event); err != nil {
+			return nil, err
+		}
+	}
+
+	r.balances[userID] = aggregate
+	return aggregate, nil
+}
@@ -0,0 +1,144 @@
+package balance
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"backend_path/internal/domain"
+	"backend_path/internal/events"
+	"backend_path/pkg/database"
+	"backend_path/pkg/logger"
+)
+
+// Projection keeps the balances table and the balance history read model up
+// to date with balance events. Projecting is idempotent: events at or below
+// the stored version of a balance are skipped.
+type Projection struct {
+	db    *sql.DB
+	repo  Repository
+	store events.EventStore
+}
+
+// NewProjection creates a new balance projection
+func NewProjection(db *sql.DB, repo Repository, store events.EventStore) *Projection {
+	return &Projection{db: db, repo: repo, store: store}
+}
+
+// Handle projects a single event in its own database transaction
+func (p *Projection) Handle(event *events.Event) error {
+	return database.WithTransaction(context.Background(), p.db, func(tx *sql.Tx) error {
+		return p.ProjectTx(tx, event)
+	})
+}
+
+// ProjectTx projects a single event inside tx. Events that are not balance
+// events are ignored.
+func (p *Projection) ProjectTx(tx *sql.Tx, event *events.Event) error {
+	userID, ok := userIDFromAggregateID(event.AggregateID)
+	if !ok || !IsBalanceEvent(event.Type) {
+		return nil
+	}
+
+	return p.project(p.repo.WithTx(tx), userID, event)
+}
+
+// project applies event to the stored balance of userID and records it in
+// the history
+func (p *Projection) project(repo Repository, userID int, event *events.Event) error {
+	aggregate := NewAggregate(userID)
+	current, err := repo.GetByUserID(userID)
+	switch {
+	case err == nil:
+		aggregate.Amount = current.Amount
+		aggregate.Version = current.Version
+		aggregate.LastUpdatedAt = current.LastUpdatedAt
+	case !errors.Is(err, ErrBalanceNotFound):
+		return err
+	}
+
+	if event.Version <= aggregate.Version {
+		return nil
+	}
+
+	expectedVersion := aggregate.Version
+	before := aggregate.Amount
+	if err := aggregate.mutate(event); err != nil {
+		return err
+	}
+	aggregate.Version = event.Version
+
+	if err := repo.Update(aggregate.toBalance(), expectedVersion); err != nil {
+		return fmt.Errorf("failed to project balance %d: %w", userID, err)
+	}
+
+	transactionID, _ := intField(event.Data, "transaction_id")
+	return repo.AddHistory(&domain.BalanceHistoryEntry{
+		UserID:        userID,
+		EventID:       event.ID,
+		EventType:     event.Type,
+		Version:       event.Version,
+		Amount:        aggregate.Amount - before,
+		Balance:       aggregate.Amount,
+		TransactionID: transactionID,
+		OccurredAt:    event.Timestamp,
+	})
+}
+
+// Rebuild deletes the balances and history read models and replays every
+// balance event from the event store into them in a single transaction. It
+// returns the number of events projected.
+func (p *Projection) Rebuild(ctx context.Context) (int, error) {
+	start := time.Now()
+	projected := 0
+
+	err := database.WithTransaction(ctx, p.db, func(tx *sql.Tx) error {
+		repo := p.repo.WithTx(tx)
+		if err := repo.Reset(); err != nil {
+			return err
+		}
+
+		page := events.Page{Limit: events.DefaultPageSize}
+		for {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			batch, err := p.store.GetEventsSince(time.Time{}, page)
+			if err != nil {
+				return err
+			}
+
+			for _, event := range batch {
+				userID, ok := userIDFromAggregateID(event.AggregateID)
+				if !ok || !IsBalanceEvent(event.Type) {
+					continue
+				}
+				if err := p.project(repo, userID, event); err != nil {
+					return err
+				}
+				projected++
+			}
+
+			if len(batch) < page.Limit {
+				return nil
+			}
+			page.Offset += page.Limit
+		}
+	})
+	if err != nil {
+		logger.Error("Failed to rebuild balance projection", err, map[string]interface{}{
+			"projected": projected,
+		})
+		return 0, err
+	}
+
+	logger.Info("Balance projection rebuilt", map[string]interface{}{
+		"projected": projected,
+		"duration":  time.Since(start).String(),
+	})
+
+	return projected, nil
+}
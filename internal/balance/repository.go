@@ -1,8 +1,23 @@
 package balance
 
-import "backend_path/internal/domain"
+import (
+	"database/sql"
+	"time"
+
+	"backend_path/internal/domain"
+)
 
 type Repository interface {
 	GetByUserID(userID int) (*domain.Balance, error)
-	Update(balance *domain.Balance) error
+	// Update saves balance if the stored row is still at expectedVersion
+	Update(balance *domain.Balance, expectedVersion int) error
+	AddHistory(entry *domain.BalanceHistoryEntry) error
+	GetHistory(userID int, from, to time.Time) ([]*domain.BalanceHistoryEntry, error)
+	// GetHistoryAt returns the last history entry at or before at, or nil if
+	// the balance had no history yet
+	GetHistoryAt(userID int, at time.Time) (*domain.BalanceHistoryEntry, error)
+	// Reset deletes every balance and history row
+	Reset() error
+	// WithTx returns a repository that runs its queries inside tx
+	WithTx(tx *sql.Tx) Repository
 }
@@ -2,21 +2,35 @@ package balance
 
 import (
 	"backend_path/internal/domain"
+	"backend_path/pkg/database"
 	"database/sql"
+	"errors"
 	"fmt"
+	"time"
+)
+
+var (
+	// ErrBalanceNotFound is returned when a user has no balance yet
+	ErrBalanceNotFound = errors.New("balance not found")
+	// ErrConcurrentUpdate is returned when a balance was modified since it was read
+	ErrConcurrentUpdate = errors.New("balance was modified concurrently")
 )
 
 type sqlRepository struct {
-	db *sql.DB
+	db database.Executor
 }
 
 func NewSQLRepository(db *sql.DB) Repository {
 	return &sqlRepository{db: db}
 }
 
+func (r *sqlRepository) WithTx(tx *sql.Tx) Repository {
+	return &sqlRepository{db: tx}
+}
+
 func (r *sqlRepository) GetByUserID(userID int) (*domain.Balance, error) {
 	query := `
-		SELECT user_id, amount, last_updated_at
+		SELECT user_id, amount, version, last_updated_at
 		FROM balances
 		WHERE user_id = ?
 	`
@@ -25,12 +39,13 @@ func (r *sqlRepository) GetByUserID(userID int) (*domain.Balance, error) {
 	err := r.db.QueryRow(query, userID).Scan(
 		&balance.UserID,
 		&balance.Amount,
+		&balance.Version,
 		&balance.LastUpdatedAt,
 	)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("balance not found")
+			return nil, ErrBalanceNotFound
 		}
 		return nil, fmt.Errorf("failed to get balance: %w", err)
 	}
@@ -38,15 +53,17 @@ func (r *sqlRepository) GetByUserID(userID int) (*domain.Balance, error) {
 	return balance, nil
 }
 
-func (r *sqlRepository) Update(balance *domain.Balance) error {
+// Update saves a balance read at expectedVersion. The write only succeeds if
+// the stored row is still at that version.
+func (r *sqlRepository) Update(balance *domain.Balance, expectedVersion int) error {
 	// First try to update existing balance
 	updateQuery := `
 		UPDATE balances 
-		SET amount = ?, last_updated_at = ?
-		WHERE user_id = ?
+		SET amount = ?, version = ?, last_updated_at = ?
+		WHERE user_id = ? AND version = ?
 	`
 
-	result, err := r.db.Exec(updateQuery, balance.Amount, balance.LastUpdatedAt, balance.UserID)
+	result, err := r.db.Exec(updateQuery, balance.Amount, balance.Version, balance.LastUpdatedAt, balance.UserID, expectedVersion)
 	if err != nil {
 		return fmt.Errorf("failed to update balance: %w", err)
 	}
@@ -60,15 +77,143 @@ func (r *sqlRepository) Update(balance *domain.Balance) error {
 	// If no rows were affected, insert new balance
 	if rowsAffected == 0 {
 		insertQuery := `
-			INSERT INTO balances (user_id, amount, last_updated_at)
-			VALUES (?, ?, ?)
+			INSERT INTO balances (user_id, amount, version, last_updated_at)
+			VALUES (?, ?, ?, ?)
 		`
 
-		_, err = r.db.Exec(insertQuery, balance.UserID, balance.Amount, balance.LastUpdatedAt)
+		_, err = r.db.Exec(insertQuery, balance.UserID, balance.Amount, balance.Version, balance.LastUpdatedAt)
 		if err != nil {
+			// The row exists at another version, so someone else updated it first
+			if database.IsUniqueViolation(err) {
+				return ErrConcurrentUpdate
+			}
 			return fmt.Errorf("failed to insert balance: %w", err)
 		}
 	}
 
 	return nil
 }
+
+func (r *sqlRepository) AddHistory(entry *domain.BalanceHistoryEntry) error {
+	query := `
+		INSERT INTO balance_history (user_id, event_id, event_type, version, amount, balance, transaction_id, occurred_at)
+		OUTPUT INSERTED.id
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	var transactionID interface{}
+	if entry.TransactionID > 0 {
+		transactionID = entry.TransactionID
+	}
+
+	err := r.db.QueryRow(query,
+		entry.UserID,
+		entry.EventID,
+		entry.EventType,
+		entry.Version,
+		entry.Amount,
+		entry.Balance,
+		transactionID,
+		entry.OccurredAt,
+	).Scan(&entry.ID)
+	if err != nil {
+		// The event has already been projected
+		if database.IsUniqueViolation(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to add balance history: %w", err)
+	}
+
+	return nil
+}
+
+func (r *sqlRepository) GetHistory(userID int, from, to time.Time) ([]*domain.BalanceHistoryEntry, error) {
+	query := `
+		SELECT id, user_id, event_id, event_type, version, amount, balance, transaction_id, occurred_at
+		FROM balance_history
+		WHERE user_id = ? AND occurred_at >= ? AND occurred_at <= ?
+		ORDER BY version
+	`
+
+	rows, err := r.db.Query(query, userID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get balance history: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*domain.BalanceHistoryEntry
+	for rows.Next() {
+		entry, err := scanHistoryEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating balance history: %w", err)
+	}
+
+	return entries, nil
+}
+
+func (r *sqlRepository) GetHistoryAt(userID int, at time.Time) (*domain.BalanceHistoryEntry, error) {
+	query := `
+		SELECT TOP 1 id, user_id, event_id, event_type, version, amount, balance, transaction_id, occurred_at
+		FROM balance_history
+		WHERE user_id = ? AND occurred_at <= ?
+		ORDER BY version DESC
+	`
+
+	entry, err := scanHistoryEntry(r.db.QueryRow(query, userID, at))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+func (r *sqlRepository) Reset() error {
+	if _, err := r.db.Exec(`DELETE FROM balance_history`); err != nil {
+		return fmt.Errorf("failed to delete balance history: %w", err)
+	}
+	if _, err := r.db.Exec(`DELETE FROM balances`); err != nil {
+		return fmt.Errorf("failed to delete balances: %w", err)
+	}
+	return nil
+}
+
+// scanner is implemented by *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanHistoryEntry scans a single balance history row
+func scanHistoryEntry(row scanner) (*domain.BalanceHistoryEntry, error) {
+	entry := &domain.BalanceHistoryEntry{}
+	var transactionID sql.NullInt64
+
+	err := row.Scan(
+		&entry.ID,
+		&entry.UserID,
+		&entry.EventID,
+		&entry.EventType,
+		&entry.Version,
+		&entry.Amount,
+		&entry.Balance,
+		&transactionID,
+		&entry.OccurredAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan balance history: %w", err)
+	}
+
+	entry.TransactionID = int(transactionID.Int64)
+	return entry, nil
+}
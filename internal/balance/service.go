@@ -1,38 +1,55 @@
 package balance
 
 import (
+	"context"
+	"database/sql"
 	"errors"
+	"sort"
 	"time"
 
+	"backend_path/internal/audit"
 	"backend_path/internal/domain"
+	"backend_path/internal/events"
+	"backend_path/pkg/database"
 	"backend_path/pkg/logger"
 )
 
+// auditRecorder records audit log entries inside a database transaction
+type auditRecorder interface {
+	RecordTx(ctx context.Context, tx *sql.Tx, entry *audit.Entry) error
+}
+
 type service struct {
-	repo Repository
+	db         *sql.DB
+	repo       Repository
+	aggregates *AggregateStore
+	projection *Projection
+	auditor    auditRecorder
 }
 
-func NewService(repo Repository) BalanceService {
-	return &service{repo: repo}
+func NewService(db *sql.DB, repo Repository, aggregates *AggregateStore, projection *Projection, auditor auditRecorder) BalanceService {
+	return &service{
+		db:         db,
+		repo:       repo,
+		aggregates: aggregates,
+		projection: projection,
+		auditor:    auditor,
+	}
 }
 
+// UpdateBalance credits or debits amount in its own database transaction
 func (s *service) UpdateBalance(userID int, amount float64) error {
-	// Get current balance
-	balance, err := s.repo.GetByUserID(userID)
-	if err != nil {
-		// Create new balance if not exists
-		balance = &domain.Balance{
-			UserID:        userID,
-			Amount:        0,
-			LastUpdatedAt: time.Now(),
-		}
+	if amount == 0 {
+		return errors.New("amount must not be zero")
 	}
 
-	// Thread-safe balance update
-	balance.Update(amount)
-
-	// Save to database
-	if err := s.repo.Update(balance); err != nil {
+	err := database.WithTransaction(context.Background(), s.db, func(tx *sql.Tx) error {
+		if amount > 0 {
+			return s.CreditTx(context.Background(), tx, userID, amount, 0)
+		}
+		return s.DebitTx(context.Background(), tx, userID, -amount, 0)
+	})
+	if err != nil {
 		logger.Error("Failed to update balance", err, map[string]interface{}{
 			"user_id": userID,
 			"amount":  amount,
@@ -48,6 +65,109 @@ func (s *service) UpdateBalance(userID int, amount float64) error {
 	return nil
 }
 
+func (s *service) CreditTx(ctx context.Context, tx *sql.Tx, userID int, amount float64, transactionID int) error {
+	return s.applyTx(ctx, tx, transactionID, []int{userID}, func(aggregates []*Aggregate) error {
+		return aggregates[0].Credit(amount, transactionID)
+	})
+}
+
+func (s *service) DebitTx(ctx context.Context, tx *sql.Tx, userID int, amount float64, transactionID int) error {
+	return s.applyTx(ctx, tx, transactionID, []int{userID}, func(aggregates []*Aggregate) error {
+		return aggregates[0].Debit(amount, transactionID)
+	})
+}
+
+func (s *service) TransferTx(ctx context.Context, tx *sql.Tx, fromUserID, toUserID int, amount float64, transactionID int) error {
+	return s.applyTx(ctx, tx, transactionID, []int{fromUserID, toUserID}, func(aggregates []*Aggregate) error {
+		for _, aggregate := range aggregates {
+			if err := aggregate.Transfer(fromUserID, toUserID, amount, transactionID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *service) ReverseTx(ctx context.Context, tx *sql.Tx, userID int, amount float64, transactionID, reversedTransactionID int) error {
+	return s.applyTx(ctx, tx, transactionID, []int{userID}, func(aggregates []*Aggregate) error {
+		return aggregates[0].Reverse(amount, transactionID, reversedTransactionID)
+	})
+}
+
+// applyTx loads the balances of userIDs, runs command against them and, inside
+// tx, saves the raised events, projects them into the read models and queues
+// a balance.updated notification for every changed balance. Balances are
+// handed to command and locked in ascending user ID order, so opposing
+// transfers cannot deadlock on each other's streams. Audit entries are
+// written last because appending to the audit chain locks its tail until tx
+// commits.
+func (s *service) applyTx(ctx context.Context, tx *sql.Tx, transactionID int, userIDs []int, command func([]*Aggregate) error) error {
+	userIDs = append([]int(nil), userIDs...)
+	sort.Ints(userIDs)
+
+	aggregates := make([]*Aggregate, len(userIDs))
+	before := make([]*domain.Balance, len(userIDs))
+	for i, userID := range userIDs {
+		aggregate, err := s.aggregates.Load(userID)
+		if err != nil {
+			return err
+		}
+		aggregates[i] = aggregate
+		before[i] = aggregate.toBalance()
+	}
+
+	if err := command(aggregates); err != nil {
+		return err
+	}
+
+	changes := make([][]*events.Event, len(aggregates))
+	for i, aggregate := range aggregates {
+		changes[i] = aggregate.Changes()
+		if err := s.aggregates.SaveTx(ctx, tx, aggregate); err != nil {
+			return err
+		}
+
+		for _, event := range changes[i] {
+			if err := s.projection.ProjectTx(tx, event); err != nil {
+				return err
+			}
+		}
+	}
+
+	for i, aggregate := range aggregates {
+		after := aggregate.toBalance()
+		notification := balanceUpdatedEvent(aggregate, after.Amount-before[i].Amount, transactionID)
+		if err := s.aggregates.recorder.PublishTx(ctx, tx, notification); err != nil {
+			return err
+		}
+
+		if err := s.auditor.RecordTx(ctx, tx, &audit.Entry{
+			EntityType: audit.EntityBalance,
+			EntityID:   aggregate.UserID,
+			Action:     audit.ActionUpdate,
+			Before:     before[i],
+			After:      after,
+			Details: map[string]interface{}{
+				"transaction_id": transactionID,
+				"events":         eventTypes(changes[i]),
+			},
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// eventTypes lists the types of evts
+func eventTypes(evts []*events.Event) []string {
+	types := make([]string, len(evts))
+	for i, event := range evts {
+		types[i] = event.Type
+	}
+	return types
+}
+
 func (s *service) GetCurrentBalance(userID int) (float64, error) {
 	balance, err := s.repo.GetByUserID(userID)
 	if err != nil {
@@ -62,14 +182,12 @@ func (s *service) GetCurrentBalance(userID int) (float64, error) {
 
 func (s *service) GetHistoricalBalance(userID int, atTime string) (float64, error) {
 	// Parse the time string
-	_, err := time.Parse(time.RFC3339, atTime)
+	at, err := time.Parse(time.RFC3339, atTime)
 	if err != nil {
 		return 0, errors.New("invalid time format, expected RFC3339")
 	}
 
-	// For now, return current balance as historical balance
-	// In a real implementation, you would query historical balance data
-	balance, err := s.repo.GetByUserID(userID)
+	entry, err := s.repo.GetHistoryAt(userID, at)
 	if err != nil {
 		logger.Error("Failed to get historical balance", err, map[string]interface{}{
 			"user_id": userID,
@@ -78,11 +196,34 @@ func (s *service) GetHistoricalBalance(userID int, atTime string) (float64, erro
 		return 0, err
 	}
 
+	// The balance did not exist yet
+	if entry == nil {
+		return 0, nil
+	}
+
 	logger.Info("Historical balance retrieved", map[string]interface{}{
 		"user_id": userID,
 		"at_time": atTime,
-		"balance": balance.GetAmount(),
+		"balance": entry.Balance,
 	})
 
-	return balance.GetAmount(), nil
+	return entry.Balance, nil
+}
+
+func (s *service) GetBalanceHistory(userID int, from, to time.Time) ([]*domain.BalanceHistoryEntry, error) {
+	entries, err := s.repo.GetHistory(userID, from, to)
+	if err != nil {
+		logger.Error("Failed to get balance history", err, map[string]interface{}{
+			"user_id": userID,
+		})
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// RebuildProjection rebuilds the balances and history read models from the
+// event store
+func (s *service) RebuildProjection(ctx context.Context) (int, error) {
+	return s.projection.Rebuild(ctx)
 }
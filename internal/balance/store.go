@@ -0,0 +1,103 @@
+package balance
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"backend_path/internal/events"
+)
+
+// eventRecorder records domain events inside a database transaction
+type eventRecorder interface {
+	RecordTx(ctx context.Context, tx *sql.Tx, evts ...*events.Event) error
+	PublishTx(ctx context.Context, tx *sql.Tx, evts ...*events.Event) error
+}
+
+// AggregateStore loads balance aggregates from their latest snapshot plus the
+// events recorded after it, and saves their new events
+type AggregateStore struct {
+	store            events.EventStore
+	snapshots        events.SnapshotStore
+	recorder         eventRecorder
+	snapshotInterval int
+}
+
+// NewAggregateStore creates a new aggregate store. A snapshot is taken every
+// snapshotInterval events; zero or less disables snapshots.
+func NewAggregateStore(store events.EventStore, snapshots events.SnapshotStore, recorder eventRecorder, snapshotInterval int) *AggregateStore {
+	return &AggregateStore{
+		store:            store,
+		snapshots:        snapshots,
+		recorder:         recorder,
+		snapshotInterval: snapshotInterval,
+	}
+}
+
+// Load rebuilds the balance aggregate of a user
+func (s *AggregateStore) Load(userID int) (*Aggregate, error) {
+	aggregate := NewAggregate(userID)
+	aggregateID := AggregateID(userID)
+
+	if s.snapshots != nil {
+		snapshot, err := s.snapshots.GetSnapshot(aggregateID)
+		if err != nil {
+			return nil, err
+		}
+		if snapshot != nil {
+			if err := aggregate.restore(snapshot); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	evts, err := s.store.GetEventsAfter(aggregateID, aggregate.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, event := range evts {
+		if err := aggregate.Apply(event); err != nil {
+			return nil, err
+		}
+	}
+
+	return aggregate, nil
+}
+
+// SaveTx records the uncommitted events of aggregate inside tx and snapshots
+// it when it has crossed a snapshot interval. Saving fails with
+// events.ErrConcurrencyConflict if another writer appended to the stream
+// since it was loaded.
+func (s *AggregateStore) SaveTx(ctx context.Context, tx *sql.Tx, aggregate *Aggregate) error {
+	changes := aggregate.Changes()
+	if len(changes) == 0 {
+		return nil
+	}
+
+	if err := s.recorder.RecordTx(ctx, tx, changes...); err != nil {
+		return err
+	}
+
+	if s.snapshotDue(aggregate.Version-len(changes), aggregate.Version) {
+		snapshot, err := aggregate.Snapshot()
+		if err != nil {
+			return err
+		}
+		if err := s.snapshots.SaveSnapshotTx(ctx, tx, snapshot); err != nil {
+			return fmt.Errorf("failed to snapshot balance %d: %w", aggregate.UserID, err)
+		}
+	}
+
+	aggregate.markSaved()
+	return nil
+}
+
+// snapshotDue reports whether moving from version from to version to crossed
+// a multiple of the snapshot interval
+func (s *AggregateStore) snapshotDue(from, to int) bool {
+	if s.snapshots == nil || s.snapshotInterval <= 0 {
+		return false
+	}
+	return to/s.snapshotInterval > from/s.snapshotInterval
+}
@@ -1,36 +1,145 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+	"math"
 	"os"
 	"strconv"
+	"strings"
 )
 
+// Placeholder secrets the settings default to, refused in production
+const (
+	defaultJWTSecret           = "your-secret-key-here"
+	defaultJWTKeyEncryptionKey = "your-jwt-key-encryption-key-here"
+	defaultMFAEncryptionKey    = "your-mfa-encryption-key-here"
+	defaultAuditSigningKey     = "your-audit-signing-key-here"
+)
+
+// minAuditSigningKeyLength is the shortest audit checkpoint signing key
+// accepted in production
+const minAuditSigningKeyLength = 32
+
 type Config struct {
-	Port                string
-	DatabaseURL         string
-	RedisURL            string
-	Environment         string
-	JWTSecret           string
-	JaegerURL           string
-	RateLimit           int
-	CacheStrategy       string
-	ReplicationMode     string
-	SupportedCurrencies []string
+	Port                   string
+	DatabaseURL            string
+	RedisURL               string
+	Environment            string
+	JWTSecret              string
+	JWTSigningAlgorithm    string
+	JWTKeyEncryptionKey    string
+	JWTKeyRotationDays     int
+	JWTKeyGraceDays        int
+	JaegerURL              string
+	RateLimit              int
+	TrustedProxies         []string
+	CacheStrategy          string
+	ReplicationMode        string
+	SupportedCurrencies    []string
+	EventPublisher         string
+	SnapshotInterval       int
+	AuditSigningKey        string
+	AuditCheckpointMinutes int
+	MFAEncryptionKey       string
+	MFAStepUpThreshold     float64
+	LoginMaxFailures       int
+	LoginMaxIPFailures     int
+	LoginLockoutMinutes    int
+	MaxSessionsPerUser     int
+	PasswordMinLength      int
+	PasswordMinClasses     int
+	PasswordBreachListDir  string
+	Argon2MemoryKiB        int
+	Argon2Iterations       int
+	Argon2Parallelism      int
+	AppBaseURL             string
+	MailSender             string
+	MailFrom               string
+	MailDir                string
+	SMTPHost               string
+	SMTPPort               int
+	SMTPUsername           string
+	SMTPPassword           string
 }
 
 func Load() *Config {
 	return &Config{
-		Port:                getEnv("PORT", "8080"),
-		DatabaseURL:         getEnv("DATABASE_URL", ""),
-		RedisURL:            getEnv("REDIS_URL", "redis://localhost:6379"),
-		Environment:         getEnv("ENVIRONMENT", "development"),
-		JWTSecret:           getEnv("JWT_SECRET", "your-secret-key-here"),
-		JaegerURL:           getEnv("JAEGER_URL", "http://localhost:14268/api/traces"),
-		RateLimit:           getEnvAsInt("RATE_LIMIT_PER_MINUTE", 100),
-		CacheStrategy:       getEnv("CACHE_STRATEGY", "write_through"),
-		ReplicationMode:     getEnv("REPLICATION_MODE", "master_slave"),
-		SupportedCurrencies: []string{"USD", "EUR", "TRY", "GBP", "JPY"},
+		Port:                   getEnv("PORT", "8080"),
+		DatabaseURL:            getEnv("DATABASE_URL", ""),
+		RedisURL:               getEnv("REDIS_URL", "redis://localhost:6379"),
+		Environment:            getEnv("ENVIRONMENT", "development"),
+		JWTSecret:              getEnv("JWT_SECRET", defaultJWTSecret),
+		JWTSigningAlgorithm:    getEnv("JWT_SIGNING_ALGORITHM", "HS256"),
+		JWTKeyEncryptionKey:    getEnv("JWT_KEY_ENCRYPTION_KEY", defaultJWTKeyEncryptionKey),
+		JWTKeyRotationDays:     getEnvAsInt("JWT_KEY_ROTATION_DAYS", 30),
+		JWTKeyGraceDays:        getEnvAsInt("JWT_KEY_GRACE_DAYS", 8),
+		JaegerURL:              getEnv("JAEGER_URL", "http://localhost:14268/api/traces"),
+		RateLimit:              getEnvAsInt("RATE_LIMIT_PER_MINUTE", 100),
+		TrustedProxies:         getEnvAsList("TRUSTED_PROXIES"),
+		CacheStrategy:          getEnv("CACHE_STRATEGY", "write_through"),
+		ReplicationMode:        getEnv("REPLICATION_MODE", "master_slave"),
+		SupportedCurrencies:    []string{"USD", "EUR", "TRY", "GBP", "JPY"},
+		EventPublisher:         getEnv("EVENT_PUBLISHER", "inprocess"),
+		SnapshotInterval:       getEnvAsInt("SNAPSHOT_INTERVAL", 100),
+		AuditSigningKey:        getEnv("AUDIT_SIGNING_KEY", defaultAuditSigningKey),
+		AuditCheckpointMinutes: getEnvAsInt("AUDIT_CHECKPOINT_INTERVAL_MINUTES", 60),
+		MFAEncryptionKey:       getEnv("MFA_ENCRYPTION_KEY", defaultMFAEncryptionKey),
+		MFAStepUpThreshold:     getEnvAsFloat("MFA_STEP_UP_THRESHOLD", 10000),
+		LoginMaxFailures:       getEnvAsInt("LOGIN_MAX_FAILURES", 5),
+		LoginMaxIPFailures:     getEnvAsInt("LOGIN_MAX_FAILURES_PER_IP", 20),
+		LoginLockoutMinutes:    getEnvAsInt("LOGIN_LOCKOUT_MINUTES", 15),
+		MaxSessionsPerUser:     getEnvAsInt("MAX_SESSIONS_PER_USER", 10),
+		PasswordMinLength:      getEnvAsInt("PASSWORD_MIN_LENGTH", 10),
+		PasswordMinClasses:     getEnvAsInt("PASSWORD_MIN_CHARACTER_CLASSES", 3),
+		PasswordBreachListDir:  getEnv("PASSWORD_BREACH_LIST_DIR", ""),
+		Argon2MemoryKiB:        getEnvAsInt("ARGON2_MEMORY_KIB", 64*1024),
+		Argon2Iterations:       getEnvAsInt("ARGON2_ITERATIONS", 3),
+		Argon2Parallelism:      getEnvAsInt("ARGON2_PARALLELISM", 2),
+		AppBaseURL:             getEnv("APP_BASE_URL", "http://localhost:3000"),
+		MailSender:             getEnv("MAIL_SENDER", "log"),
+		MailFrom:               getEnv("MAIL_FROM", "GoFintech <no-reply@gofintech.local>"),
+		MailDir:                getEnv("MAIL_DIR", "mail"),
+		SMTPHost:               getEnv("SMTP_HOST", "localhost"),
+		SMTPPort:               getEnvAsInt("SMTP_PORT", 587),
+		SMTPUsername:           getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:           getEnv("SMTP_PASSWORD", ""),
+	}
+}
+
+// Validate reports settings that are unsafe to run with, such as argon2id
+// parameters it cannot hash with, or the placeholder secrets of the JWT
+// signing algorithm, two-factor secret encryption and audit checkpoints in
+// production
+func (c *Config) Validate() error {
+	// argon2id panics on these, failing every registration and login
+	if c.Argon2Parallelism < 1 || c.Argon2Parallelism > math.MaxUint8 {
+		return fmt.Errorf("ARGON2_PARALLELISM must be between 1 and %d", math.MaxUint8)
+	}
+	if c.Argon2Iterations < 1 || int64(c.Argon2Iterations) > math.MaxUint32 {
+		return errors.New("ARGON2_ITERATIONS must be at least 1")
+	}
+	if c.Argon2MemoryKiB < 8*c.Argon2Parallelism || int64(c.Argon2MemoryKiB) > math.MaxUint32 {
+		return errors.New("ARGON2_MEMORY_KIB must be at least 8 times ARGON2_PARALLELISM")
+	}
+
+	if c.Environment != "production" {
+		return nil
+	}
+	if c.JWTSigningAlgorithm == "HS256" && c.JWTSecret == defaultJWTSecret {
+		return errors.New("JWT_SECRET must be set in production")
+	}
+	if c.JWTSigningAlgorithm != "HS256" && c.JWTKeyEncryptionKey == defaultJWTKeyEncryptionKey {
+		return errors.New("JWT_KEY_ENCRYPTION_KEY must be set in production")
 	}
+	if c.MFAEncryptionKey == defaultMFAEncryptionKey {
+		return errors.New("MFA_ENCRYPTION_KEY must be set in production")
+	}
+	// Anyone holding the key can re-sign checkpoints over an edited chain
+	if c.AuditSigningKey == defaultAuditSigningKey || len(c.AuditSigningKey) < minAuditSigningKeyLength {
+		return fmt.Errorf("AUDIT_SIGNING_KEY must be set to at least %d characters in production", minAuditSigningKeyLength)
+	}
+	return nil
 }
 
 func getEnv(key, defaultValue string) string {
@@ -48,3 +157,23 @@ func getEnvAsInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return defaultValue
+}
+
+// getEnvAsList splits a comma-separated variable, dropping empty entries
+func getEnvAsList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
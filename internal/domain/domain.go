@@ -10,13 +10,20 @@ import (
 
 // User represents a user in the system
 type User struct {
-	ID           int       `json:"id"`
-	Username     string    `json:"username"`
-	Email        string    `json:"email"`
-	PasswordHash string    `json:"-"`
-	Role         string    `json:"role"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID              int        `json:"id"`
+	Username        string     `json:"username"`
+	Email           string     `json:"email"`
+	PasswordHash    string     `json:"-"`
+	Role            string     `json:"role"`
+	KYCTier         int        `json:"kyc_tier"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// EmailVerified reports whether the user proved they own their email address
+func (u *User) EmailVerified() bool {
+	return u.EmailVerifiedAt != nil
 }
 
 func (u *User) Validate() error {
@@ -82,6 +89,7 @@ func (t *Transaction) MarshalJSON() ([]byte, error) {
 type Balance struct {
 	UserID        int       `json:"user_id"`
 	Amount        float64   `json:"amount"`
+	Version       int       `json:"version"`
 	LastUpdatedAt time.Time `json:"last_updated_at"`
 	mu            sync.RWMutex
 }
@@ -90,6 +98,7 @@ func (b *Balance) Update(amount float64) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 	b.Amount += amount
+	b.Version++
 	b.LastUpdatedAt = time.Now()
 }
 
@@ -108,12 +117,38 @@ func (b *Balance) MarshalJSON() ([]byte, error) {
 	})
 }
 
-// AuditLog represents an audit log entry
+// BalanceHistoryEntry records a single change to a user's balance
+type BalanceHistoryEntry struct {
+	ID            int64     `json:"id"`
+	UserID        int       `json:"user_id"`
+	EventID       string    `json:"event_id"`
+	EventType     string    `json:"event_type"`
+	Version       int       `json:"version"`
+	Amount        float64   `json:"amount"`
+	Balance       float64   `json:"balance"`
+	TransactionID int       `json:"transaction_id,omitempty"`
+	OccurredAt    time.Time `json:"occurred_at"`
+}
+
+// AuditLog represents an audit log entry. Before and After hold the JSON
+// state of the entity and Changes the fields that differ between them.
+// PrevHash and Hash chain every entry to the one written before it.
+// ImpersonatorID is the user who acted as ActorID, if any.
 type AuditLog struct {
-	ID         int       `json:"id"`
-	EntityType string    `json:"entity_type"`
-	EntityID   int       `json:"entity_id"`
-	Action     string    `json:"action"`
-	Details    string    `json:"details"`
-	CreatedAt  time.Time `json:"created_at"`
+	ID             int             `json:"id"`
+	EntityType     string          `json:"entity_type"`
+	EntityID       int             `json:"entity_id"`
+	Action         string          `json:"action"`
+	Details        string          `json:"details"`
+	ActorID        int             `json:"actor_id,omitempty"`
+	ImpersonatorID int             `json:"impersonator_id,omitempty"`
+	IPAddress      string          `json:"ip_address,omitempty"`
+	UserAgent      string          `json:"user_agent,omitempty"`
+	TraceID        string          `json:"trace_id,omitempty"`
+	Before         json.RawMessage `json:"before,omitempty"`
+	After          json.RawMessage `json:"after,omitempty"`
+	Changes        json.RawMessage `json:"changes,omitempty"`
+	PrevHash       string          `json:"prev_hash,omitempty"`
+	Hash           string          `json:"hash,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
 }
@@ -1,8 +1,13 @@
 package events
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"time"
 
+	"backend_path/pkg/logger"
+
 	"github.com/google/uuid"
 )
 
@@ -30,6 +35,21 @@ func NewEvent(eventType, aggregateID string, data map[string]interface{}) *Event
 	}
 }
 
+// InvolvedUsers returns the users an event is about
+func InvolvedUsers(event *Event) map[int]bool {
+	users := make(map[int]bool)
+	for _, key := range []string{"user_id", "from_user_id", "to_user_id"} {
+		// JSON round trips turn numbers into float64
+		switch id := event.Data[key].(type) {
+		case int:
+			users[id] = true
+		case float64:
+			users[int(id)] = true
+		}
+	}
+	return users
+}
+
 // Event types
 const (
 	EventUserCreated          = "user.created"
@@ -38,14 +58,50 @@ const (
 	EventTransactionCompleted = "transaction.completed"
 	EventTransactionFailed    = "transaction.failed"
 	EventBalanceUpdated       = "balance.updated"
+	EventBalanceOpened        = "balance.opened"
+	EventBalanceCredited      = "balance.credited"
+	EventBalanceDebited       = "balance.debited"
+	EventBalanceTransferred   = "balance.transferred"
+	EventBalanceReversed      = "balance.reversed"
 )
 
+// ErrConcurrencyConflict is returned when an appended event reuses a version
+// that already exists for its aggregate
+var ErrConcurrencyConflict = errors.New("event version conflict")
+
+// DefaultPageSize is used when a Page has no limit set
+const DefaultPageSize = 100
+
+// Page selects a window of events from a query
+type Page struct {
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+}
+
+// normalize fills in defaults for an unset or invalid page
+func (p Page) normalize() Page {
+	if p.Limit <= 0 {
+		p.Limit = DefaultPageSize
+	}
+	if p.Offset < 0 {
+		p.Offset = 0
+	}
+	return p
+}
+
 // EventStore interface for storing and retrieving events
 type EventStore interface {
 	Append(events []*Event) error
 	GetEvents(aggregateID string) ([]*Event, error)
-	GetEventsByType(eventType string) ([]*Event, error)
-	GetEventsSince(timestamp time.Time) ([]*Event, error)
+	GetEventsAfter(aggregateID string, version int) ([]*Event, error)
+	GetEventsByType(eventType string, page Page) ([]*Event, error)
+	GetEventsSince(timestamp time.Time, page Page) ([]*Event, error)
+}
+
+// TxAppender is implemented by event stores that can append inside an
+// existing database transaction
+type TxAppender interface {
+	AppendTx(ctx context.Context, tx *sql.Tx, events []*Event) error
 }
 
 // EventPublisher interface for publishing events
@@ -54,11 +110,43 @@ type EventPublisher interface {
 	Subscribe(eventType string, handler func(*Event)) error
 }
 
+// namedSubscriber is implemented by publishers with durable named subscriptions
+type namedSubscriber interface {
+	SubscribeNamed(name, eventType string, handler EventHandler) error
+}
+
+// SubscribeHandler subscribes handler to eventType under name. Publishers with
+// durable subscriptions redeliver events the handler returns an error for;
+// other publishers log the error and move on.
+func SubscribeHandler(publisher EventPublisher, name, eventType string, handler EventHandler) error {
+	if named, ok := publisher.(namedSubscriber); ok {
+		return named.SubscribeNamed(name, eventType, handler)
+	}
+
+	return publisher.Subscribe(eventType, func(event *Event) {
+		if err := handler.Handle(event); err != nil {
+			logger.Error("Event handler failed", err, map[string]interface{}{
+				"subscriber": name,
+				"event_id":   event.ID,
+				"event_type": event.Type,
+			})
+		}
+	})
+}
+
 // EventHandler interface for handling events
 type EventHandler interface {
 	Handle(event *Event) error
 }
 
+// EventHandlerFunc adapts a function to the EventHandler interface
+type EventHandlerFunc func(event *Event) error
+
+// Handle calls f(event)
+func (f EventHandlerFunc) Handle(event *Event) error {
+	return f(event)
+}
+
 // EventBus combines EventStore and EventPublisher
 type EventBus struct {
 	store     EventStore
@@ -75,7 +163,9 @@ func NewEventBus(store EventStore, publisher EventPublisher) *EventBus {
 	}
 }
 
-// Publish publishes an event
+// Publish stores and then publishes an event. The two steps are not atomic, so
+// events produced by database writes should be recorded through the outbox
+// instead, which publishes them only after the write has committed.
 func (eb *EventBus) Publish(event *Event) error {
 	// Store the event
 	if err := eb.store.Append([]*Event{event}); err != nil {
@@ -112,7 +202,8 @@ func (eb *EventBus) handleEvent(event *Event) error {
 	return nil
 }
 
-// RebuildState rebuilds the state from event stream
+// RebuildState rebuilds the state from event stream. Aggregates with long
+// streams should load from a snapshot and replay only the events after it.
 func (eb *EventBus) RebuildState(aggregateID string, initialState interface{}, applyFunc func(interface{}, *Event) interface{}) (interface{}, error) {
 	events, err := eb.store.GetEvents(aggregateID)
 	if err != nil {
@@ -0,0 +1,148 @@
+package events
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"backend_path/pkg/logger"
+)
+
+var (
+	// ErrPublisherClosed is returned when publishing to or subscribing on a closed publisher
+	ErrPublisherClosed = errors.New("event publisher is closed")
+	// ErrPublishTimeout is returned when a subscriber's queue stayed full for
+	// publishTimeout
+	ErrPublishTimeout = errors.New("timed out queueing event for a subscriber")
+)
+
+// publishTimeout bounds how long Publish waits for room in a full
+// subscriber queue
+const publishTimeout = 5 * time.Second
+
+// InProcessPublisher delivers events to subscribers in the same process. It is
+// meant for single-node setups. Every subscriber has its own queue and
+// goroutine, so a slow subscriber does not delay the others.
+type InProcessPublisher struct {
+	subscribers map[string][]*inProcessSubscriber
+	queueSize   int
+	closed      bool
+	mu          sync.RWMutex
+	// sending counts Publish calls queueing events, which Close waits for
+	// before closing the queues
+	sending sync.WaitGroup
+	wg      sync.WaitGroup
+}
+
+// inProcessSubscriber is a single subscription with its own delivery queue
+type inProcessSubscriber struct {
+	eventType string
+	handler   func(*Event)
+	queue     chan *Event
+}
+
+// NewInProcessPublisher creates a new in-process publisher. queueSize is the
+// number of events buffered per subscriber before Publish waits for room.
+func NewInProcessPublisher(queueSize int) *InProcessPublisher {
+	return &InProcessPublisher{
+		subscribers: make(map[string][]*inProcessSubscriber),
+		queueSize:   queueSize,
+	}
+}
+
+// Publish queues the event for every subscriber of its type. If a queue
+// stays full for publishTimeout it fails with ErrPublishTimeout, so the
+// caller can retry later; subscribers queued before then get the event again.
+func (p *InProcessPublisher) Publish(event *Event) error {
+	p.mu.RLock()
+	if p.closed {
+		p.mu.RUnlock()
+		return ErrPublisherClosed
+	}
+	subs := append([]*inProcessSubscriber(nil), p.subscribers[event.Type]...)
+	p.sending.Add(1)
+	p.mu.RUnlock()
+	defer p.sending.Done()
+
+	timer := time.NewTimer(publishTimeout)
+	defer timer.Stop()
+
+	for _, sub := range subs {
+		select {
+		case sub.queue <- event:
+		case <-timer.C:
+			logger.Warn("Event subscriber queue is full", map[string]interface{}{
+				"event_id":   event.ID,
+				"event_type": event.Type,
+			})
+			return ErrPublishTimeout
+		}
+	}
+
+	return nil
+}
+
+// Subscribe registers handler for events of eventType
+func (p *InProcessPublisher) Subscribe(eventType string, handler func(*Event)) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return ErrPublisherClosed
+	}
+
+	sub := &inProcessSubscriber{
+		eventType: eventType,
+		handler:   handler,
+		queue:     make(chan *Event, p.queueSize),
+	}
+	p.subscribers[eventType] = append(p.subscribers[eventType], sub)
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		for event := range sub.queue {
+			sub.deliver(event)
+		}
+	}()
+
+	return nil
+}
+
+// Close stops accepting events and waits until queued events are delivered
+func (p *InProcessPublisher) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	p.mu.Unlock()
+
+	// No Publish starts once closed is set; wait for the ones queueing
+	p.sending.Wait()
+	for _, subs := range p.subscribers {
+		for _, sub := range subs {
+			close(sub.queue)
+		}
+	}
+
+	p.wg.Wait()
+	return nil
+}
+
+// deliver calls the handler, recovering from panics so one bad event does not
+// stop the subscriber
+func (s *inProcessSubscriber) deliver(event *Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("Event handler panicked", fmt.Errorf("%v", r), map[string]interface{}{
+				"event_id":   event.ID,
+				"event_type": s.eventType,
+			})
+		}
+	}()
+
+	s.handler(event)
+}
@@ -0,0 +1,363 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"backend_path/pkg/logger"
+	"backend_path/pkg/redis"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// RedisStreamConfig configures the Redis Streams publisher
+type RedisStreamConfig struct {
+	StreamPrefix  string        // Key prefix; each event type gets its own stream
+	Group         string        // Consumer group prefix shared by every instance of the service
+	Consumer      string        // Consumer name, unique per instance
+	MaxLen        int64         // Approximate stream length cap, 0 for unbounded
+	BatchSize     int64         // Entries read or reclaimed per call
+	Block         time.Duration // How long a read waits for new entries
+	ClaimInterval time.Duration // How often pending entries are checked
+	MinIdle       time.Duration // Pending entries idle longer than this are reclaimed
+	MaxDeliveries int64         // Deliveries before an entry is moved to the dead-letter stream
+}
+
+// DefaultRedisStreamConfig returns the default Redis Streams configuration
+func DefaultRedisStreamConfig() RedisStreamConfig {
+	hostname, _ := os.Hostname()
+	return RedisStreamConfig{
+		StreamPrefix:  "events:",
+		Group:         "gofintech",
+		Consumer:      fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+		MaxLen:        100000,
+		BatchSize:     50,
+		Block:         5 * time.Second,
+		ClaimInterval: 30 * time.Second,
+		MinIdle:       1 * time.Minute,
+		MaxDeliveries: 5,
+	}
+}
+
+// RedisStreamPublisher publishes events to Redis Streams so subscribers on
+// every API instance can consume them. Each named subscriber is a consumer
+// group with its own offset; instances using the same name share the work.
+type RedisStreamPublisher struct {
+	client *redis.RedisClient
+	config RedisStreamConfig
+	counts map[string]int
+	ctx    context.Context
+	cancel context.CancelFunc
+	mu     sync.Mutex
+	wg     sync.WaitGroup
+}
+
+// redisSubscription consumes one stream as one consumer group
+type redisSubscription struct {
+	publisher *RedisStreamPublisher
+	stream    string
+	group     string
+	handler   EventHandler
+}
+
+// NewRedisStreamPublisher creates a new Redis Streams publisher
+func NewRedisStreamPublisher(client *redis.RedisClient, config RedisStreamConfig) *RedisStreamPublisher {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &RedisStreamPublisher{
+		client: client,
+		config: config,
+		counts: make(map[string]int),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// streamKey returns the stream that holds events of eventType
+func (p *RedisStreamPublisher) streamKey(eventType string) string {
+	return p.config.StreamPrefix + eventType
+}
+
+// deadLetterKey returns the stream that holds undeliverable entries
+func (p *RedisStreamPublisher) deadLetterKey() string {
+	return p.config.StreamPrefix + "dead"
+}
+
+// Publish appends the event to the stream of its type
+func (p *RedisStreamPublisher) Publish(event *Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	err = p.client.XAdd(p.ctx, &goredis.XAddArgs{
+		Stream: p.streamKey(event.Type),
+		MaxLen: p.config.MaxLen,
+		Approx: true,
+		Values: map[string]interface{}{
+			"id":    event.ID,
+			"type":  event.Type,
+			"event": string(data),
+		},
+	}).Err()
+	if err != nil {
+		return fmt.Errorf("failed to publish event to redis: %w", err)
+	}
+
+	return nil
+}
+
+// Subscribe registers handler for events of eventType. Subscriptions are named
+// after their event type and registration order, so every instance must
+// subscribe in the same order. Entries are acknowledged once handler returns.
+func (p *RedisStreamPublisher) Subscribe(eventType string, handler func(*Event)) error {
+	p.mu.Lock()
+	n := p.counts[eventType]
+	p.counts[eventType]++
+	p.mu.Unlock()
+
+	name := eventType
+	if n > 0 {
+		name = fmt.Sprintf("%s#%d", eventType, n)
+	}
+
+	return p.SubscribeNamed(name, eventType, EventHandlerFunc(func(event *Event) error {
+		handler(event)
+		return nil
+	}))
+}
+
+// SubscribeNamed registers a durable subscriber. Entries are acknowledged only
+// when handler returns nil; failed entries stay pending and are redelivered
+// after MinIdle until MaxDeliveries is reached.
+func (p *RedisStreamPublisher) SubscribeNamed(name, eventType string, handler EventHandler) error {
+	sub := &redisSubscription{
+		publisher: p,
+		stream:    p.streamKey(eventType),
+		group:     p.config.Group + ":" + name,
+		handler:   handler,
+	}
+
+	// A new group starts at the end of the stream and keeps its own offset from then on
+	err := p.client.XGroupCreateMkStream(p.ctx, sub.stream, sub.group, "$").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("failed to create consumer group %s: %w", sub.group, err)
+	}
+
+	p.wg.Add(2)
+	go func() {
+		defer p.wg.Done()
+		sub.consume()
+	}()
+	go func() {
+		defer p.wg.Done()
+		sub.reclaim()
+	}()
+
+	logger.Info("Subscribed to event stream", map[string]interface{}{
+		"stream":   sub.stream,
+		"group":    sub.group,
+		"consumer": p.config.Consumer,
+	})
+
+	return nil
+}
+
+// Close stops all subscriptions and waits for in-flight handlers to return
+func (p *RedisStreamPublisher) Close() error {
+	p.cancel()
+	p.wg.Wait()
+	return nil
+}
+
+// consume reads new entries for the group until the publisher is closed
+func (s *redisSubscription) consume() {
+	p := s.publisher
+
+	for p.ctx.Err() == nil {
+		streams, err := p.client.XReadGroup(p.ctx, &goredis.XReadGroupArgs{
+			Group:    s.group,
+			Consumer: p.config.Consumer,
+			Streams:  []string{s.stream, ">"},
+			Count:    p.config.BatchSize,
+			Block:    p.config.Block,
+		}).Result()
+		if err != nil {
+			if errors.Is(err, goredis.Nil) || p.ctx.Err() != nil {
+				continue
+			}
+			logger.Error("Failed to read event stream", err, map[string]interface{}{
+				"stream": s.stream,
+				"group":  s.group,
+			})
+			s.sleep(time.Second)
+			continue
+		}
+
+		for _, stream := range streams {
+			for _, msg := range stream.Messages {
+				s.process(msg)
+			}
+		}
+	}
+}
+
+// reclaim periodically takes over entries that another consumer read but did
+// not acknowledge, for example because its instance crashed
+func (s *redisSubscription) reclaim() {
+	p := s.publisher
+	ticker := time.NewTicker(p.config.ClaimInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.reclaimPending(); err != nil && p.ctx.Err() == nil {
+				logger.Error("Failed to reclaim pending events", err, map[string]interface{}{
+					"stream": s.stream,
+					"group":  s.group,
+				})
+			}
+		}
+	}
+}
+
+// reclaimPending claims idle pending entries and processes them again
+func (s *redisSubscription) reclaimPending() error {
+	p := s.publisher
+
+	pending, err := p.client.XPendingExt(p.ctx, &goredis.XPendingExtArgs{
+		Stream: s.stream,
+		Group:  s.group,
+		Idle:   p.config.MinIdle,
+		Start:  "-",
+		End:    "+",
+		Count:  p.config.BatchSize,
+	}).Result()
+	if err != nil {
+		return err
+	}
+
+	var ids []string
+	for _, entry := range pending {
+		if entry.RetryCount >= p.config.MaxDeliveries {
+			s.deadLetter(entry.ID, fmt.Sprintf("exceeded %d deliveries", p.config.MaxDeliveries))
+			continue
+		}
+		ids = append(ids, entry.ID)
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	messages, err := p.client.XClaim(p.ctx, &goredis.XClaimArgs{
+		Stream:   s.stream,
+		Group:    s.group,
+		Consumer: p.config.Consumer,
+		MinIdle:  p.config.MinIdle,
+		Messages: ids,
+	}).Result()
+	if err != nil {
+		return err
+	}
+
+	for _, msg := range messages {
+		s.process(msg)
+	}
+
+	return nil
+}
+
+// process hands one entry to the handler and acknowledges it on success
+func (s *redisSubscription) process(msg goredis.XMessage) {
+	payload, _ := msg.Values["event"].(string)
+
+	var event Event
+	if err := json.Unmarshal([]byte(payload), &event); err != nil {
+		s.deadLetter(msg.ID, "invalid event payload")
+		return
+	}
+
+	if err := s.handle(&event); err != nil {
+		// Left pending; reclaim will redeliver it
+		logger.Error("Event handler failed", err, map[string]interface{}{
+			"event_id":   event.ID,
+			"event_type": event.Type,
+			"group":      s.group,
+		})
+		return
+	}
+
+	s.ack(msg.ID)
+}
+
+// handle calls the handler, converting a panic into an error
+func (s *redisSubscription) handle(event *Event) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("event handler panicked: %v", r)
+		}
+	}()
+
+	return s.handler.Handle(event)
+}
+
+// ack acknowledges an entry for the group
+func (s *redisSubscription) ack(id string) {
+	p := s.publisher
+	if err := p.client.XAck(p.ctx, s.stream, s.group, id).Err(); err != nil {
+		logger.Error("Failed to acknowledge event", err, map[string]interface{}{
+			"stream":   s.stream,
+			"group":    s.group,
+			"entry_id": id,
+		})
+	}
+}
+
+// deadLetter copies an entry to the dead-letter stream and acknowledges it so
+// it is not delivered again
+func (s *redisSubscription) deadLetter(id, reason string) {
+	p := s.publisher
+
+	values := map[string]interface{}{
+		"stream":   s.stream,
+		"group":    s.group,
+		"entry_id": id,
+		"reason":   reason,
+	}
+	if messages, err := p.client.XRangeN(p.ctx, s.stream, id, id, 1).Result(); err == nil && len(messages) > 0 {
+		if payload, ok := messages[0].Values["event"].(string); ok {
+			values["event"] = payload
+		}
+	}
+
+	err := p.client.XAdd(p.ctx, &goredis.XAddArgs{
+		Stream: p.deadLetterKey(),
+		MaxLen: p.config.MaxLen,
+		Approx: true,
+		Values: values,
+	}).Err()
+	if err != nil {
+		logger.Error("Failed to dead-letter event", err, values)
+		return
+	}
+
+	logger.Warn("Event moved to dead-letter stream", values)
+	s.ack(id)
+}
+
+// sleep waits for d or until the publisher is closed
+func (s *redisSubscription) sleep(d time.Duration) {
+	select {
+	case <-s.publisher.ctx.Done():
+	case <-time.After(d):
+	}
+}
@@ -0,0 +1,120 @@
+package events
+
+import (
+	"time"
+)
+
+// ReplayFilter selects the events to replay. Zero fields match everything.
+type ReplayFilter struct {
+	AggregateID string
+	Types       []string
+	Since       time.Time
+	Until       time.Time
+}
+
+// Matches reports whether event is selected by the filter
+func (f ReplayFilter) Matches(event *Event) bool {
+	if f.AggregateID != "" && event.AggregateID != f.AggregateID {
+		return false
+	}
+	if len(f.Types) > 0 && !containsType(f.Types, event.Type) {
+		return false
+	}
+	if !f.Since.IsZero() && event.Timestamp.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && event.Timestamp.After(f.Until) {
+		return false
+	}
+	return true
+}
+
+func containsType(types []string, eventType string) bool {
+	for _, t := range types {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Replay reads the events selected by filter from store and passes them to fn
+// in batches of up to batchSize. Events of a single aggregate are replayed in
+// version order; otherwise events are replayed in the order they occurred,
+// except for a single type without a time range, which follows append order.
+func Replay(store EventStore, filter ReplayFilter, batchSize int, fn func(batch []*Event) error) error {
+	if batchSize <= 0 {
+		batchSize = DefaultPageSize
+	}
+
+	if filter.AggregateID != "" {
+		stream, err := store.GetEvents(filter.AggregateID)
+		if err != nil {
+			return err
+		}
+		return replayBatches(stream, filter, batchSize, fn)
+	}
+
+	fetch := func(page Page) ([]*Event, error) {
+		return store.GetEventsSince(filter.Since, page)
+	}
+	if len(filter.Types) == 1 && filter.Since.IsZero() && filter.Until.IsZero() {
+		fetch = func(page Page) ([]*Event, error) {
+			return store.GetEventsByType(filter.Types[0], page)
+		}
+	}
+
+	page := Page{Limit: batchSize}
+	for {
+		batch, err := fetch(page)
+		if err != nil {
+			return err
+		}
+
+		selected := make([]*Event, 0, len(batch))
+		done := len(batch) < page.Limit
+		for _, event := range batch {
+			// Events are ordered by time here, so nothing later can match
+			if !filter.Until.IsZero() && event.Timestamp.After(filter.Until) {
+				done = true
+				break
+			}
+			if filter.Matches(event) {
+				selected = append(selected, event)
+			}
+		}
+
+		if len(selected) > 0 {
+			if err := fn(selected); err != nil {
+				return err
+			}
+		}
+
+		if done {
+			return nil
+		}
+		page.Offset += page.Limit
+	}
+}
+
+// replayBatches passes the matching events of an in-memory stream to fn in batches
+func replayBatches(stream []*Event, filter ReplayFilter, batchSize int, fn func(batch []*Event) error) error {
+	batch := make([]*Event, 0, batchSize)
+	for _, event := range stream {
+		if !filter.Matches(event) {
+			continue
+		}
+		batch = append(batch, event)
+		if len(batch) == batchSize {
+			if err := fn(batch); err != nil {
+				return err
+			}
+			batch = make([]*Event, 0, batchSize)
+		}
+	}
+
+	if len(batch) > 0 {
+		return fn(batch)
+	}
+	return nil
+}
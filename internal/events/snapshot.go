@@ -0,0 +1,112 @@
+package events
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Snapshot captures the state of an aggregate at a version so that it can be
+// rebuilt without replaying its whole event stream
+type Snapshot struct {
+	AggregateID string          `json:"aggregate_id"`
+	Version     int             `json:"version"`
+	State       json.RawMessage `json:"state"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// SnapshotStore keeps the latest snapshot of each aggregate
+type SnapshotStore interface {
+	// GetSnapshot returns the latest snapshot of an aggregate, or nil if it has none
+	GetSnapshot(aggregateID string) (*Snapshot, error)
+	// SaveSnapshotTx replaces the snapshot of an aggregate inside tx so that it
+	// is only kept if the events it covers are committed
+	SaveSnapshotTx(ctx context.Context, tx *sql.Tx, snapshot *Snapshot) error
+}
+
+// SQLSnapshotStore implements SnapshotStore for SQL Server
+type SQLSnapshotStore struct {
+	db *sql.DB
+}
+
+// NewSQLSnapshotStore creates a new SQL snapshot store
+func NewSQLSnapshotStore(db *sql.DB) *SQLSnapshotStore {
+	return &SQLSnapshotStore{db: db}
+}
+
+// GetSnapshot returns the latest snapshot of an aggregate, or nil if it has none
+func (s *SQLSnapshotStore) GetSnapshot(aggregateID string) (*Snapshot, error) {
+	query := `
+		SELECT aggregate_id, version, state, created_at
+		FROM snapshots
+		WHERE aggregate_id = ?
+	`
+
+	snapshot := &Snapshot{}
+	var state string
+	err := s.db.QueryRow(query, aggregateID).Scan(
+		&snapshot.AggregateID,
+		&snapshot.Version,
+		&state,
+		&snapshot.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get snapshot: %w", err)
+	}
+
+	snapshot.State = json.RawMessage(state)
+	return snapshot, nil
+}
+
+// SaveSnapshotTx replaces the snapshot of an aggregate inside tx. An older
+// snapshot never overwrites a newer one.
+func (s *SQLSnapshotStore) SaveSnapshotTx(ctx context.Context, tx *sql.Tx, snapshot *Snapshot) error {
+	updateQuery := `
+		UPDATE snapshots
+		SET version = ?, state = ?, created_at = ?
+		WHERE aggregate_id = ? AND version < ?
+	`
+
+	result, err := tx.ExecContext(ctx, updateQuery,
+		snapshot.Version,
+		string(snapshot.State),
+		snapshot.CreatedAt,
+		snapshot.AggregateID,
+		snapshot.Version,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update snapshot: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected > 0 {
+		return nil
+	}
+
+	insertQuery := `
+		INSERT INTO snapshots (aggregate_id, version, state, created_at)
+		SELECT ?, ?, ?, ?
+		WHERE NOT EXISTS (SELECT 1 FROM snapshots WHERE aggregate_id = ?)
+	`
+
+	_, err = tx.ExecContext(ctx, insertQuery,
+		snapshot.AggregateID,
+		snapshot.Version,
+		string(snapshot.State),
+		snapshot.CreatedAt,
+		snapshot.AggregateID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert snapshot: %w", err)
+	}
+
+	return nil
+}
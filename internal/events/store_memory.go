@@ -0,0 +1,139 @@
+package events
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryEventStore is an in-memory EventStore for tests and local development.
+// It enforces the same (aggregate_id, version) uniqueness as the SQL store.
+type MemoryEventStore struct {
+	events     []*Event
+	aggregates map[string][]*Event
+	mu         sync.RWMutex
+}
+
+// NewMemoryEventStore creates a new in-memory event store
+func NewMemoryEventStore() *MemoryEventStore {
+	return &MemoryEventStore{
+		aggregates: make(map[string][]*Event),
+	}
+}
+
+// Append appends events atomically; either all events are stored or none are
+func (s *MemoryEventStore) Append(events []*Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Check every version before storing anything
+	pending := make(map[string]bool)
+	for _, event := range events {
+		key := fmt.Sprintf("%s:%d", event.AggregateID, event.Version)
+		if pending[key] || s.hasVersion(event.AggregateID, event.Version) {
+			return fmt.Errorf("%w: aggregate %s version %d", ErrConcurrencyConflict, event.AggregateID, event.Version)
+		}
+		pending[key] = true
+	}
+
+	for _, event := range events {
+		s.events = append(s.events, event)
+
+		stream := append(s.aggregates[event.AggregateID], event)
+		sort.SliceStable(stream, func(i, j int) bool {
+			return stream[i].Version < stream[j].Version
+		})
+		s.aggregates[event.AggregateID] = stream
+	}
+
+	return nil
+}
+
+// hasVersion reports whether an aggregate already has an event with version
+func (s *MemoryEventStore) hasVersion(aggregateID string, version int) bool {
+	for _, event := range s.aggregates[aggregateID] {
+		if event.Version == version {
+			return true
+		}
+	}
+	return false
+}
+
+// GetEvents returns the full event stream of an aggregate ordered by version
+func (s *MemoryEventStore) GetEvents(aggregateID string) ([]*Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stream := s.aggregates[aggregateID]
+	result := make([]*Event, len(stream))
+	copy(result, stream)
+	return result, nil
+}
+
+// GetEventsAfter returns the events of an aggregate with a version greater than
+// version, ordered by version
+func (s *MemoryEventStore) GetEventsAfter(aggregateID string, version int) ([]*Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []*Event
+	for _, event := range s.aggregates[aggregateID] {
+		if event.Version > version {
+			result = append(result, event)
+		}
+	}
+	return result, nil
+}
+
+// GetEventsByType returns a page of events of the given type in append order
+func (s *MemoryEventStore) GetEventsByType(eventType string, page Page) ([]*Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var matched []*Event
+	for _, event := range s.events {
+		if event.Type == eventType {
+			matched = append(matched, event)
+		}
+	}
+
+	return paginate(matched, page), nil
+}
+
+// GetEventsSince returns a page of events that occurred at or after timestamp
+func (s *MemoryEventStore) GetEventsSince(timestamp time.Time, page Page) ([]*Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var matched []*Event
+	for _, event := range s.events {
+		if !event.Timestamp.Before(timestamp) {
+			matched = append(matched, event)
+		}
+	}
+
+	// Stable sort keeps append order for equal timestamps, like the SQL store
+	sort.SliceStable(matched, func(i, j int) bool {
+		return matched[i].Timestamp.Before(matched[j].Timestamp)
+	})
+
+	return paginate(matched, page), nil
+}
+
+// paginate returns the window of events selected by page
+func paginate(events []*Event, page Page) []*Event {
+	page = page.normalize()
+	if page.Offset >= len(events) {
+		return []*Event{}
+	}
+
+	end := page.Offset + page.Limit
+	if end > len(events) {
+		end = len(events)
+	}
+
+	result := make([]*Event, end-page.Offset)
+	copy(result, events[page.Offset:end])
+	return result
+}
@@ -0,0 +1,180 @@
+package events
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"backend_path/pkg/database"
+)
+
+// SQLEventStore implements EventStore for SQL Server
+type SQLEventStore struct {
+	db *sql.DB
+}
+
+// NewSQLEventStore creates a new SQL event store
+func NewSQLEventStore(db *sql.DB) *SQLEventStore {
+	return &SQLEventStore{db: db}
+}
+
+// Append appends events in their own database transaction
+func (s *SQLEventStore) Append(events []*Event) error {
+	ctx := context.Background()
+	return database.WithTransaction(ctx, s.db, func(tx *sql.Tx) error {
+		return s.AppendTx(ctx, tx, events)
+	})
+}
+
+// AppendTx appends events using an existing database transaction so that they
+// are committed atomically with the business write that produced them
+func (s *SQLEventStore) AppendTx(ctx context.Context, tx *sql.Tx, events []*Event) error {
+	query := `
+		INSERT INTO events (id, type, aggregate_id, version, data, metadata, occurred_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+
+	for _, event := range events {
+		data, err := json.Marshal(event.Data)
+		if err != nil {
+			return fmt.Errorf("failed to marshal event data: %w", err)
+		}
+
+		metadata, err := json.Marshal(event.Metadata)
+		if err != nil {
+			return fmt.Errorf("failed to marshal event metadata: %w", err)
+		}
+
+		_, err = tx.ExecContext(ctx,
+			query,
+			event.ID,
+			event.Type,
+			event.AggregateID,
+			event.Version,
+			string(data),
+			string(metadata),
+			event.Timestamp,
+		)
+		if err != nil {
+			if database.IsUniqueViolation(err) {
+				return fmt.Errorf("%w: aggregate %s version %d", ErrConcurrencyConflict, event.AggregateID, event.Version)
+			}
+			return fmt.Errorf("failed to append event: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// GetEvents returns the full event stream of an aggregate ordered by version
+func (s *SQLEventStore) GetEvents(aggregateID string) ([]*Event, error) {
+	query := `
+		SELECT id, type, aggregate_id, version, data, metadata, occurred_at
+		FROM events
+		WHERE aggregate_id = ?
+		ORDER BY version
+	`
+
+	return s.query(query, aggregateID)
+}
+
+// GetEventsAfter returns the events of an aggregate with a version greater than
+// version, ordered by version
+func (s *SQLEventStore) GetEventsAfter(aggregateID string, version int) ([]*Event, error) {
+	query := `
+		SELECT id, type, aggregate_id, version, data, metadata, occurred_at
+		FROM events
+		WHERE aggregate_id = ? AND version > ?
+		ORDER BY version
+	`
+
+	return s.query(query, aggregateID, version)
+}
+
+// GetEventsByType returns a page of events of the given type in append order
+func (s *SQLEventStore) GetEventsByType(eventType string, page Page) ([]*Event, error) {
+	page = page.normalize()
+	query := `
+		SELECT id, type, aggregate_id, version, data, metadata, occurred_at
+		FROM events
+		WHERE type = ?
+		ORDER BY sequence
+		OFFSET ? ROWS FETCH NEXT ? ROWS ONLY
+	`
+
+	return s.query(query, eventType, page.Offset, page.Limit)
+}
+
+// GetEventsSince returns a page of events that occurred at or after timestamp
+func (s *SQLEventStore) GetEventsSince(timestamp time.Time, page Page) ([]*Event, error) {
+	page = page.normalize()
+	query := `
+		SELECT id, type, aggregate_id, version, data, metadata, occurred_at
+		FROM events
+		WHERE occurred_at >= ?
+		ORDER BY occurred_at, sequence
+		OFFSET ? ROWS FETCH NEXT ? ROWS ONLY
+	`
+
+	return s.query(query, timestamp, page.Offset, page.Limit)
+}
+
+// query runs an event query and scans the resulting rows
+func (s *SQLEventStore) query(query string, args ...interface{}) ([]*Event, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*Event
+	for rows.Next() {
+		event, err := scanEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating events: %w", err)
+	}
+
+	return events, nil
+}
+
+// scanEvent scans a single event row
+func scanEvent(rows *sql.Rows) (*Event, error) {
+	event := &Event{}
+	var data, metadata sql.NullString
+
+	err := rows.Scan(
+		&event.ID,
+		&event.Type,
+		&event.AggregateID,
+		&event.Version,
+		&data,
+		&metadata,
+		&event.Timestamp,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan event: %w", err)
+	}
+
+	if data.Valid && data.String != "" {
+		if err := json.Unmarshal([]byte(data.String), &event.Data); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal event data: %w", err)
+		}
+	}
+
+	event.Metadata = make(map[string]interface{})
+	if metadata.Valid && metadata.String != "" {
+		if err := json.Unmarshal([]byte(metadata.String), &event.Metadata); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal event metadata: %w", err)
+		}
+	}
+
+	return event, nil
+}
@@ -0,0 +1,222 @@
+package lockout
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"backend_path/internal/metrics"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	failuresKeyPrefix = "auth:failures:"
+	delayKeyPrefix    = "auth:delay:"
+	lockKeyPrefix     = "auth:lock:"
+)
+
+// Scopes failed logins are counted in
+const (
+	ScopeEmail = "email"
+	ScopeIP    = "ip"
+)
+
+// Config tunes brute-force protection
+type Config struct {
+	// MaxFailures locks an email after this many failures within Window
+	MaxFailures int
+	// MaxIPFailures locks an IP address after this many failures within
+	// Window, across every email tried from it
+	MaxIPFailures int
+	Window        time.Duration
+	// LockoutDuration is how long a lock lasts unless an admin lifts it
+	LockoutDuration time.Duration
+	// BaseDelay is the wait imposed after the second failure; it doubles
+	// with every further failure up to MaxDelay
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// DefaultConfig returns the default brute-force protection settings
+func DefaultConfig() Config {
+	return Config{
+		MaxFailures:     5,
+		MaxIPFailures:   20,
+		Window:          15 * time.Minute,
+		LockoutDuration: 15 * time.Minute,
+		BaseDelay:       time.Second,
+		MaxDelay:        30 * time.Second,
+	}
+}
+
+// Block tells why a login attempt is refused and when it may be retried
+type Block struct {
+	// Locked is true for a lockout, false for a progressive delay
+	Locked     bool
+	Scope      string
+	RetryAfter time.Duration
+}
+
+// Result reports the lockouts a failure caused
+type Result struct {
+	EmailLocked bool
+	IPLocked    bool
+}
+
+// Guard counts failed logins per email and per IP address in Redis, delays
+// further attempts progressively and locks them out after too many
+type Guard struct {
+	client *redis.Client
+	config Config
+}
+
+// NewGuard creates a guard backed by Redis
+func NewGuard(client *redis.Client, config Config) *Guard {
+	return &Guard{
+		client: client,
+		config: config,
+	}
+}
+
+// Check returns a Block if login attempts for email or from ip are currently
+// refused, or nil if they are allowed
+func (g *Guard) Check(ctx context.Context, email, ip string) (*Block, error) {
+	email = normalizeEmail(email)
+
+	pipe := g.client.Pipeline()
+	emailLock := pipe.PTTL(ctx, key(lockKeyPrefix, ScopeEmail, email))
+	ipLock := pipe.PTTL(ctx, key(lockKeyPrefix, ScopeIP, ip))
+	emailDelay := pipe.PTTL(ctx, key(delayKeyPrefix, ScopeEmail, email))
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("failed to check login lockout: %w", err)
+	}
+
+	// PTTL is negative for missing keys
+	switch {
+	case emailLock.Val() > 0:
+		return &Block{Locked: true, Scope: ScopeEmail, RetryAfter: emailLock.Val()}, nil
+	case ipLock.Val() > 0:
+		return &Block{Locked: true, Scope: ScopeIP, RetryAfter: ipLock.Val()}, nil
+	case emailDelay.Val() > 0:
+		return &Block{Scope: ScopeEmail, RetryAfter: emailDelay.Val()}, nil
+	}
+
+	return nil, nil
+}
+
+// RecordFailure counts a failed login for email from ip, imposing a delay on
+// the next attempt and locking the email or IP address once it reaches its
+// limit
+func (g *Guard) RecordFailure(ctx context.Context, email, ip string) (*Result, error) {
+	email = normalizeEmail(email)
+	result := &Result{}
+
+	emailFailures, err := g.increment(ctx, key(failuresKeyPrefix, ScopeEmail, email))
+	if err != nil {
+		return nil, err
+	}
+	ipFailures, err := g.increment(ctx, key(failuresKeyPrefix, ScopeIP, ip))
+	if err != nil {
+		return nil, err
+	}
+
+	if emailFailures >= int64(g.config.MaxFailures) {
+		if err := g.lock(ctx, ScopeEmail, email); err != nil {
+			return nil, err
+		}
+		result.EmailLocked = true
+	} else if delay := g.delay(emailFailures); delay > 0 {
+		if err := g.client.Set(ctx, key(delayKeyPrefix, ScopeEmail, email), 1, delay).Err(); err != nil {
+			return nil, fmt.Errorf("failed to delay logins: %w", err)
+		}
+	}
+
+	if ipFailures >= int64(g.config.MaxIPFailures) {
+		if err := g.lock(ctx, ScopeIP, ip); err != nil {
+			return nil, err
+		}
+		result.IPLocked = true
+	}
+
+	return result, nil
+}
+
+// RecordSuccess clears the failures of email after a successful login.
+// Failures from the IP address keep counting.
+func (g *Guard) RecordSuccess(ctx context.Context, email string) error {
+	return g.clear(ctx, ScopeEmail, normalizeEmail(email))
+}
+
+// Unlock lifts the lockout of email and forgets its failures
+func (g *Guard) Unlock(ctx context.Context, email string) error {
+	return g.clear(ctx, ScopeEmail, normalizeEmail(email))
+}
+
+// UnlockIP lifts the lockout of ip and forgets its failures
+func (g *Guard) UnlockIP(ctx context.Context, ip string) error {
+	return g.clear(ctx, ScopeIP, ip)
+}
+
+// increment counts a failure in the window that started with the first one
+func (g *Guard) increment(ctx context.Context, counterKey string) (int64, error) {
+	count, err := g.client.Incr(ctx, counterKey).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count login failure: %w", err)
+	}
+	if count == 1 {
+		if err := g.client.Expire(ctx, counterKey, g.config.Window).Err(); err != nil {
+			return 0, fmt.Errorf("failed to count login failure: %w", err)
+		}
+	}
+	return count, nil
+}
+
+// lock locks value out and restarts its failure count
+func (g *Guard) lock(ctx context.Context, scope, value string) error {
+	pipe := g.client.TxPipeline()
+	pipe.Set(ctx, key(lockKeyPrefix, scope, value), 1, g.config.LockoutDuration)
+	pipe.Del(ctx, key(failuresKeyPrefix, scope, value), key(delayKeyPrefix, scope, value))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to lock out logins: %w", err)
+	}
+
+	metrics.LoginLockoutsTotal.WithLabelValues(scope).Inc()
+	return nil
+}
+
+func (g *Guard) clear(ctx context.Context, scope, value string) error {
+	err := g.client.Del(ctx,
+		key(lockKeyPrefix, scope, value),
+		key(failuresKeyPrefix, scope, value),
+		key(delayKeyPrefix, scope, value),
+	).Err()
+	if err != nil {
+		return fmt.Errorf("failed to clear login failures: %w", err)
+	}
+	return nil
+}
+
+// delay returns the wait imposed after failures consecutive failures
+func (g *Guard) delay(failures int64) time.Duration {
+	if failures < 2 || g.config.BaseDelay <= 0 {
+		return 0
+	}
+	delay := g.config.BaseDelay
+	for i := int64(2); i < failures && delay < g.config.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > g.config.MaxDelay {
+		delay = g.config.MaxDelay
+	}
+	return delay
+}
+
+func key(prefix, scope, value string) string {
+	return prefix + scope + ":" + value
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
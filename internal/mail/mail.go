@@ -0,0 +1,34 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// ErrInvalidMessage is returned for messages without a recipient or with
+// header values that could inject further headers
+var ErrInvalidMessage = errors.New("invalid mail message")
+
+// Message is a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender delivers email. Implementations must be safe for concurrent use.
+type Sender interface {
+	Send(ctx context.Context, message *Message) error
+}
+
+// validate rejects messages that cannot be sent safely
+func (m *Message) validate() error {
+	if m.To == "" {
+		return ErrInvalidMessage
+	}
+	if strings.ContainsAny(m.To, "\r\n") || strings.ContainsAny(m.Subject, "\r\n") {
+		return ErrInvalidMessage
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	netmail "net/mail"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+// SMTPConfig holds the SMTP server settings
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// SMTPSender delivers email through an SMTP server, upgrading the connection
+// with STARTTLS when the server offers it
+type SMTPSender struct {
+	config SMTPConfig
+}
+
+// NewSMTPSender creates a sender for the SMTP server in config
+func NewSMTPSender(config SMTPConfig) *SMTPSender {
+	return &SMTPSender{config: config}
+}
+
+func (s *SMTPSender) Send(ctx context.Context, message *Message) error {
+	if err := message.validate(); err != nil {
+		return err
+	}
+
+	addr := net.JoinHostPort(s.config.Host, strconv.Itoa(s.config.Port))
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to smtp server: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, s.config.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to connect to smtp server: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: s.config.Host}); err != nil {
+			return fmt.Errorf("failed to start tls: %w", err)
+		}
+	}
+	if s.config.Username != "" {
+		auth := smtp.PlainAuth("", s.config.Username, s.config.Password, s.config.Host)
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("failed to authenticate with smtp server: %w", err)
+		}
+	}
+
+	// From may carry a display name, which the envelope does not
+	envelopeFrom := s.config.From
+	if address, err := netmail.ParseAddress(s.config.From); err == nil {
+		envelopeFrom = address.Address
+	}
+	if err := client.Mail(envelopeFrom); err != nil {
+		return fmt.Errorf("failed to send mail: %w", err)
+	}
+	if err := client.Rcpt(message.To); err != nil {
+		return fmt.Errorf("failed to send mail: %w", err)
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("failed to send mail: %w", err)
+	}
+	if _, err := writer.Write(format(s.config.From, message)); err != nil {
+		writer.Close()
+		return fmt.Errorf("failed to send mail: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to send mail: %w", err)
+	}
+
+	return client.Quit()
+}
+
+// format renders message as an RFC 5322 message
+func format(from string, message *Message) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", message.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", message.Subject)
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(message.Body)
+	return buf.Bytes()
+}
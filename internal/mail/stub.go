@@ -0,0 +1,63 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"backend_path/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+// LogSender writes email to the application log instead of sending it. It
+// is meant for local development only: links in the body end up in the log.
+type LogSender struct{}
+
+// NewLogSender creates a sender that logs email
+func NewLogSender() *LogSender {
+	return &LogSender{}
+}
+
+func (s *LogSender) Send(ctx context.Context, message *Message) error {
+	if err := message.validate(); err != nil {
+		return err
+	}
+
+	logger.Info("Mail sent to log", map[string]interface{}{
+		"to":      message.To,
+		"subject": message.Subject,
+		"body":    message.Body,
+	})
+	return nil
+}
+
+// FileSender writes each email to a .eml file in a directory instead of
+// sending it, for local development
+type FileSender struct {
+	dir  string
+	from string
+}
+
+// NewFileSender creates a sender that writes email from from into dir,
+// creating dir if needed
+func NewFileSender(dir, from string) (*FileSender, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create mail directory: %w", err)
+	}
+	return &FileSender{dir: dir, from: from}, nil
+}
+
+func (s *FileSender) Send(ctx context.Context, message *Message) error {
+	if err := message.validate(); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405"), uuid.New().String())
+	if err := os.WriteFile(filepath.Join(s.dir, name), format(s.from, message), 0o600); err != nil {
+		return fmt.Errorf("failed to write mail: %w", err)
+	}
+	return nil
+}
@@ -29,4 +29,112 @@ var (
 			Help: "Current number of HTTP requests being processed",
 		},
 	)
-) 
\ No newline at end of file
+
+	OutboxPendingMessages = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "outbox_pending_messages",
+			Help: "Number of outbox messages waiting to be published",
+		},
+	)
+
+	OutboxLagSeconds = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "outbox_lag_seconds",
+			Help: "Age of the oldest pending outbox message",
+		},
+	)
+
+	OutboxDeadMessages = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "outbox_dead_messages",
+			Help: "Number of outbox messages in the dead-letter state",
+		},
+	)
+
+	OutboxPublishedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "outbox_published_total",
+			Help: "Total number of outbox messages published",
+		},
+		[]string{"event_type"},
+	)
+
+	OutboxPublishFailuresTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "outbox_publish_failures_total",
+			Help: "Total number of failed outbox publish attempts",
+		},
+		[]string{"event_type"},
+	)
+
+	OutboxDeadLetteredTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "outbox_dead_lettered_total",
+			Help: "Total number of outbox messages moved to the dead-letter state",
+		},
+		[]string{"event_type"},
+	)
+
+	OutboxDeliveryDelay = promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "outbox_delivery_delay_seconds",
+			Help:    "Time between an event being recorded and published",
+			Buckets: prometheus.DefBuckets,
+		},
+	)
+
+	WebhookDeliveryAttemptsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "webhook_delivery_attempts_total",
+			Help: "Total number of webhook delivery attempts",
+		},
+		[]string{"event_type", "result"},
+	)
+
+	WebhookDeliveryDuration = promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "webhook_delivery_duration_seconds",
+			Help:    "Duration of webhook HTTP requests",
+			Buckets: prometheus.DefBuckets,
+		},
+	)
+
+	StreamConnectionsActive = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "stream_connections_active",
+			Help: "Number of open real-time stream connections",
+		},
+		[]string{"transport"},
+	)
+
+	StreamMessagesSentTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "stream_messages_sent_total",
+			Help: "Total number of messages written to real-time stream connections",
+		},
+		[]string{"event_type"},
+	)
+
+	StreamSlowConsumersTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "stream_slow_consumers_total",
+			Help: "Total number of stream connections closed because they fell behind",
+		},
+	)
+
+	LoginFailuresTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "auth_login_failures_total",
+			Help: "Total number of failed or refused login attempts",
+		},
+		[]string{"reason"},
+	)
+
+	LoginLockoutsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "auth_login_lockouts_total",
+			Help: "Total number of login lockouts",
+		},
+		[]string{"scope"},
+	)
+)
@@ -0,0 +1,59 @@
+package mfa
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"backend_path/internal/domain"
+)
+
+var (
+	// ErrNotEnabled is returned when verifying a code for a user without
+	// confirmed two-factor authentication
+	ErrNotEnabled = errors.New("two-factor authentication is not enabled")
+	// ErrNotEnrolled is returned when confirming without a pending enrollment
+	ErrNotEnrolled = errors.New("no pending two-factor enrollment")
+	// ErrAlreadyEnabled is returned when enrolling a user who already has
+	// two-factor authentication
+	ErrAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	// ErrInvalidCode is returned for wrong, expired or already used codes
+	ErrInvalidCode = errors.New("invalid two-factor code")
+)
+
+// Enrollment is a user's TOTP secret. It takes effect once confirmed with a
+// code from the authenticator app.
+type Enrollment struct {
+	UserID int
+	// Secret is the encrypted base32 TOTP secret
+	Secret      string
+	ConfirmedAt *time.Time
+	// LastUsedStep is the time step of the last accepted code, which cannot
+	// be used again
+	LastUsedStep int64
+	CreatedAt    time.Time
+}
+
+// Provisioning is what an authenticator app needs to add an account. URI is
+// the otpauth:// URI to render as a QR code.
+type Provisioning struct {
+	Secret string
+	URI    string
+}
+
+// MFAService manages TOTP two-factor authentication and recovery codes
+type MFAService interface {
+	// Enroll starts enrollment for user, replacing any pending one
+	Enroll(ctx context.Context, user *domain.User) (*Provisioning, error)
+	// Confirm enables two-factor authentication with a code from the pending
+	// enrollment and returns a new set of recovery codes
+	Confirm(ctx context.Context, userID int, code string) ([]string, error)
+	// Verify checks a TOTP code or an unused recovery code. Either can only
+	// be used once.
+	Verify(ctx context.Context, userID int, code string) error
+	IsEnabled(userID int) (bool, error)
+	// Disable turns two-factor authentication off after verifying code
+	Disable(ctx context.Context, userID int, code string) error
+	// RegenerateRecoveryCodes replaces the recovery codes after verifying code
+	RegenerateRecoveryCodes(ctx context.Context, userID int, code string) ([]string, error)
+}
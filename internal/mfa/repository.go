@@ -0,0 +1,29 @@
+package mfa
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Repository stores TOTP enrollments and recovery codes
+type Repository interface {
+	// GetEnrollment returns the enrollment of userID, or nil if there is none
+	GetEnrollment(userID int) (*Enrollment, error)
+	// ReplaceEnrollment stores enrollment in place of any existing one
+	ReplaceEnrollment(enrollment *Enrollment) error
+	ConfirmEnrollment(userID int, at time.Time) error
+	// UseStep records step as the last used time step. It returns false if
+	// step is not newer than the last one, so the code was already used.
+	UseStep(userID int, step int64) (bool, error)
+	// DeleteEnrollment removes the enrollment and recovery codes of userID
+	DeleteEnrollment(userID int) error
+
+	// ReplaceRecoveryCodes stores hashes as the only recovery codes of userID
+	ReplaceRecoveryCodes(userID int, hashes []string) error
+	// UseRecoveryCode marks the unused code with hash as used. It returns
+	// false if there is no such code.
+	UseRecoveryCode(userID int, hash string, at time.Time) (bool, error)
+
+	// WithTx returns a repository that runs its queries inside tx
+	WithTx(tx *sql.Tx) Repository
+}
@@ -0,0 +1,129 @@
+package mfa
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"backend_path/pkg/database"
+)
+
+type sqlRepository struct {
+	db database.Executor
+}
+
+func NewSQLRepository(db *sql.DB) Repository {
+	return &sqlRepository{db: db}
+}
+
+func (r *sqlRepository) WithTx(tx *sql.Tx) Repository {
+	return &sqlRepository{db: tx}
+}
+
+func (r *sqlRepository) GetEnrollment(userID int) (*Enrollment, error) {
+	query := `
+		SELECT user_id, secret, confirmed_at, last_used_step, created_at
+		FROM mfa_enrollments
+		WHERE user_id = ?
+	`
+
+	enrollment := &Enrollment{}
+	var confirmedAt sql.NullTime
+	err := r.db.QueryRow(query, userID).Scan(
+		&enrollment.UserID,
+		&enrollment.Secret,
+		&confirmedAt,
+		&enrollment.LastUsedStep,
+		&enrollment.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mfa enrollment: %w", err)
+	}
+
+	if confirmedAt.Valid {
+		enrollment.ConfirmedAt = &confirmedAt.Time
+	}
+
+	return enrollment, nil
+}
+
+func (r *sqlRepository) ReplaceEnrollment(enrollment *Enrollment) error {
+	if _, err := r.db.Exec(`DELETE FROM mfa_enrollments WHERE user_id = ?`, enrollment.UserID); err != nil {
+		return fmt.Errorf("failed to replace mfa enrollment: %w", err)
+	}
+
+	query := `
+		INSERT INTO mfa_enrollments (user_id, secret, last_used_step, created_at)
+		VALUES (?, ?, ?, ?)
+	`
+	_, err := r.db.Exec(query, enrollment.UserID, enrollment.Secret, enrollment.LastUsedStep, enrollment.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create mfa enrollment: %w", err)
+	}
+
+	return nil
+}
+
+func (r *sqlRepository) ConfirmEnrollment(userID int, at time.Time) error {
+	if _, err := r.db.Exec(`UPDATE mfa_enrollments SET confirmed_at = ? WHERE user_id = ?`, at, userID); err != nil {
+		return fmt.Errorf("failed to confirm mfa enrollment: %w", err)
+	}
+	return nil
+}
+
+func (r *sqlRepository) UseStep(userID int, step int64) (bool, error) {
+	query := `UPDATE mfa_enrollments SET last_used_step = ? WHERE user_id = ? AND last_used_step < ?`
+	result, err := r.db.Exec(query, step, userID, step)
+	if err != nil {
+		return false, fmt.Errorf("failed to record mfa time step: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to record mfa time step: %w", err)
+	}
+
+	return rows == 1, nil
+}
+
+func (r *sqlRepository) DeleteEnrollment(userID int) error {
+	if _, err := r.db.Exec(`DELETE FROM mfa_recovery_codes WHERE user_id = ?`, userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+	if _, err := r.db.Exec(`DELETE FROM mfa_enrollments WHERE user_id = ?`, userID); err != nil {
+		return fmt.Errorf("failed to delete mfa enrollment: %w", err)
+	}
+	return nil
+}
+
+func (r *sqlRepository) ReplaceRecoveryCodes(userID int, hashes []string) error {
+	if _, err := r.db.Exec(`DELETE FROM mfa_recovery_codes WHERE user_id = ?`, userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	for _, hash := range hashes {
+		if _, err := r.db.Exec(`INSERT INTO mfa_recovery_codes (user_id, code_hash) VALUES (?, ?)`, userID, hash); err != nil {
+			return fmt.Errorf("failed to create recovery code: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (r *sqlRepository) UseRecoveryCode(userID int, hash string, at time.Time) (bool, error) {
+	query := `UPDATE mfa_recovery_codes SET used_at = ? WHERE user_id = ? AND code_hash = ? AND used_at IS NULL`
+	result, err := r.db.Exec(query, at, userID, hash)
+	if err != nil {
+		return false, fmt.Errorf("failed to use recovery code: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to use recovery code: %w", err)
+	}
+
+	return rows == 1, nil
+}
@@ -0,0 +1,285 @@
+package mfa
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"backend_path/internal/domain"
+	"backend_path/pkg/database"
+	"backend_path/pkg/logger"
+)
+
+// Issuer labels accounts in authenticator apps
+const Issuer = "GoFintech"
+
+// recoveryCodeCount is the number of recovery codes issued at a time
+const recoveryCodeCount = 10
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+type service struct {
+	db   *sql.DB
+	repo Repository
+	aead cipher.AEAD
+}
+
+// NewService creates an MFA service that encrypts TOTP secrets with a key
+// derived from encryptionKey
+func NewService(db *sql.DB, repo Repository, encryptionKey string) (MFAService, error) {
+	key := sha256.Sum256([]byte(encryptionKey))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to create mfa cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create mfa cipher: %w", err)
+	}
+
+	return &service{
+		db:   db,
+		repo: repo,
+		aead: aead,
+	}, nil
+}
+
+func (s *service) Enroll(ctx context.Context, user *domain.User) (*Provisioning, error) {
+	enrollment, err := s.repo.GetEnrollment(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if enrollment != nil && enrollment.ConfirmedAt != nil {
+		return nil, ErrAlreadyEnabled
+	}
+
+	secret, err := GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := s.encrypt(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	err = database.WithTransaction(ctx, s.db, func(tx *sql.Tx) error {
+		return s.repo.WithTx(tx).ReplaceEnrollment(&Enrollment{
+			UserID:    user.ID,
+			Secret:    encrypted,
+			CreatedAt: time.Now(),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Provisioning{
+		Secret: secret,
+		URI:    ProvisioningURI(Issuer, user.Email, secret),
+	}, nil
+}
+
+func (s *service) Confirm(ctx context.Context, userID int, code string) ([]string, error) {
+	enrollment, err := s.repo.GetEnrollment(userID)
+	if err != nil {
+		return nil, err
+	}
+	if enrollment == nil {
+		return nil, ErrNotEnrolled
+	}
+	if enrollment.ConfirmedAt != nil {
+		return nil, ErrAlreadyEnabled
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	err = database.WithTransaction(ctx, s.db, func(tx *sql.Tx) error {
+		repo := s.repo.WithTx(tx)
+		if err := s.verifyTOTP(repo, enrollment, code); err != nil {
+			return err
+		}
+		if err := repo.ConfirmEnrollment(userID, time.Now()); err != nil {
+			return err
+		}
+		return repo.ReplaceRecoveryCodes(userID, hashes)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Two-factor authentication enabled", map[string]interface{}{
+		"user_id": userID,
+	})
+
+	return codes, nil
+}
+
+func (s *service) Verify(ctx context.Context, userID int, code string) error {
+	enrollment, err := s.enabledEnrollment(userID)
+	if err != nil {
+		return err
+	}
+
+	if isTOTPCode(code) {
+		return s.verifyTOTP(s.repo, enrollment, code)
+	}
+
+	used, err := s.repo.UseRecoveryCode(userID, hashRecoveryCode(code), time.Now())
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidCode
+	}
+
+	logger.Info("Recovery code used", map[string]interface{}{
+		"user_id": userID,
+	})
+
+	return nil
+}
+
+func (s *service) IsEnabled(userID int) (bool, error) {
+	enrollment, err := s.repo.GetEnrollment(userID)
+	if err != nil {
+		return false, err
+	}
+	return enrollment != nil && enrollment.ConfirmedAt != nil, nil
+}
+
+func (s *service) Disable(ctx context.Context, userID int, code string) error {
+	if err := s.Verify(ctx, userID, code); err != nil {
+		return err
+	}
+
+	err := database.WithTransaction(ctx, s.db, func(tx *sql.Tx) error {
+		return s.repo.WithTx(tx).DeleteEnrollment(userID)
+	})
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Two-factor authentication disabled", map[string]interface{}{
+		"user_id": userID,
+	})
+
+	return nil
+}
+
+func (s *service) RegenerateRecoveryCodes(ctx context.Context, userID int, code string) ([]string, error) {
+	if err := s.Verify(ctx, userID, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	err = database.WithTransaction(ctx, s.db, func(tx *sql.Tx) error {
+		return s.repo.WithTx(tx).ReplaceRecoveryCodes(userID, hashes)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// enabledEnrollment returns the confirmed enrollment of userID
+func (s *service) enabledEnrollment(userID int) (*Enrollment, error) {
+	enrollment, err := s.repo.GetEnrollment(userID)
+	if err != nil {
+		return nil, err
+	}
+	if enrollment == nil || enrollment.ConfirmedAt == nil {
+		return nil, ErrNotEnabled
+	}
+	return enrollment, nil
+}
+
+// verifyTOTP checks code against the enrollment's secret and records its
+// time step through repo so it cannot be replayed
+func (s *service) verifyTOTP(repo Repository, enrollment *Enrollment, code string) error {
+	secret, err := s.decrypt(enrollment.Secret)
+	if err != nil {
+		return err
+	}
+
+	step, ok, err := matchStep(secret, code, time.Now())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvalidCode
+	}
+
+	fresh, err := repo.UseStep(enrollment.UserID, step)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return ErrInvalidCode
+	}
+
+	return nil
+}
+
+// encrypt seals secret for storage as base64 of the nonce and ciphertext
+func (s *service) encrypt(secret string) (string, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to encrypt totp secret: %w", err)
+	}
+	sealed := s.aead.Seal(nonce, nonce, []byte(secret), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (s *service) decrypt(stored string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(stored)
+	if err != nil || len(sealed) < s.aead.NonceSize() {
+		return "", errors.New("failed to decrypt totp secret: malformed ciphertext")
+	}
+	nonce, ciphertext := sealed[:s.aead.NonceSize()], sealed[s.aead.NonceSize():]
+	secret, err := s.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt totp secret: %w", err)
+	}
+	return string(secret), nil
+}
+
+// generateRecoveryCodes returns new recovery codes and their hashes
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		raw := make([]byte, 7)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		encoded := strings.ToLower(recoveryCodeEncoding.EncodeToString(raw))[:10]
+		codes[i] = encoded[:5] + "-" + encoded[5:]
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode hashes code ignoring case, spaces and dashes
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,103 @@
+package mfa
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TOTP parameters (RFC 6238) understood by common authenticator apps
+const (
+	totpDigits = 6
+	totpPeriod = 30 * time.Second
+	// totpSkew is the number of steps either side of now that are accepted,
+	// to allow for clock drift
+	totpSkew   = 1
+	secretSize = 20
+)
+
+var secretEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret returns a random base32 TOTP secret
+func GenerateSecret() (string, error) {
+	secret := make([]byte, secretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate totp secret: %w", err)
+	}
+	return secretEncoding.EncodeToString(secret), nil
+}
+
+// ProvisioningURI returns the otpauth:// URI for secret, labelled with
+// issuer and account
+func ProvisioningURI(issuer, account, secret string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(totpDigits))
+	query.Set("period", fmt.Sprint(int(totpPeriod.Seconds())))
+
+	label := url.PathEscape(issuer + ":" + account)
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
+
+// timeStep returns the TOTP time step at t
+func timeStep(t time.Time) int64 {
+	return t.Unix() / int64(totpPeriod.Seconds())
+}
+
+// totpCode computes the HOTP value (RFC 4226) of key at step
+func totpCode(key []byte, step int64) string {
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	modulus := uint32(1)
+	for i := 0; i < totpDigits; i++ {
+		modulus *= 10
+	}
+	return fmt.Sprintf("%0*d", totpDigits, value%modulus)
+}
+
+// matchStep returns the time step near now whose code is code, if any
+func matchStep(secret, code string, now time.Time) (int64, bool, error) {
+	key, err := secretEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid totp secret: %w", err)
+	}
+
+	current := timeStep(now)
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if subtle.ConstantTimeCompare([]byte(totpCode(key, step)), []byte(code)) == 1 {
+			return step, true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+// isTOTPCode reports whether code has the shape of a TOTP code rather than
+// a recovery code
+func isTOTPCode(code string) bool {
+	if len(code) != totpDigits {
+		return false
+	}
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,194 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// Grant types
+const (
+	GrantAuthorizationCode = "authorization_code"
+	GrantClientCredentials = "client_credentials"
+)
+
+// Error codes of RFC 6749
+const (
+	ErrorInvalidRequest          = "invalid_request"
+	ErrorInvalidClient           = "invalid_client"
+	ErrorInvalidGrant            = "invalid_grant"
+	ErrorUnauthorizedClient      = "unauthorized_client"
+	ErrorUnsupportedGrantType    = "unsupported_grant_type"
+	ErrorUnsupportedResponseType = "unsupported_response_type"
+	ErrorInvalidScope            = "invalid_scope"
+	ErrorAccessDenied            = "access_denied"
+)
+
+var (
+	// ErrClientNotFound is returned for unknown or revoked clients
+	ErrClientNotFound = errors.New("oauth client not found")
+	// ErrInvalidClientDefinition is returned when registering a client with
+	// a missing name or malformed grant types, redirect URIs or scopes
+	ErrInvalidClientDefinition = errors.New("invalid oauth client definition")
+)
+
+// Error is an OAuth 2.0 error response
+type Error struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+func (e *Error) Error() string {
+	if e.Description == "" {
+		return e.Code
+	}
+	return e.Code + ": " + e.Description
+}
+
+// Status returns the HTTP status the error is reported with
+func (e *Error) Status() int {
+	if e.Code == ErrorInvalidClient {
+		return http.StatusUnauthorized
+	}
+	return http.StatusBadRequest
+}
+
+func newError(code, description string) *Error {
+	return &Error{Code: code, Description: description}
+}
+
+// Client is a registered OAuth client. Confidential clients authenticate
+// with a secret; public clients, such as mobile apps, only use the
+// authorization code grant with PKCE.
+type Client struct {
+	ID int
+	// ClientID is the public identifier of the client
+	ClientID     string
+	SecretHash   string
+	Name         string
+	RedirectURIs []string
+	GrantTypes   []string
+	// Scopes are the most a client may be granted
+	Scopes []string
+	// UserID is the account client_credentials tokens act as
+	UserID    *int
+	CreatedBy int
+	CreatedAt time.Time
+	RevokedAt *time.Time
+}
+
+// Confidential reports whether the client authenticates with a secret
+func (c *Client) Confidential() bool {
+	return c.SecretHash != ""
+}
+
+// AllowsGrant reports whether the client may use grantType
+func (c *Client) AllowsGrant(grantType string) bool {
+	for _, allowed := range c.GrantTypes {
+		if allowed == grantType {
+			return true
+		}
+	}
+	return false
+}
+
+// AuthorizationCode is a single-use code exchanged for a token by the
+// client it was issued to. Only the SHA-256 hash of the code is stored.
+type AuthorizationCode struct {
+	ID          int
+	CodeHash    string
+	ClientID    int
+	UserID      int
+	RedirectURI string
+	// RedirectURIGiven is whether the authorization request included
+	// redirect_uri, rather than using the client's only registered one
+	RedirectURIGiven bool
+	Scopes           []string
+	CodeChallenge    string
+	ExpiresAt        time.Time
+	UsedAt           *time.Time
+	CreatedAt        time.Time
+}
+
+// AuthorizationRequest is a request to /oauth/authorize
+type AuthorizationRequest struct {
+	ResponseType        string
+	ClientID            string
+	RedirectURI         string
+	Scope               string
+	State               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+}
+
+// Authorization is a validated authorization request awaiting the user's
+// consent
+type Authorization struct {
+	Client      *Client
+	RedirectURI string
+	Scopes      []string
+	// Consented is true if the user already granted every scope
+	Consented bool
+}
+
+// TokenRequest is a request to /oauth/token. ClientSecret is empty for
+// public clients.
+type TokenRequest struct {
+	GrantType    string
+	ClientID     string
+	ClientSecret string
+	Code         string
+	RedirectURI  string
+	CodeVerifier string
+	Scope        string
+}
+
+// Token is a successful token response
+type Token struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+	Scope       string `json:"scope,omitempty"`
+}
+
+// Introspection is a token introspection response (RFC 7662). Only Active
+// is set for inactive tokens.
+type Introspection struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Username  string `json:"username,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	NotBefore int64  `json:"nbf,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	Issuer    string `json:"iss,omitempty"`
+	TokenID   string `json:"jti,omitempty"`
+}
+
+// OAuthService is an OAuth 2.0 authorization server. Methods taking client
+// credentials return an *Error for anything the client should be told.
+type OAuthService interface {
+	// RegisterClient stores client and returns its secret if confidential,
+	// which is only available now
+	RegisterClient(ctx context.Context, client *Client, confidential bool) (string, error)
+	// ListClients returns the clients that are not revoked
+	ListClients() ([]*Client, error)
+	RevokeClient(ctx context.Context, id int) error
+
+	// PrepareAuthorization validates request for userID, to show them a
+	// consent screen
+	PrepareAuthorization(request *AuthorizationRequest, userID int) (*Authorization, error)
+	// Authorize records the decision of userID on request and returns the
+	// client's redirect URI, carrying either a code or access_denied
+	Authorize(ctx context.Context, request *AuthorizationRequest, userID int, approved bool) (string, error)
+
+	// Token issues an access token for request
+	Token(ctx context.Context, request *TokenRequest) (*Token, error)
+	// Revoke revokes an access token issued to the client (RFC 7009)
+	Revoke(ctx context.Context, clientID, clientSecret, token string) error
+	// Introspect describes token to an authenticated client (RFC 7662)
+	Introspect(ctx context.Context, clientID, clientSecret, token string) (*Introspection, error)
+}
@@ -0,0 +1,32 @@
+package oauth
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Repository stores OAuth clients, authorization codes and consents
+type Repository interface {
+	CreateClient(client *Client) error
+	// GetClient returns the client with clientID, revoked or not, or nil if
+	// there is none
+	GetClient(clientID string) (*Client, error)
+	// ListActiveClients returns the clients that are not revoked
+	ListActiveClients() ([]*Client, error)
+	// RevokeClient revokes the client with id. It returns false if there is
+	// no such client or it is already revoked.
+	RevokeClient(id int, at time.Time) (bool, error)
+
+	CreateCode(code *AuthorizationCode) error
+	// UseCode marks the unused, unexpired code with hash as used and returns
+	// it, or returns nil if there is no such code
+	UseCode(hash string, at time.Time) (*AuthorizationCode, error)
+
+	// GetConsent returns the scopes userID granted the client with id
+	GetConsent(userID, clientID int) ([]string, error)
+	// SaveConsent replaces the scopes userID granted the client with id
+	SaveConsent(userID, clientID int, scopes []string, at time.Time) error
+
+	// WithTx returns a repository that runs its queries inside tx
+	WithTx(tx *sql.Tx) Repository
+}
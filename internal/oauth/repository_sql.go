@@ -0,0 +1,244 @@
+package oauth
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"backend_path/pkg/database"
+)
+
+const clientColumns = `id, client_id, secret_hash, name, redirect_uris, grant_types, scopes, user_id, created_by, created_at, revoked_at`
+
+type sqlRepository struct {
+	db database.Executor
+}
+
+func NewSQLRepository(db *sql.DB) Repository {
+	return &sqlRepository{db: db}
+}
+
+func (r *sqlRepository) WithTx(tx *sql.Tx) Repository {
+	return &sqlRepository{db: tx}
+}
+
+func (r *sqlRepository) CreateClient(client *Client) error {
+	query := `
+		INSERT INTO oauth_clients (client_id, secret_hash, name, redirect_uris, grant_types, scopes, user_id, created_by, created_at)
+		OUTPUT INSERTED.id
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	var secretHash sql.NullString
+	if client.SecretHash != "" {
+		secretHash = sql.NullString{String: client.SecretHash, Valid: true}
+	}
+
+	err := r.db.QueryRow(
+		query,
+		client.ClientID,
+		secretHash,
+		client.Name,
+		strings.Join(client.RedirectURIs, ","),
+		strings.Join(client.GrantTypes, ","),
+		strings.Join(client.Scopes, ","),
+		client.UserID,
+		client.CreatedBy,
+		client.CreatedAt,
+	).Scan(&client.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create oauth client: %w", err)
+	}
+	return nil
+}
+
+func (r *sqlRepository) GetClient(clientID string) (*Client, error) {
+	client, err := scanClient(r.db.QueryRow(`SELECT `+clientColumns+` FROM oauth_clients WHERE client_id = ?`, clientID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return client, err
+}
+
+func (r *sqlRepository) ListActiveClients() ([]*Client, error) {
+	rows, err := r.db.Query(`SELECT ` + clientColumns + ` FROM oauth_clients WHERE revoked_at IS NULL ORDER BY created_at DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list oauth clients: %w", err)
+	}
+	defer rows.Close()
+
+	var clients []*Client
+	for rows.Next() {
+		client, err := scanClient(rows)
+		if err != nil {
+			return nil, err
+		}
+		clients = append(clients, client)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating oauth clients: %w", err)
+	}
+
+	return clients, nil
+}
+
+func (r *sqlRepository) RevokeClient(id int, at time.Time) (bool, error) {
+	result, err := r.db.Exec(`UPDATE oauth_clients SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL`, at, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke oauth client: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+func (r *sqlRepository) CreateCode(code *AuthorizationCode) error {
+	query := `
+		INSERT INTO oauth_authorization_codes (code_hash, client_id, user_id, redirect_uri, redirect_uri_given, scopes, code_challenge, expires_at, created_at)
+		OUTPUT INSERTED.id
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	err := r.db.QueryRow(
+		query,
+		code.CodeHash,
+		code.ClientID,
+		code.UserID,
+		code.RedirectURI,
+		code.RedirectURIGiven,
+		strings.Join(code.Scopes, ","),
+		code.CodeChallenge,
+		code.ExpiresAt,
+		code.CreatedAt,
+	).Scan(&code.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create authorization code: %w", err)
+	}
+	return nil
+}
+
+func (r *sqlRepository) UseCode(hash string, at time.Time) (*AuthorizationCode, error) {
+	// A single conditional update, so a code cannot be exchanged twice
+	query := `
+		UPDATE oauth_authorization_codes
+		SET used_at = ?
+		OUTPUT INSERTED.id, INSERTED.code_hash, INSERTED.client_id, INSERTED.user_id, INSERTED.redirect_uri,
+			INSERTED.redirect_uri_given, INSERTED.scopes, INSERTED.code_challenge, INSERTED.expires_at, INSERTED.used_at, INSERTED.created_at
+		WHERE code_hash = ? AND used_at IS NULL AND expires_at > ?
+	`
+
+	code := &AuthorizationCode{}
+	var scopes string
+	var usedAt time.Time
+	err := r.db.QueryRow(query, at, hash, at).Scan(
+		&code.ID,
+		&code.CodeHash,
+		&code.ClientID,
+		&code.UserID,
+		&code.RedirectURI,
+		&code.RedirectURIGiven,
+		&scopes,
+		&code.CodeChallenge,
+		&code.ExpiresAt,
+		&usedAt,
+		&code.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to use authorization code: %w", err)
+	}
+
+	code.Scopes = splitList(scopes)
+	code.UsedAt = &usedAt
+	return code, nil
+}
+
+func (r *sqlRepository) GetConsent(userID, clientID int) ([]string, error) {
+	var scopes string
+	err := r.db.QueryRow(`SELECT scopes FROM oauth_consents WHERE user_id = ? AND client_id = ?`, userID, clientID).Scan(&scopes)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get oauth consent: %w", err)
+	}
+	return splitList(scopes), nil
+}
+
+func (r *sqlRepository) SaveConsent(userID, clientID int, scopes []string, at time.Time) error {
+	if _, err := r.db.Exec(`DELETE FROM oauth_consents WHERE user_id = ? AND client_id = ?`, userID, clientID); err != nil {
+		return fmt.Errorf("failed to save oauth consent: %w", err)
+	}
+
+	query := `INSERT INTO oauth_consents (user_id, client_id, scopes, granted_at) VALUES (?, ?, ?, ?)`
+	if _, err := r.db.Exec(query, userID, clientID, strings.Join(scopes, ","), at); err != nil {
+		return fmt.Errorf("failed to save oauth consent: %w", err)
+	}
+	return nil
+}
+
+// scanner is implemented by *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanClient scans a single client row. It returns sql.ErrNoRows unwrapped.
+func scanClient(row scanner) (*Client, error) {
+	client := &Client{}
+	var secretHash sql.NullString
+	var redirectURIs, grantTypes, scopes string
+	var userID sql.NullInt64
+	var revokedAt sql.NullTime
+	err := row.Scan(
+		&client.ID,
+		&client.ClientID,
+		&secretHash,
+		&client.Name,
+		&redirectURIs,
+		&grantTypes,
+		&scopes,
+		&userID,
+		&client.CreatedBy,
+		&client.CreatedAt,
+		&revokedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan oauth client: %w", err)
+	}
+
+	client.SecretHash = secretHash.String
+	client.RedirectURIs = splitList(redirectURIs)
+	client.GrantTypes = splitList(grantTypes)
+	client.Scopes = splitList(scopes)
+	if userID.Valid {
+		id := int(userID.Int64)
+		client.UserID = &id
+	}
+	if revokedAt.Valid {
+		client.RevokedAt = &revokedAt.Time
+	}
+
+	return client, nil
+}
+
+// splitList splits a comma-separated column, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
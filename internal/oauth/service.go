@@ -0,0 +1,514 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend_path/internal/auth"
+	"backend_path/pkg/database"
+	"backend_path/pkg/jwt"
+	"backend_path/pkg/logger"
+)
+
+// codeTTL is how long an authorization code can be exchanged
+const codeTTL = 10 * time.Minute
+
+// PKCE code verifiers are 43 to 128 characters (RFC 7636)
+const (
+	minVerifierLength = 43
+	maxVerifierLength = 128
+)
+
+// TokenRevoker revokes access tokens before they expire
+type TokenRevoker interface {
+	DenyToken(ctx context.Context, id string, expiresAt time.Time) error
+	IsRevoked(ctx context.Context, claims *jwt.Claims) (bool, error)
+}
+
+type service struct {
+	db          *sql.DB
+	repo        Repository
+	jwtService  *jwt.JWTService
+	users       auth.UserLookup
+	revocations TokenRevoker
+}
+
+// NewService creates an OAuth service that issues tokens with jwtService for
+// users looked up in users
+func NewService(db *sql.DB, repo Repository, jwtService *jwt.JWTService, users auth.UserLookup, revocations TokenRevoker) OAuthService {
+	return &service{
+		db:          db,
+		repo:        repo,
+		jwtService:  jwtService,
+		users:       users,
+		revocations: revocations,
+	}
+}
+
+func (s *service) RegisterClient(ctx context.Context, client *Client, confidential bool) (string, error) {
+	if err := validateClient(client, confidential); err != nil {
+		return "", err
+	}
+	if client.UserID != nil {
+		if _, err := s.users.GetByID(*client.UserID); err != nil {
+			return "", err
+		}
+	}
+
+	clientID, err := randomToken(16)
+	if err != nil {
+		return "", err
+	}
+	client.ClientID = clientID
+
+	var secret string
+	if confidential {
+		if secret, err = randomToken(32); err != nil {
+			return "", err
+		}
+		client.SecretHash = hashSecret(secret)
+	}
+	client.CreatedAt = time.Now()
+
+	err = database.WithTransaction(ctx, s.db, func(tx *sql.Tx) error {
+		return s.repo.WithTx(tx).CreateClient(client)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return secret, nil
+}
+
+func (s *service) ListClients() ([]*Client, error) {
+	return s.repo.ListActiveClients()
+}
+
+func (s *service) RevokeClient(ctx context.Context, id int) error {
+	return database.WithTransaction(ctx, s.db, func(tx *sql.Tx) error {
+		revoked, err := s.repo.WithTx(tx).RevokeClient(id, time.Now())
+		if err != nil {
+			return err
+		}
+		if !revoked {
+			return ErrClientNotFound
+		}
+		return nil
+	})
+}
+
+func (s *service) PrepareAuthorization(request *AuthorizationRequest, userID int) (*Authorization, error) {
+	client, err := s.activeClient(request.ClientID)
+	if err != nil {
+		return nil, err
+	}
+	if !client.AllowsGrant(GrantAuthorizationCode) {
+		return nil, newError(ErrorUnauthorizedClient, "client may not use the authorization code grant")
+	}
+
+	redirectURI, err := resolveRedirectURI(client, request.RedirectURI)
+	if err != nil {
+		return nil, err
+	}
+	if request.ResponseType != "code" {
+		return nil, newError(ErrorUnsupportedResponseType, "response_type must be code")
+	}
+	if request.CodeChallenge == "" {
+		return nil, newError(ErrorInvalidRequest, "code_challenge is required")
+	}
+	if request.CodeChallengeMethod != "S256" {
+		return nil, newError(ErrorInvalidRequest, "code_challenge_method must be S256")
+	}
+
+	scopes, err := grantedScopes(client, request.Scope)
+	if err != nil {
+		return nil, err
+	}
+
+	consented, err := s.repo.GetConsent(userID, client.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Authorization{
+		Client:      client,
+		RedirectURI: redirectURI,
+		Scopes:      scopes,
+		Consented:   containsAll(consented, scopes),
+	}, nil
+}
+
+func (s *service) Authorize(ctx context.Context, request *AuthorizationRequest, userID int, approved bool) (string, error) {
+	authorization, err := s.PrepareAuthorization(request, userID)
+	if err != nil {
+		return "", err
+	}
+
+	params := url.Values{}
+	if request.State != "" {
+		params.Set("state", request.State)
+	}
+	if !approved {
+		params.Set("error", ErrorAccessDenied)
+		return withQuery(authorization.RedirectURI, params), nil
+	}
+
+	code, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	err = database.WithTransaction(ctx, s.db, func(tx *sql.Tx) error {
+		repo := s.repo.WithTx(tx)
+		if err := repo.SaveConsent(userID, authorization.Client.ID, authorization.Scopes, now); err != nil {
+			return err
+		}
+		return repo.CreateCode(&AuthorizationCode{
+			CodeHash:         hashSecret(code),
+			ClientID:         authorization.Client.ID,
+			UserID:           userID,
+			RedirectURI:      authorization.RedirectURI,
+			RedirectURIGiven: request.RedirectURI != "",
+			Scopes:           authorization.Scopes,
+			CodeChallenge:    request.CodeChallenge,
+			ExpiresAt:        now.Add(codeTTL),
+			CreatedAt:        now,
+		})
+	})
+	if err != nil {
+		return "", err
+	}
+
+	params.Set("code", code)
+	return withQuery(authorization.RedirectURI, params), nil
+}
+
+func (s *service) Token(ctx context.Context, request *TokenRequest) (*Token, error) {
+	client, err := s.authenticateClient(request.ClientID, request.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	switch request.GrantType {
+	case GrantClientCredentials:
+		return s.clientCredentials(client, request)
+	case GrantAuthorizationCode:
+		return s.exchangeCode(client, request)
+	case "":
+		return nil, newError(ErrorInvalidRequest, "grant_type is required")
+	default:
+		return nil, newError(ErrorUnsupportedGrantType, "")
+	}
+}
+
+func (s *service) Revoke(ctx context.Context, clientID, clientSecret, token string) error {
+	client, err := s.authenticateClient(clientID, clientSecret)
+	if err != nil {
+		return err
+	}
+
+	// Invalid tokens are not an error, the client wants them unusable and
+	// they are (RFC 7009, section 2.2)
+	claims, err := s.jwtService.ValidateToken(token)
+	if err != nil || claims.TokenType != jwt.TokenTypeOAuth {
+		return nil
+	}
+	if claims.ClientID != client.ClientID {
+		return newError(ErrorUnauthorizedClient, "token was issued to another client")
+	}
+
+	return s.revocations.DenyToken(ctx, claims.ID, claims.ExpiresAt.Time)
+}
+
+func (s *service) Introspect(ctx context.Context, clientID, clientSecret, token string) (*Introspection, error) {
+	client, err := s.authenticateClient(clientID, clientSecret)
+	if err != nil {
+		return nil, err
+	}
+	if !client.Confidential() {
+		return nil, newError(ErrorUnauthorizedClient, "only confidential clients may introspect tokens")
+	}
+
+	claims, err := s.jwtService.ValidateResourceToken(token)
+	if err != nil {
+		return &Introspection{Active: false}, nil
+	}
+	revoked, err := s.revocations.IsRevoked(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return &Introspection{Active: false}, nil
+	}
+
+	introspection := &Introspection{
+		Active:    true,
+		Scope:     claims.Scope,
+		ClientID:  claims.ClientID,
+		Username:  claims.Username,
+		TokenType: "Bearer",
+		Subject:   strconv.Itoa(claims.UserID),
+		Issuer:    claims.Issuer,
+		TokenID:   claims.ID,
+	}
+	if claims.ExpiresAt != nil {
+		introspection.ExpiresAt = claims.ExpiresAt.Unix()
+	}
+	if claims.IssuedAt != nil {
+		introspection.IssuedAt = claims.IssuedAt.Unix()
+	}
+	if claims.NotBefore != nil {
+		introspection.NotBefore = claims.NotBefore.Unix()
+	}
+	return introspection, nil
+}
+
+// clientCredentials issues a token acting as the client's own user
+func (s *service) clientCredentials(client *Client, request *TokenRequest) (*Token, error) {
+	if !client.AllowsGrant(GrantClientCredentials) || client.UserID == nil {
+		return nil, newError(ErrorUnauthorizedClient, "client may not use the client credentials grant")
+	}
+	// Clients registered before delegation was checked
+	if err := validateDelegation(client); err != nil {
+		return nil, newError(ErrorUnauthorizedClient, "client may not move another user's money")
+	}
+
+	scopes, err := grantedScopes(client, request.Scope)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.issue(client, *client.UserID, scopes)
+}
+
+// exchangeCode issues a token for an authorization code, after checking the
+// PKCE code verifier against the challenge it was requested with
+func (s *service) exchangeCode(client *Client, request *TokenRequest) (*Token, error) {
+	if !client.AllowsGrant(GrantAuthorizationCode) {
+		return nil, newError(ErrorUnauthorizedClient, "client may not use the authorization code grant")
+	}
+	if request.Code == "" {
+		return nil, newError(ErrorInvalidRequest, "code is required")
+	}
+	if len(request.CodeVerifier) < minVerifierLength || len(request.CodeVerifier) > maxVerifierLength {
+		return nil, newError(ErrorInvalidRequest, "code_verifier must be 43 to 128 characters")
+	}
+
+	code, err := s.repo.UseCode(hashSecret(request.Code), time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if code == nil || code.ClientID != client.ID {
+		return nil, newError(ErrorInvalidGrant, "authorization code is invalid, expired or already used")
+	}
+	// redirect_uri is only required if the authorization request included
+	// it (RFC 6749, section 4.1.3), but must match whenever it is sent
+	if (code.RedirectURIGiven || request.RedirectURI != "") && request.RedirectURI != code.RedirectURI {
+		return nil, newError(ErrorInvalidGrant, "redirect_uri does not match the authorization request")
+	}
+
+	sum := sha256.Sum256([]byte(request.CodeVerifier))
+	challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+	if subtle.ConstantTimeCompare([]byte(challenge), []byte(code.CodeChallenge)) != 1 {
+		return nil, newError(ErrorInvalidGrant, "code_verifier does not match the code challenge")
+	}
+
+	return s.issue(client, code.UserID, code.Scopes)
+}
+
+// issue signs an access token for client acting as userID
+func (s *service) issue(client *Client, userID int, scopes []string) (*Token, error) {
+	owner, err := s.users.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	scope := strings.Join(scopes, " ")
+	token, _, err := s.jwtService.GenerateOAuthToken(owner.ID, owner.Username, owner.Email, owner.Role, client.ClientID, scope)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate oauth token: %w", err)
+	}
+
+	logger.Info("OAuth token issued", map[string]interface{}{
+		"client_id": client.ClientID,
+		"user_id":   owner.ID,
+		"scope":     scope,
+	})
+
+	return &Token{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(s.jwtService.TokenDuration().Seconds()),
+		Scope:       scope,
+	}, nil
+}
+
+// activeClient returns the client with clientID unless it is revoked
+func (s *service) activeClient(clientID string) (*Client, error) {
+	if clientID == "" {
+		return nil, newError(ErrorInvalidRequest, "client_id is required")
+	}
+	client, err := s.repo.GetClient(clientID)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil || client.RevokedAt != nil {
+		return nil, newError(ErrorInvalidClient, "unknown client")
+	}
+	return client, nil
+}
+
+// authenticateClient checks the credentials of a client. Public clients
+// authenticate with their client ID alone.
+func (s *service) authenticateClient(clientID, secret string) (*Client, error) {
+	client, err := s.activeClient(clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !client.Confidential() {
+		if secret != "" {
+			return nil, newError(ErrorInvalidClient, "public clients have no secret")
+		}
+		return client, nil
+	}
+	if subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(client.SecretHash)) != 1 {
+		return nil, newError(ErrorInvalidClient, "client authentication failed")
+	}
+	return client, nil
+}
+
+// resolveRedirectURI returns the registered redirect URI requested, or the
+// only one registered if none was requested
+func resolveRedirectURI(client *Client, requested string) (string, error) {
+	if requested == "" {
+		if len(client.RedirectURIs) == 1 {
+			return client.RedirectURIs[0], nil
+		}
+		return "", newError(ErrorInvalidRequest, "redirect_uri is required")
+	}
+	for _, registered := range client.RedirectURIs {
+		if registered == requested {
+			return requested, nil
+		}
+	}
+	return "", newError(ErrorInvalidRequest, "redirect_uri is not registered for the client")
+}
+
+// grantedScopes returns the space-separated scopes requested, which must all
+// be registered for the client, or all the client's scopes if none were
+func grantedScopes(client *Client, requested string) ([]string, error) {
+	scopes := strings.Fields(requested)
+	if len(scopes) == 0 {
+		return client.Scopes, nil
+	}
+	if !containsAll(client.Scopes, scopes) {
+		return nil, newError(ErrorInvalidScope, "requested scope exceeds the scopes of the client")
+	}
+	return scopes, nil
+}
+
+func validateClient(client *Client, confidential bool) error {
+	if strings.TrimSpace(client.Name) == "" || len(client.Name) > 100 {
+		return fmt.Errorf("%w: name is required and at most 100 characters", ErrInvalidClientDefinition)
+	}
+	if len(client.GrantTypes) == 0 {
+		return fmt.Errorf("%w: at least one grant type is required", ErrInvalidClientDefinition)
+	}
+	for _, grantType := range client.GrantTypes {
+		switch grantType {
+		case GrantAuthorizationCode:
+			if len(client.RedirectURIs) == 0 {
+				return fmt.Errorf("%w: the authorization code grant requires a redirect URI", ErrInvalidClientDefinition)
+			}
+		case GrantClientCredentials:
+			if !confidential || client.UserID == nil {
+				return fmt.Errorf("%w: the client credentials grant requires a confidential client acting as a user", ErrInvalidClientDefinition)
+			}
+		default:
+			return fmt.Errorf("%w: unsupported grant type %q", ErrInvalidClientDefinition, grantType)
+		}
+	}
+	for _, redirectURI := range client.RedirectURIs {
+		parsed, err := url.Parse(redirectURI)
+		if err != nil || !parsed.IsAbs() || parsed.Fragment != "" || strings.Contains(redirectURI, ",") {
+			return fmt.Errorf("%w: redirect URI %q must be absolute without a fragment", ErrInvalidClientDefinition, redirectURI)
+		}
+	}
+	if len(client.Scopes) == 0 {
+		return fmt.Errorf("%w: at least one scope is required", ErrInvalidClientDefinition)
+	}
+	for _, scope := range client.Scopes {
+		if _, _, err := auth.ParseScope(scope); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidClientDefinition, err)
+		}
+	}
+	return validateDelegation(client)
+}
+
+// validateDelegation refuses money-moving scopes on clients acting as
+// another user than the one who registered them. Their client credentials
+// tokens would move the user's money with nothing naming who actually acts.
+func validateDelegation(client *Client) error {
+	if client.UserID != nil && *client.UserID != client.CreatedBy && auth.ScopesAllow(client.Scopes, "transaction", "create") {
+		return fmt.Errorf("%w: clients acting as another user cannot have scopes that move money", ErrInvalidClientDefinition)
+	}
+	return nil
+}
+
+// containsAll reports whether every item of subset is in set
+func containsAll(set, subset []string) bool {
+	for _, item := range subset {
+		found := false
+		for _, candidate := range set {
+			if candidate == item {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// withQuery adds params to the query of uri
+func withQuery(uri string, params url.Values) string {
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return uri
+	}
+	query := parsed.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
+
+// randomToken returns size random bytes, base64url encoded
+func randomToken(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate random token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// hashSecret returns the hash client secrets and codes are stored as
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
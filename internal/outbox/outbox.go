@@ -0,0 +1,90 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"backend_path/internal/events"
+)
+
+// Status represents the delivery state of an outbox message
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusPublished Status = "published"
+	StatusDead      Status = "dead"
+)
+
+// Message is a domain event waiting to be published
+type Message struct {
+	ID            int64      `json:"id"`
+	EventID       string     `json:"event_id"`
+	EventType     string     `json:"event_type"`
+	AggregateID   string     `json:"aggregate_id"`
+	Payload       string     `json:"payload"`
+	Status        Status     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	LastError     string     `json:"last_error,omitempty"`
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	PublishedAt   *time.Time `json:"published_at,omitempty"`
+}
+
+// Event decodes the event carried by the message
+func (m *Message) Event() (*events.Event, error) {
+	var event events.Event
+	if err := json.Unmarshal([]byte(m.Payload), &event); err != nil {
+		return nil, fmt.Errorf("failed to decode outbox payload: %w", err)
+	}
+	return &event, nil
+}
+
+// Stats summarizes the current outbox backlog
+type Stats struct {
+	Pending       int        `json:"pending"`
+	Dead          int        `json:"dead"`
+	OldestPending *time.Time `json:"oldest_pending,omitempty"`
+}
+
+// Recorder writes domain events to the event store and the outbox inside the
+// business transaction that produced them, so events are published only if
+// the write commits
+type Recorder struct {
+	store events.TxAppender
+	repo  Repository
+}
+
+// NewRecorder creates a new recorder. store may be nil to skip the event store.
+func NewRecorder(store events.TxAppender, repo Repository) *Recorder {
+	return &Recorder{store: store, repo: repo}
+}
+
+// RecordTx records events inside tx
+func (r *Recorder) RecordTx(ctx context.Context, tx *sql.Tx, evts ...*events.Event) error {
+	if len(evts) == 0 {
+		return nil
+	}
+
+	if r.store != nil {
+		if err := r.store.AppendTx(ctx, tx, evts); err != nil {
+			return err
+		}
+	}
+
+	return r.repo.EnqueueTx(ctx, tx, evts)
+}
+
+// PublishTx queues events for publishing inside tx without appending them to
+// the event store. It is meant for notifications derived from events that are
+// already recorded on their aggregate.
+func (r *Recorder) PublishTx(ctx context.Context, tx *sql.Tx, evts ...*events.Event) error {
+	if len(evts) == 0 {
+		return nil
+	}
+
+	return r.repo.EnqueueTx(ctx, tx, evts)
+}
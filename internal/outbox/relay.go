@@ -0,0 +1,207 @@
+package outbox
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"backend_path/internal/events"
+	"backend_path/internal/metrics"
+	"backend_path/pkg/logger"
+)
+
+// RelayConfig configures the outbox relay
+type RelayConfig struct {
+	PollInterval time.Duration // Wait between polls when the outbox is drained
+	BatchSize    int           // Messages claimed per poll
+	Lease        time.Duration // How long a claimed message is hidden from other relays
+	MaxAttempts  int           // Attempts before a message is dead-lettered
+	BaseBackoff  time.Duration // Delay after the first failed attempt
+	MaxBackoff   time.Duration // Upper bound for the retry delay
+}
+
+// DefaultRelayConfig returns the default relay configuration
+func DefaultRelayConfig() RelayConfig {
+	return RelayConfig{
+		PollInterval: 1 * time.Second,
+		BatchSize:    100,
+		Lease:        30 * time.Second,
+		MaxAttempts:  10,
+		BaseBackoff:  1 * time.Second,
+		MaxBackoff:   10 * time.Minute,
+	}
+}
+
+// Relay polls the outbox and pushes pending messages to an EventPublisher.
+// Delivery is at-least-once: a message is marked published only after the
+// publisher accepted it, so subscribers should deduplicate on event ID.
+type Relay struct {
+	repo      Repository
+	publisher events.EventPublisher
+	config    RelayConfig
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+}
+
+// NewRelay creates a new outbox relay
+func NewRelay(repo Repository, publisher events.EventPublisher, config RelayConfig) *Relay {
+	return &Relay{
+		repo:      repo,
+		publisher: publisher,
+		config:    config,
+	}
+}
+
+// Start starts polling the outbox in the background
+func (r *Relay) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		r.run(ctx)
+	}()
+
+	logger.Info("Outbox relay started", map[string]interface{}{
+		"poll_interval": r.config.PollInterval.String(),
+		"batch_size":    r.config.BatchSize,
+	})
+}
+
+// Stop stops the relay and waits for the current batch to finish
+func (r *Relay) Stop() {
+	if r.cancel != nil {
+		r.cancel()
+	}
+	r.wg.Wait()
+	logger.Info("Outbox relay stopped", nil)
+}
+
+// run polls until ctx is cancelled. A full batch is followed immediately by
+// another poll so a backlog drains without waiting for the interval.
+func (r *Relay) run(ctx context.Context) {
+	for {
+		processed, err := r.poll(ctx)
+		if err != nil {
+			logger.Error("Outbox poll failed", err, nil)
+		}
+		r.recordStats()
+
+		wait := r.config.PollInterval
+		if err == nil && processed == r.config.BatchSize {
+			wait = 0
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// poll claims and publishes one batch of due messages
+func (r *Relay) poll(ctx context.Context) (int, error) {
+	messages, err := r.repo.ClaimDue(r.config.BatchSize, r.config.Lease)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, msg := range messages {
+		if ctx.Err() != nil {
+			// Unpublished claims become due again once their lease expires
+			break
+		}
+		r.publish(msg)
+	}
+
+	return len(messages), nil
+}
+
+// publish publishes a single message and records the outcome
+func (r *Relay) publish(msg *Message) {
+	event, err := msg.Event()
+	if err == nil {
+		err = r.publisher.Publish(event)
+	}
+
+	if err == nil {
+		if err := r.repo.MarkPublished(msg.ID); err != nil {
+			// The message will be published again after the lease expires
+			logger.Error("Failed to mark outbox message published", err, map[string]interface{}{
+				"outbox_id": msg.ID,
+				"event_id":  msg.EventID,
+			})
+			return
+		}
+		metrics.OutboxPublishedTotal.WithLabelValues(msg.EventType).Inc()
+		metrics.OutboxDeliveryDelay.Observe(time.Since(msg.CreatedAt).Seconds())
+		return
+	}
+
+	metrics.OutboxPublishFailuresTotal.WithLabelValues(msg.EventType).Inc()
+	attempts := msg.Attempts + 1
+
+	if attempts >= r.config.MaxAttempts {
+		logger.Error("Outbox message dead-lettered", err, map[string]interface{}{
+			"outbox_id":  msg.ID,
+			"event_id":   msg.EventID,
+			"event_type": msg.EventType,
+			"attempts":   attempts,
+		})
+		if markErr := r.repo.MarkDead(msg.ID, attempts, err.Error()); markErr != nil {
+			logger.Error("Failed to dead-letter outbox message", markErr, map[string]interface{}{
+				"outbox_id": msg.ID,
+			})
+			return
+		}
+		metrics.OutboxDeadLetteredTotal.WithLabelValues(msg.EventType).Inc()
+		return
+	}
+
+	nextAttemptAt := time.Now().Add(r.backoff(attempts))
+	logger.Warn("Outbox publish failed, will retry", map[string]interface{}{
+		"outbox_id":       msg.ID,
+		"event_id":        msg.EventID,
+		"event_type":      msg.EventType,
+		"attempts":        attempts,
+		"next_attempt_at": nextAttemptAt,
+		"error":           err.Error(),
+	})
+	if markErr := r.repo.MarkRetry(msg.ID, attempts, nextAttemptAt, err.Error()); markErr != nil {
+		logger.Error("Failed to reschedule outbox message", markErr, map[string]interface{}{
+			"outbox_id": msg.ID,
+		})
+	}
+}
+
+// backoff returns the exponential retry delay after the given attempt
+func (r *Relay) backoff(attempts int) time.Duration {
+	delay := r.config.BaseBackoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= r.config.MaxBackoff {
+			return r.config.MaxBackoff
+		}
+	}
+	return delay
+}
+
+// recordStats updates the backlog and lag gauges
+func (r *Relay) recordStats() {
+	stats, err := r.repo.Stats()
+	if err != nil {
+		logger.Error("Failed to get outbox stats", err, nil)
+		return
+	}
+
+	metrics.OutboxPendingMessages.Set(float64(stats.Pending))
+	metrics.OutboxDeadMessages.Set(float64(stats.Dead))
+
+	lag := 0.0
+	if stats.OldestPending != nil {
+		lag = time.Since(*stats.OldestPending).Seconds()
+	}
+	metrics.OutboxLagSeconds.Set(lag)
+}
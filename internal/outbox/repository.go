@@ -0,0 +1,21 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"backend_path/internal/events"
+)
+
+type Repository interface {
+	// EnqueueTx stores events as pending messages inside tx
+	EnqueueTx(ctx context.Context, tx *sql.Tx, events []*events.Event) error
+	// ClaimDue returns pending messages that are due and hides them from other
+	// relays for the lease duration
+	ClaimDue(limit int, lease time.Duration) ([]*Message, error)
+	MarkPublished(id int64) error
+	MarkRetry(id int64, attempts int, nextAttemptAt time.Time, lastError string) error
+	MarkDead(id int64, attempts int, lastError string) error
+	Stats() (*Stats, error)
+}
@@ -0,0 +1,183 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"backend_path/internal/events"
+)
+
+type sqlRepository struct {
+	db *sql.DB
+}
+
+func NewSQLRepository(db *sql.DB) Repository {
+	return &sqlRepository{db: db}
+}
+
+func (r *sqlRepository) EnqueueTx(ctx context.Context, tx *sql.Tx, evts []*events.Event) error {
+	query := `
+		INSERT INTO outbox (event_id, event_type, aggregate_id, payload, status, next_attempt_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+
+	now := time.Now()
+	for _, event := range evts {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("failed to marshal outbox payload: %w", err)
+		}
+
+		_, err = tx.ExecContext(ctx,
+			query,
+			event.ID,
+			event.Type,
+			event.AggregateID,
+			string(payload),
+			StatusPending,
+			now,
+			now,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to enqueue outbox message: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (r *sqlRepository) ClaimDue(limit int, lease time.Duration) ([]*Message, error) {
+	// READPAST lets concurrent relays skip rows another relay is claiming, and
+	// pushing next_attempt_at forward hides claimed rows until the lease ends
+	query := `
+		WITH due AS (
+			SELECT TOP (?) *
+			FROM outbox WITH (UPDLOCK, READPAST, ROWLOCK)
+			WHERE status = ? AND next_attempt_at <= ?
+			ORDER BY id
+		)
+		UPDATE due
+		SET next_attempt_at = ?
+		OUTPUT INSERTED.id, INSERTED.event_id, INSERTED.event_type, INSERTED.aggregate_id,
+			INSERTED.payload, INSERTED.status, INSERTED.attempts, INSERTED.last_error,
+			INSERTED.next_attempt_at, INSERTED.created_at
+	`
+
+	now := time.Now()
+	rows, err := r.db.Query(query, limit, StatusPending, now, now.Add(lease))
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim outbox messages: %w", err)
+	}
+	defer rows.Close()
+
+	var messages []*Message
+	for rows.Next() {
+		msg := &Message{}
+		var lastError sql.NullString
+
+		err := rows.Scan(
+			&msg.ID,
+			&msg.EventID,
+			&msg.EventType,
+			&msg.AggregateID,
+			&msg.Payload,
+			&msg.Status,
+			&msg.Attempts,
+			&lastError,
+			&msg.NextAttemptAt,
+			&msg.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan outbox message: %w", err)
+		}
+
+		msg.LastError = lastError.String
+		messages = append(messages, msg)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating outbox messages: %w", err)
+	}
+
+	return messages, nil
+}
+
+func (r *sqlRepository) MarkPublished(id int64) error {
+	query := `
+		UPDATE outbox
+		SET status = ?, attempts = attempts + 1, last_error = NULL, published_at = ?
+		WHERE id = ?
+	`
+
+	if _, err := r.db.Exec(query, StatusPublished, time.Now(), id); err != nil {
+		return fmt.Errorf("failed to mark outbox message published: %w", err)
+	}
+
+	return nil
+}
+
+func (r *sqlRepository) MarkRetry(id int64, attempts int, nextAttemptAt time.Time, lastError string) error {
+	query := `
+		UPDATE outbox
+		SET attempts = ?, next_attempt_at = ?, last_error = ?
+		WHERE id = ?
+	`
+
+	if _, err := r.db.Exec(query, attempts, nextAttemptAt, lastError, id); err != nil {
+		return fmt.Errorf("failed to reschedule outbox message: %w", err)
+	}
+
+	return nil
+}
+
+func (r *sqlRepository) MarkDead(id int64, attempts int, lastError string) error {
+	query := `
+		UPDATE outbox
+		SET status = ?, attempts = ?, last_error = ?
+		WHERE id = ?
+	`
+
+	if _, err := r.db.Exec(query, StatusDead, attempts, lastError, id); err != nil {
+		return fmt.Errorf("failed to dead-letter outbox message: %w", err)
+	}
+
+	return nil
+}
+
+func (r *sqlRepository) Stats() (*Stats, error) {
+	query := `
+		SELECT
+			SUM(CASE WHEN status = ? THEN 1 ELSE 0 END),
+			SUM(CASE WHEN status = ? THEN 1 ELSE 0 END),
+			MIN(CASE WHEN status = ? THEN created_at END)
+		FROM outbox
+		WHERE status IN (?, ?)
+	`
+
+	var pending, dead sql.NullInt64
+	var oldest sql.NullTime
+
+	err := r.db.QueryRow(query,
+		StatusPending,
+		StatusDead,
+		StatusPending,
+		StatusPending,
+		StatusDead,
+	).Scan(&pending, &dead, &oldest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get outbox stats: %w", err)
+	}
+
+	stats := &Stats{
+		Pending: int(pending.Int64),
+		Dead:    int(dead.Int64),
+	}
+	if oldest.Valid {
+		stats.OldestPending = &oldest.Time
+	}
+
+	return stats, nil
+}
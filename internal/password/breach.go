@@ -0,0 +1,70 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// prefixLength is how many hex characters of a SHA-1 hash select a range
+const prefixLength = 5
+
+// BreachChecker reports whether a password appeared in a data breach
+type BreachChecker interface {
+	Breached(password string) (bool, error)
+}
+
+// BreachList is a local copy of a breached password list, split into
+// ranges by k-anonymity hash prefix like the Pwned Passwords range API: the
+// file <PREFIX>.txt in the directory lists the remaining 35 hex characters
+// of every breached SHA-1 hash starting with PREFIX, one "SUFFIX:COUNT" per
+// line. Only the range of the password's prefix is read.
+type BreachList struct {
+	dir string
+}
+
+// NewBreachList creates a breach list reading ranges from dir
+func NewBreachList(dir string) (*BreachList, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open breached password list: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("failed to open breached password list: %s is not a directory", dir)
+	}
+	return &BreachList{dir: dir}, nil
+}
+
+// Breached reports whether the SHA-1 hash of password is in its range
+func (l *BreachList) Breached(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:prefixLength], hash[prefixLength:]
+
+	file, err := os.Open(filepath.Join(l.dir, prefix+".txt"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read breached password range: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), ":")
+		if strings.EqualFold(strings.TrimSpace(line), suffix) {
+			return true, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return false, fmt.Errorf("failed to read breached password range: %w", err)
+	}
+	return false, nil
+}
@@ -0,0 +1,133 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Params tune argon2id. Raising them makes hashes slower to compute and to
+// crack; existing hashes are upgraded on the next login.
+type Params struct {
+	// Memory is in KiB
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultParams returns the default argon2id parameters
+func DefaultParams() Params {
+	return Params{
+		Memory:      64 * 1024,
+		Iterations:  3,
+		Parallelism: 2,
+		SaltLength:  16,
+		KeyLength:   32,
+	}
+}
+
+var errMalformedHash = errors.New("malformed password hash")
+
+// Hasher hashes passwords with argon2id and verifies argon2id and legacy
+// bcrypt hashes
+type Hasher struct {
+	params Params
+}
+
+// NewHasher creates a hasher using params for new hashes
+func NewHasher(params Params) *Hasher {
+	return &Hasher{params: params}
+}
+
+// Hash returns the argon2id hash of password in the PHC string format
+func (h *Hasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate password salt: %w", err)
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.params.Iterations, h.params.Memory, h.params.Parallelism, h.params.KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version,
+		h.params.Memory,
+		h.params.Iterations,
+		h.params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// Verify reports whether password matches encoded and whether encoded
+// should be rehashed with the current parameters
+func (h *Hasher) Verify(password, encoded string) (bool, bool, error) {
+	if !strings.HasPrefix(encoded, "$argon2id$") {
+		err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, false, nil
+		}
+		if err != nil {
+			return false, false, fmt.Errorf("failed to verify password: %w", err)
+		}
+		return true, true, nil
+	}
+
+	params, salt, key, err := decodeHash(encoded)
+	if err != nil {
+		return false, false, err
+	}
+
+	computed := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+	if subtle.ConstantTimeCompare(computed, key) != 1 {
+		return false, false, nil
+	}
+
+	rehash := params.Memory != h.params.Memory ||
+		params.Iterations != h.params.Iterations ||
+		params.Parallelism != h.params.Parallelism ||
+		params.SaltLength != h.params.SaltLength ||
+		params.KeyLength != h.params.KeyLength
+	return true, rehash, nil
+}
+
+// decodeHash parses an argon2id hash written by Hash
+func decodeHash(encoded string) (Params, []byte, []byte, error) {
+	var params Params
+
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return params, nil, nil, errMalformedHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, errMalformedHash
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return params, nil, nil, errMalformedHash
+	}
+	// argon2id panics on these
+	if params.Iterations < 1 || params.Parallelism < 1 {
+		return params, nil, nil, errMalformedHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, errMalformedHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, errMalformedHash
+	}
+
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+	return params, salt, key, nil
+}
@@ -0,0 +1,61 @@
+package password
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrWeakPassword is returned for new passwords the policy rejects. The
+// wrapping error lists every rule the password breaks.
+var ErrWeakPassword = errors.New("password does not meet the password policy")
+
+// Manager validates new passwords against the policy and the breached
+// password list, and hashes and verifies passwords
+type Manager struct {
+	policy   Policy
+	breaches BreachChecker
+	hasher   *Hasher
+}
+
+// NewManager creates a password manager. breaches may be nil to skip the
+// breached password check.
+func NewManager(policy Policy, breaches BreachChecker, params Params) *Manager {
+	return &Manager{
+		policy:   policy,
+		breaches: breaches,
+		hasher:   NewHasher(params),
+	}
+}
+
+// Validate checks a new password of the user with username and email
+func (m *Manager) Validate(password, username, email string) error {
+	violations := m.policy.violations(password, username, email)
+
+	if m.breaches != nil && len(violations) == 0 {
+		breached, err := m.breaches.Breached(password)
+		if err != nil {
+			return err
+		}
+		if breached {
+			violations = append(violations, "must not have appeared in a data breach")
+		}
+	}
+
+	if len(violations) > 0 {
+		return fmt.Errorf("%w: password %s", ErrWeakPassword, strings.Join(violations, ", "))
+	}
+	return nil
+}
+
+// Hash hashes password for storage with argon2id
+func (m *Manager) Hash(password string) (string, error) {
+	return m.hasher.Hash(password)
+}
+
+// Verify reports whether password matches encoded and whether encoded
+// should be replaced by a new hash, because it is bcrypt or uses other
+// argon2id parameters than the current ones
+func (m *Manager) Verify(password, encoded string) (bool, bool, error) {
+	return m.hasher.Verify(password, encoded)
+}
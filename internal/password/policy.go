@@ -0,0 +1,112 @@
+package password
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// minFragmentLength is the shortest part of a username or email address a
+// password may not contain
+const minFragmentLength = 4
+
+// Policy are the rules new passwords must follow
+type Policy struct {
+	MinLength int
+	// MaxLength bounds the work hashing a password takes
+	MaxLength int
+	// MinClasses is how many of lowercase letters, uppercase letters, digits
+	// and symbols a password must mix
+	MinClasses int
+	// RejectUserFragments rejects passwords containing the username or a
+	// part of the email address
+	RejectUserFragments bool
+}
+
+// DefaultPolicy returns the default password policy
+func DefaultPolicy() Policy {
+	return Policy{
+		MinLength:           10,
+		MaxLength:           128,
+		MinClasses:          3,
+		RejectUserFragments: true,
+	}
+}
+
+// violations returns the rules password breaks
+func (p Policy) violations(password, username, email string) []string {
+	var violations []string
+
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		violations = append(violations, fmt.Sprintf("must be at least %d characters", p.MinLength))
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		violations = append(violations, fmt.Sprintf("must be at most %d characters", p.MaxLength))
+	}
+	if classes := characterClasses(password); classes < p.MinClasses {
+		violations = append(violations, fmt.Sprintf("must mix at least %d of lowercase letters, uppercase letters, digits and symbols", p.MinClasses))
+	}
+	if p.RejectUserFragments {
+		lowered := strings.ToLower(password)
+		for _, fragment := range userFragments(username, email) {
+			if strings.Contains(lowered, fragment) {
+				violations = append(violations, "must not contain your username or email address")
+				break
+			}
+		}
+	}
+
+	return violations
+}
+
+// characterClasses counts the character classes password uses
+func characterClasses(password string) int {
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+
+	classes := 0
+	for _, used := range []bool{lower, upper, digit, symbol} {
+		if used {
+			classes++
+		}
+	}
+	return classes
+}
+
+// userFragments returns the lowercased username, the local part of email
+// and the words of both and of the domain name, ignoring short ones and the
+// top-level domain
+func userFragments(username, email string) []string {
+	local, domain, _ := strings.Cut(strings.ToLower(email), "@")
+	if i := strings.LastIndex(domain, "."); i >= 0 {
+		domain = domain[:i]
+	}
+
+	candidates := []string{strings.ToLower(username), local}
+	for _, value := range []string{strings.ToLower(username), local, domain} {
+		candidates = append(candidates, strings.FieldsFunc(value, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})...)
+	}
+
+	var fragments []string
+	for _, candidate := range candidates {
+		if utf8.RuneCountInString(candidate) >= minFragmentLength {
+			fragments = append(fragments, candidate)
+		}
+	}
+	return fragments
+}
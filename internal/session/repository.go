@@ -0,0 +1,33 @@
+package session
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Repository stores refresh tokens
+type Repository interface {
+	Create(token *RefreshToken) error
+	// GetForUpdate returns the token with id, or nil if there is none, and
+	// locks it until the surrounding transaction ends
+	GetForUpdate(id string) (*RefreshToken, error)
+	MarkUsed(id string, at time.Time) error
+	// RevokeFamily revokes the session familyID and its refresh tokens
+	RevokeFamily(familyID string, at time.Time) error
+	// RevokeUser revokes every session and refresh token of userID
+	RevokeUser(userID int, at time.Time) error
+
+	CreateSession(session *Session) error
+	// GetSession returns the session with id, or nil if there is none
+	GetSession(id string) (*Session, error)
+	// ListActiveSessions returns the sessions of userID that are neither
+	// revoked nor expired at at, oldest first. Inside a transaction they stay
+	// locked until it ends, so concurrent logins respect the session cap.
+	ListActiveSessions(userID int, at time.Time) ([]*Session, error)
+	// TouchSession records a refresh of the session id from device, which
+	// now expires at expiresAt
+	TouchSession(id string, device *Device, at, expiresAt time.Time) error
+
+	// WithTx returns a repository that runs its queries inside tx
+	WithTx(tx *sql.Tx) Repository
+}
@@ -0,0 +1,210 @@
+package session
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"backend_path/pkg/database"
+)
+
+const sessionColumns = `id, user_id, device_name, user_agent, ip_address, created_at, last_seen_at, expires_at, revoked_at`
+
+type sqlRepository struct {
+	db database.Executor
+}
+
+func NewSQLRepository(db *sql.DB) Repository {
+	return &sqlRepository{db: db}
+}
+
+func (r *sqlRepository) WithTx(tx *sql.Tx) Repository {
+	return &sqlRepository{db: tx}
+}
+
+func (r *sqlRepository) Create(token *RefreshToken) error {
+	query := `
+		INSERT INTO refresh_tokens (id, family_id, user_id, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`
+
+	_, err := r.db.Exec(query, token.ID, token.FamilyID, token.UserID, token.ExpiresAt, token.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create refresh token: %w", err)
+	}
+
+	return nil
+}
+
+func (r *sqlRepository) GetForUpdate(id string) (*RefreshToken, error) {
+	query := `
+		SELECT id, family_id, user_id, expires_at, used_at, revoked_at, created_at
+		FROM refresh_tokens WITH (UPDLOCK, ROWLOCK)
+		WHERE id = ?
+	`
+
+	token := &RefreshToken{}
+	var usedAt, revokedAt sql.NullTime
+	err := r.db.QueryRow(query, id).Scan(
+		&token.ID,
+		&token.FamilyID,
+		&token.UserID,
+		&token.ExpiresAt,
+		&usedAt,
+		&revokedAt,
+		&token.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get refresh token: %w", err)
+	}
+
+	if usedAt.Valid {
+		token.UsedAt = &usedAt.Time
+	}
+	if revokedAt.Valid {
+		token.RevokedAt = &revokedAt.Time
+	}
+
+	return token, nil
+}
+
+func (r *sqlRepository) MarkUsed(id string, at time.Time) error {
+	if _, err := r.db.Exec(`UPDATE refresh_tokens SET used_at = ? WHERE id = ?`, at, id); err != nil {
+		return fmt.Errorf("failed to mark refresh token used: %w", err)
+	}
+	return nil
+}
+
+func (r *sqlRepository) RevokeFamily(familyID string, at time.Time) error {
+	query := `UPDATE refresh_tokens SET revoked_at = ? WHERE family_id = ? AND revoked_at IS NULL`
+	if _, err := r.db.Exec(query, at, familyID); err != nil {
+		return fmt.Errorf("failed to revoke refresh token family: %w", err)
+	}
+
+	query = `UPDATE sessions SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL`
+	if _, err := r.db.Exec(query, at, familyID); err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	return nil
+}
+
+func (r *sqlRepository) RevokeUser(userID int, at time.Time) error {
+	query := `UPDATE refresh_tokens SET revoked_at = ? WHERE user_id = ? AND revoked_at IS NULL`
+	if _, err := r.db.Exec(query, at, userID); err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+
+	query = `UPDATE sessions SET revoked_at = ? WHERE user_id = ? AND revoked_at IS NULL`
+	if _, err := r.db.Exec(query, at, userID); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	return nil
+}
+
+func (r *sqlRepository) CreateSession(session *Session) error {
+	query := `
+		INSERT INTO sessions (id, user_id, device_name, user_agent, ip_address, created_at, last_seen_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := r.db.Exec(
+		query,
+		session.ID,
+		session.UserID,
+		session.DeviceName,
+		session.UserAgent,
+		session.IP,
+		session.CreatedAt,
+		session.LastSeenAt,
+		session.ExpiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return nil
+}
+
+func (r *sqlRepository) GetSession(id string) (*Session, error) {
+	session, err := scanSession(r.db.QueryRow(`SELECT `+sessionColumns+` FROM sessions WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return session, err
+}
+
+func (r *sqlRepository) ListActiveSessions(userID int, at time.Time) ([]*Session, error) {
+	query := `
+		SELECT ` + sessionColumns + `
+		FROM sessions WITH (UPDLOCK, HOLDLOCK)
+		WHERE user_id = ? AND revoked_at IS NULL AND expires_at > ?
+		ORDER BY created_at ASC
+	`
+
+	rows, err := r.db.Query(query, userID, at)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []*Session
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating sessions: %w", err)
+	}
+
+	return sessions, nil
+}
+
+func (r *sqlRepository) TouchSession(id string, device *Device, at, expiresAt time.Time) error {
+	query := `UPDATE sessions SET user_agent = ?, ip_address = ?, last_seen_at = ?, expires_at = ? WHERE id = ?`
+	if _, err := r.db.Exec(query, device.UserAgent, device.IP, at, expiresAt, id); err != nil {
+		return fmt.Errorf("failed to update session: %w", err)
+	}
+	return nil
+}
+
+// scanner is implemented by *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanSession scans a single session row. It returns sql.ErrNoRows
+// unwrapped.
+func scanSession(row scanner) (*Session, error) {
+	session := &Session{}
+	var revokedAt sql.NullTime
+	err := row.Scan(
+		&session.ID,
+		&session.UserID,
+		&session.DeviceName,
+		&session.UserAgent,
+		&session.IP,
+		&session.CreatedAt,
+		&session.LastSeenAt,
+		&session.ExpiresAt,
+		&revokedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan session: %w", err)
+	}
+
+	if revokedAt.Valid {
+		session.RevokedAt = &revokedAt.Time
+	}
+
+	return session, nil
+}
@@ -0,0 +1,324 @@
+package session
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"backend_path/internal/domain"
+	"backend_path/pkg/database"
+	"backend_path/pkg/jwt"
+	"backend_path/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+// Limits of the device details stored with a session
+const (
+	maxDeviceNameLength = 100
+	maxUserAgentLength  = 500
+)
+
+type service struct {
+	db          *sql.DB
+	repo        Repository
+	jwtService  *jwt.JWTService
+	revocations *RevocationStore
+	maxSessions int
+}
+
+// NewService creates a session service that allows each user maxSessions
+// concurrent sessions, or any number if maxSessions is 0
+func NewService(db *sql.DB, repo Repository, jwtService *jwt.JWTService, revocations *RevocationStore, maxSessions int) SessionService {
+	return &service{
+		db:          db,
+		repo:        repo,
+		jwtService:  jwtService,
+		revocations: revocations,
+		maxSessions: maxSessions,
+	}
+}
+
+func (s *service) Issue(ctx context.Context, user *domain.User, device *Device) (*Session, string, error) {
+	device = normalizeDevice(device)
+
+	var session *Session
+	var token string
+	var evicted []*Session
+	err := database.WithTransaction(ctx, s.db, func(tx *sql.Tx) error {
+		repo := s.repo.WithTx(tx)
+		now := time.Now()
+
+		// Make room for the new session by ending the oldest ones
+		if s.maxSessions > 0 {
+			active, err := repo.ListActiveSessions(user.ID, now)
+			if err != nil {
+				return err
+			}
+			for len(active) >= s.maxSessions {
+				if err := repo.RevokeFamily(active[0].ID, now); err != nil {
+					return err
+				}
+				evicted = append(evicted, active[0])
+				active = active[1:]
+			}
+		}
+
+		var claims *jwt.Claims
+		var err error
+		token, claims, err = s.issue(repo, user.ID, user.Username, uuid.New().String())
+		if err != nil {
+			return err
+		}
+
+		session = &Session{
+			ID:         claims.FamilyID,
+			UserID:     user.ID,
+			DeviceName: device.Name,
+			UserAgent:  device.UserAgent,
+			IP:         device.IP,
+			CreatedAt:  now,
+			LastSeenAt: now,
+			ExpiresAt:  claims.ExpiresAt.Time,
+		}
+		return repo.CreateSession(session)
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	for _, old := range evicted {
+		if err := s.revocations.DenySession(ctx, old.ID, s.jwtService.TokenDuration()); err != nil {
+			logger.Error("Failed to revoke access tokens of evicted session", err, map[string]interface{}{
+				"user_id":    user.ID,
+				"session_id": old.ID,
+			})
+			continue
+		}
+		logger.Info("Oldest session evicted at the session cap", map[string]interface{}{
+			"user_id":    user.ID,
+			"session_id": old.ID,
+		})
+	}
+
+	return session, token, nil
+}
+
+// issue generates a refresh token in familyID and stores it through repo
+func (s *service) issue(repo Repository, userID int, username, familyID string) (string, *jwt.Claims, error) {
+	token, claims, err := s.jwtService.GenerateRefreshToken(userID, username, familyID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	err = repo.Create(&RefreshToken{
+		ID:        claims.ID,
+		FamilyID:  familyID,
+		UserID:    userID,
+		ExpiresAt: claims.ExpiresAt.Time,
+		CreatedAt: claims.IssuedAt.Time,
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	return token, claims, nil
+}
+
+func (s *service) Rotate(ctx context.Context, refreshToken string, device *Device) (*jwt.Claims, string, error) {
+	claims, err := s.jwtService.ValidateRefreshToken(refreshToken)
+	if err != nil || claims.ID == "" || claims.FamilyID == "" {
+		return nil, "", ErrInvalidToken
+	}
+
+	var rotated string
+	var rotateErr error
+	err = database.WithTransaction(ctx, s.db, func(tx *sql.Tx) error {
+		repo := s.repo.WithTx(tx)
+
+		stored, err := repo.GetForUpdate(claims.ID)
+		if err != nil {
+			return err
+		}
+		if stored == nil || stored.FamilyID != claims.FamilyID || stored.UserID != claims.UserID {
+			rotateErr = ErrInvalidToken
+			return nil
+		}
+		if stored.RevokedAt != nil {
+			rotateErr = ErrTokenRevoked
+			return nil
+		}
+
+		now := time.Now()
+
+		// A rotated token should never come back. Either it was stolen or
+		// the client leaked it, so end the whole session. The revocation
+		// is committed even though the refresh fails.
+		if stored.UsedAt != nil {
+			rotateErr = ErrTokenReused
+			return repo.RevokeFamily(stored.FamilyID, now)
+		}
+
+		if err := repo.MarkUsed(stored.ID, now); err != nil {
+			return err
+		}
+
+		var rotatedClaims *jwt.Claims
+		rotated, rotatedClaims, err = s.issue(repo, claims.UserID, claims.Username, claims.FamilyID)
+		if err != nil {
+			return err
+		}
+		return repo.TouchSession(claims.FamilyID, normalizeDevice(device), now, rotatedClaims.ExpiresAt.Time)
+	})
+	if err != nil {
+		logger.Error("Failed to rotate refresh token", err, map[string]interface{}{
+			"user_id": claims.UserID,
+		})
+		return claims, "", err
+	}
+
+	if rotateErr == ErrTokenReused {
+		logger.Warn("Refresh token reuse detected, token family revoked", map[string]interface{}{
+			"user_id":   claims.UserID,
+			"family_id": claims.FamilyID,
+		})
+		if err := s.revocations.DenySession(ctx, claims.FamilyID, s.jwtService.TokenDuration()); err != nil {
+			logger.Error("Failed to revoke access tokens of reused token family", err, map[string]interface{}{
+				"user_id": claims.UserID,
+			})
+		}
+	}
+	if rotateErr != nil {
+		return claims, "", rotateErr
+	}
+
+	return claims, rotated, nil
+}
+
+func (s *service) RevokeFamily(familyID string) error {
+	return s.repo.RevokeFamily(familyID, time.Now())
+}
+
+func (s *service) List(userID int) ([]*Session, error) {
+	sessions, err := s.repo.ListActiveSessions(userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(sessions, func(i, j int) bool {
+		return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt)
+	})
+	return sessions, nil
+}
+
+func (s *service) Revoke(ctx context.Context, userID int, sessionID string) error {
+	err := database.WithTransaction(ctx, s.db, func(tx *sql.Tx) error {
+		repo := s.repo.WithTx(tx)
+		now := time.Now()
+
+		session, err := repo.GetSession(sessionID)
+		if err != nil {
+			return err
+		}
+		if session == nil || session.UserID != userID || session.RevokedAt != nil || !session.ExpiresAt.After(now) {
+			return ErrSessionNotFound
+		}
+		return repo.RevokeFamily(session.ID, now)
+	})
+	if err != nil {
+		return err
+	}
+
+	// Access tokens of the session stay valid until they expire otherwise
+	if err := s.revocations.DenySession(ctx, sessionID, s.jwtService.TokenDuration()); err != nil {
+		return err
+	}
+
+	logger.Info("Session revoked", map[string]interface{}{
+		"user_id":    userID,
+		"session_id": sessionID,
+	})
+
+	return nil
+}
+
+func (s *service) Logout(ctx context.Context, claims *jwt.Claims, refreshToken string) error {
+	if claims.ExpiresAt != nil {
+		if err := s.revocations.DenyToken(ctx, claims.ID, claims.ExpiresAt.Time); err != nil {
+			return err
+		}
+	}
+
+	// Access tokens name their session, so the login ends even without
+	// its refresh token
+	if claims.FamilyID != "" {
+		if err := s.RevokeFamily(claims.FamilyID); err != nil {
+			return err
+		}
+		if err := s.revocations.DenySession(ctx, claims.FamilyID, s.jwtService.TokenDuration()); err != nil {
+			return err
+		}
+	}
+
+	if refreshToken != "" {
+		refreshClaims, err := s.jwtService.ValidateRefreshToken(refreshToken)
+		if err == nil && refreshClaims.UserID == claims.UserID && refreshClaims.FamilyID != "" && refreshClaims.FamilyID != claims.FamilyID {
+			if err := s.RevokeFamily(refreshClaims.FamilyID); err != nil {
+				return err
+			}
+		}
+	}
+
+	logger.Info("User logged out", map[string]interface{}{
+		"user_id":    claims.UserID,
+		"session_id": claims.FamilyID,
+	})
+
+	return nil
+}
+
+func (s *service) LogoutAll(ctx context.Context, userID int) error {
+	now := time.Now()
+
+	if err := s.revocations.SetWatermark(ctx, userID, now); err != nil {
+		return err
+	}
+	if err := s.repo.RevokeUser(userID, now); err != nil {
+		return err
+	}
+
+	logger.Info("All sessions of user revoked", map[string]interface{}{
+		"user_id": userID,
+	})
+
+	return nil
+}
+
+// normalizeDevice returns device cut to the lengths that are stored
+func normalizeDevice(device *Device) *Device {
+	if device == nil {
+		return &Device{}
+	}
+	return &Device{
+		Name:      truncate(strings.TrimSpace(device.Name), maxDeviceNameLength),
+		UserAgent: truncate(device.UserAgent, maxUserAgentLength),
+		IP:        device.IP,
+	}
+}
+
+// truncate cuts value to at most max bytes without splitting a character
+func truncate(value string, max int) string {
+	if len(value) <= max {
+		return value
+	}
+	for max > 0 && !utf8.RuneStart(value[max]) {
+		max--
+	}
+	return value[:max]
+}
+
+func (s *service) IsRevoked(ctx context.Context, claims *jwt.Claims) (bool, error) {
+	return s.revocations.IsRevoked(ctx, claims)
+}
@@ -0,0 +1,88 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"backend_path/internal/domain"
+	"backend_path/pkg/jwt"
+)
+
+var (
+	// ErrInvalidToken is returned for refresh tokens that are malformed,
+	// expired, of the wrong type or unknown to the server
+	ErrInvalidToken = errors.New("invalid refresh token")
+	// ErrTokenRevoked is returned for refresh tokens whose family was revoked
+	ErrTokenRevoked = errors.New("refresh token revoked")
+	// ErrTokenReused is returned when a refresh token that was already
+	// rotated is used again. Its whole family is revoked.
+	ErrTokenReused = errors.New("refresh token reused")
+	// ErrSessionNotFound is returned for sessions that do not exist, belong
+	// to another user or have already ended
+	ErrSessionNotFound = errors.New("session not found")
+)
+
+// Device describes where a login comes from
+type Device struct {
+	Name      string
+	UserAgent string
+	IP        string
+}
+
+// Session is a login, made of one refresh token family. Its ID is the
+// family ID, which access tokens of the session carry as well.
+type Session struct {
+	ID         string
+	UserID     int
+	DeviceName string
+	UserAgent  string
+	IP         string
+	CreatedAt  time.Time
+	// LastSeenAt is when the session last logged in or refreshed its tokens
+	LastSeenAt time.Time
+	ExpiresAt  time.Time
+	RevokedAt  *time.Time
+}
+
+// RefreshToken is the server-side record of an issued refresh token. Every
+// login starts a family of tokens; each refresh rotates the current token
+// for a new one in the same family.
+type RefreshToken struct {
+	ID        string // jti of the token
+	FamilyID  string
+	UserID    int
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
+
+// SessionService issues and rotates refresh tokens
+type SessionService interface {
+	// Issue starts a new session for user on device and returns it with its
+	// first refresh token. If user is at the session cap, their oldest
+	// sessions are revoked.
+	Issue(ctx context.Context, user *domain.User, device *Device) (*Session, string, error)
+	// Rotate exchanges refreshToken for a new token in the same family and
+	// records device as the last seen one of the session. The claims of
+	// refreshToken are returned whenever its signature is valid, including
+	// alongside ErrTokenReused and ErrTokenRevoked.
+	Rotate(ctx context.Context, refreshToken string, device *Device) (*jwt.Claims, string, error)
+	// RevokeFamily revokes every refresh token of a family
+	RevokeFamily(familyID string) error
+
+	// List returns the active sessions of userID, most recently seen first
+	List(userID int) ([]*Session, error)
+	// Revoke ends a session of userID along with its access tokens
+	Revoke(ctx context.Context, userID int, sessionID string) error
+
+	// Logout revokes the access token with claims and the session it was
+	// issued for, with its refresh tokens, and, if refreshToken belongs to
+	// the same user, the family of refreshToken
+	Logout(ctx context.Context, claims *jwt.Claims, refreshToken string) error
+	// LogoutAll revokes every access and refresh token issued to userID so far
+	LogoutAll(ctx context.Context, userID int) error
+	// IsRevoked reports whether the access token with claims was revoked
+	IsRevoked(ctx context.Context, claims *jwt.Claims) (bool, error)
+}
@@ -0,0 +1,23 @@
+package signingkey
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Repository stores signing keys
+type Repository interface {
+	// Lock takes the rotation lock, held until the transaction ends, so only
+	// one instance rotates the keys at a time. It must run inside a
+	// transaction.
+	Lock() error
+	Create(key *Key) error
+	// ListValid returns the keys that have not expired at at, latest
+	// activation first
+	ListValid(at time.Time) ([]*Key, error)
+	// Expire sets when the key with id stops verifying tokens
+	Expire(id int, at time.Time) error
+
+	// WithTx returns a repository that runs its queries inside tx
+	WithTx(tx *sql.Tx) Repository
+}
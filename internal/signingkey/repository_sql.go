@@ -0,0 +1,95 @@
+package signingkey
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"backend_path/pkg/database"
+)
+
+type sqlRepository struct {
+	db database.Executor
+}
+
+func NewSQLRepository(db *sql.DB) Repository {
+	return &sqlRepository{db: db}
+}
+
+func (r *sqlRepository) WithTx(tx *sql.Tx) Repository {
+	return &sqlRepository{db: tx}
+}
+
+func (r *sqlRepository) Lock() error {
+	// An application lock serializes rotation even while there are no keys
+	// to lock yet
+	query := `
+		DECLARE @result INT;
+		EXEC @result = sp_getapplock @Resource = 'signing_keys', @LockMode = 'Exclusive', @LockOwner = 'Transaction', @LockTimeout = 30000;
+		SELECT @result;
+	`
+
+	var result int
+	if err := r.db.QueryRow(query).Scan(&result); err != nil {
+		return fmt.Errorf("failed to lock signing keys: %w", err)
+	}
+	if result < 0 {
+		return fmt.Errorf("failed to lock signing keys: sp_getapplock returned %d", result)
+	}
+	return nil
+}
+
+func (r *sqlRepository) Create(key *Key) error {
+	query := `
+		INSERT INTO signing_keys (kid, algorithm, private_key, activates_at, created_at)
+		OUTPUT INSERTED.id
+		VALUES (?, ?, ?, ?, ?)
+	`
+
+	err := r.db.QueryRow(query, key.KeyID, key.Algorithm, key.PrivateKey, key.ActivatesAt, key.CreatedAt).Scan(&key.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create signing key: %w", err)
+	}
+	return nil
+}
+
+func (r *sqlRepository) ListValid(at time.Time) ([]*Key, error) {
+	query := `
+		SELECT id, kid, algorithm, private_key, activates_at, expires_at, created_at
+		FROM signing_keys WITH (UPDLOCK, HOLDLOCK)
+		WHERE expires_at IS NULL OR expires_at > ?
+		ORDER BY activates_at DESC, id DESC
+	`
+
+	rows, err := r.db.Query(query, at)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list signing keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []*Key
+	for rows.Next() {
+		key := &Key{}
+		var expiresAt sql.NullTime
+		if err := rows.Scan(&key.ID, &key.KeyID, &key.Algorithm, &key.PrivateKey, &key.ActivatesAt, &expiresAt, &key.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan signing key: %w", err)
+		}
+		if expiresAt.Valid {
+			key.ExpiresAt = &expiresAt.Time
+		}
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating signing keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+func (r *sqlRepository) Expire(id int, at time.Time) error {
+	if _, err := r.db.Exec(`UPDATE signing_keys SET expires_at = ? WHERE id = ?`, at, id); err != nil {
+		return fmt.Errorf("failed to expire signing key: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,272 @@
+package signingkey
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"backend_path/pkg/database"
+	"backend_path/pkg/jwt"
+	"backend_path/pkg/logger"
+)
+
+// Rotator keeps a key ring in sync with the stored signing keys and rotates
+// them when due. Keys are shared by every instance through the database.
+type Rotator struct {
+	db     *sql.DB
+	repo   Repository
+	ring   *jwt.KeyRing
+	aead   cipher.AEAD
+	config Config
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewRotator creates a rotator for ring that encrypts private keys with a
+// key derived from encryptionKey
+func NewRotator(db *sql.DB, repo Repository, ring *jwt.KeyRing, encryptionKey string, config Config) (*Rotator, error) {
+	if config.Algorithm != jwt.AlgorithmRS256 && config.Algorithm != jwt.AlgorithmEdDSA {
+		return nil, fmt.Errorf("%w: %s", jwt.ErrUnsupportedAlgorithm, config.Algorithm)
+	}
+	// Every instance must load a new key before it starts signing
+	if config.RefreshInterval <= 0 || config.RefreshInterval >= config.PublishAhead {
+		return nil, fmt.Errorf("signing key refresh interval %s must be positive and shorter than the publish ahead time %s", config.RefreshInterval, config.PublishAhead)
+	}
+	// Otherwise a new key would be created on every refresh
+	if config.RotationInterval <= config.PublishAhead {
+		return nil, fmt.Errorf("signing key rotation interval %s must be longer than the publish ahead time %s", config.RotationInterval, config.PublishAhead)
+	}
+	// Otherwise refresh tokens signed by a replaced key fail before they expire
+	if config.GracePeriod < jwt.RefreshTokenDuration {
+		return nil, fmt.Errorf("signing key grace period %s must be at least the refresh token lifetime %s", config.GracePeriod, jwt.RefreshTokenDuration)
+	}
+
+	key := sha256.Sum256([]byte(encryptionKey))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to create signing key cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create signing key cipher: %w", err)
+	}
+
+	return &Rotator{
+		db:     db,
+		repo:   repo,
+		ring:   ring,
+		aead:   aead,
+		config: config,
+	}, nil
+}
+
+// Refresh rotates the keys if due and loads them into the ring. A new key is
+// published PublishAhead before it signs, except the very first one; the key
+// it replaces keeps verifying tokens for GracePeriod.
+func (r *Rotator) Refresh(ctx context.Context) error {
+	var keys []*Key
+	var current *Key
+	err := database.WithTransaction(ctx, r.db, func(tx *sql.Tx) error {
+		repo := r.repo.WithTx(tx)
+		if err := repo.Lock(); err != nil {
+			return err
+		}
+		now := time.Now()
+
+		var err error
+		keys, err = repo.ListValid(now)
+		if err != nil {
+			return err
+		}
+
+		if r.due(keys, now) {
+			activatesAt := now
+			if len(keys) > 0 {
+				activatesAt = now.Add(r.config.PublishAhead)
+			}
+			key, err := r.create(repo, activatesAt)
+			if err != nil {
+				return err
+			}
+			keys = append([]*Key{key}, keys...)
+		}
+
+		// Keys are ordered by activation, latest first: the first active key
+		// signs and the ones it superseded start their grace period
+		for _, key := range keys {
+			if key.ActivatesAt.After(now) {
+				continue
+			}
+			if current == nil {
+				current = key
+				continue
+			}
+			if key.ExpiresAt == nil {
+				expiresAt := now.Add(r.config.GracePeriod)
+				if err := repo.Expire(key.ID, expiresAt); err != nil {
+					return err
+				}
+				key.ExpiresAt = &expiresAt
+			}
+		}
+		if current == nil {
+			return jwt.ErrNoSigningKey
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	signingKeys := make([]*jwt.SigningKey, 0, len(keys))
+	var signer *jwt.SigningKey
+	for _, key := range keys {
+		signingKey, err := r.decrypt(key)
+		if err != nil {
+			return err
+		}
+		if key == current {
+			signer = signingKey
+		}
+		signingKeys = append(signingKeys, signingKey)
+	}
+	r.ring.Set(signer, signingKeys)
+
+	return nil
+}
+
+// Start refreshes the keys every RefreshInterval in the background
+func (r *Rotator) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		r.run(ctx)
+	}()
+
+	logger.Info("Signing key rotator started", map[string]interface{}{
+		"algorithm":         r.config.Algorithm,
+		"rotation_interval": r.config.RotationInterval.String(),
+		"grace_period":      r.config.GracePeriod.String(),
+	})
+}
+
+// Stop stops the rotator and waits for a running refresh to finish
+func (r *Rotator) Stop() {
+	if r.cancel != nil {
+		r.cancel()
+	}
+	r.wg.Wait()
+	logger.Info("Signing key rotator stopped", nil)
+}
+
+func (r *Rotator) run(ctx context.Context) {
+	ticker := time.NewTicker(r.config.RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Refresh(ctx); err != nil {
+				logger.Error("Signing key refresh failed", err, nil)
+			}
+		}
+	}
+}
+
+// due reports whether a new key must be created: there is none yet, the
+// algorithm changed, or the latest key is old enough to be replaced once
+// the new one has been published
+func (r *Rotator) due(keys []*Key, now time.Time) bool {
+	if len(keys) == 0 {
+		return true
+	}
+	latest := keys[0]
+	if latest.Algorithm != r.config.Algorithm {
+		return true
+	}
+	return now.Sub(latest.ActivatesAt) >= r.config.RotationInterval-r.config.PublishAhead
+}
+
+// create generates a key activating at activatesAt and stores it
+func (r *Rotator) create(repo Repository, activatesAt time.Time) (*Key, error) {
+	signingKey, err := jwt.GenerateSigningKey(r.config.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := jwt.MarshalPrivateKey(signingKey.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := r.encrypt(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &Key{
+		KeyID:       signingKey.ID,
+		Algorithm:   signingKey.Algorithm,
+		PrivateKey:  encrypted,
+		ActivatesAt: activatesAt,
+		CreatedAt:   signingKey.CreatedAt,
+	}
+	if err := repo.Create(key); err != nil {
+		return nil, err
+	}
+
+	logger.Info("Signing key created", map[string]interface{}{
+		"kid":          key.KeyID,
+		"algorithm":    key.Algorithm,
+		"activates_at": key.ActivatesAt,
+	})
+	return key, nil
+}
+
+// decrypt returns the ring entry of a stored key
+func (r *Rotator) decrypt(key *Key) (*jwt.SigningKey, error) {
+	sealed, err := base64.StdEncoding.DecodeString(key.PrivateKey)
+	if err != nil || len(sealed) < r.aead.NonceSize() {
+		return nil, errors.New("failed to decrypt signing key: malformed ciphertext")
+	}
+	nonce, ciphertext := sealed[:r.aead.NonceSize()], sealed[r.aead.NonceSize():]
+	encoded, err := r.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt signing key %s: %w", key.KeyID, err)
+	}
+	private, err := jwt.ParsePrivateKey(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	return &jwt.SigningKey{
+		ID:          key.KeyID,
+		Algorithm:   key.Algorithm,
+		PrivateKey:  private,
+		ActivatesAt: key.ActivatesAt,
+		ExpiresAt:   key.ExpiresAt,
+		CreatedAt:   key.CreatedAt,
+	}, nil
+}
+
+// encrypt seals an encoded private key for storage as base64 of the nonce
+// and ciphertext
+func (r *Rotator) encrypt(encoded []byte) (string, error) {
+	nonce := make([]byte, r.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to encrypt signing key: %w", err)
+	}
+	sealed := r.aead.Seal(nonce, nonce, encoded, nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
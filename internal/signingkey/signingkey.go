@@ -0,0 +1,47 @@
+package signingkey
+
+import (
+	"time"
+
+	"backend_path/pkg/jwt"
+)
+
+// Key is a stored signing key. PrivateKey is the encrypted PKCS #8 PEM of
+// the key.
+type Key struct {
+	ID          int
+	KeyID       string
+	Algorithm   string
+	PrivateKey  string
+	ActivatesAt time.Time
+	ExpiresAt   *time.Time
+	CreatedAt   time.Time
+}
+
+// Config configures key rotation
+type Config struct {
+	// Algorithm is the algorithm of new keys, RS256 or EdDSA
+	Algorithm string
+	// RotationInterval is how long a key signs tokens before it is replaced
+	RotationInterval time.Duration
+	// GracePeriod is how long a replaced key still verifies tokens. It must
+	// outlast the tokens it signed, refresh tokens included.
+	GracePeriod time.Duration
+	// PublishAhead is how long a new key is published before it signs, so
+	// other instances and verifiers caching the key set know it in time
+	PublishAhead time.Duration
+	// RefreshInterval is how often keys are reloaded and rotated when due.
+	// It must be shorter than PublishAhead.
+	RefreshInterval time.Duration
+}
+
+// DefaultConfig returns the default rotation configuration
+func DefaultConfig() Config {
+	return Config{
+		Algorithm:        jwt.AlgorithmRS256,
+		RotationInterval: 30 * 24 * time.Hour,
+		GracePeriod:      jwt.RefreshTokenDuration + 24*time.Hour,
+		PublishAhead:     10 * time.Minute,
+		RefreshInterval:  time.Minute,
+	}
+}
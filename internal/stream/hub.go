@@ -0,0 +1,361 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"backend_path/internal/events"
+	"backend_path/internal/metrics"
+	"backend_path/pkg/logger"
+	pkgredis "backend_path/pkg/redis"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// EventTypes are the events pushed to stream clients
+var EventTypes = []string{
+	events.EventBalanceUpdated,
+	events.EventTransactionCompleted,
+	events.EventTransactionFailed,
+}
+
+// Message is a notification for a single user. ID orders the messages of a
+// user and is what clients send back as Last-Event-ID.
+type Message struct {
+	ID     string          `json:"id"`
+	UserID int             `json:"user_id"`
+	Type   string          `json:"type"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// HubConfig configures a Hub
+type HubConfig struct {
+	// KeyPrefix prefixes the per-user Redis stream that keeps recent messages for resume
+	KeyPrefix string
+	// ChannelPrefix prefixes the per-user pub/sub channel used to fan messages
+	// out to every instance
+	ChannelPrefix string
+	// MaxLen is the approximate number of messages kept per user
+	MaxLen int64
+	// Retention expires the messages of users without activity
+	Retention time.Duration
+	// BufferSize is the number of messages queued per connection before it is
+	// considered too slow and closed
+	BufferSize int
+	// Heartbeat is the interval between keep-alive messages on idle connections
+	Heartbeat time.Duration
+}
+
+// DefaultHubConfig returns the default hub configuration
+func DefaultHubConfig() HubConfig {
+	return HubConfig{
+		KeyPrefix:     "stream:user:",
+		ChannelPrefix: "stream:notify:",
+		MaxLen:        1000,
+		Retention:     24 * time.Hour,
+		BufferSize:    64,
+		Heartbeat:     15 * time.Second,
+	}
+}
+
+// Hub turns domain events into per-user messages and pushes them to the
+// connections of those users on every instance. Messages are written to a
+// per-user Redis stream so that reconnecting clients can resume, and are
+// announced over Redis pub/sub so that every instance can push them to its
+// own connections.
+type Hub struct {
+	client *pkgredis.RedisClient
+	cfg    HubConfig
+
+	mu          sync.RWMutex
+	subscribers map[int]map[*Subscriber]struct{}
+	closed      bool
+
+	pubsub *redis.PubSub
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewHub creates a new hub
+func NewHub(client *pkgredis.RedisClient, cfg HubConfig) *Hub {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Hub{
+		client:      client,
+		cfg:         cfg,
+		subscribers: make(map[int]map[*Subscriber]struct{}),
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+}
+
+// Config returns the hub configuration
+func (h *Hub) Config() HubConfig {
+	return h.cfg
+}
+
+// Start subscribes to the pub/sub channels of every user
+func (h *Hub) Start() error {
+	h.pubsub = h.client.PSubscribe(h.ctx, h.cfg.ChannelPrefix+"*")
+	if _, err := h.pubsub.Receive(h.ctx); err != nil {
+		h.pubsub.Close()
+		return fmt.Errorf("failed to subscribe to stream notifications: %w", err)
+	}
+
+	h.wg.Add(1)
+	go h.listen()
+
+	logger.Info("Stream hub started", map[string]interface{}{
+		"channel": h.cfg.ChannelPrefix + "*",
+	})
+	return nil
+}
+
+// Close disconnects every subscriber and stops listening for messages
+func (h *Hub) Close() {
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return
+	}
+	h.closed = true
+	for _, subs := range h.subscribers {
+		for sub := range subs {
+			sub.close()
+		}
+	}
+	h.subscribers = make(map[int]map[*Subscriber]struct{})
+	h.mu.Unlock()
+
+	h.cancel()
+	if h.pubsub != nil {
+		h.pubsub.Close()
+	}
+	h.wg.Wait()
+}
+
+// listen dispatches messages received over pub/sub to local subscribers
+func (h *Hub) listen() {
+	defer h.wg.Done()
+
+	for msg := range h.pubsub.Channel() {
+		var message Message
+		if err := json.Unmarshal([]byte(msg.Payload), &message); err != nil {
+			logger.Error("Failed to decode stream notification", err, map[string]interface{}{
+				"channel": msg.Channel,
+			})
+			continue
+		}
+		h.dispatch(&message)
+	}
+}
+
+// dispatch queues message for every local subscriber of its user. Subscribers
+// whose queue is full are closed; they resume from Last-Event-ID when they
+// reconnect.
+func (h *Hub) dispatch(message *Message) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for sub := range h.subscribers[message.UserID] {
+		select {
+		case sub.messages <- message:
+		default:
+			if sub.close() {
+				metrics.StreamSlowConsumersTotal.Inc()
+				logger.Warn("Closing slow stream subscriber", map[string]interface{}{
+					"user_id": message.UserID,
+				})
+			}
+		}
+	}
+}
+
+// HandleEvent stores and announces a message for every user involved in event
+func (h *Hub) HandleEvent(event *events.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal stream event: %w", err)
+	}
+
+	for userID := range events.InvolvedUsers(event) {
+		// System credits and debits use non-positive user IDs
+		if userID <= 0 {
+			continue
+		}
+		if err := h.publish(userID, event.Type, data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// publish appends a message to the user's stream and announces it
+func (h *Hub) publish(userID int, eventType string, data []byte) error {
+	ctx := context.Background()
+	key := h.streamKey(userID)
+
+	id, err := h.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: key,
+		MaxLen: h.cfg.MaxLen,
+		Approx: true,
+		Values: map[string]interface{}{
+			"type": eventType,
+			"data": string(data),
+		},
+	}).Result()
+	if err != nil {
+		return fmt.Errorf("failed to store stream message: %w", err)
+	}
+
+	if h.cfg.Retention > 0 {
+		if err := h.client.Expire(ctx, key, h.cfg.Retention).Err(); err != nil {
+			return fmt.Errorf("failed to set stream retention: %w", err)
+		}
+	}
+
+	payload, err := json.Marshal(&Message{ID: id, UserID: userID, Type: eventType, Data: data})
+	if err != nil {
+		return fmt.Errorf("failed to marshal stream message: %w", err)
+	}
+
+	if err := h.client.Publish(ctx, h.cfg.ChannelPrefix+strconv.Itoa(userID), payload).Err(); err != nil {
+		return fmt.Errorf("failed to announce stream message: %w", err)
+	}
+
+	return nil
+}
+
+// Subscribe registers a connection of userID. The subscriber must be
+// released with Unsubscribe.
+func (h *Hub) Subscribe(userID int) (*Subscriber, error) {
+	sub := &Subscriber{
+		userID:   userID,
+		messages: make(chan *Message, h.cfg.BufferSize),
+		done:     make(chan struct{}),
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil, ErrHubClosed
+	}
+
+	if h.subscribers[userID] == nil {
+		h.subscribers[userID] = make(map[*Subscriber]struct{})
+	}
+	h.subscribers[userID][sub] = struct{}{}
+	return sub, nil
+}
+
+// Unsubscribe releases a subscriber
+func (h *Hub) Unsubscribe(sub *Subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if subs := h.subscribers[sub.userID]; subs != nil {
+		delete(subs, sub)
+		if len(subs) == 0 {
+			delete(h.subscribers, sub.userID)
+		}
+	}
+	sub.close()
+}
+
+// Since returns the stored messages of userID after lastID, oldest first
+func (h *Hub) Since(ctx context.Context, userID int, lastID string) ([]*Message, error) {
+	entries, err := h.client.XRangeN(ctx, h.streamKey(userID), "("+lastID, "+", h.cfg.MaxLen).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stream backlog: %w", err)
+	}
+
+	messages := make([]*Message, 0, len(entries))
+	for _, entry := range entries {
+		eventType, _ := entry.Values["type"].(string)
+		data, _ := entry.Values["data"].(string)
+		messages = append(messages, &Message{
+			ID:     entry.ID,
+			UserID: userID,
+			Type:   eventType,
+			Data:   json.RawMessage(data),
+		})
+	}
+
+	return messages, nil
+}
+
+func (h *Hub) streamKey(userID int) string {
+	return h.cfg.KeyPrefix + strconv.Itoa(userID)
+}
+
+// Subscriber is a single client connection registered with a Hub
+type Subscriber struct {
+	userID    int
+	messages  chan *Message
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// Messages returns the queued messages of the subscriber
+func (s *Subscriber) Messages() <-chan *Message {
+	return s.messages
+}
+
+// Done is closed when the hub drops the subscriber, either because it fell
+// behind or because the hub is shutting down
+func (s *Subscriber) Done() <-chan struct{} {
+	return s.done
+}
+
+// close marks the subscriber as dropped and reports whether this call did it
+func (s *Subscriber) close() bool {
+	closed := false
+	s.closeOnce.Do(func() {
+		close(s.done)
+		closed = true
+	})
+	return closed
+}
+
+// ValidID reports whether id looks like a message ID, i.e. a Redis stream ID
+func ValidID(id string) bool {
+	_, _, ok := parseID(id)
+	return ok
+}
+
+// After reports whether message ID a comes after b
+func After(a, b string) bool {
+	aMs, aSeq, okA := parseID(a)
+	bMs, bSeq, okB := parseID(b)
+	if !okA || !okB {
+		return true
+	}
+	if aMs != bMs {
+		return aMs > bMs
+	}
+	return aSeq > bSeq
+}
+
+// parseID splits a Redis stream ID of the form <milliseconds>-<sequence>
+func parseID(id string) (uint64, uint64, bool) {
+	ms, seq, found := strings.Cut(id, "-")
+	if !found {
+		return 0, 0, false
+	}
+	msValue, err := strconv.ParseUint(ms, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	seqValue, err := strconv.ParseUint(seq, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return msValue, seqValue, true
+}
@@ -0,0 +1,81 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"backend_path/internal/metrics"
+)
+
+var (
+	// ErrHubClosed is returned when subscribing to a hub that is shutting down
+	ErrHubClosed = errors.New("stream hub is closed")
+	// ErrDropped is returned by Serve when the hub dropped the connection
+	// because it fell behind or the hub is shutting down
+	ErrDropped = errors.New("stream connection dropped")
+)
+
+// Writer writes messages to a client connection using a specific transport
+type Writer interface {
+	WriteMessage(message *Message) error
+	WriteHeartbeat() error
+}
+
+// Serve streams the messages of userID to w until ctx is done, the hub drops
+// the connection or a write fails. When lastID is set, messages stored after
+// it are sent first so that a reconnecting client misses nothing.
+func Serve(ctx context.Context, hub *Hub, userID int, lastID string, w Writer) error {
+	// Subscribe before reading the backlog so nothing published in between is lost
+	sub, err := hub.Subscribe(userID)
+	if err != nil {
+		return err
+	}
+	defer hub.Unsubscribe(sub)
+
+	if lastID != "" {
+		backlog, err := hub.Since(ctx, userID, lastID)
+		if err != nil {
+			return err
+		}
+		for _, message := range backlog {
+			if err := send(w, message); err != nil {
+				return err
+			}
+			lastID = message.ID
+		}
+	}
+
+	heartbeat := time.NewTicker(hub.Config().Heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-sub.Done():
+			return ErrDropped
+		case message := <-sub.Messages():
+			// Skip messages already sent from the backlog
+			if lastID != "" && !After(message.ID, lastID) {
+				continue
+			}
+			if err := send(w, message); err != nil {
+				return err
+			}
+			lastID = message.ID
+		case <-heartbeat.C:
+			if err := w.WriteHeartbeat(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func send(w Writer, message *Message) error {
+	if err := w.WriteMessage(message); err != nil {
+		return err
+	}
+	metrics.StreamMessagesSentTotal.WithLabelValues(message.Type).Inc()
+	return nil
+}
@@ -0,0 +1,80 @@
+package stream
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"backend_path/pkg/jwt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// TicketTTL is how long a stream ticket can be redeemed
+const TicketTTL = 30 * time.Second
+
+const ticketKeyPrefix = "stream:ticket:"
+
+// ErrInvalidTicket is returned for tickets that are unknown, expired or
+// already redeemed
+var ErrInvalidTicket = errors.New("invalid or expired stream ticket")
+
+// TicketStore issues tickets that stand in for an access token in the query
+// string of stream requests, for clients such as browser EventSource and
+// WebSocket that cannot set headers. A ticket is redeemed once and expires
+// quickly, so it is worthless once it shows up in a log. Only its SHA-256
+// hash is stored, with the claims of the token it was issued for.
+type TicketStore struct {
+	client *redis.Client
+}
+
+// NewTicketStore creates a ticket store backed by Redis
+func NewTicketStore(client *redis.Client) *TicketStore {
+	return &TicketStore{client: client}
+}
+
+// Issue returns a ticket for the caller with claims
+func (s *TicketStore) Issue(ctx context.Context, claims *jwt.Claims) (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate stream ticket: %w", err)
+	}
+	ticket := base64.RawURLEncoding.EncodeToString(raw)
+
+	data, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal stream ticket: %w", err)
+	}
+	if err := s.client.Set(ctx, ticketKey(ticket), data, TicketTTL).Err(); err != nil {
+		return "", fmt.Errorf("failed to store stream ticket: %w", err)
+	}
+	return ticket, nil
+}
+
+// Redeem consumes ticket and returns the claims it was issued for
+func (s *TicketStore) Redeem(ctx context.Context, ticket string) (*jwt.Claims, error) {
+	data, err := s.client.GetDel(ctx, ticketKey(ticket)).Bytes()
+	if err == redis.Nil {
+		return nil, ErrInvalidTicket
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to redeem stream ticket: %w", err)
+	}
+
+	var claims jwt.Claims
+	if err := json.Unmarshal(data, &claims); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal stream ticket: %w", err)
+	}
+	return &claims, nil
+}
+
+func ticketKey(ticket string) string {
+	sum := sha256.Sum256([]byte(ticket))
+	return ticketKeyPrefix + hex.EncodeToString(sum[:])
+}
@@ -1,10 +1,16 @@
 package transaction
 
-import "backend_path/internal/domain"
+import (
+	"database/sql"
+
+	"backend_path/internal/domain"
+)
 
 type Repository interface {
 	Create(tx *domain.Transaction) error
 	GetByID(id int) (*domain.Transaction, error)
 	GetByUser(userID int) ([]*domain.Transaction, error)
 	Update(tx *domain.Transaction) error
+	// WithTx returns a repository that runs its queries inside tx
+	WithTx(tx *sql.Tx) Repository
 }
@@ -2,19 +2,23 @@ package transaction
 
 import (
 	"backend_path/internal/domain"
-	"context"
+	"backend_path/pkg/database"
 	"database/sql"
 	"fmt"
 )
 
 type sqlRepository struct {
-	db *sql.DB
+	db database.Executor
 }
 
 func NewSQLRepository(db *sql.DB) Repository {
 	return &sqlRepository{db: db}
 }
 
+func (r *sqlRepository) WithTx(tx *sql.Tx) Repository {
+	return &sqlRepository{db: tx}
+}
+
 func (r *sqlRepository) Create(tx *domain.Transaction) error {
 	query := `
 		INSERT INTO transactions (from_user_id, to_user_id, amount, type, status, created_at)
@@ -58,51 +62,6 @@ func (r *sqlRepository) Create(tx *domain.Transaction) error {
 	return nil
 }
 
-func (r *sqlRepository) CreateWithTransaction(ctx context.Context, tx *domain.Transaction) error {
-	// Begin database transaction
-	dbTx, err := r.db.BeginTx(ctx, nil)
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
-	}
-
-	// Defer rollback in case of error
-	defer func() {
-		if err != nil {
-			dbTx.Rollback()
-		}
-	}()
-
-	query := `
-		INSERT INTO transactions (from_user_id, to_user_id, amount, type, status, created_at)
-		OUTPUT INSERTED.id
-		VALUES (?, ?, ?, ?, ?, ?)
-	`
-
-	var id int
-	err = dbTx.QueryRowContext(ctx,
-		query,
-		tx.FromUserID,
-		tx.ToUserID,
-		tx.Amount,
-		tx.Type,
-		tx.Status,
-		tx.CreatedAt,
-	).Scan(&id)
-
-	if err != nil {
-		return fmt.Errorf("failed to create transaction: %w", err)
-	}
-
-	tx.ID = id
-
-	// Commit transaction
-	if err = dbTx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
-	}
-
-	return nil
-}
-
 func (r *sqlRepository) GetByID(id int) (*domain.Transaction, error) {
 	query := `
 		SELECT id, from_user_id, to_user_id, amount, type, status, created_at
@@ -1,29 +1,61 @@
 package transaction
 
 import (
+	"context"
+	"database/sql"
 	"errors"
+	"fmt"
 	"time"
 
+	"backend_path/internal/audit"
 	"backend_path/internal/domain"
+	"backend_path/internal/events"
+	"backend_path/pkg/database"
 	"backend_path/pkg/logger"
 )
 
+// maxAttempts bounds how often a transaction is tried when a concurrent
+// transaction changes one of its balances first
+const maxAttempts = 3
+
+// balanceUpdater applies balance changes inside a database transaction
+type balanceUpdater interface {
+	CreditTx(ctx context.Context, tx *sql.Tx, userID int, amount float64, transactionID int) error
+	DebitTx(ctx context.Context, tx *sql.Tx, userID int, amount float64, transactionID int) error
+	TransferTx(ctx context.Context, tx *sql.Tx, fromUserID, toUserID int, amount float64, transactionID int) error
+}
+
+// eventRecorder records domain events inside a database transaction
+type eventRecorder interface {
+	RecordTx(ctx context.Context, tx *sql.Tx, evts ...*events.Event) error
+}
+
+// auditRecorder records audit log entries inside a database transaction
+type auditRecorder interface {
+	RecordTx(ctx context.Context, tx *sql.Tx, entry *audit.Entry) error
+}
+
 type service struct {
+	db             *sql.DB
 	repo           Repository
-	balanceService interface {
-		UpdateBalance(userID int, amount float64) error
-	}
+	balanceService balanceUpdater
+	recorder       eventRecorder
+	auditor        auditRecorder
 }
 
-func NewService(repo Repository, balanceService interface {
-	UpdateBalance(userID int, amount float64) error
-}) TransactionService {
-	return &service{repo: repo, balanceService: balanceService}
+func NewService(db *sql.DB, repo Repository, balanceService balanceUpdater, recorder eventRecorder, auditor auditRecorder) TransactionService {
+	return &service{
+		db:             db,
+		repo:           repo,
+		balanceService: balanceService,
+		recorder:       recorder,
+		auditor:        auditor,
+	}
 }
 
-func (s *service) ProcessCredit(userID int, amount float64) (*domain.Transaction, error) {
+func (s *service) ProcessCredit(ctx context.Context, userID int, amount float64) (*domain.Transaction, error) {
 	if amount <= 0 {
-		return nil, errors.New("credit amount must be positive")
+		return nil, ErrInvalidAmount
 	}
 
 	// Create credit transaction
@@ -36,33 +68,17 @@ func (s *service) ProcessCredit(userID int, amount float64) (*domain.Transaction
 		CreatedAt:  time.Now(),
 	}
 
-	// Save transaction
-	if err := s.repo.Create(tx); err != nil {
-		logger.Error("Failed to create credit transaction", err, map[string]interface{}{
+	err := s.execute(ctx, tx, func(ctx context.Context, dbTx *sql.Tx) error {
+		return s.balanceService.CreditTx(ctx, dbTx, userID, amount, tx.ID)
+	})
+	if err != nil {
+		logger.Error("Failed to process credit transaction", err, map[string]interface{}{
 			"user_id": userID,
 			"amount":  amount,
 		})
 		return nil, err
 	}
 
-	// Update status to completed
-	tx.SetStatus(domain.StatusCompleted)
-	if err := s.repo.Update(tx); err != nil {
-		logger.Error("Failed to update credit transaction status", err, map[string]interface{}{
-			"transaction_id": tx.ID,
-		})
-		return nil, err
-	}
-
-	// Update user balance
-	if err := s.balanceService.UpdateBalance(userID, amount); err != nil {
-		logger.Error("Failed to update user balance after credit", err, map[string]interface{}{
-			"user_id": userID,
-			"amount":  amount,
-		})
-		// Don't fail the transaction if balance update fails
-	}
-
 	logger.Info("Credit transaction processed successfully", map[string]interface{}{
 		"transaction_id": tx.ID,
 		"user_id":        userID,
@@ -72,9 +88,9 @@ func (s *service) ProcessCredit(userID int, amount float64) (*domain.Transaction
 	return tx, nil
 }
 
-func (s *service) ProcessDebit(userID int, amount float64) (*domain.Transaction, error) {
+func (s *service) ProcessDebit(ctx context.Context, userID int, amount float64) (*domain.Transaction, error) {
 	if amount <= 0 {
-		return nil, errors.New("debit amount must be positive")
+		return nil, ErrInvalidAmount
 	}
 
 	// Create debit transaction
@@ -87,33 +103,18 @@ func (s *service) ProcessDebit(userID int, amount float64) (*domain.Transaction,
 		CreatedAt:  time.Now(),
 	}
 
-	// Save transaction
-	if err := s.repo.Create(tx); err != nil {
-		logger.Error("Failed to create debit transaction", err, map[string]interface{}{
+	// Subtract amount from user balance
+	err := s.execute(ctx, tx, func(ctx context.Context, dbTx *sql.Tx) error {
+		return s.balanceService.DebitTx(ctx, dbTx, userID, amount, tx.ID)
+	})
+	if err != nil {
+		logger.Error("Failed to process debit transaction", err, map[string]interface{}{
 			"user_id": userID,
 			"amount":  amount,
 		})
 		return nil, err
 	}
 
-	// Update status to completed
-	tx.SetStatus(domain.StatusCompleted)
-	if err := s.repo.Update(tx); err != nil {
-		logger.Error("Failed to update debit transaction status", err, map[string]interface{}{
-			"transaction_id": tx.ID,
-		})
-		return nil, err
-	}
-
-	// Update user balance (subtract amount)
-	if err := s.balanceService.UpdateBalance(userID, -amount); err != nil {
-		logger.Error("Failed to update user balance after debit", err, map[string]interface{}{
-			"user_id": userID,
-			"amount":  amount,
-		})
-		// Don't fail the transaction if balance update fails
-	}
-
 	logger.Info("Debit transaction processed successfully", map[string]interface{}{
 		"transaction_id": tx.ID,
 		"user_id":        userID,
@@ -123,13 +124,13 @@ func (s *service) ProcessDebit(userID int, amount float64) (*domain.Transaction,
 	return tx, nil
 }
 
-func (s *service) ProcessTransfer(fromUserID, toUserID int, amount float64) (*domain.Transaction, error) {
+func (s *service) ProcessTransfer(ctx context.Context, fromUserID, toUserID int, amount float64) (*domain.Transaction, error) {
 	if amount <= 0 {
-		return nil, errors.New("transfer amount must be positive")
+		return nil, ErrInvalidAmount
 	}
 
 	if fromUserID == toUserID {
-		return nil, errors.New("cannot transfer to same user")
+		return nil, ErrSameUser
 	}
 
 	// Create transfer transaction
@@ -142,9 +143,11 @@ func (s *service) ProcessTransfer(fromUserID, toUserID int, amount float64) (*do
 		CreatedAt:  time.Now(),
 	}
 
-	// Save transaction
-	if err := s.repo.Create(tx); err != nil {
-		logger.Error("Failed to create transfer transaction", err, map[string]interface{}{
+	err := s.execute(ctx, tx, func(ctx context.Context, dbTx *sql.Tx) error {
+		return s.balanceService.TransferTx(ctx, dbTx, fromUserID, toUserID, amount, tx.ID)
+	})
+	if err != nil {
+		logger.Error("Failed to process transfer transaction", err, map[string]interface{}{
 			"from_user_id": fromUserID,
 			"to_user_id":   toUserID,
 			"amount":       amount,
@@ -152,33 +155,6 @@ func (s *service) ProcessTransfer(fromUserID, toUserID int, amount float64) (*do
 		return nil, err
 	}
 
-	// Update status to completed
-	tx.SetStatus(domain.StatusCompleted)
-	if err := s.repo.Update(tx); err != nil {
-		logger.Error("Failed to update transfer transaction status", err, map[string]interface{}{
-			"transaction_id": tx.ID,
-		})
-		return nil, err
-	}
-
-	// Update sender balance (subtract amount)
-	if err := s.balanceService.UpdateBalance(fromUserID, -amount); err != nil {
-		logger.Error("Failed to update sender balance after transfer", err, map[string]interface{}{
-			"from_user_id": fromUserID,
-			"amount":       amount,
-		})
-		// Don't fail the transaction if balance update fails
-	}
-
-	// Update receiver balance (add amount)
-	if err := s.balanceService.UpdateBalance(toUserID, amount); err != nil {
-		logger.Error("Failed to update receiver balance after transfer", err, map[string]interface{}{
-			"to_user_id": toUserID,
-			"amount":     amount,
-		})
-		// Don't fail the transaction if balance update fails
-	}
-
 	logger.Info("Transfer transaction processed successfully", map[string]interface{}{
 		"transaction_id": tx.ID,
 		"from_user_id":   fromUserID,
@@ -189,6 +165,79 @@ func (s *service) ProcessTransfer(fromUserID, toUserID int, amount float64) (*do
 	return tx, nil
 }
 
+// execute saves tx, applies its balance changes and records the resulting
+// events in a single database transaction, so either all of them are
+// committed or none are. If a concurrent transaction appends to one of the
+// balances first, or the database picks this one as a deadlock victim, the
+// whole transaction is retried against the new balance, up to maxAttempts
+// times before failing with ErrConflict.
+func (s *service) execute(ctx context.Context, tx *domain.Transaction, applyBalances func(ctx context.Context, dbTx *sql.Tx) error) error {
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		// Start over from a transaction that has not been saved
+		tx.ID = 0
+		tx.Status = domain.StatusPending
+
+		err = s.executeOnce(ctx, tx, applyBalances)
+		if !errors.Is(err, events.ErrConcurrencyConflict) && !database.IsDeadlock(err) {
+			return err
+		}
+
+		logger.Warn("Balance changed concurrently, retrying transaction", map[string]interface{}{
+			"type":    tx.Type,
+			"attempt": attempt,
+			"error":   err.Error(),
+		})
+	}
+
+	return fmt.Errorf("%w: %v", ErrConflict, err)
+}
+
+// executeOnce runs a single attempt of execute
+func (s *service) executeOnce(ctx context.Context, tx *domain.Transaction, applyBalances func(ctx context.Context, dbTx *sql.Tx) error) error {
+	return database.WithTransaction(ctx, s.db, func(dbTx *sql.Tx) error {
+		repo := s.repo.WithTx(dbTx)
+
+		// Save transaction
+		if err := repo.Create(tx); err != nil {
+			return err
+		}
+
+		// Update status to completed
+		tx.SetStatus(domain.StatusCompleted)
+		if err := repo.Update(tx); err != nil {
+			return fmt.Errorf("failed to update transaction status: %w", err)
+		}
+
+		if err := applyBalances(ctx, dbTx); err != nil {
+			return fmt.Errorf("failed to update balances: %w", err)
+		}
+
+		if err := s.recorder.RecordTx(ctx, dbTx, transactionEvent(tx)); err != nil {
+			return err
+		}
+
+		return s.auditor.RecordTx(ctx, dbTx, &audit.Entry{
+			EntityType: audit.EntityTransaction,
+			EntityID:   tx.ID,
+			Action:     audit.ActionCreate,
+			After:      tx,
+		})
+	})
+}
+
+// transactionEvent builds the event published when a transaction completes
+func transactionEvent(tx *domain.Transaction) *events.Event {
+	return events.NewEvent(events.EventTransactionCompleted, fmt.Sprintf("transaction-%d", tx.ID), map[string]interface{}{
+		"transaction_id": tx.ID,
+		"from_user_id":   tx.FromUserID,
+		"to_user_id":     tx.ToUserID,
+		"amount":         tx.Amount,
+		"type":           tx.Type,
+		"status":         tx.Status,
+	})
+}
+
 func (s *service) GetTransaction(id int) (*domain.Transaction, error) {
 	tx, err := s.repo.GetByID(id)
 	if err != nil {
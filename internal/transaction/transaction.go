@@ -1,12 +1,28 @@
 package transaction
 
-import "backend_path/internal/domain"
+import (
+	"context"
+	"errors"
+
+	"backend_path/internal/domain"
+)
+
+var (
+	// ErrInvalidAmount is returned for transactions of zero or a negative
+	// amount
+	ErrInvalidAmount = errors.New("amount must be positive")
+	// ErrSameUser is returned for transfers to the sender
+	ErrSameUser = errors.New("cannot transfer to same user")
+	// ErrConflict is returned when the balances of a transaction were
+	// changed by concurrent transactions on every attempt
+	ErrConflict = errors.New("balance was modified concurrently")
+)
 
 // TransactionService provides transaction-related operations
 type TransactionService interface {
-	ProcessCredit(userID int, amount float64) (*domain.Transaction, error)
-	ProcessDebit(userID int, amount float64) (*domain.Transaction, error)
-	ProcessTransfer(fromUserID, toUserID int, amount float64) (*domain.Transaction, error)
+	ProcessCredit(ctx context.Context, userID int, amount float64) (*domain.Transaction, error)
+	ProcessDebit(ctx context.Context, userID int, amount float64) (*domain.Transaction, error)
+	ProcessTransfer(ctx context.Context, fromUserID, toUserID int, amount float64) (*domain.Transaction, error)
 	GetTransaction(id int) (*domain.Transaction, error)
 	GetTransactionHistory(userID int) ([]*domain.Transaction, error)
 }
@@ -7,6 +7,7 @@ type Repository interface {
 	GetByID(id int) (*domain.User, error)
 	GetByEmail(email string) (*domain.User, error)
 	Update(user *domain.User) error
+	UpdatePasswordHash(id int, hash string) error
 	Delete(id int) error
 	GetAll() ([]*domain.User, error)
 }
@@ -48,28 +48,34 @@ func (r *SQLRepository) Create(user *domain.User) error {
 // GetByID retrieves a user by ID
 func (r *SQLRepository) GetByID(id int) (*domain.User, error) {
 	query := `
-		SELECT id, username, email, password_hash, role, created_at, updated_at
+		SELECT id, username, email, password_hash, role, kyc_tier, email_verified_at, created_at, updated_at
 		FROM users
 		WHERE id = ?
 	`
 
 	user := &domain.User{}
+	var emailVerifiedAt sql.NullTime
 	err := r.db.QueryRow(query, id).Scan(
 		&user.ID,
 		&user.Username,
 		&user.Email,
 		&user.PasswordHash,
 		&user.Role,
+		&user.KYCTier,
+		&emailVerifiedAt,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("user not found: %d", id)
+			return nil, fmt.Errorf("%w: %d", ErrNotFound, id)
 		}
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
+	if emailVerifiedAt.Valid {
+		user.EmailVerifiedAt = &emailVerifiedAt.Time
+	}
 
 	return user, nil
 }
@@ -77,28 +83,34 @@ func (r *SQLRepository) GetByID(id int) (*domain.User, error) {
 // GetByEmail retrieves a user by email
 func (r *SQLRepository) GetByEmail(email string) (*domain.User, error) {
 	query := `
-		SELECT id, username, email, password_hash, role, created_at, updated_at
+		SELECT id, username, email, password_hash, role, kyc_tier, email_verified_at, created_at, updated_at
 		FROM users
 		WHERE email = ?
 	`
 
 	user := &domain.User{}
+	var emailVerifiedAt sql.NullTime
 	err := r.db.QueryRow(query, email).Scan(
 		&user.ID,
 		&user.Username,
 		&user.Email,
 		&user.PasswordHash,
 		&user.Role,
+		&user.KYCTier,
+		&emailVerifiedAt,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("user not found: %s", email)
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, email)
 		}
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
+	if emailVerifiedAt.Valid {
+		user.EmailVerifiedAt = &emailVerifiedAt.Time
+	}
 
 	return user, nil
 }
@@ -137,6 +149,15 @@ func (r *SQLRepository) Update(user *domain.User) error {
 	return nil
 }
 
+// UpdatePasswordHash replaces the password hash of a user
+func (r *SQLRepository) UpdatePasswordHash(id int, hash string) error {
+	query := `UPDATE users SET password_hash = ?, updated_at = ? WHERE id = ?`
+	if _, err := r.db.Exec(query, hash, time.Now(), id); err != nil {
+		return fmt.Errorf("failed to update password hash: %w", err)
+	}
+	return nil
+}
+
 // Delete deletes a user by ID
 func (r *SQLRepository) Delete(id int) error {
 	query := `DELETE FROM users WHERE id = ?`
@@ -161,7 +182,7 @@ func (r *SQLRepository) Delete(id int) error {
 // GetAll retrieves all users
 func (r *SQLRepository) GetAll() ([]*domain.User, error) {
 	query := `
-		SELECT id, username, email, password_hash, role, created_at, updated_at
+		SELECT id, username, email, password_hash, role, kyc_tier, email_verified_at, created_at, updated_at
 		FROM users
 		ORDER BY created_at DESC
 	`
@@ -175,18 +196,24 @@ func (r *SQLRepository) GetAll() ([]*domain.User, error) {
 	var users []*domain.User
 	for rows.Next() {
 		user := &domain.User{}
+		var emailVerifiedAt sql.NullTime
 		err := rows.Scan(
 			&user.ID,
 			&user.Username,
 			&user.Email,
 			&user.PasswordHash,
 			&user.Role,
+			&user.KYCTier,
+			&emailVerifiedAt,
 			&user.CreatedAt,
 			&user.UpdatedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan user: %w", err)
 		}
+		if emailVerifiedAt.Valid {
+			user.EmailVerifiedAt = &emailVerifiedAt.Time
+		}
 		users = append(users, user)
 	}
 
@@ -2,42 +2,78 @@ package user
 
 import (
 	"backend_path/internal/domain"
+	"backend_path/internal/password"
+	"backend_path/pkg/logger"
 	"errors"
-
-	"golang.org/x/crypto/bcrypt"
 )
 
 type service struct {
-	repo Repository
+	repo      Repository
+	passwords *password.Manager
 }
 
-func NewService(repo Repository) UserService {
-	return &service{repo: repo}
+// NewService creates a user service that checks and hashes passwords with
+// passwords
+func NewService(repo Repository, passwords *password.Manager) UserService {
+	return &service{
+		repo:      repo,
+		passwords: passwords,
+	}
 }
 
-func (s *service) Register(user *domain.User, password string) error {
+func (s *service) Register(user *domain.User, plaintext string) error {
+	user.Role = DefaultRole
 	if err := user.Validate(); err != nil {
 		return err
 	}
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err := s.passwords.Validate(plaintext, user.Username, user.Email); err != nil {
+		return err
+	}
+	hash, err := s.passwords.Hash(plaintext)
 	if err != nil {
 		return err
 	}
-	user.PasswordHash = string(hash)
+	user.PasswordHash = hash
 	return s.repo.Create(user)
 }
 
-func (s *service) Authenticate(email, password string) (*domain.User, error) {
+func (s *service) Authenticate(email, plaintext string) (*domain.User, error) {
 	user, err := s.repo.GetByEmail(email)
 	if err != nil {
 		return nil, err
 	}
-	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+	ok, rehash, err := s.passwords.Verify(plaintext, user.PasswordHash)
+	if err != nil || !ok {
 		return nil, errors.New("invalid credentials")
 	}
+
+	// Upgrade bcrypt and outdated argon2id hashes while the password is at
+	// hand. Failing to do so does not fail the login.
+	if rehash {
+		if err := s.rehash(user, plaintext); err != nil {
+			logger.Warn("Failed to upgrade password hash", map[string]interface{}{
+				"user_id": user.ID,
+				"error":   err.Error(),
+			})
+		}
+	}
+
 	return user, nil
 }
 
+// rehash replaces the password hash of user with a current one
+func (s *service) rehash(user *domain.User, plaintext string) error {
+	hash, err := s.passwords.Hash(plaintext)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.UpdatePasswordHash(user.ID, hash); err != nil {
+		return err
+	}
+	user.PasswordHash = hash
+	return nil
+}
+
 func (s *service) Authorize(user *domain.User, role string) bool {
 	return user.Role == role
 }
@@ -1,9 +1,21 @@
 package user
 
-import "backend_path/internal/domain"
+import (
+	"errors"
+
+	"backend_path/internal/domain"
+)
+
+// ErrNotFound is returned when a user does not exist
+var ErrNotFound = errors.New("user not found")
+
+// DefaultRole is the role of every publicly registered user. Privileged
+// accounts are created through invitations.
+const DefaultRole = "user"
 
 // UserService provides user-related operations
 type UserService interface {
+	// Register creates user with DefaultRole, whatever role it carries
 	Register(user *domain.User, password string) error
 	Authenticate(email, password string) (*domain.User, error)
 	Authorize(user *domain.User, role string) bool
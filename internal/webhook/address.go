@@ -0,0 +1,130 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenAddress is returned when a webhook URL points at an address the
+// dispatcher must not reach, such as loopback, private or link-local hosts
+var ErrForbiddenAddress = errors.New("webhook url resolves to a forbidden address")
+
+// forbiddenNetworks are internal or special-purpose ranges that
+// net.IP's predicates do not cover
+var forbiddenNetworks = parseCIDRs(
+	"0.0.0.0/8",      // "this network" (RFC 791)
+	"100.64.0.0/10",  // carrier-grade NAT shared address space (RFC 6598)
+	"192.0.0.0/24",   // IETF protocol assignments (RFC 6890)
+	"198.18.0.0/15",  // benchmarking (RFC 2544)
+	"64:ff9b::/96",   // NAT64, which reaches any IPv4 address (RFC 6052)
+	"64:ff9b:1::/48", // local-use NAT64 (RFC 8215)
+)
+
+// parseCIDRs parses CIDR notations, panicking on an invalid one
+func parseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks[i] = network
+	}
+	return networks
+}
+
+// forbiddenIP reports whether ip is not a public unicast address
+func forbiddenIP(ip net.IP) bool {
+	if ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() ||
+		ip.IsUnspecified() {
+		return true
+	}
+
+	for _, network := range forbiddenNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkURL requires an absolute https URL whose host resolves only to public
+// addresses. The dispatcher checks again at dial time, so a record that
+// changes after the endpoint is saved cannot reach an internal host either.
+func checkURL(ctx context.Context, raw string) error {
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Hostname() == "" || parsed.Scheme != "https" {
+		return errors.New("webhook url must be an absolute https url")
+	}
+
+	host := parsed.Hostname()
+	if ip := net.ParseIP(host); ip != nil {
+		if forbiddenIP(ip) {
+			return ErrForbiddenAddress
+		}
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("failed to resolve webhook host %s: %w", host, err)
+	}
+	for _, addr := range addrs {
+		if forbiddenIP(addr.IP) {
+			return ErrForbiddenAddress
+		}
+	}
+
+	return nil
+}
+
+// dialControl refuses connections to forbidden addresses. It runs after name
+// resolution, on the address actually being dialled.
+func dialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || forbiddenIP(ip) {
+		return ErrForbiddenAddress
+	}
+	return nil
+}
+
+// newDeliveryClient returns the HTTP client used to post deliveries. It never
+// uses a proxy, so the dial check sees the endpoint's own address, and it
+// does not follow redirects, so a 3xx response counts as a failed attempt.
+func newDeliveryClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   timeout,
+		KeepAlive: 30 * time.Second,
+		Control:   dialControl,
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:           dialer.DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: 1 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
@@ -0,0 +1,291 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"backend_path/internal/metrics"
+	"backend_path/pkg/circuitbreaker"
+	"backend_path/pkg/logger"
+)
+
+// maxResponseBody is how much of an endpoint's response is kept per attempt
+const maxResponseBody = 1024
+
+// DispatcherConfig configures the webhook dispatcher
+type DispatcherConfig struct {
+	PollInterval    time.Duration // Wait between polls when nothing is due
+	BatchSize       int           // Deliveries claimed per poll
+	Workers         int           // Deliveries sent concurrently
+	Lease           time.Duration // How long a claimed delivery is hidden from other dispatchers
+	Timeout         time.Duration // HTTP request timeout
+	MaxAttempts     int           // Attempts before a delivery is marked failed
+	BaseBackoff     time.Duration // Delay after the first failed attempt
+	MaxBackoff      time.Duration // Upper bound for the retry delay
+	BreakerFailures int64         // Consecutive failures that open an endpoint's breaker
+	BreakerTimeout  time.Duration // How long an open breaker rejects requests
+}
+
+// DefaultDispatcherConfig returns the default dispatcher configuration
+func DefaultDispatcherConfig() DispatcherConfig {
+	return DispatcherConfig{
+		PollInterval:    2 * time.Second,
+		BatchSize:       50,
+		Workers:         10,
+		Lease:           1 * time.Minute,
+		Timeout:         10 * time.Second,
+		MaxAttempts:     8,
+		BaseBackoff:     30 * time.Second,
+		MaxBackoff:      6 * time.Hour,
+		BreakerFailures: 5,
+		BreakerTimeout:  5 * time.Minute,
+	}
+}
+
+// Dispatcher sends queued deliveries to their endpoints. Every endpoint has
+// its own circuit breaker so one failing partner does not slow down the rest.
+type Dispatcher struct {
+	repo     Repository
+	client   *http.Client
+	breakers *circuitbreaker.CircuitBreakerManager
+	config   DispatcherConfig
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+// NewDispatcher creates a new webhook dispatcher
+func NewDispatcher(repo Repository, config DispatcherConfig) *Dispatcher {
+	return &Dispatcher{
+		repo:     repo,
+		client:   newDeliveryClient(config.Timeout),
+		breakers: circuitbreaker.NewCircuitBreakerManager(),
+		config:   config,
+	}
+}
+
+// Start starts dispatching deliveries in the background
+func (d *Dispatcher) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	d.cancel = cancel
+
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		d.run(ctx)
+	}()
+
+	logger.Info("Webhook dispatcher started", map[string]interface{}{
+		"poll_interval": d.config.PollInterval.String(),
+		"workers":       d.config.Workers,
+	})
+}
+
+// Stop stops the dispatcher and waits for in-flight requests
+func (d *Dispatcher) Stop() {
+	if d.cancel != nil {
+		d.cancel()
+	}
+	d.wg.Wait()
+	logger.Info("Webhook dispatcher stopped", nil)
+}
+
+// BreakerStats returns the circuit breaker state of every endpoint seen so far
+func (d *Dispatcher) BreakerStats() map[string]map[string]interface{} {
+	return d.breakers.GetAllStats()
+}
+
+// run polls until ctx is cancelled
+func (d *Dispatcher) run(ctx context.Context) {
+	for {
+		processed, err := d.poll(ctx)
+		if err != nil {
+			logger.Error("Webhook poll failed", err, nil)
+		}
+
+		wait := d.config.PollInterval
+		if err == nil && processed == d.config.BatchSize {
+			wait = 0
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// poll claims one batch of due deliveries and sends them concurrently
+func (d *Dispatcher) poll(ctx context.Context) (int, error) {
+	deliveries, err := d.repo.ClaimDue(d.config.BatchSize, d.config.Lease)
+	if err != nil {
+		return 0, err
+	}
+
+	sem := make(chan struct{}, d.config.Workers)
+	var wg sync.WaitGroup
+	for _, delivery := range deliveries {
+		if ctx.Err() != nil {
+			// Unsent claims become due again once their lease expires
+			break
+		}
+
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(delivery *Delivery) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			d.deliver(ctx, delivery)
+		}(delivery)
+	}
+	wg.Wait()
+
+	return len(deliveries), nil
+}
+
+// deliver makes one attempt for a delivery and schedules the next one if needed
+func (d *Dispatcher) deliver(ctx context.Context, delivery *Delivery) {
+	endpoint, err := d.repo.GetEndpoint(delivery.EndpointID)
+	if err != nil || !endpoint.Active {
+		delivery.Status = DeliveryFailed
+		delivery.LastError = "endpoint is missing or inactive"
+		d.save(delivery)
+		return
+	}
+
+	breaker := d.breakers.GetOrCreate(
+		fmt.Sprintf("webhook-endpoint-%d", endpoint.ID),
+		d.config.BreakerFailures,
+		d.config.BreakerTimeout,
+		1,
+	)
+
+	var attempt *DeliveryAttempt
+	sendErr := breaker.Execute(ctx, func() error {
+		attempt = d.send(ctx, endpoint, delivery)
+		if attempt.Error != "" {
+			return fmt.Errorf("%s", attempt.Error)
+		}
+		return nil
+	})
+
+	if attempt == nil {
+		// The breaker is open, so no request was made; try again once it may have closed
+		delivery.NextAttemptAt = time.Now().Add(d.config.BreakerTimeout)
+		delivery.LastError = sendErr.Error()
+		d.save(delivery)
+		return
+	}
+
+	delivery.Attempts++
+	attempt.Attempt = delivery.Attempts
+	if err := d.repo.CreateAttempt(attempt); err != nil {
+		logger.Error("Failed to record webhook delivery attempt", err, map[string]interface{}{
+			"delivery_id": delivery.ID,
+		})
+	}
+
+	delivery.LastStatusCode = attempt.StatusCode
+	delivery.LastError = attempt.Error
+
+	switch {
+	case sendErr == nil:
+		now := time.Now()
+		delivery.Status = DeliverySucceeded
+		delivery.DeliveredAt = &now
+		metrics.WebhookDeliveryAttemptsTotal.WithLabelValues(delivery.EventType, "succeeded").Inc()
+	case delivery.Attempts >= d.config.MaxAttempts:
+		delivery.Status = DeliveryFailed
+		metrics.WebhookDeliveryAttemptsTotal.WithLabelValues(delivery.EventType, "failed").Inc()
+		logger.Warn("Webhook delivery failed permanently", map[string]interface{}{
+			"delivery_id": delivery.ID,
+			"endpoint_id": endpoint.ID,
+			"attempts":    delivery.Attempts,
+			"error":       attempt.Error,
+		})
+	default:
+		delivery.NextAttemptAt = time.Now().Add(d.backoff(delivery.Attempts))
+		metrics.WebhookDeliveryAttemptsTotal.WithLabelValues(delivery.EventType, "retry").Inc()
+	}
+
+	d.save(delivery)
+}
+
+// send signs and posts the payload, returning the attempt record
+func (d *Dispatcher) send(ctx context.Context, endpoint *Endpoint, delivery *Delivery) *DeliveryAttempt {
+	attempt := &DeliveryAttempt{
+		DeliveryID: delivery.ID,
+		CreatedAt:  time.Now(),
+	}
+
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	// Endpoints saved before https was required are not sent to
+	if req.URL.Scheme != "https" {
+		attempt.Error = "webhook url must be an absolute https url"
+		return attempt
+	}
+
+	timestamp := time.Now()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "GoFintech-Webhooks/1.0")
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp.Unix(), 10))
+	req.Header.Set(HeaderSignature, Sign(endpoint.Secret, timestamp, body))
+
+	start := time.Now()
+	resp, err := d.client.Do(req)
+	duration := time.Since(start)
+	attempt.DurationMs = int(duration.Milliseconds())
+	metrics.WebhookDeliveryDuration.Observe(duration.Seconds())
+
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	defer resp.Body.Close()
+
+	responseBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	attempt.StatusCode = resp.StatusCode
+	attempt.ResponseBody = string(responseBody)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		attempt.Error = fmt.Sprintf("endpoint responded with status %d", resp.StatusCode)
+	}
+
+	return attempt
+}
+
+// backoff returns the exponential retry delay after the given attempt
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.config.BaseBackoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= d.config.MaxBackoff {
+			return d.config.MaxBackoff
+		}
+	}
+	return delay
+}
+
+// save persists the delivery state
+func (d *Dispatcher) save(delivery *Delivery) {
+	if err := d.repo.UpdateDelivery(delivery); err != nil {
+		logger.Error("Failed to update webhook delivery", err, map[string]interface{}{
+			"delivery_id": delivery.ID,
+		})
+	}
+}
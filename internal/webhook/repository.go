@@ -0,0 +1,26 @@
+package webhook
+
+import "time"
+
+type Repository interface {
+	CreateEndpoint(endpoint *Endpoint) error
+	GetEndpoint(id int) (*Endpoint, error)
+	GetEndpointsByUser(userID int) ([]*Endpoint, error)
+	GetClientEndpoints() ([]*Endpoint, error)
+	GetActiveEndpointsForEvent(eventType string) ([]*Endpoint, error)
+	UpdateEndpoint(endpoint *Endpoint) error
+	DeleteEndpoint(id int) error
+
+	// CreateDelivery queues a delivery. It returns false without error when
+	// the endpoint already has a delivery for the event.
+	CreateDelivery(delivery *Delivery) (bool, error)
+	GetDelivery(id int64) (*Delivery, error)
+	GetDeliveriesByEndpoint(endpointID int, limit, offset int) ([]*Delivery, error)
+	// ClaimDue returns pending deliveries that are due and hides them from
+	// other dispatchers for the lease duration
+	ClaimDue(limit int, lease time.Duration) ([]*Delivery, error)
+	UpdateDelivery(delivery *Delivery) error
+
+	CreateAttempt(attempt *DeliveryAttempt) error
+	GetAttempts(deliveryID int64) ([]*DeliveryAttempt, error)
+}
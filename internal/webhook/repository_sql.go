@@ -0,0 +1,456 @@
+package webhook
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"backend_path/pkg/database"
+)
+
+// SQLRepository implements Repository interface for SQL Server
+type SQLRepository struct {
+	db *sql.DB
+}
+
+// NewSQLRepository creates a new SQL repository
+func NewSQLRepository(db *sql.DB) Repository {
+	return &SQLRepository{db: db}
+}
+
+const endpointColumns = `id, user_id, client_id, url, secret, event_types, description, active, created_at, updated_at`
+
+const deliveryColumns = `id, endpoint_id, event_id, event_type, payload, status, attempts, next_attempt_at,
+	last_status_code, last_error, created_at, delivered_at`
+
+// CreateEndpoint creates a new endpoint
+func (r *SQLRepository) CreateEndpoint(endpoint *Endpoint) error {
+	query := `
+		INSERT INTO webhook_endpoints (user_id, client_id, url, secret, event_types, description, active, created_at, updated_at)
+		OUTPUT INSERTED.id
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	now := time.Now()
+	endpoint.CreatedAt = now
+	endpoint.UpdatedAt = now
+
+	err := r.db.QueryRow(
+		query,
+		nullInt(endpoint.UserID),
+		nullString(endpoint.ClientID),
+		endpoint.URL,
+		endpoint.Secret,
+		strings.Join(endpoint.EventTypes, ","),
+		endpoint.Description,
+		endpoint.Active,
+		endpoint.CreatedAt,
+		endpoint.UpdatedAt,
+	).Scan(&endpoint.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook endpoint: %w", err)
+	}
+	return nil
+}
+
+// GetEndpoint retrieves an endpoint by ID
+func (r *SQLRepository) GetEndpoint(id int) (*Endpoint, error) {
+	query := `SELECT ` + endpointColumns + ` FROM webhook_endpoints WHERE id = ?`
+
+	endpoint, err := scanEndpoint(r.db.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("webhook endpoint not found: %d", id)
+		}
+		return nil, fmt.Errorf("failed to get webhook endpoint: %w", err)
+	}
+
+	return endpoint, nil
+}
+
+// GetEndpointsByUser retrieves the endpoints owned by a user
+func (r *SQLRepository) GetEndpointsByUser(userID int) ([]*Endpoint, error) {
+	query := `SELECT ` + endpointColumns + ` FROM webhook_endpoints WHERE user_id = ? ORDER BY created_at DESC`
+	return r.queryEndpoints(query, userID)
+}
+
+// GetClientEndpoints retrieves the endpoints registered for API clients
+func (r *SQLRepository) GetClientEndpoints() ([]*Endpoint, error) {
+	query := `SELECT ` + endpointColumns + ` FROM webhook_endpoints WHERE user_id IS NULL ORDER BY created_at DESC`
+	return r.queryEndpoints(query)
+}
+
+// GetActiveEndpointsForEvent retrieves active endpoints subscribed to eventType
+func (r *SQLRepository) GetActiveEndpointsForEvent(eventType string) ([]*Endpoint, error) {
+	query := `
+		SELECT ` + endpointColumns + `
+		FROM webhook_endpoints
+		WHERE active = 1 AND ',' + event_types + ',' LIKE ?
+	`
+	return r.queryEndpoints(query, "%,"+eventType+",%")
+}
+
+// UpdateEndpoint updates an endpoint
+func (r *SQLRepository) UpdateEndpoint(endpoint *Endpoint) error {
+	query := `
+		UPDATE webhook_endpoints
+		SET url = ?, secret = ?, event_types = ?, description = ?, active = ?, updated_at = ?
+		WHERE id = ?
+	`
+
+	endpoint.UpdatedAt = time.Now()
+	result, err := r.db.Exec(
+		query,
+		endpoint.URL,
+		endpoint.Secret,
+		strings.Join(endpoint.EventTypes, ","),
+		endpoint.Description,
+		endpoint.Active,
+		endpoint.UpdatedAt,
+		endpoint.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook endpoint: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("webhook endpoint not found: %d", endpoint.ID)
+	}
+
+	return nil
+}
+
+// DeleteEndpoint deletes an endpoint and its delivery history
+func (r *SQLRepository) DeleteEndpoint(id int) error {
+	result, err := r.db.Exec(`DELETE FROM webhook_endpoints WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook endpoint: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("webhook endpoint not found: %d", id)
+	}
+
+	return nil
+}
+
+// CreateDelivery queues a delivery unless one already exists for the event
+func (r *SQLRepository) CreateDelivery(delivery *Delivery) (bool, error) {
+	query := `
+		INSERT INTO webhook_deliveries (endpoint_id, event_id, event_type, payload, status, attempts, next_attempt_at, created_at)
+		OUTPUT INSERTED.id
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	err := r.db.QueryRow(
+		query,
+		delivery.EndpointID,
+		delivery.EventID,
+		delivery.EventType,
+		delivery.Payload,
+		delivery.Status,
+		delivery.Attempts,
+		delivery.NextAttemptAt,
+		delivery.CreatedAt,
+	).Scan(&delivery.ID)
+	if err != nil {
+		if database.IsUniqueViolation(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to create webhook delivery: %w", err)
+	}
+
+	return true, nil
+}
+
+// GetDelivery retrieves a delivery by ID
+func (r *SQLRepository) GetDelivery(id int64) (*Delivery, error) {
+	query := `SELECT ` + deliveryColumns + ` FROM webhook_deliveries WHERE id = ?`
+
+	delivery, err := scanDelivery(r.db.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("webhook delivery not found: %d", id)
+		}
+		return nil, fmt.Errorf("failed to get webhook delivery: %w", err)
+	}
+
+	return delivery, nil
+}
+
+// GetDeliveriesByEndpoint retrieves a page of deliveries for an endpoint, newest first
+func (r *SQLRepository) GetDeliveriesByEndpoint(endpointID int, limit, offset int) ([]*Delivery, error) {
+	query := `
+		SELECT ` + deliveryColumns + `
+		FROM webhook_deliveries
+		WHERE endpoint_id = ?
+		ORDER BY id DESC
+		OFFSET ? ROWS FETCH NEXT ? ROWS ONLY
+	`
+	return r.queryDeliveries(query, endpointID, offset, limit)
+}
+
+// ClaimDue claims pending deliveries that are due
+func (r *SQLRepository) ClaimDue(limit int, lease time.Duration) ([]*Delivery, error) {
+	query := `
+		WITH due AS (
+			SELECT TOP (?) *
+			FROM webhook_deliveries WITH (UPDLOCK, READPAST, ROWLOCK)
+			WHERE status = ? AND next_attempt_at <= ?
+			ORDER BY next_attempt_at, id
+		)
+		UPDATE due
+		SET next_attempt_at = ?
+		OUTPUT INSERTED.id, INSERTED.endpoint_id, INSERTED.event_id, INSERTED.event_type, INSERTED.payload,
+			INSERTED.status, INSERTED.attempts, INSERTED.next_attempt_at, INSERTED.last_status_code,
+			INSERTED.last_error, INSERTED.created_at, INSERTED.delivered_at
+	`
+
+	now := time.Now()
+	return r.queryDeliveries(query, limit, DeliveryPending, now, now.Add(lease))
+}
+
+// UpdateDelivery saves the state of a delivery
+func (r *SQLRepository) UpdateDelivery(delivery *Delivery) error {
+	query := `
+		UPDATE webhook_deliveries
+		SET status = ?, attempts = ?, next_attempt_at = ?, last_status_code = ?, last_error = ?, delivered_at = ?
+		WHERE id = ?
+	`
+
+	_, err := r.db.Exec(
+		query,
+		delivery.Status,
+		delivery.Attempts,
+		delivery.NextAttemptAt,
+		nullInt(delivery.LastStatusCode),
+		nullString(delivery.LastError),
+		nullTime(delivery.DeliveredAt),
+		delivery.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook delivery: %w", err)
+	}
+
+	return nil
+}
+
+// CreateAttempt records a delivery attempt
+func (r *SQLRepository) CreateAttempt(attempt *DeliveryAttempt) error {
+	query := `
+		INSERT INTO webhook_delivery_attempts (delivery_id, attempt, status_code, response_body, error, duration_ms, created_at)
+		OUTPUT INSERTED.id
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+
+	err := r.db.QueryRow(
+		query,
+		attempt.DeliveryID,
+		attempt.Attempt,
+		nullInt(attempt.StatusCode),
+		nullString(attempt.ResponseBody),
+		nullString(attempt.Error),
+		attempt.DurationMs,
+		attempt.CreatedAt,
+	).Scan(&attempt.ID)
+	if err != nil {
+		return fmt.Errorf("failed to record webhook delivery attempt: %w", err)
+	}
+
+	return nil
+}
+
+// GetAttempts retrieves the attempts made for a delivery, oldest first
+func (r *SQLRepository) GetAttempts(deliveryID int64) ([]*DeliveryAttempt, error) {
+	query := `
+		SELECT id, delivery_id, attempt, status_code, response_body, error, duration_ms, created_at
+		FROM webhook_delivery_attempts
+		WHERE delivery_id = ?
+		ORDER BY id
+	`
+
+	rows, err := r.db.Query(query, deliveryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook delivery attempts: %w", err)
+	}
+	defer rows.Close()
+
+	var attempts []*DeliveryAttempt
+	for rows.Next() {
+		attempt := &DeliveryAttempt{}
+		var statusCode sql.NullInt64
+		var responseBody, attemptErr sql.NullString
+
+		err := rows.Scan(
+			&attempt.ID,
+			&attempt.DeliveryID,
+			&attempt.Attempt,
+			&statusCode,
+			&responseBody,
+			&attemptErr,
+			&attempt.DurationMs,
+			&attempt.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery attempt: %w", err)
+		}
+
+		attempt.StatusCode = int(statusCode.Int64)
+		attempt.ResponseBody = responseBody.String
+		attempt.Error = attemptErr.String
+		attempts = append(attempts, attempt)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhook delivery attempts: %w", err)
+	}
+
+	return attempts, nil
+}
+
+// queryEndpoints runs an endpoint query and scans the resulting rows
+func (r *SQLRepository) queryEndpoints(query string, args ...interface{}) ([]*Endpoint, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook endpoints: %w", err)
+	}
+	defer rows.Close()
+
+	var endpoints []*Endpoint
+	for rows.Next() {
+		endpoint, err := scanEndpoint(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook endpoint: %w", err)
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhook endpoints: %w", err)
+	}
+
+	return endpoints, nil
+}
+
+// queryDeliveries runs a delivery query and scans the resulting rows
+func (r *SQLRepository) queryDeliveries(query string, args ...interface{}) ([]*Delivery, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []*Delivery
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhook deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// scanner is implemented by *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanEndpoint(row scanner) (*Endpoint, error) {
+	endpoint := &Endpoint{}
+	var userID sql.NullInt64
+	var clientID, description sql.NullString
+	var eventTypes string
+
+	err := row.Scan(
+		&endpoint.ID,
+		&userID,
+		&clientID,
+		&endpoint.URL,
+		&endpoint.Secret,
+		&eventTypes,
+		&description,
+		&endpoint.Active,
+		&endpoint.CreatedAt,
+		&endpoint.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint.UserID = int(userID.Int64)
+	endpoint.ClientID = clientID.String
+	endpoint.Description = description.String
+	if eventTypes != "" {
+		endpoint.EventTypes = strings.Split(eventTypes, ",")
+	}
+
+	return endpoint, nil
+}
+
+func scanDelivery(row scanner) (*Delivery, error) {
+	delivery := &Delivery{}
+	var lastStatusCode sql.NullInt64
+	var lastError sql.NullString
+	var deliveredAt sql.NullTime
+
+	err := row.Scan(
+		&delivery.ID,
+		&delivery.EndpointID,
+		&delivery.EventID,
+		&delivery.EventType,
+		&delivery.Payload,
+		&delivery.Status,
+		&delivery.Attempts,
+		&delivery.NextAttemptAt,
+		&lastStatusCode,
+		&lastError,
+		&delivery.CreatedAt,
+		&deliveredAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery.LastStatusCode = int(lastStatusCode.Int64)
+	delivery.LastError = lastError.String
+	if deliveredAt.Valid {
+		delivery.DeliveredAt = &deliveredAt.Time
+	}
+
+	return delivery, nil
+}
+
+// nullInt stores zero as NULL
+func nullInt(value int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(value), Valid: value != 0}
+}
+
+// nullString stores an empty string as NULL
+func nullString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
+
+// nullTime stores a nil time as NULL
+func nullTime(value *time.Time) sql.NullTime {
+	if value == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: *value, Valid: true}
+}
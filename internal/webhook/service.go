@@ -0,0 +1,208 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"backend_path/internal/events"
+	"backend_path/pkg/logger"
+)
+
+// resolveTimeout bounds the DNS lookup made when an endpoint is saved
+const resolveTimeout = 5 * time.Second
+
+type service struct {
+	repo Repository
+}
+
+func NewService(repo Repository) WebhookService {
+	return &service{repo: repo}
+}
+
+// CreateEndpoint validates the endpoint and generates its signing secret
+func (s *service) CreateEndpoint(endpoint *Endpoint) error {
+	if err := validateEndpoint(endpoint); err != nil {
+		return err
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		return err
+	}
+	endpoint.Secret = secret
+	endpoint.Active = true
+
+	if err := s.repo.CreateEndpoint(endpoint); err != nil {
+		return err
+	}
+
+	logger.Info("Webhook endpoint created", map[string]interface{}{
+		"endpoint_id": endpoint.ID,
+		"user_id":     endpoint.UserID,
+		"client_id":   endpoint.ClientID,
+		"event_types": endpoint.EventTypes,
+	})
+
+	return nil
+}
+
+// GetEndpoint retrieves an endpoint by ID
+func (s *service) GetEndpoint(id int) (*Endpoint, error) {
+	return s.repo.GetEndpoint(id)
+}
+
+// ListEndpoints retrieves the endpoints owned by a user
+func (s *service) ListEndpoints(userID int) ([]*Endpoint, error) {
+	return s.repo.GetEndpointsByUser(userID)
+}
+
+// ListClientEndpoints retrieves the endpoints registered for API clients
+func (s *service) ListClientEndpoints() ([]*Endpoint, error) {
+	return s.repo.GetClientEndpoints()
+}
+
+// UpdateEndpoint validates and saves an endpoint
+func (s *service) UpdateEndpoint(endpoint *Endpoint) error {
+	if err := validateEndpoint(endpoint); err != nil {
+		return err
+	}
+	return s.repo.UpdateEndpoint(endpoint)
+}
+
+// DeleteEndpoint deletes an endpoint
+func (s *service) DeleteEndpoint(id int) error {
+	return s.repo.DeleteEndpoint(id)
+}
+
+// GetDelivery retrieves a delivery by ID
+func (s *service) GetDelivery(id int64) (*Delivery, error) {
+	return s.repo.GetDelivery(id)
+}
+
+// ListDeliveries retrieves a page of deliveries for an endpoint
+func (s *service) ListDeliveries(endpointID int, limit, offset int) ([]*Delivery, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return s.repo.GetDeliveriesByEndpoint(endpointID, limit, offset)
+}
+
+// ListAttempts retrieves the attempts made for a delivery
+func (s *service) ListAttempts(deliveryID int64) ([]*DeliveryAttempt, error) {
+	return s.repo.GetAttempts(deliveryID)
+}
+
+// Redeliver makes a delivery due immediately. A delivery that already used
+// up its retries gets exactly one more attempt.
+func (s *service) Redeliver(deliveryID int64) (*Delivery, error) {
+	delivery, err := s.repo.GetDelivery(deliveryID)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery.Status = DeliveryPending
+	delivery.NextAttemptAt = time.Now()
+	delivery.DeliveredAt = nil
+
+	if err := s.repo.UpdateDelivery(delivery); err != nil {
+		return nil, err
+	}
+
+	logger.Info("Webhook delivery queued for redelivery", map[string]interface{}{
+		"delivery_id": delivery.ID,
+		"endpoint_id": delivery.EndpointID,
+		"event_id":    delivery.EventID,
+	})
+
+	return delivery, nil
+}
+
+// HandleEvent queues a delivery for every active endpoint that subscribes to
+// the event and is allowed to see it. It is safe to call more than once for
+// the same event.
+func (s *service) HandleEvent(event *events.Event) error {
+	if !IsSupportedEventType(event.Type) {
+		return nil
+	}
+
+	endpoints, err := s.repo.GetActiveEndpointsForEvent(event.Type)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"id":         event.ID,
+		"type":       event.Type,
+		"created_at": event.Timestamp,
+		"data":       event.Data,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook payload: %w", err)
+	}
+
+	users := events.InvolvedUsers(event)
+	now := time.Now()
+
+	for _, endpoint := range endpoints {
+		if endpoint.UserID != 0 && !users[endpoint.UserID] {
+			continue
+		}
+
+		delivery := &Delivery{
+			EndpointID:    endpoint.ID,
+			EventID:       event.ID,
+			EventType:     event.Type,
+			Payload:       string(payload),
+			Status:        DeliveryPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		}
+		if _, err := s.repo.CreateDelivery(delivery); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateEndpoint checks the URL and subscribed event types
+func validateEndpoint(endpoint *Endpoint) error {
+	ctx, cancel := context.WithTimeout(context.Background(), resolveTimeout)
+	defer cancel()
+	if err := checkURL(ctx, endpoint.URL); err != nil {
+		return err
+	}
+
+	if len(endpoint.EventTypes) == 0 {
+		return errors.New("at least one event type is required")
+	}
+
+	for _, eventType := range endpoint.EventTypes {
+		if !IsSupportedEventType(eventType) {
+			return fmt.Errorf("unsupported event type: %s", eventType)
+		}
+	}
+
+	if endpoint.UserID == 0 && endpoint.ClientID == "" {
+		return errors.New("endpoint must belong to a user or a client")
+	}
+
+	return nil
+}
+
+// generateSecret returns a random signing secret
+func generateSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return "whsec_" + hex.EncodeToString(buf), nil
+}
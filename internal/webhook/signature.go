@@ -0,0 +1,59 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers sent with every webhook request
+const (
+	HeaderSignature = "X-Webhook-Signature"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+)
+
+// signaturePrefix identifies the signing scheme in the signature header
+const signaturePrefix = "sha256="
+
+// Sign returns the signature header value for body sent at timestamp. The
+// signed message is "<unix timestamp>.<body>", so a captured request cannot be
+// replayed with a different timestamp.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a signature and rejects timestamps older than tolerance. It is
+// what receivers are expected to do and is exported for client libraries.
+func Verify(secret, signature, timestampHeader string, body []byte, tolerance time.Duration) error {
+	unix, err := strconv.ParseInt(timestampHeader, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp header: %w", err)
+	}
+
+	timestamp := time.Unix(unix, 0)
+	if age := time.Since(timestamp); age > tolerance || age < -tolerance {
+		return errors.New("timestamp outside of tolerance")
+	}
+
+	if !strings.HasPrefix(signature, signaturePrefix) {
+		return errors.New("unsupported signature scheme")
+	}
+
+	expected := Sign(secret, timestamp, body)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return errors.New("signature mismatch")
+	}
+
+	return nil
+}
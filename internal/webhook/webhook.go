@@ -0,0 +1,103 @@
+package webhook
+
+import (
+	"time"
+
+	"backend_path/internal/events"
+)
+
+// DeliveryStatus represents the state of a webhook delivery
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	DeliveryFailed    DeliveryStatus = "failed"
+)
+
+// SupportedEventTypes lists the events endpoints can subscribe to
+var SupportedEventTypes = []string{
+	events.EventTransactionCompleted,
+	events.EventTransactionFailed,
+	events.EventBalanceUpdated,
+}
+
+// IsSupportedEventType reports whether endpoints can subscribe to eventType
+func IsSupportedEventType(eventType string) bool {
+	for _, supported := range SupportedEventTypes {
+		if supported == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Endpoint is a URL that receives signed event notifications. Endpoints owned
+// by a user only receive events that involve that user; client endpoints
+// (UserID 0) are registered by admins for partners and receive every event
+// of the subscribed types.
+type Endpoint struct {
+	ID          int       `json:"id"`
+	UserID      int       `json:"user_id,omitempty"`
+	ClientID    string    `json:"client_id,omitempty"`
+	URL         string    `json:"url"`
+	Secret      string    `json:"-"`
+	EventTypes  []string  `json:"event_types"`
+	Description string    `json:"description,omitempty"`
+	Active      bool      `json:"active"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Subscribes reports whether the endpoint wants events of eventType
+func (e *Endpoint) Subscribes(eventType string) bool {
+	for _, t := range e.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Delivery is one event queued for one endpoint
+type Delivery struct {
+	ID             int64          `json:"id"`
+	EndpointID     int            `json:"endpoint_id"`
+	EventID        string         `json:"event_id"`
+	EventType      string         `json:"event_type"`
+	Payload        string         `json:"payload"`
+	Status         DeliveryStatus `json:"status"`
+	Attempts       int            `json:"attempts"`
+	NextAttemptAt  time.Time      `json:"next_attempt_at"`
+	LastStatusCode int            `json:"last_status_code,omitempty"`
+	LastError      string         `json:"last_error,omitempty"`
+	CreatedAt      time.Time      `json:"created_at"`
+	DeliveredAt    *time.Time     `json:"delivered_at,omitempty"`
+}
+
+// DeliveryAttempt records the outcome of a single HTTP request for a delivery
+type DeliveryAttempt struct {
+	ID           int64     `json:"id"`
+	DeliveryID   int64     `json:"delivery_id"`
+	Attempt      int       `json:"attempt"`
+	StatusCode   int       `json:"status_code,omitempty"`
+	ResponseBody string    `json:"response_body,omitempty"`
+	Error        string    `json:"error,omitempty"`
+	DurationMs   int       `json:"duration_ms"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// WebhookService provides webhook-related operations
+type WebhookService interface {
+	CreateEndpoint(endpoint *Endpoint) error
+	GetEndpoint(id int) (*Endpoint, error)
+	ListEndpoints(userID int) ([]*Endpoint, error)
+	ListClientEndpoints() ([]*Endpoint, error)
+	UpdateEndpoint(endpoint *Endpoint) error
+	DeleteEndpoint(id int) error
+	GetDelivery(id int64) (*Delivery, error)
+	ListDeliveries(endpointID int, limit, offset int) ([]*Delivery, error)
+	ListAttempts(deliveryID int64) ([]*DeliveryAttempt, error)
+	Redeliver(deliveryID int64) (*Delivery, error)
+	HandleEvent(event *events.Event) error
+}
@@ -0,0 +1,18 @@
+-- Event store
+CREATE TABLE events (
+    sequence BIGINT IDENTITY(1,1) PRIMARY KEY,
+    id NVARCHAR(36) NOT NULL UNIQUE,
+    type NVARCHAR(100) NOT NULL,
+    aggregate_id NVARCHAR(100) NOT NULL,
+    version INT NOT NULL,
+    data NVARCHAR(MAX),
+    metadata NVARCHAR(MAX),
+    occurred_at DATETIME2 NOT NULL DEFAULT GETDATE(),
+    -- Concurrent appends to the same aggregate version conflict here
+    CONSTRAINT UQ_events_aggregate_version UNIQUE (aggregate_id, version)
+);
+
+CREATE INDEX IX_events_type ON events(type, sequence);
+CREATE INDEX IX_events_occurred_at ON events(occurred_at, sequence);
+
+PRINT 'Event store created successfully!';
@@ -0,0 +1,22 @@
+-- Optimistic concurrency for balances
+ALTER TABLE balances ADD version INT NOT NULL DEFAULT 0;
+GO
+
+-- Transactional outbox for domain events
+CREATE TABLE outbox (
+    id BIGINT IDENTITY(1,1) PRIMARY KEY,
+    event_id NVARCHAR(36) NOT NULL UNIQUE,
+    event_type NVARCHAR(100) NOT NULL,
+    aggregate_id NVARCHAR(100) NOT NULL,
+    payload NVARCHAR(MAX) NOT NULL,
+    status NVARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    last_error NVARCHAR(MAX),
+    next_attempt_at DATETIME2 NOT NULL DEFAULT GETDATE(),
+    created_at DATETIME2 NOT NULL DEFAULT GETDATE(),
+    published_at DATETIME2 NULL
+);
+
+CREATE INDEX IX_outbox_status_next_attempt ON outbox(status, next_attempt_at, id);
+
+PRINT 'Outbox created successfully!';
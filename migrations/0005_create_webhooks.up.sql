@@ -0,0 +1,48 @@
+-- Outgoing webhooks
+CREATE TABLE webhook_endpoints (
+    id INT IDENTITY(1,1) PRIMARY KEY,
+    user_id INT NULL FOREIGN KEY REFERENCES users(id) ON DELETE CASCADE,
+    client_id NVARCHAR(100) NULL,
+    url NVARCHAR(2048) NOT NULL,
+    secret NVARCHAR(100) NOT NULL,
+    event_types NVARCHAR(1000) NOT NULL,
+    description NVARCHAR(255),
+    active BIT NOT NULL DEFAULT 1,
+    created_at DATETIME2 NOT NULL DEFAULT GETDATE(),
+    updated_at DATETIME2 NOT NULL DEFAULT GETDATE()
+);
+
+CREATE TABLE webhook_deliveries (
+    id BIGINT IDENTITY(1,1) PRIMARY KEY,
+    endpoint_id INT NOT NULL FOREIGN KEY REFERENCES webhook_endpoints(id) ON DELETE CASCADE,
+    event_id NVARCHAR(36) NOT NULL,
+    event_type NVARCHAR(100) NOT NULL,
+    payload NVARCHAR(MAX) NOT NULL,
+    status NVARCHAR(20) NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at DATETIME2 NOT NULL DEFAULT GETDATE(),
+    last_status_code INT NULL,
+    last_error NVARCHAR(MAX),
+    created_at DATETIME2 NOT NULL DEFAULT GETDATE(),
+    delivered_at DATETIME2 NULL,
+    -- Events are delivered at least once, so fan-out must be idempotent
+    CONSTRAINT UQ_webhook_deliveries_endpoint_event UNIQUE (endpoint_id, event_id)
+);
+
+CREATE TABLE webhook_delivery_attempts (
+    id BIGINT IDENTITY(1,1) PRIMARY KEY,
+    delivery_id BIGINT NOT NULL FOREIGN KEY REFERENCES webhook_deliveries(id) ON DELETE CASCADE,
+    attempt INT NOT NULL,
+    status_code INT NULL,
+    response_body NVARCHAR(1024),
+    error NVARCHAR(MAX),
+    duration_ms INT NOT NULL,
+    created_at DATETIME2 NOT NULL DEFAULT GETDATE()
+);
+
+CREATE INDEX IX_webhook_endpoints_user_id ON webhook_endpoints(user_id);
+CREATE INDEX IX_webhook_deliveries_status_next_attempt ON webhook_deliveries(status, next_attempt_at, id);
+CREATE INDEX IX_webhook_deliveries_endpoint_id ON webhook_deliveries(endpoint_id, created_at);
+CREATE INDEX IX_webhook_delivery_attempts_delivery_id ON webhook_delivery_attempts(delivery_id);
+
+PRINT 'Webhook tables created successfully!';
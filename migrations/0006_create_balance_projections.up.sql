@@ -0,0 +1,65 @@
+-- Latest snapshot of each event-sourced aggregate
+CREATE TABLE snapshots (
+    aggregate_id NVARCHAR(100) NOT NULL PRIMARY KEY,
+    version INT NOT NULL,
+    state NVARCHAR(MAX) NOT NULL,
+    created_at DATETIME2 NOT NULL DEFAULT GETDATE()
+);
+
+-- Balance history read model, one row per balance event
+CREATE TABLE balance_history (
+    id BIGINT IDENTITY(1,1) PRIMARY KEY,
+    user_id INT NOT NULL FOREIGN KEY REFERENCES users(id),
+    event_id NVARCHAR(36) NOT NULL UNIQUE,
+    event_type NVARCHAR(100) NOT NULL,
+    version INT NOT NULL,
+    amount DECIMAL(18,2) NOT NULL,
+    balance DECIMAL(18,2) NOT NULL,
+    transaction_id INT NULL,
+    occurred_at DATETIME2 NOT NULL
+);
+
+CREATE INDEX IX_balance_history_user ON balance_history(user_id, version);
+CREATE INDEX IX_balance_history_user_occurred_at ON balance_history(user_id, occurred_at);
+GO
+
+-- Open an event stream for balances that were never recorded as events
+INSERT INTO events (id, type, aggregate_id, version, data, metadata, occurred_at)
+SELECT
+    LOWER(CONVERT(NVARCHAR(36), NEWID())),
+    'balance.opened',
+    CONCAT('balance-', b.user_id),
+    b.version + 1,
+    CONCAT('{"user_id":', b.user_id, ',"balance":', b.amount, '}'),
+    '{}',
+    b.last_updated_at
+FROM balances b
+WHERE NOT EXISTS (
+    SELECT 1 FROM events e WHERE e.aggregate_id = CONCAT('balance-', b.user_id)
+);
+
+UPDATE b
+SET b.version = e.version
+FROM balances b
+JOIN events e ON e.aggregate_id = CONCAT('balance-', b.user_id) AND e.type = 'balance.opened';
+GO
+
+-- Backfill the history from the balance events recorded so far
+INSERT INTO balance_history (user_id, event_id, event_type, version, amount, balance, transaction_id, occurred_at)
+SELECT
+    CAST(JSON_VALUE(e.data, '$.user_id') AS INT),
+    e.id,
+    e.type,
+    e.version,
+    CASE WHEN e.type = 'balance.opened'
+        THEN CAST(JSON_VALUE(e.data, '$.balance') AS DECIMAL(18,2))
+        ELSE CAST(JSON_VALUE(e.data, '$.amount') AS DECIMAL(18,2))
+    END,
+    CAST(JSON_VALUE(e.data, '$.balance') AS DECIMAL(18,2)),
+    NULLIF(CAST(JSON_VALUE(e.data, '$.transaction_id') AS INT), 0),
+    e.occurred_at
+FROM events e
+WHERE e.type IN ('balance.opened', 'balance.updated')
+  AND e.aggregate_id LIKE 'balance-%';
+
+PRINT 'Balance projections created successfully!';
@@ -0,0 +1,13 @@
+-- Who made an audited change, from where, and what it changed
+ALTER TABLE audit_logs ADD
+    actor_id INT NULL,
+    ip_address NVARCHAR(45) NULL,
+    user_agent NVARCHAR(512) NULL,
+    trace_id NVARCHAR(32) NULL,
+    before_state NVARCHAR(MAX) NULL,
+    after_state NVARCHAR(MAX) NULL,
+    changes NVARCHAR(MAX) NULL;
+GO
+
+CREATE INDEX IX_audit_logs_actor_id ON audit_logs(actor_id);
+CREATE INDEX IX_audit_logs_action ON audit_logs(action);
@@ -0,0 +1,18 @@
+-- Tamper-evident audit trail: every entry stores the hash of the entry
+-- before it and its own content hash. Existing entries stay unhashed and
+-- precede the chain.
+ALTER TABLE audit_logs ADD
+    prev_hash NVARCHAR(64) NULL,
+    hash NVARCHAR(64) NULL;
+GO
+
+-- Signed checkpoints of the head of the chain
+CREATE TABLE audit_checkpoints (
+    id INT IDENTITY(1,1) PRIMARY KEY,
+    last_log_id INT NOT NULL,
+    last_hash NVARCHAR(64) NOT NULL,
+    signature NVARCHAR(64) NOT NULL,
+    created_at DATETIME2 NOT NULL DEFAULT GETDATE()
+);
+
+CREATE INDEX IX_audit_checkpoints_last_log_id ON audit_checkpoints(last_log_id);
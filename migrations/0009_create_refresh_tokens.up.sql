@@ -0,0 +1,14 @@
+-- Server-side state of issued refresh tokens. A family is the chain of
+-- tokens rotated from one login; reusing a rotated token revokes the family.
+CREATE TABLE refresh_tokens (
+    id NVARCHAR(36) PRIMARY KEY,
+    family_id NVARCHAR(36) NOT NULL,
+    user_id INT NOT NULL FOREIGN KEY REFERENCES users(id) ON DELETE CASCADE,
+    expires_at DATETIME2 NOT NULL,
+    used_at DATETIME2 NULL,
+    revoked_at DATETIME2 NULL,
+    created_at DATETIME2 NOT NULL DEFAULT GETDATE()
+);
+
+CREATE INDEX IX_refresh_tokens_family_id ON refresh_tokens(family_id);
+CREATE INDEX IX_refresh_tokens_user_id ON refresh_tokens(user_id);
@@ -0,0 +1,38 @@
+-- RBAC roles, their permissions and inheritance, and access control
+-- policies. The application seeds the default roles and policies when the
+-- roles table is empty.
+CREATE TABLE roles (
+    name NVARCHAR(50) PRIMARY KEY,
+    created_at DATETIME2 NOT NULL DEFAULT GETDATE(),
+    updated_at DATETIME2 NOT NULL DEFAULT GETDATE()
+);
+
+CREATE TABLE role_permissions (
+    id INT IDENTITY(1,1) PRIMARY KEY,
+    role_name NVARCHAR(50) NOT NULL FOREIGN KEY REFERENCES roles(name) ON DELETE CASCADE,
+    resource NVARCHAR(50) NOT NULL,
+    action NVARCHAR(50) NOT NULL,
+    resource_id NVARCHAR(50) NULL
+);
+
+-- Inherited roles are validated by the application so roles can be
+-- created in any order
+CREATE TABLE role_inherits (
+    role_name NVARCHAR(50) NOT NULL FOREIGN KEY REFERENCES roles(name) ON DELETE CASCADE,
+    inherits NVARCHAR(50) NOT NULL,
+    CONSTRAINT PK_role_inherits PRIMARY KEY (role_name, inherits)
+);
+
+CREATE TABLE policies (
+    id INT IDENTITY(1,1) PRIMARY KEY,
+    resource NVARCHAR(50) NOT NULL,
+    actions NVARCHAR(500) NOT NULL,
+    roles NVARCHAR(500) NOT NULL,
+    conditions NVARCHAR(MAX) NULL,
+    created_at DATETIME2 NOT NULL DEFAULT GETDATE(),
+    updated_at DATETIME2 NOT NULL DEFAULT GETDATE()
+);
+
+CREATE INDEX IX_role_permissions_role_name ON role_permissions(role_name);
+CREATE INDEX IX_role_inherits_inherits ON role_inherits(inherits);
+CREATE INDEX IX_policies_resource ON policies(resource);
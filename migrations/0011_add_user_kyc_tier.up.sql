@@ -0,0 +1,3 @@
+-- Know-your-customer verification tier of a user, evaluated by policy
+-- conditions. 0 means unverified.
+ALTER TABLE users ADD kyc_tier INT NOT NULL DEFAULT 0;
@@ -0,0 +1,20 @@
+-- TOTP two-factor authentication. The secret is encrypted by the
+-- application; last_used_step keeps each code from being used twice.
+CREATE TABLE mfa_enrollments (
+    user_id INT PRIMARY KEY FOREIGN KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret NVARCHAR(255) NOT NULL,
+    confirmed_at DATETIME2 NULL,
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    created_at DATETIME2 NOT NULL DEFAULT GETDATE()
+);
+
+-- One-time recovery codes, stored as SHA-256 hashes
+CREATE TABLE mfa_recovery_codes (
+    id INT IDENTITY(1,1) PRIMARY KEY,
+    user_id INT NOT NULL FOREIGN KEY REFERENCES users(id) ON DELETE CASCADE,
+    code_hash NVARCHAR(64) NOT NULL,
+    used_at DATETIME2 NULL,
+    created_at DATETIME2 NOT NULL DEFAULT GETDATE()
+);
+
+CREATE INDEX IX_mfa_recovery_codes_user_id ON mfa_recovery_codes(user_id);
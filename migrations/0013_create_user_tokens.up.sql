@@ -0,0 +1,21 @@
+-- When a user proved they own their email address. Users who existed
+-- before verification was introduced are treated as verified.
+ALTER TABLE users ADD email_verified_at DATETIME2 NULL;
+GO
+
+UPDATE users SET email_verified_at = created_at;
+GO
+
+-- Single-use tokens mailed to users to reset their password or verify their
+-- email address, stored as SHA-256 hashes
+CREATE TABLE user_tokens (
+    id INT IDENTITY(1,1) PRIMARY KEY,
+    user_id INT NOT NULL FOREIGN KEY REFERENCES users(id) ON DELETE CASCADE,
+    purpose NVARCHAR(20) NOT NULL,
+    token_hash NVARCHAR(64) NOT NULL UNIQUE,
+    expires_at DATETIME2 NOT NULL,
+    used_at DATETIME2 NULL,
+    created_at DATETIME2 NOT NULL DEFAULT GETDATE()
+);
+
+CREATE INDEX IX_user_tokens_user_id ON user_tokens(user_id, purpose);
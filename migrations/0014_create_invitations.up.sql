@@ -0,0 +1,16 @@
+-- Invitations to create privileged accounts. The token is mailed to the
+-- invitee and stored as a SHA-256 hash.
+CREATE TABLE invitations (
+    id INT IDENTITY(1,1) PRIMARY KEY,
+    email NVARCHAR(100) NOT NULL,
+    role NVARCHAR(20) NOT NULL,
+    token_hash NVARCHAR(64) NOT NULL UNIQUE,
+    invited_by INT NOT NULL FOREIGN KEY REFERENCES users(id),
+    expires_at DATETIME2 NOT NULL,
+    accepted_at DATETIME2 NULL,
+    user_id INT NULL,
+    revoked_at DATETIME2 NULL,
+    created_at DATETIME2 NOT NULL DEFAULT GETDATE()
+);
+
+CREATE INDEX IX_invitations_email ON invitations(email);
@@ -0,0 +1,18 @@
+-- API keys for server-to-server clients, stored as SHA-256 hashes. Scopes
+-- and allowed IPs are comma-separated.
+CREATE TABLE api_keys (
+    id INT IDENTITY(1,1) PRIMARY KEY,
+    user_id INT NOT NULL FOREIGN KEY REFERENCES users(id) ON DELETE CASCADE,
+    name NVARCHAR(100) NOT NULL,
+    prefix NVARCHAR(20) NOT NULL,
+    key_hash NVARCHAR(64) NOT NULL UNIQUE,
+    scopes NVARCHAR(MAX) NOT NULL,
+    allowed_ips NVARCHAR(MAX) NOT NULL DEFAULT '',
+    expires_at DATETIME2 NULL,
+    last_used_at DATETIME2 NULL,
+    revoked_at DATETIME2 NULL,
+    created_by INT NOT NULL,
+    created_at DATETIME2 NOT NULL DEFAULT GETDATE()
+);
+
+CREATE INDEX IX_api_keys_user_id ON api_keys(user_id);
@@ -0,0 +1,42 @@
+-- OAuth 2.0 clients. Confidential clients have a secret, stored as a
+-- SHA-256 hash; user_id is the account client_credentials tokens act as.
+-- Lists are comma-separated.
+CREATE TABLE oauth_clients (
+    id INT IDENTITY(1,1) PRIMARY KEY,
+    client_id NVARCHAR(64) NOT NULL UNIQUE,
+    secret_hash NVARCHAR(64) NULL,
+    name NVARCHAR(100) NOT NULL,
+    redirect_uris NVARCHAR(MAX) NOT NULL DEFAULT '',
+    grant_types NVARCHAR(255) NOT NULL,
+    scopes NVARCHAR(MAX) NOT NULL,
+    user_id INT NULL FOREIGN KEY REFERENCES users(id),
+    created_by INT NOT NULL,
+    created_at DATETIME2 NOT NULL DEFAULT GETDATE(),
+    revoked_at DATETIME2 NULL
+);
+GO
+
+-- Single-use authorization codes with their PKCE challenge, stored as
+-- SHA-256 hashes
+CREATE TABLE oauth_authorization_codes (
+    id INT IDENTITY(1,1) PRIMARY KEY,
+    code_hash NVARCHAR(64) NOT NULL UNIQUE,
+    client_id INT NOT NULL FOREIGN KEY REFERENCES oauth_clients(id),
+    user_id INT NOT NULL FOREIGN KEY REFERENCES users(id) ON DELETE CASCADE,
+    redirect_uri NVARCHAR(2048) NOT NULL,
+    scopes NVARCHAR(MAX) NOT NULL,
+    code_challenge NVARCHAR(128) NOT NULL,
+    expires_at DATETIME2 NOT NULL,
+    used_at DATETIME2 NULL,
+    created_at DATETIME2 NOT NULL DEFAULT GETDATE()
+);
+GO
+
+-- Scopes each user granted each client
+CREATE TABLE oauth_consents (
+    user_id INT NOT NULL FOREIGN KEY REFERENCES users(id) ON DELETE CASCADE,
+    client_id INT NOT NULL FOREIGN KEY REFERENCES oauth_clients(id),
+    scopes NVARCHAR(MAX) NOT NULL,
+    granted_at DATETIME2 NOT NULL DEFAULT GETDATE(),
+    PRIMARY KEY (user_id, client_id)
+);
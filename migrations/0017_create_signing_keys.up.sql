@@ -0,0 +1,14 @@
+-- Asymmetric JWT signing keys. Private keys are PKCS #8 PEM, encrypted with
+-- AES-GCM. A key signs from activates_at until a newer key activates, and
+-- verifies until expires_at, which is set when it is superseded.
+CREATE TABLE signing_keys (
+    id INT IDENTITY(1,1) PRIMARY KEY,
+    kid NVARCHAR(64) NOT NULL UNIQUE,
+    algorithm NVARCHAR(10) NOT NULL,
+    private_key NVARCHAR(MAX) NOT NULL,
+    activates_at DATETIME2 NOT NULL,
+    expires_at DATETIME2 NULL,
+    created_at DATETIME2 NOT NULL DEFAULT GETDATE()
+);
+
+CREATE INDEX IX_signing_keys_expires_at ON signing_keys(expires_at);
@@ -0,0 +1,24 @@
+-- Logins, one per refresh token family, with the device they came from.
+-- last_seen_at and expires_at move on with every refresh.
+CREATE TABLE sessions (
+    id NVARCHAR(36) PRIMARY KEY,
+    user_id INT NOT NULL FOREIGN KEY REFERENCES users(id) ON DELETE CASCADE,
+    device_name NVARCHAR(100) NOT NULL DEFAULT '',
+    user_agent NVARCHAR(500) NOT NULL DEFAULT '',
+    ip_address NVARCHAR(45) NOT NULL DEFAULT '',
+    created_at DATETIME2 NOT NULL DEFAULT GETDATE(),
+    last_seen_at DATETIME2 NOT NULL,
+    expires_at DATETIME2 NOT NULL,
+    revoked_at DATETIME2 NULL
+);
+
+CREATE INDEX IX_sessions_user_id ON sessions(user_id);
+GO
+
+-- Existing logins become sessions from an unknown device
+INSERT INTO sessions (id, user_id, created_at, last_seen_at, expires_at)
+SELECT family_id, MIN(user_id), MIN(created_at), MAX(created_at), MAX(expires_at)
+FROM refresh_tokens
+WHERE revoked_at IS NULL
+GROUP BY family_id
+HAVING MAX(expires_at) > GETDATE();
@@ -0,0 +1,6 @@
+-- The user who acted as the actor of entries recorded under impersonation
+ALTER TABLE audit_logs ADD
+    impersonator_id INT NULL;
+GO
+
+CREATE INDEX IX_audit_logs_impersonator_id ON audit_logs(impersonator_id);
@@ -0,0 +1,9 @@
+-- Policy conditions now deny access when they do not hold. The seeded
+-- transaction amount limit only has an amount to check on create, so it
+-- would otherwise deny admins every other transaction action.
+UPDATE policies
+SET actions = 'create', updated_at = GETDATE()
+WHERE resource = 'transaction'
+    AND actions = 'create,read,update,delete'
+    AND roles = 'admin,super_admin'
+    AND conditions = '{"amount_limit":"amount \u003c= 1000000"}';
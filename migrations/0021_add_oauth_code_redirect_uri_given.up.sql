@@ -0,0 +1,4 @@
+-- Whether the authorization request included redirect_uri, which the token
+-- request must then repeat. Codes issued before are held to that.
+ALTER TABLE oauth_authorization_codes ADD
+    redirect_uri_given BIT NOT NULL DEFAULT 1;
@@ -0,0 +1,72 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// SQL Server error numbers for unique constraint and unique index
+// violations, and for a transaction chosen as a deadlock victim
+const (
+	errUniqueConstraint = 2627
+	errUniqueIndex      = 2601
+	errDeadlockVictim   = 1205
+)
+
+// WithTransaction runs fn inside a database transaction. The transaction is
+// committed when fn returns nil and rolled back otherwise.
+func WithTransaction(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	if err := fn(tx); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("%w (rollback failed: %v)", err, rbErr)
+		}
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// IsUniqueViolation reports whether err was caused by a unique constraint or
+// unique index violation
+func IsUniqueViolation(err error) bool {
+	var sqlErr interface{ SQLErrorNumber() int32 }
+	if !errors.As(err, &sqlErr) {
+		return false
+	}
+
+	number := sqlErr.SQLErrorNumber()
+	return number == errUniqueConstraint || number == errUniqueIndex
+}
+
+// IsDeadlock reports whether err was caused by the transaction being chosen
+// as a deadlock victim and rolled back. Running it again may succeed.
+func IsDeadlock(err error) bool {
+	var sqlErr interface{ SQLErrorNumber() int32 }
+	if !errors.As(err, &sqlErr) {
+		return false
+	}
+
+	return sqlErr.SQLErrorNumber() == errDeadlockVictim
+}
+
+// Executor is implemented by both *sql.DB and *sql.Tx so repositories can run
+// the same queries inside or outside a transaction
+type Executor interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
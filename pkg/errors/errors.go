@@ -15,10 +15,14 @@ type ErrorCode string
 
 const (
 	// Authentication errors
-	ErrorCodeUnauthorized       ErrorCode = "UNAUTHORIZED"
-	ErrorCodeInvalidCredentials ErrorCode = "INVALID_CREDENTIALS"
-	ErrorCodeTokenExpired       ErrorCode = "TOKEN_EXPIRED"
-	ErrorCodeInsufficientRole   ErrorCode = "INSUFFICIENT_ROLE"
+	ErrorCodeUnauthorized          ErrorCode = "UNAUTHORIZED"
+	ErrorCodeInvalidCredentials    ErrorCode = "INVALID_CREDENTIALS"
+	ErrorCodeTokenExpired          ErrorCode = "TOKEN_EXPIRED"
+	ErrorCodeInsufficientRole      ErrorCode = "INSUFFICIENT_ROLE"
+	ErrorCodeMFARequired           ErrorCode = "MFA_REQUIRED"
+	ErrorCodeInvalidMFACode        ErrorCode = "INVALID_MFA_CODE"
+	ErrorCodeEmailNotVerified      ErrorCode = "EMAIL_NOT_VERIFIED"
+	ErrorCodeImpersonationReadOnly ErrorCode = "IMPERSONATION_READ_ONLY"
 
 	// Validation errors
 	ErrorCodeValidationFailed ErrorCode = "VALIDATION_FAILED"
@@ -30,6 +34,7 @@ const (
 	ErrorCodeUserNotFound        ErrorCode = "USER_NOT_FOUND"
 	ErrorCodeTransactionFailed   ErrorCode = "TRANSACTION_FAILED"
 	ErrorCodeDuplicateResource   ErrorCode = "DUPLICATE_RESOURCE"
+	ErrorCodeResourceNotFound    ErrorCode = "RESOURCE_NOT_FOUND"
 
 	// System errors
 	ErrorCodeInternalError        ErrorCode = "INTERNAL_ERROR"
@@ -127,3 +132,27 @@ func InsufficientBalance(message string) *AppError {
 func RateLimitExceeded(message string) *AppError {
 	return NewAppError(ErrorCodeRateLimitExceeded, message, http.StatusTooManyRequests)
 }
+
+func InsufficientRole(message string) *AppError {
+	return NewAppError(ErrorCodeInsufficientRole, message, http.StatusForbidden)
+}
+
+func ResourceNotFound(message string) *AppError {
+	return NewAppError(ErrorCodeResourceNotFound, message, http.StatusNotFound)
+}
+
+func MFARequired(message string) *AppError {
+	return NewAppError(ErrorCodeMFARequired, message, http.StatusForbidden)
+}
+
+func InvalidMFACode(message string) *AppError {
+	return NewAppError(ErrorCodeInvalidMFACode, message, http.StatusForbidden)
+}
+
+func EmailNotVerified(message string) *AppError {
+	return NewAppError(ErrorCodeEmailNotVerified, message, http.StatusForbidden)
+}
+
+func ImpersonationReadOnly(message string) *AppError {
+	return NewAppError(ErrorCodeImpersonationReadOnly, message, http.StatusForbidden)
+}
@@ -0,0 +1,16 @@
+package jwt
+
+import "context"
+
+type contextKey struct{}
+
+// WithClaims returns a copy of ctx carrying the claims of the request's token
+func WithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, contextKey{}, claims)
+}
+
+// ClaimsFromContext returns the claims carried by ctx, or nil if there are none
+func ClaimsFromContext(ctx context.Context) *Claims {
+	claims, _ := ctx.Value(contextKey{}).(*Claims)
+	return claims
+}
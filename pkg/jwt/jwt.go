@@ -2,23 +2,91 @@ package jwt
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
-// Claims represents JWT claims
+// Token types
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+	// TokenTypeMFA is issued after a password check to users with two-factor
+	// authentication and only exchanged, with a code, for access tokens
+	TokenTypeMFA = "mfa"
+	// TokenTypeAPIKey marks the claims of a request authenticated with an
+	// API key. They are built by the server and never signed.
+	TokenTypeAPIKey = "api_key"
+	// TokenTypeOAuth is an access token issued to an OAuth client, restricted
+	// to the scopes granted to it
+	TokenTypeOAuth = "oauth_access"
+)
+
+// RefreshTokenDuration is how long a refresh token stays valid
+const RefreshTokenDuration = 7 * 24 * time.Hour
+
+// MFATokenDuration is how long a user has to enter their two-factor code
+const MFATokenDuration = 5 * time.Minute
+
+// ImpersonationTokenDuration is how long an impersonation token stays valid.
+// Impersonation tokens cannot be refreshed.
+const ImpersonationTokenDuration = 15 * time.Minute
+
+// ErrWrongTokenType is returned when a token of one type is used as another
+var ErrWrongTokenType = errors.New("wrong token type")
+
+// Claims represents JWT claims. Every token carries a unique ID (jti);
+// refresh tokens also carry the ID of the family they were rotated from,
+// which access tokens of the same login carry as their session.
 type Claims struct {
-	UserID   int    `json:"user_id"`
-	Username string `json:"username"`
-	Email    string `json:"email"`
-	Role     string `json:"role"`
+	UserID    int    `json:"user_id"`
+	Username  string `json:"username"`
+	Email     string `json:"email,omitempty"`
+	Role      string `json:"role,omitempty"`
+	TokenType string `json:"typ"`
+	FamilyID  string `json:"fid,omitempty"`
+	// Scope is the space-separated list of scopes a scoped credential is
+	// restricted to
+	Scope string `json:"scope,omitempty"`
+	// ClientID is the OAuth client a token was issued to
+	ClientID string `json:"cid,omitempty"`
+	// Actor is the user acting as the subject of an impersonation token
+	Actor *Actor `json:"act,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// JWTService provides JWT token operations
+// Actor identifies who acts on behalf of the subject of a token (RFC 8693,
+// section 4.1)
+type Actor struct {
+	UserID   int    `json:"user_id"`
+	Username string `json:"sub"`
+}
+
+// Impersonated reports whether the claims belong to a user impersonated by
+// another one
+func (c *Claims) Impersonated() bool {
+	return c.Actor != nil
+}
+
+// Scoped reports whether the claims belong to a credential restricted to
+// its scopes rather than to an interactive login
+func (c *Claims) Scoped() bool {
+	return c.TokenType == TokenTypeAPIKey || c.TokenType == TokenTypeOAuth
+}
+
+// Scopes returns the scopes of the claims
+func (c *Claims) Scopes() []string {
+	return strings.Fields(c.Scope)
+}
+
+// JWTService provides JWT token operations. Tokens are signed with HS256 and
+// a shared secret, or with the asymmetric keys of a KeyRing.
 type JWTService struct {
 	secretKey     []byte
+	keys          *KeyRing
 	tokenDuration time.Duration
 }
 
@@ -30,14 +98,42 @@ func NewJWTService(secretKey string, tokenDuration time.Duration) *JWTService {
 	}
 }
 
+// NewJWTServiceWithKeys creates a JWT service that signs tokens with the
+// current key of keys and accepts tokens signed by any of them. Tokens
+// signed with a shared secret are refused.
+func NewJWTServiceWithKeys(keys *KeyRing, tokenDuration time.Duration) *JWTService {
+	return &JWTService{
+		keys:          keys,
+		tokenDuration: tokenDuration,
+	}
+}
+
+// JWKS returns the public keys tokens are verified with. It is empty for
+// services signing with a shared secret, which must never be published.
+func (j *JWTService) JWKS() *JWKS {
+	if j.keys == nil {
+		return &JWKS{Keys: []JWK{}}
+	}
+	return j.keys.JWKS()
+}
+
 // GenerateToken generates a new JWT token
 func (j *JWTService) GenerateToken(userID int, username, email, role string) (string, error) {
+	return j.GenerateSessionToken(userID, username, email, role, "")
+}
+
+// GenerateSessionToken generates an access token for the session with
+// sessionID, so revoking the session revokes the token
+func (j *JWTService) GenerateSessionToken(userID int, username, email, role, sessionID string) (string, error) {
 	claims := &Claims{
-		UserID:   userID,
-		Username: username,
-		Email:    email,
-		Role:     role,
+		UserID:    userID,
+		Username:  username,
+		Email:     email,
+		Role:      role,
+		TokenType: TokenTypeAccess,
+		FamilyID:  sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(j.tokenDuration)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
@@ -46,17 +142,20 @@ func (j *JWTService) GenerateToken(userID int, username, email, role string) (st
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(j.secretKey)
+	return j.sign(claims)
 }
 
-// GenerateRefreshToken generates a refresh token
-func (j *JWTService) GenerateRefreshToken(userID int, username string) (string, error) {
+// GenerateRefreshToken generates a refresh token belonging to familyID and
+// returns it with its claims
+func (j *JWTService) GenerateRefreshToken(userID int, username, familyID string) (string, *Claims, error) {
 	claims := &Claims{
-		UserID:   userID,
-		Username: username,
+		UserID:    userID,
+		Username:  username,
+		TokenType: TokenTypeRefresh,
+		FamilyID:  familyID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(7 * 24 * time.Hour)), // 7 days
+			ID:        uuid.New().String(),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(RefreshTokenDuration)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 			Issuer:    "gofintech-api",
@@ -64,18 +163,137 @@ func (j *JWTService) GenerateRefreshToken(userID int, username string) (string,
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(j.secretKey)
+	signed, err := j.sign(claims)
+	if err != nil {
+		return "", nil, err
+	}
+	return signed, claims, nil
 }
 
-// ValidateToken validates a JWT token and returns claims
-func (j *JWTService) ValidateToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
+// GenerateMFAToken generates the token that stands between the password and
+// the two-factor step of a login
+func (j *JWTService) GenerateMFAToken(userID int, username string) (string, error) {
+	claims := &Claims{
+		UserID:    userID,
+		Username:  username,
+		TokenType: TokenTypeMFA,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(MFATokenDuration)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			NotBefore: jwt.NewNumericDate(time.Now()),
+			Issuer:    "gofintech-api",
+			Subject:   username,
+		},
+	}
+
+	return j.sign(claims)
+}
+
+// GenerateOAuthToken generates an access token for an OAuth client acting as
+// a user, restricted to scope, and returns it with its claims
+func (j *JWTService) GenerateOAuthToken(userID int, username, email, role, clientID, scope string) (string, *Claims, error) {
+	claims := &Claims{
+		UserID:    userID,
+		Username:  username,
+		Email:     email,
+		Role:      role,
+		TokenType: TokenTypeOAuth,
+		Scope:     scope,
+		ClientID:  clientID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(j.tokenDuration)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			NotBefore: jwt.NewNumericDate(time.Now()),
+			Issuer:    "gofintech-api",
+			Subject:   username,
+		},
+	}
+
+	signed, err := j.sign(claims)
+	if err != nil {
+		return "", nil, err
+	}
+	return signed, claims, nil
+}
+
+// GenerateImpersonationToken generates an access token for a user acting as
+// another one, carrying the acting user in the act claim, and returns it with
+// its claims
+func (j *JWTService) GenerateImpersonationToken(userID int, username, email, role string, actor *Actor) (string, *Claims, error) {
+	claims := &Claims{
+		UserID:    userID,
+		Username:  username,
+		Email:     email,
+		Role:      role,
+		TokenType: TokenTypeAccess,
+		Actor:     actor,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ImpersonationTokenDuration)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			NotBefore: jwt.NewNumericDate(time.Now()),
+			Issuer:    "gofintech-api",
+			Subject:   username,
+		},
+	}
+
+	signed, err := j.sign(claims)
+	if err != nil {
+		return "", nil, err
+	}
+	return signed, claims, nil
+}
+
+// TokenDuration returns how long access tokens stay valid
+func (j *JWTService) TokenDuration() time.Duration {
+	return j.tokenDuration
+}
+
+// sign signs claims with the secret, or with the current key and its ID in
+// the kid header
+func (j *JWTService) sign(claims *Claims) (string, error) {
+	if j.keys == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(j.secretKey)
+	}
+
+	key, err := j.keys.Current()
+	if err != nil {
+		return "", err
+	}
+	method := jwt.GetSigningMethod(key.Algorithm)
+	if method == nil {
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, key.Algorithm)
+	}
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.PrivateKey)
+}
+
+// verificationKey returns the key token is verified with
+func (j *JWTService) verificationKey(token *jwt.Token) (interface{}, error) {
+	if j.keys == nil {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, errors.New("unexpected signing method")
 		}
 		return j.secretKey, nil
-	})
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	key := j.keys.Lookup(kid)
+	if key == nil {
+		return nil, errors.New("unknown signing key")
+	}
+	if token.Method.Alg() != key.Algorithm {
+		return nil, errors.New("unexpected signing method")
+	}
+	return key.PublicKey(), nil
+}
+
+// ValidateToken validates a JWT token and returns claims
+func (j *JWTService) ValidateToken(tokenString string) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, j.verificationKey)
 
 	if err != nil {
 		return nil, err
@@ -88,6 +306,45 @@ func (j *JWTService) ValidateToken(tokenString string) (*Claims, error) {
 	return nil, errors.New("invalid token")
 }
 
+// ValidateAccessToken validates an access token and returns its claims
+func (j *JWTService) ValidateAccessToken(tokenString string) (*Claims, error) {
+	return j.validateTokenType(tokenString, TokenTypeAccess)
+}
+
+// ValidateResourceToken validates a token that grants access to resources,
+// either an access token or an OAuth access token, and returns its claims
+func (j *JWTService) ValidateResourceToken(tokenString string) (*Claims, error) {
+	claims, err := j.ValidateToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.TokenType != TokenTypeAccess && claims.TokenType != TokenTypeOAuth {
+		return nil, ErrWrongTokenType
+	}
+	return claims, nil
+}
+
+// ValidateRefreshToken validates a refresh token and returns its claims
+func (j *JWTService) ValidateRefreshToken(tokenString string) (*Claims, error) {
+	return j.validateTokenType(tokenString, TokenTypeRefresh)
+}
+
+// ValidateMFAToken validates an MFA token and returns its claims
+func (j *JWTService) ValidateMFAToken(tokenString string) (*Claims, error) {
+	return j.validateTokenType(tokenString, TokenTypeMFA)
+}
+
+func (j *JWTService) validateTokenType(tokenString, tokenType string) (*Claims, error) {
+	claims, err := j.ValidateToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.TokenType != tokenType {
+		return nil, ErrWrongTokenType
+	}
+	return claims, nil
+}
+
 // ExtractUserID extracts user ID from token
 func (j *JWTService) ExtractUserID(tokenString string) (int, error) {
 	claims, err := j.ValidateToken(tokenString)
@@ -0,0 +1,218 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Signing algorithms. HS256 signs with the shared secret; the others sign
+// with keys from a KeyRing whose public halves can be published.
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// rsaKeyBits is the size of generated RSA keys
+const rsaKeyBits = 2048
+
+var (
+	// ErrUnsupportedAlgorithm is returned for signing algorithms other than
+	// RS256 and EdDSA
+	ErrUnsupportedAlgorithm = errors.New("unsupported signing algorithm")
+	// ErrNoSigningKey is returned when a key ring has no key to sign with yet
+	ErrNoSigningKey = errors.New("no signing key available")
+)
+
+// SigningKey is an asymmetric key tokens are signed with, identified in
+// their kid header
+type SigningKey struct {
+	ID         string
+	Algorithm  string
+	PrivateKey crypto.Signer
+	// ActivatesAt is when the key starts signing tokens. Keys are published
+	// before they activate so verifiers can fetch them in time.
+	ActivatesAt time.Time
+	// ExpiresAt is when tokens signed by the key stop being accepted; nil
+	// while the key is current
+	ExpiresAt *time.Time
+	CreatedAt time.Time
+}
+
+// PublicKey returns the key tokens signed by k are verified with
+func (k *SigningKey) PublicKey() crypto.PublicKey {
+	return k.PrivateKey.Public()
+}
+
+// GenerateSigningKey generates a key for algorithm with a random ID
+func GenerateSigningKey(algorithm string) (*SigningKey, error) {
+	var private crypto.Signer
+	var err error
+	switch algorithm {
+	case AlgorithmRS256:
+		private, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	case AlgorithmEdDSA:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, algorithm)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate signing key: %w", err)
+	}
+
+	now := time.Now()
+	return &SigningKey{
+		ID:          uuid.New().String(),
+		Algorithm:   algorithm,
+		PrivateKey:  private,
+		ActivatesAt: now,
+		CreatedAt:   now,
+	}, nil
+}
+
+// MarshalPrivateKey encodes key as a PKCS #8 PEM block
+func MarshalPrivateKey(key crypto.Signer) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal signing key: %w", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// ParsePrivateKey decodes a PKCS #8 PEM block written by MarshalPrivateKey
+func ParsePrivateKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, errors.New("failed to parse signing key: no PKCS #8 PEM block")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse signing key: %w", err)
+	}
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		return key, nil
+	case ed25519.PrivateKey:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedAlgorithm, key)
+	}
+}
+
+// KeyRing holds the key tokens are signed with and the keys they are
+// verified with. It is safe for concurrent use and replaced as a whole when
+// keys rotate.
+type KeyRing struct {
+	mu      sync.RWMutex
+	current *SigningKey
+	keys    map[string]*SigningKey
+	ordered []*SigningKey
+}
+
+// NewKeyRing creates an empty key ring
+func NewKeyRing() *KeyRing {
+	return &KeyRing{keys: make(map[string]*SigningKey)}
+}
+
+// Set replaces the keys of the ring. current signs new tokens; keys, which
+// should include current, verify them.
+func (r *KeyRing) Set(current *SigningKey, keys []*SigningKey) {
+	byID := make(map[string]*SigningKey, len(keys))
+	for _, key := range keys {
+		byID[key.ID] = key
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.current = current
+	r.keys = byID
+	r.ordered = keys
+}
+
+// Current returns the key new tokens are signed with
+func (r *KeyRing) Current() (*SigningKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.current == nil {
+		return nil, ErrNoSigningKey
+	}
+	return r.current, nil
+}
+
+// Lookup returns the key with id, or nil if it is unknown or expired
+func (r *KeyRing) Lookup(id string) *SigningKey {
+	r.mu.RLock()
+	key := r.keys[id]
+	r.mu.RUnlock()
+
+	if key == nil || (key.ExpiresAt != nil && !time.Now().Before(*key.ExpiresAt)) {
+		return nil
+	}
+	return key
+}
+
+// JWKS returns the public keys of the ring as a JSON Web Key Set
+func (r *KeyRing) JWKS() *JWKS {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	set := &JWKS{Keys: make([]JWK, 0, len(r.ordered))}
+	now := time.Now()
+	for _, key := range r.ordered {
+		if key.ExpiresAt != nil && !now.Before(*key.ExpiresAt) {
+			continue
+		}
+		if jwk, ok := toJWK(key); ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	return set
+}
+
+// JWKS is a JSON Web Key Set (RFC 7517)
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWK is the public half of a signing key (RFC 7517, RFC 8037)
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+	// N and E are the modulus and exponent of RSA keys
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Curve and X describe Ed25519 keys
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+}
+
+func toJWK(key *SigningKey) (JWK, bool) {
+	jwk := JWK{Use: "sig", Algorithm: key.Algorithm, KeyID: key.ID}
+	switch public := key.PublicKey().(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	default:
+		return JWK{}, false
+	}
+	return jwk, true
+}
@@ -33,6 +33,16 @@ func NewConnection(addr string, password string, db int) (*RedisClient, error) {
 	return &RedisClient{client}, nil
 }
 
+// NewConnectionFromURL connects using a redis:// URL such as the REDIS_URL setting
+func NewConnectionFromURL(redisURL string) (*RedisClient, error) {
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis url: %w", err)
+	}
+
+	return NewConnection(opts.Addr, opts.Password, opts.DB)
+}
+
 func (r *RedisClient) Close() error {
 	return r.Client.Close()
 } 
\ No newline at end of file
@@ -73,6 +73,12 @@ func (s *Server) Start() error {
 	return nil
 }
 
+// RegisterOnShutdown registers a function to call when the server starts
+// shutting down, e.g. to close long-lived connections such as event streams
+func (s *Server) RegisterOnShutdown(f func()) {
+	s.httpServer.RegisterOnShutdown(f)
+}
+
 // Stop gracefully stops the server
 func (s *Server) Stop(ctx context.Context) error {
 	return s.httpServer.Shutdown(ctx)